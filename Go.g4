@@ -1,29 +1,73 @@
 grammar Go;
 
-program: package functionDefinition* EOF;
+program: package importDeclaration* (typeDeclaration | functionDefinition)* EOF;
 
 package: 'package' NAME;
-typename: NAME;
+importDeclaration: 'import' (importSpec | '(' importSpec* ')');
+importSpec: NAME? STRING;
 
-functionDefinition: 'func' NAME '(' arguments? ')' typename? block;
+typeDeclaration: 'type' (typeSpec | '(' (typeSpec ';'?)* ')');
+typeSpec: typeAlias | typeDefinition;
+typeAlias: NAME '=' typename;
+typeDefinition: NAME typeParameters? typename;
+
+typeParameters: '[' typeParameterDeclaration (',' typeParameterDeclaration)* ']';
+typeParameterDeclaration: NAME (',' NAME)* typeConstraint;
+typeConstraint: typeTerm ('|' typeTerm)*;
+typeTerm: '~'? typename;
+
+typename: (qualifiedName typeArguments?) | sliceType | arrayType | mapType | channelType | functionType | pointerType | structType | interfaceType | ('(' typename ')');
+typeArguments: '[' typeList ']';
+sliceType: '[' ']' typename;
+arrayType: '[' (NUMBER | ELLIPSIS) ']' typename;
+mapType: 'map' '[' typename ']' typename;
+channelType: ('chan' '<-'?) typename | '<-' 'chan' typename;
+functionType: 'func' '(' parameterTypes? ')' resultTypes?;
+pointerType: '*' typename;
+structType: 'struct' '{' (fieldDeclaration ';'?)* '}';
+fieldDeclaration: NAME (',' NAME)* typename STRING?;
+interfaceType: 'interface' '{' (interfaceElement ';'?)* '}';
+interfaceElement: methodSpecification | typeConstraint;
+methodSpecification: NAME '(' parameterTypes? ')' resultTypes?;
+typeList: typename (',' typename)*;
+parameterTypes: (typeList (',' variadicType)?) | variadicType;
+variadicType: ELLIPSIS typename;
+resultTypes: ('(' typeList ')') | typename;
+
+functionDefinition: 'func' receiver? NAME typeParameters? '(' arguments? ')' results? block;
+receiver: '(' NAME typename ')';
 block: '{' line*'}';
 
-arguments: NAME typename (',' NAME typename)*;
+arguments: parameterDeclaration (',' parameterDeclaration)*;
+parameterDeclaration: NAME (',' NAME)* ELLIPSIS? typename;
+results: ('(' (arguments | typeList) ')') | typename;
 
-line: ((variableDefinition | expression | assigment | functionReturn | break) ';') | expressionIF | expressionFOR;
+line: ((variableDefinition | shortVariableDefinition | expression | assigment | functionReturn | break | sendStatement | deferStatement | goStatement | gotoStatement) ';') | expressionIF | expressionFOR | selectStatement | labeledStatement;
 
 expressionIF: 'if' expression block expressionELSE?;
 expressionELSE: 'else' (block | expressionIF);
-expressionFOR: 'for' expression? block;
+expressionFOR: 'for' (rangeClause | expression)? block;
+rangeClause: ((NAME (',' NAME)? DEFINE) | (assigmentTarget (',' assigmentTarget)? '='))? 'range' expression;
 
 break: 'break';
+gotoStatement: 'goto' NAME;
+labeledStatement: NAME ':' (line | ';');
+deferStatement: 'defer' expression;
+goStatement: 'go' expression;
+sendStatement: expression '<-' expression;
+selectStatement: 'select' '{' commClause* '}';
+commClause: commCase ':' line*;
+commCase: ('case' (sendStatement | receiveClause)) | 'default';
+receiveClause: ((NAME (',' NAME)? DEFINE) | (assigmentTarget (',' assigmentTarget)? '='))? expression;
 
 variableDefinition: 'var' NAME typename;
+shortVariableDefinition: NAME (',' NAME)* DEFINE expression (',' expression)*;
 // variableDefinitionWithValue: 'var' NAME typename '=' expression;
 // variableDefinitionWithValueShort: NAME ':=' expression;
 
-functionReturn: 'return' expression?;
-assigment: NAME '=' expression;
+functionReturn: 'return' (expression (',' expression)*)?;
+assigment: assigmentTarget (',' assigmentTarget)* '=' expression (',' expression)*;
+assigmentTarget: expression;
 
 expression: expressionAdd;
 expressionAdd: expressionSub ('+' expressionSub)*;
@@ -33,18 +77,37 @@ expressionDiv: expressionLogic ('/' expressionLogic)*;
 expressionLogic: ('!' expressionLogic) | expressionLogicOr;
 expressionLogicOr: expressionLogicAnd ('||' expressionLogicAnd)*;
 expressionLogicAnd: compareExpression ('&&' compareExpression)*;
-compareExpression: simpleExpresion (COMPARETOKEN simpleExpresion)?;
-simpleExpresion: ('(' expression ')') | callExpression | variableUsing | numberUsing | stringUsing | boolUsing;
-callExpression: NAME '(' (expression (',' expression)*)? ')';
+compareExpression: unaryExpression (COMPARETOKEN unaryExpression)?;
+unaryExpression: (('&' | '*' | '<-') unaryExpression) | simpleExpresion;
+simpleExpresion: operand (selectorExpression | indexExpression | sliceExpression | callExpression)*;
+operand: ('(' expression ')') | compositeLiteral | literalType | functionLiteral | variableUsing | floatUsing | numberUsing | runeUsing | stringUsing | boolUsing;
+// the type literals are the arguments of the built-in functions and the conversions
+literalType: sliceType | arrayType | mapType | channelType | structType | interfaceType;
+functionLiteral: 'func' '(' arguments? ')' results? block;
+callExpression: '(' (expression (',' expression)* ELLIPSIS?)? ')';
+indexExpression: '[' indexElement (',' indexElement)* ']';
+indexElement: expression | typename;
+sliceExpression: '[' expression? ':' expression? (':' expression)? ']';
+selectorExpression: '.' NAME;
+qualifiedName: (NAME '.')? NAME;
+
+compositeLiteral: (sliceType | arrayType | mapType | structType | (qualifiedName typeArguments?)) '{' (keyedElement (',' keyedElement)* ','?)? '}';
+keyedElement: (NAME ':' expression) | (expression (':' expression)?);
 
 boolUsing:      BOOL;
 variableUsing:  NAME;
+floatUsing:     FLOAT;
 numberUsing:    NUMBER;
+runeUsing:      RUNE;
 stringUsing:    STRING;
 
 BOOL: ('true' | 'false');
-STRING: '"' .*? '"';
+STRING: '"' (~["\\\r\n] | '\\' ~[\r\n])* '"' | '`' ~[`]* '`';
+RUNE: '\'' (~['\\\r\n] | '\\' ~[\r\n])+ '\'';
+ELLIPSIS: '...';
+DEFINE: ':=';
 COMPARETOKEN: ('==' | '<=' | '>=' | '<' | '>' | '!=');
+FLOAT: [-+]?[0-9]+ '.' [0-9]+;
 NUMBER: [-+]?[0-9]+;
-NAME:   [a-zA-Z][a-zA-Z0-9]*;
+NAME:   [a-zA-Z_][a-zA-Z0-9_]*;
 EMPTY:  [ \t\r\n]+ -> skip;
@@ -0,0 +1,55 @@
+package main
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "atomic",
+		Path:    "sync/atomic",
+		Declare: declareAtomic,
+	})
+}
+
+// declareAtomic declares the atomic types, the goroutines are not switched inside the operations, so the plain operations are atomic.
+func declareAtomic(prog *Program, pkg *Package) {
+	for _, value := range []struct {
+		name string
+		Type Type
+	}{{"Int64", IntType}, {"Int32", Int32Type}} {
+		atomic := declareAtomicValue(prog, pkg, value.name, value.Type)
+		atomicPointer := &PointerType{Elem: atomic}
+
+		prog.declareMethod(atomic, "Add", signature([]Type{atomicPointer, value.Type}, value.Type), hostMethod(func(receiver *StructValue, args ...any) []any {
+			sum, _ := AddAny(*receiver.fields[0], args[0])
+			*receiver.fields[0] = sum
+			return []any{sum}
+		}))
+	}
+
+	declareAtomicValue(prog, pkg, "Bool", BoolType)
+}
+
+// declareAtomicValue declares the type of the atomic value with the methods common for all the atomic types.
+func declareAtomicValue(prog *Program, pkg *Package, name string, valueType Type) *NamedType {
+	atomic := prog.declareType(pkg, name, hostStruct(pkg, &Field{Name: "v", Type: valueType}))
+	atomicPointer := &PointerType{Elem: atomic}
+
+	prog.declareMethod(atomic, "Load", signature([]Type{atomicPointer}, valueType), hostGetter(0))
+	prog.declareMethod(atomic, "Store", signature([]Type{atomicPointer, valueType}), hostMethod(func(receiver *StructValue, args ...any) []any {
+		*receiver.fields[0] = args[0]
+		return nil
+	}))
+	prog.declareMethod(atomic, "Swap", signature([]Type{atomicPointer, valueType}, valueType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		old := *receiver.fields[0]
+		*receiver.fields[0] = args[0]
+		return []any{old}
+	}))
+	prog.declareMethod(atomic, "CompareAndSwap", signature([]Type{atomicPointer, valueType, valueType}, BoolType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		if *receiver.fields[0] != args[0] {
+			return []any{false}
+		}
+
+		*receiver.fields[0] = args[1]
+		return []any{true}
+	}))
+
+	return atomic
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+	"unsafe"
+)
+
+// checkInterval is the count of the steps between the checks of the context,
+// it is too slow to be checked on each step.
+const checkInterval = 1024
+
+// slotSize is the size of the slot of the value, the slices, the maps and the structs keep the values of the script in them.
+const slotSize = uint64(unsafe.Sizeof(any(nil)))
+
+// StepLimitError stops the script which has executed more operations than it may.
+type StepLimitError struct {
+	Limit int64
+}
+
+func (e StepLimitError) Error() string {
+	return fmt.Sprintf("step limit exceeded: the script has executed more than %v steps", e.Limit)
+}
+
+// DeadlineError stops the script which runs past the deadline of its context.
+type DeadlineError struct {
+	Deadline time.Time
+}
+
+func (e DeadlineError) Error() string {
+	return "deadline exceeded: the script has run out of its time"
+}
+
+func (DeadlineError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// CanceledError stops the script whose context is canceled.
+type CanceledError struct {
+	Cause error
+}
+
+func (e CanceledError) Error() string {
+	return fmt.Sprintf("execution canceled: %v", e.Cause)
+}
+
+func (e CanceledError) Unwrap() error {
+	return e.Cause
+}
+
+// HeapLimitError stops the script which allocates more memory than its budget, Heap is what it would have allocated.
+type HeapLimitError struct {
+	Limit, Heap uint64
+}
+
+func (e HeapLimitError) Error() string {
+	return fmt.Sprintf("heap limit exceeded: the script allocates %v bytes of the heap, the limit is %v bytes", e.Heap, e.Limit)
+}
+
+// fatal reports that the error stops the program at once: the deferred calls don't run and it can't be recovered.
+func fatal(err error) bool {
+	switch err.(type) {
+	case FatalError, StepLimitError, DeadlineError, CanceledError, HeapLimitError, GoroutinePanicError, ExitError:
+		return true
+	}
+
+	return false
+}
+
+// budget is what the script has spent of its limits.
+type budget struct {
+	ctx   context.Context
+	steps int64
+	// the step the limits are checked at next time
+	check int64
+	// the bytes the script has allocated, the memory the collector frees is not given back
+	heap uint64
+}
+
+// start starts the budget of the run with the context.
+func (prog *Program) start(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	prog.budget = budget{ctx: ctx}
+	prog.schedule()
+}
+
+// step counts the operation the script executes, the error is the limit it has exceeded.
+func (prog *Program) step() error {
+	prog.budget.steps++
+	if prog.budget.steps < prog.budget.check {
+		return nil
+	}
+
+	return prog.checkBudget()
+}
+
+func (prog *Program) checkBudget() error {
+	if prog.MaxSteps > 0 && prog.budget.steps > prog.MaxSteps {
+		return StepLimitError{Limit: prog.MaxSteps}
+	}
+	if err := prog.interrupted(); err != nil {
+		return err
+	}
+
+	prog.schedule()
+	// the goroutine which runs for long lets the other ones run too
+	return prog.yield()
+}
+
+// schedule sets the step of the next check, the step limit is checked exactly.
+func (prog *Program) schedule() {
+	prog.budget.check = prog.budget.steps + checkInterval
+	if prog.MaxSteps > 0 && prog.budget.check > prog.MaxSteps+1 {
+		prog.budget.check = prog.MaxSteps + 1
+	}
+}
+
+// interrupted is the error of the done context of the run.
+func (prog *Program) interrupted() error {
+	ctx := prog.budget.ctx
+	if ctx.Err() == nil {
+		return nil
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		deadline, _ := ctx.Deadline()
+		return DeadlineError{Deadline: deadline}
+	}
+	return CanceledError{Cause: context.Cause(ctx)}
+}
+
+// allocate charges the script for the memory before it is allocated, so the allocation beyond the budget never happens.
+func (prog *Program) allocate(size uint64) error {
+	if prog.MaxHeap == 0 {
+		return nil
+	}
+	if size > prog.MaxHeap-min(prog.budget.heap, prog.MaxHeap) {
+		return HeapLimitError{Limit: prog.MaxHeap, Heap: addSize(prog.budget.heap, size)}
+	}
+
+	prog.budget.heap += size
+	return nil
+}
+
+// add is AddAny which charges the script for the string it makes.
+func (prog *Program) add(x, y any) (any, error) {
+	if x, ok := x.(string); ok {
+		if y, ok := y.(string); ok {
+			err := prog.allocate(uint64(len(x)) + uint64(len(y)))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return AddAny(x, y)
+}
+
+// allocateAppend charges the script for the new array of the slice the elements don't fit in.
+func (prog *Program) allocateAppend(slice []any, count int) error {
+	if len(slice)+count <= cap(slice) {
+		return nil
+	}
+
+	return prog.allocate(mulSize(uint64(grownCap(cap(slice), len(slice)+count)), slotSize))
+}
+
+// grownCap is the capacity append grows the slice to, the way the runtime of Go grows it.
+func grownCap(capacity, needed int) int {
+	if needed > 2*capacity {
+		return needed
+	}
+	if capacity < 256 {
+		return 2 * capacity
+	}
+
+	for capacity < needed {
+		capacity += (capacity + 3*256) / 4
+	}
+	return capacity
+}
+
+// valueSize is the memory the zero value of the type takes besides its slot.
+func valueSize(Type Type) uint64 {
+	if Type == nil {
+		return 0
+	}
+
+	switch Type := Type.Underlying().(type) {
+	case *BasicType:
+		return uint64(Type.reflectType.Size())
+	case *StructType:
+		size := uint64(0)
+		for _, field := range Type.Fields {
+			// the field is the cell the pointer to it shares
+			size = addSize(size, slotSize+uint64(unsafe.Sizeof(&size))+valueSize(field.Type))
+		}
+		return size
+	case *ArrayType:
+		return mulSize(uint64(Type.Len), slotSize+valueSize(Type.Elem))
+	}
+
+	return 0
+}
+
+// addSize and mulSize stop at the biggest size instead of overflowing.
+func addSize(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+func mulSize(a, b uint64) uint64 {
+	if a != 0 && b > math.MaxUint64/a {
+		return math.MaxUint64
+	}
+	return a * b
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const spin = `package main
+
+func main() {
+	for {
+	}
+}
+`
+
+const grow = `package main
+
+func main() {
+	s := []int{};
+	for {
+		s = append(s, len(s));
+	}
+}
+`
+
+const oversized = `package main
+
+import "fmt"
+
+func main() {
+	s := make([]int, 1099511627776);
+	fmt.Println(len(s));
+}
+`
+
+const concatenate = `package main
+
+func main() {
+	s := "ab";
+	for {
+		s = s + s;
+	}
+}
+`
+
+func TestBudget(t *testing.T) {
+	stop := errors.New("stop")
+
+	for _, test := range []struct {
+		name   string
+		source string
+		// sets the limits of the program, the context is the one the program runs with
+		limit func(prog *Program) (context.Context, context.CancelFunc)
+		check func(t *testing.T, err error)
+	}{{
+		name:   "canceled",
+		source: spin,
+		limit: func(prog *Program) (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			time.AfterFunc(50*time.Millisecond, func() {
+				cancel(stop)
+			})
+			return ctx, func() { cancel(nil) }
+		},
+		check: func(t *testing.T, err error) {
+			if _, ok := err.(CanceledError); !ok {
+				t.Fatalf("the error is %T %q, CanceledError is expected", err, err)
+			}
+			if !errors.Is(err, stop) {
+				t.Errorf("the cause of %q is lost", err)
+			}
+		},
+	}, {
+		name:   "deadline",
+		source: spin,
+		limit: func(prog *Program) (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 50*time.Millisecond)
+		},
+		check: func(t *testing.T, err error) {
+			if _, ok := err.(DeadlineError); !ok {
+				t.Fatalf("the error is %T %q, DeadlineError is expected", err, err)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("%q is not context.DeadlineExceeded", err)
+			}
+		},
+	}, {
+		name:   "steps",
+		source: spin,
+		limit: func(prog *Program) (context.Context, context.CancelFunc) {
+			prog.MaxSteps = 10000
+			return context.WithCancel(context.Background())
+		},
+		check: func(t *testing.T, err error) {
+			limit, ok := err.(StepLimitError)
+			if !ok {
+				t.Fatalf("the error is %T %q, StepLimitError is expected", err, err)
+			}
+			if limit.Limit != 10000 {
+				t.Errorf("the limit is %v, 10000 is expected", limit.Limit)
+			}
+		},
+	}, {
+		name:   "heap",
+		source: grow,
+		limit: func(prog *Program) (context.Context, context.CancelFunc) {
+			prog.MaxHeap = 1 << 20
+			return context.WithTimeout(context.Background(), 10*time.Second)
+		},
+		check: func(t *testing.T, err error) {
+			limit, ok := err.(HeapLimitError)
+			if !ok {
+				t.Fatalf("the error is %T %q, HeapLimitError is expected", err, err)
+			}
+			if limit.Heap <= limit.Limit {
+				t.Errorf("the heap of %v bytes is within the limit of %v bytes", limit.Heap, limit.Limit)
+			}
+		},
+	}, {
+		name:   "make",
+		source: oversized,
+		limit: func(prog *Program) (context.Context, context.CancelFunc) {
+			prog.MaxHeap = 1 << 20
+			return context.WithTimeout(context.Background(), 10*time.Second)
+		},
+		check: func(t *testing.T, err error) {
+			limit, ok := err.(HeapLimitError)
+			if !ok {
+				t.Fatalf("the error is %T %q, HeapLimitError is expected", err, err)
+			}
+			// the slice is refused before it is made
+			if limit.Heap < 1<<40 {
+				t.Errorf("the heap of %v bytes is not the size of the slice", limit.Heap)
+			}
+		},
+	}, {
+		name:   "concatenation",
+		source: concatenate,
+		limit: func(prog *Program) (context.Context, context.CancelFunc) {
+			prog.MaxHeap = 1 << 20
+			return context.WithTimeout(context.Background(), 10*time.Second)
+		},
+		check: func(t *testing.T, err error) {
+			limit, ok := err.(HeapLimitError)
+			if !ok {
+				t.Fatalf("the error is %T %q, HeapLimitError is expected", err, err)
+			}
+			if limit.Heap <= limit.Limit {
+				t.Errorf("the heap of %v bytes is within the limit of %v bytes", limit.Heap, limit.Limit)
+			}
+		},
+	}} {
+		for _, backend := range backends {
+			t.Run(test.name+"/"+backend, func(t *testing.T) {
+				prog := compileScript(t, test.source)
+				prog.Backend = backend
+				ctx, cancel := test.limit(prog)
+				defer cancel()
+
+				err := prog.Execute(ctx)
+				test.check(t, err)
+				if !fatal(err) {
+					t.Errorf("%q doesn't stop the program at once", err)
+				}
+			})
+		}
+	}
+}
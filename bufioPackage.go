@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"reflect"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "bufio",
+		Path:    "bufio",
+		Imports: []string{"errors", "io"},
+		Declare: declareBufio,
+	})
+
+	hostSentinels[bufio.ErrTooLong] = "bufio.ErrTooLong"
+	hostSentinels[bufio.ErrFinalToken] = "bufio.ErrFinalToken"
+}
+
+func declareBufio(prog *Program, pkg *Package) {
+	reader := prog.lookupStdType("io", "Reader")
+	bytes := &SliceType{Elem: Uint8Type}
+
+	prog.RegisterValue(pkg.QualifiedName("ErrTooLong"), &PackageValue{Type: ErrorType, Value: prog.NewError(bufio.ErrTooLong.Error())})
+	prog.RegisterValue(pkg.QualifiedName("ErrFinalToken"), &PackageValue{Type: ErrorType, Value: prog.NewError(bufio.ErrFinalToken.Error())})
+	prog.declareConstant(pkg, "MaxScanTokenSize", UntypedIntType, bufio.MaxScanTokenSize)
+
+	splitFunc := prog.declareType(pkg, "SplitFunc", signature([]Type{bytes, BoolType}, IntType, bytes, ErrorType))
+	prog.declareHostFunctions(pkg, map[string]any{
+		"ScanBytes": bufio.ScanBytes,
+		"ScanLines": bufio.ScanLines,
+		"ScanRunes": bufio.ScanRunes,
+		"ScanWords": bufio.ScanWords,
+	})
+
+	scanner := prog.declareType(pkg, "Scanner", hostStruct(pkg, &Field{Name: "handle", Type: IntType}))
+	scannerPointer := &PointerType{Elem: scanner}
+
+	prog.declareFunction(pkg, "NewScanner", signature([]Type{reader}, scannerPointer), func(args ...any) ([]any, error) {
+		handle := len(prog.hostScanners) + 1
+		prog.hostScanners[handle] = bufio.NewScanner(prog.Reader(args[0]))
+
+		return []any{NewPointer(NewStructValue(handle))}, nil
+	})
+
+	// the host scanner panics on the misuse, the panics are the ones of the script
+	method := func(name string, signature *FunctionType, call func(scanner *bufio.Scanner, args ...any) []any) {
+		prog.declareMethod(scanner, name, signature, func(args ...any) (res []any, err error) {
+			defer catchScriptError(&err)
+
+			handle, err := hostGetter(0)(args[0])
+			if err != nil {
+				return nil, err
+			}
+			scanner, ok := prog.hostScanners[handle[0].(int)]
+			if !ok {
+				return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+			}
+
+			return call(scanner, args[1:]...), nil
+		})
+	}
+
+	method("Scan", signature([]Type{scannerPointer}, BoolType), func(scanner *bufio.Scanner, args ...any) []any {
+		return []any{scanner.Scan()}
+	})
+	method("Text", signature([]Type{scannerPointer}, StringType), func(scanner *bufio.Scanner, args ...any) []any {
+		return []any{scanner.Text()}
+	})
+	method("Bytes", signature([]Type{scannerPointer}, bytes), func(scanner *bufio.Scanner, args ...any) []any {
+		return []any{StringToSlice(string(scanner.Bytes()), Uint8Type)}
+	})
+	method("Err", signature([]Type{scannerPointer}, ErrorType), func(scanner *bufio.Scanner, args ...any) []any {
+		if err := scanner.Err(); err != nil {
+			return []any{prog.hostError(err)}
+		}
+		return []any{nil}
+	})
+	method("Buffer", signature([]Type{scannerPointer, bytes, IntType}), func(scanner *bufio.Scanner, args ...any) []any {
+		scanner.Buffer(make([]byte, 0, cap(args[0].([]any))), args[1].(int))
+		return nil
+	})
+	method("Split", signature([]Type{scannerPointer, splitFunc}), func(scanner *bufio.Scanner, args ...any) []any {
+		split := prog.toHost(args[0], reflect.TypeOf(bufio.SplitFunc(nil)))
+		scanner.Split(split.Interface().(bufio.SplitFunc))
+		return nil
+	})
+}
@@ -0,0 +1,569 @@
+package main
+
+import (
+	"fmt"
+)
+
+// builtin checks the call of the built-in function, which is compiled into its own instruction.
+type builtin func(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand)
+
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		"len":     builtinLen,
+		"cap":     builtinCap,
+		"append":  builtinAppend,
+		"copy":    builtinCopy,
+		"make":    builtinMake,
+		"new":     builtinNew,
+		"delete":  builtinDelete,
+		"close":   builtinClose,
+		"min":     builtinExtremum("min"),
+		"max":     builtinExtremum("max"),
+		"clear":   builtinClear,
+		"complex": builtinComplex,
+		"real":    builtinPart("real"),
+		"imag":    builtinPart("imag"),
+	}
+}
+
+// builtinCalls make the implementations of the built-in functions by their names, the type is the one of the values
+// they make. The calls keep the names and the types, so the compiled programs make the implementations again.
+var builtinCalls = map[string]func(Type Type) func(args []any) ([]any, error){
+	"cap": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			if ch, ok := args[0].(*ChannelValue); ok {
+				return []any{ch.capacity}, nil
+			}
+			slice, _ := args[0].([]any)
+			return []any{cap(slice)}, nil
+		}
+	},
+	"copy": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			dst, _ := args[0].([]any)
+			src, _ := args[1].([]any)
+
+			// the elements are cloned before the copying, as the slices may overlap
+			elements := make([]any, min(len(dst), len(src)))
+			for i := range elements {
+				elements[i] = CloneAny(src[i])
+			}
+
+			return []any{copy(dst, elements)}, nil
+		}
+	},
+	"make slice": func(elem Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			length := args[0].(int)
+			capacity := length
+			if len(args) == 2 {
+				capacity = args[1].(int)
+			}
+			if length < 0 {
+				return nil, fmt.Errorf("runtime error: makeslice: len out of range")
+			}
+			if capacity < length {
+				return nil, fmt.Errorf("runtime error: makeslice: cap out of range")
+			}
+
+			res := make([]any, length, capacity)
+			for i := range res {
+				res[i] = NewVariable(elem)
+			}
+			return []any{res}, nil
+		}
+	},
+	"make map": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			hint := 0
+			if len(args) == 1 {
+				hint = args[0].(int)
+			}
+			if hint < 0 {
+				return nil, fmt.Errorf("runtime error: makemap: size out of range")
+			}
+
+			return []any{make(map[any]any, hint)}, nil
+		}
+	},
+	"make chan": func(elem Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			capacity := 0
+			if len(args) == 1 {
+				capacity = args[0].(int)
+			}
+			if capacity < 0 {
+				return nil, fmt.Errorf("makechan: size out of range")
+			}
+
+			return []any{NewChannel(elem, capacity)}, nil
+		}
+	},
+	"new": func(Type Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			value := NewVariable(Type)
+			return []any{&value}, nil
+		}
+	},
+	// new(1) makes the variable with the copy of the value
+	"new value": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			value := CloneAny(args[0])
+			return []any{&value}, nil
+		}
+	},
+	"delete": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			// the deletion from the nil map does nothing
+			m, _ := args[0].(map[any]any)
+			delete(m, args[1])
+			return nil, nil
+		}
+	},
+	"min": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			res, err := ExtremumAny(args, false)
+			return []any{res}, err
+		}
+	},
+	"max": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			res, err := ExtremumAny(args, true)
+			return []any{res}, err
+		}
+	},
+	"clear map": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			m, _ := args[0].(map[any]any)
+			clear(m)
+			return nil, nil
+		}
+	},
+	"clear slice": func(elem Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			slice, _ := args[0].([]any)
+			for i := range slice {
+				slice[i] = NewVariable(elem)
+			}
+			return nil, nil
+		}
+	},
+	"complex": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			return []any{complex(args[0].(float64), args[1].(float64))}, nil
+		}
+	},
+	"real": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			return []any{real(args[0].(complex128))}, nil
+		}
+	},
+	"imag": func(Type) func(args []any) ([]any, error) {
+		return func(args []any) ([]any, error) {
+			return []any{imag(args[0].(complex128))}, nil
+		}
+	},
+}
+
+// builtinSize is the memory the call of make allocates, the negative sizes are reported by the call itself.
+func builtinSize(name string, Type Type, args []any) uint64 {
+	sizes := make([]uint64, len(args))
+	for i, arg := range args {
+		size, _ := arg.(int)
+		sizes[i] = uint64(max(size, 0))
+	}
+
+	switch name {
+	case "make slice":
+		// the elements beyond the length are made only when the slice grows over them
+		capacity := sizes[0]
+		if len(sizes) == 2 {
+			capacity = max(capacity, sizes[1])
+		}
+		return addSize(mulSize(capacity, slotSize), mulSize(sizes[0], valueSize(Type)))
+	case "make map":
+		if len(sizes) == 1 {
+			return mulSize(sizes[0], 2*slotSize)
+		}
+	case "make chan":
+		if len(sizes) == 1 {
+			return mulSize(sizes[0], slotSize+valueSize(Type))
+		}
+	}
+
+	return 0
+}
+
+// builtinCall calls the implementation of the built-in function.
+func (l *GoCompilerListener) builtinCall(name string, Type Type, arguments []Instruction) *BuiltinCallInstruction {
+	return &BuiltinCallInstruction{
+		program:   l.program,
+		name:      name,
+		Type:      Type,
+		arguments: arguments,
+		call:      builtinCalls[name](Type),
+	}
+}
+
+func invalidCall(text string) (Instruction, *operand) {
+	return &placeholderInstruction{text: text}, &operand{mode: invalidOperand, Type: InvalidType, text: text}
+}
+
+// builtinArguments checks the count of the arguments, only append accepts the spread argument.
+func (l *GoCompilerListener) builtinArguments(name string, arguments []Instruction, spread bool, least, most int) bool {
+	if spread {
+		l.errorf("invalid use of ... with built-in %v", name)
+		return false
+	}
+	if len(arguments) < least {
+		l.errorf("not enough arguments in call to %v", name)
+		return false
+	}
+	if most >= 0 && len(arguments) > most {
+		l.errorf("too many arguments in call to %v", name)
+		return false
+	}
+
+	return true
+}
+
+func builtinLen(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("len", arguments, spread, 1, 1) {
+		return invalidCall(text)
+	}
+
+	if length, ok := l.arrayLen(operands[0]); ok {
+		return length, &operand{mode: valueOperand, Type: IntType, text: text}
+	}
+
+	argument, op := l.defaultValue(arguments[0], operands[0], "argument to built-in len")
+	switch op.Underlying().(type) {
+	case *SliceType, *MapType, *ChannelType:
+	default:
+		if op != InvalidType && !IsString(op) {
+			l.errorf("invalid argument: %v for built-in len", describe(operands[0]))
+		}
+	}
+
+	return &LenInstruction{
+		program:     l.program,
+		instruction: argument,
+	}, &operand{mode: valueOperand, Type: IntType, text: text}
+}
+
+// arrayLen is the length of the array or of the array the pointer points to, the operand isn't evaluated then.
+func (l *GoCompilerListener) arrayLen(op *operand) (Instruction, bool) {
+	Type := op.Type.Underlying()
+	if pointerType, ok := Type.(*PointerType); ok {
+		Type = pointerType.Elem.Underlying()
+	}
+
+	arrayType, ok := Type.(*ArrayType)
+	if !ok {
+		return nil, false
+	}
+	return &IntUsingInstruction{program: l.program, integer: arrayType.Len}, true
+}
+
+func builtinCap(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("cap", arguments, spread, 1, 1) || !l.isValue(operands[0]) {
+		return invalidCall(text)
+	}
+
+	if length, ok := l.arrayLen(operands[0]); ok {
+		return length, &operand{mode: valueOperand, Type: IntType, text: text}
+	}
+	switch operands[0].Type.Underlying().(type) {
+	case *SliceType, *ChannelType:
+	default:
+		if operands[0].Type != InvalidType {
+			l.errorf("invalid argument: %v for built-in cap", describe(operands[0]))
+		}
+		return invalidCall(text)
+	}
+
+	return l.builtinCall("cap", nil, arguments), &operand{mode: valueOperand, Type: IntType, text: text}
+}
+
+func builtinAppend(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if len(arguments) == 0 {
+		l.errorf("not enough arguments in call to append")
+		return invalidCall(text)
+	}
+
+	if !l.isValue(operands[0]) {
+		return invalidCall(text)
+	}
+
+	sliceType, ok := operands[0].Type.Underlying().(*SliceType)
+	if !ok {
+		if operands[0].Type != InvalidType {
+			l.errorf("invalid argument: %v is not a slice", describe(operands[0]))
+		}
+		return invalidCall(text)
+	}
+
+	if spread && len(arguments) != 2 {
+		l.errorf("can only use ... with final argument in list")
+		return invalidCall(text)
+	}
+
+	elements := make([]Instruction, len(arguments)-1)
+	for i := range elements {
+		if spread {
+			elements[i] = l.assign(arguments[i+1], operands[i+1], &SliceType{Elem: sliceType.Elem}, "argument to append")
+			continue
+		}
+		elements[i] = l.assign(arguments[i+1], operands[i+1], sliceType.Elem, "argument to append")
+	}
+
+	return &AppendInstruction{
+		program:  l.program,
+		slice:    arguments[0],
+		elements: elements,
+		spread:   spread,
+	}, &operand{mode: valueOperand, Type: operands[0].Type, text: text}
+}
+
+func builtinCopy(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("copy", arguments, spread, 2, 2) || !l.isValue(operands[0]) || !l.isValue(operands[1]) {
+		return invalidCall(text)
+	}
+
+	dst, dstOk := operands[0].Type.Underlying().(*SliceType)
+	src, srcOk := operands[1].Type.Underlying().(*SliceType)
+	switch {
+	case operands[0].Type == InvalidType || operands[1].Type == InvalidType:
+		return invalidCall(text)
+	case !dstOk || !srcOk:
+		l.errorf("invalid argument: copy expects slice arguments; found %v and %v", describe(operands[0]), describe(operands[1]))
+		return invalidCall(text)
+	case !Identical(dst.Elem, src.Elem):
+		l.errorf("invalid argument: arguments to copy %v and %v have different element types %v and %v",
+			describe(operands[0]), describe(operands[1]), dst.Elem, src.Elem)
+		return invalidCall(text)
+	}
+
+	return l.builtinCall("copy", nil, arguments), &operand{mode: valueOperand, Type: IntType, text: text}
+}
+
+// size checks the length or the capacity argument of make.
+func (l *GoCompilerListener) size(instruction Instruction, op *operand) Instruction {
+	if !l.isValue(op) || op.Type == InvalidType {
+		return instruction
+	}
+
+	if !IsInteger(op.Type) && !(op.Type == UntypedFloatType && op.value != nil) {
+		l.errorf("cannot convert %v to type int", describe(op))
+		return instruction
+	}
+
+	switch value := op.value.(type) {
+	case int:
+		if value < 0 {
+			l.errorf("invalid argument: index %v must not be negative", describe(op))
+		}
+	case float64:
+		if value != float64(int(value)) {
+			l.errorf("%v truncated to int", describe(op))
+		} else if value < 0 {
+			l.errorf("invalid argument: index %v must not be negative", describe(op))
+		}
+	}
+
+	return l.convert(instruction, op.Type, IntType)
+}
+
+func builtinMake(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("make", arguments, spread, 1, 3) {
+		return invalidCall(text)
+	}
+
+	if operands[0].mode != typeOperand {
+		if operands[0].mode != invalidOperand {
+			l.errorf("%v is not a type", operands[0].text)
+		}
+		return invalidCall(text)
+	}
+	Type := operands[0].Type
+
+	sizes := make([]Instruction, len(arguments)-1)
+	for i := range sizes {
+		sizes[i] = l.size(arguments[i+1], operands[i+1])
+	}
+
+	switch Type := Type.Underlying().(type) {
+	case *SliceType:
+		if len(arguments) == 1 {
+			l.errorf("invalid operation: %v expects 2 or 3 arguments; found 1", text)
+			return invalidCall(text)
+		}
+		if len(arguments) == 3 {
+			length, lengthOk := operands[1].value.(int)
+			capacity, capacityOk := operands[2].value.(int)
+			if lengthOk && capacityOk && length > capacity {
+				l.errorf("invalid argument: length and capacity swapped")
+				return invalidCall(text)
+			}
+		}
+
+		return l.builtinCall("make slice", Type.Elem, sizes), &operand{mode: valueOperand, Type: operands[0].Type, text: text}
+	case *MapType:
+		if len(arguments) == 3 {
+			l.errorf("invalid operation: %v expects 1 or 2 arguments; found 3", text)
+			return invalidCall(text)
+		}
+
+		return l.builtinCall("make map", nil, sizes), &operand{mode: valueOperand, Type: operands[0].Type, text: text}
+	case *ChannelType:
+		if len(arguments) == 3 {
+			l.errorf("invalid operation: %v expects 1 or 2 arguments; found 3", text)
+			return invalidCall(text)
+		}
+
+		return l.builtinCall("make chan", Type.Elem, sizes), &operand{mode: valueOperand, Type: operands[0].Type, text: text}
+	}
+
+	if Type != InvalidType {
+		l.errorf("invalid argument: cannot make %v; type must be slice, map, or channel", describe(operands[0]))
+	}
+	return invalidCall(text)
+}
+
+// builtinNew allocates the zero value of the type, or the copy of the value as in new(1).
+func builtinNew(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("new", arguments, spread, 1, 1) {
+		return invalidCall(text)
+	}
+
+	if operands[0].mode == typeOperand {
+		Type := operands[0].Type
+		return l.builtinCall("new", Type, nil), &operand{mode: valueOperand, Type: &PointerType{Elem: Type}, text: text}
+	}
+
+	argument, Type := l.defaultValue(arguments[0], operands[0], "argument to built-in new")
+	if Type == InvalidType {
+		return invalidCall(text)
+	}
+
+	return l.builtinCall("new value", nil, []Instruction{argument}), &operand{mode: valueOperand, Type: &PointerType{Elem: Type}, text: text}
+}
+
+func builtinDelete(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("delete", arguments, spread, 2, 2) || !l.isValue(operands[0]) {
+		return invalidCall(text)
+	}
+
+	mapType, ok := operands[0].Type.Underlying().(*MapType)
+	if !ok {
+		if operands[0].Type != InvalidType {
+			l.errorf("invalid argument: %v is not a map", describe(operands[0]))
+		}
+		return invalidCall(text)
+	}
+
+	return l.builtinCall("delete", nil, []Instruction{arguments[0], l.assign(arguments[1], operands[1], mapType.Key, "argument to delete")}), &operand{mode: novalueOperand, Type: InvalidType, text: text}
+}
+
+func builtinClose(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("close", arguments, spread, 1, 1) || !l.isValue(operands[0]) {
+		return invalidCall(text)
+	}
+
+	channelType, ok := operands[0].Type.Underlying().(*ChannelType)
+	switch {
+	case operands[0].Type == InvalidType:
+	case !ok:
+		l.errorf("invalid operation: cannot close non-channel %v", describe(operands[0]))
+	case channelType.Dir == RecvOnly:
+		l.errorf("invalid operation: cannot close receive-only channel %v", describe(operands[0]))
+	default:
+		return &CloseInstruction{
+			program: l.program,
+			channel: arguments[0],
+		}, &operand{mode: novalueOperand, Type: InvalidType, text: text}
+	}
+	return invalidCall(text)
+}
+
+func builtinExtremum(name string) builtin {
+	return func(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+		if !l.builtinArguments(name, arguments, spread, 1, -1) {
+			return invalidCall(text)
+		}
+
+		arguments, Type := l.unify(arguments, operands, text)
+		if Type == InvalidType {
+			return invalidCall(text)
+		}
+		if !IsOrdered(Type) {
+			l.errorf("invalid argument: %v cannot be ordered", describe(operands[0]))
+			return invalidCall(text)
+		}
+
+		return l.builtinCall(name, nil, arguments), &operand{mode: valueOperand, Type: Type, text: text}
+	}
+}
+
+func builtinClear(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("clear", arguments, spread, 1, 1) || !l.isValue(operands[0]) {
+		return invalidCall(text)
+	}
+
+	switch Type := operands[0].Type.Underlying().(type) {
+	case *MapType:
+		return l.builtinCall("clear map", nil, arguments), &operand{mode: novalueOperand, Type: InvalidType, text: text}
+	case *SliceType:
+		return l.builtinCall("clear slice", Type.Elem, arguments), &operand{mode: novalueOperand, Type: InvalidType, text: text}
+	}
+
+	if operands[0].Type != InvalidType {
+		l.errorf("invalid argument: %v (argument must be map or slice)", describe(operands[0]))
+	}
+	return invalidCall(text)
+}
+
+func builtinComplex(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+	if !l.builtinArguments("complex", arguments, spread, 2, 2) {
+		return invalidCall(text)
+	}
+
+	arguments, Type := l.unify(arguments, operands, text)
+	if Type == InvalidType {
+		return invalidCall(text)
+	}
+	if IsUntyped(Type) && IsNumeric(Type) {
+		arguments[0] = l.convert(arguments[0], Type, Float64Type)
+		arguments[1] = l.convert(arguments[1], Type, Float64Type)
+		Type = Float64Type
+	}
+	if !IsFloat(Type) {
+		l.errorf("invalid argument: arguments have type %v, expected floating-point", Type)
+		return invalidCall(text)
+	}
+
+	return l.builtinCall("complex", nil, arguments), &operand{mode: valueOperand, Type: Complex128Type, text: text}
+}
+
+// builtinPart gives the real or the imaginary part of the complex number.
+func builtinPart(name string) builtin {
+	return func(l *GoCompilerListener, arguments []Instruction, operands []*operand, spread bool, text string) (Instruction, *operand) {
+		if !l.builtinArguments(name, arguments, spread, 1, 1) || !l.isValue(operands[0]) {
+			return invalidCall(text)
+		}
+
+		argument, op := arguments[0], operands[0]
+		if IsUntyped(op.Type) && IsNumeric(op.Type) {
+			argument = l.convert(argument, op.Type, Complex128Type)
+		} else if !IsComplex(op.Type) {
+			if op.Type != InvalidType {
+				l.errorf("invalid argument: %v not of complex type", describe(op))
+			}
+			return invalidCall(text)
+		}
+
+		return l.builtinCall(name, nil, []Instruction{argument}), &operand{mode: valueOperand, Type: Float64Type, text: text}
+	}
+}
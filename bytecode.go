@@ -0,0 +1,1186 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+)
+
+// vmOpcode is the operation of the register machine. The operands are the registers of the call,
+// the operand below zero is the constant: -1 is the first one of the pool.
+type vmOpcode uint8
+
+const (
+	// vmMove sets a to b, vmCopy sets a to the copy of b
+	vmMove vmOpcode = iota
+	vmCopy
+	// vmZero sets a to the zero value of the type b
+	vmZero
+	// the arithmetic, the logic and the comparisons set a to b op c
+	vmAdd
+	vmSub
+	vmMul
+	vmDiv
+	vmOr
+	vmAnd
+	vmEq
+	vmNe
+	vmLt
+	vmLe
+	vmGt
+	vmGe
+	vmNot
+	// vmConvert converts b to the type c, vmBox makes the interface value of the type c
+	vmConvert
+	vmBox
+	vmLen
+	// vmIndex sets a to the copy of b[c], the missing key of the map gives the zero value of the type d,
+	// vmIndexRef doesn't copy the element
+	vmIndex
+	vmIndexRef
+	// vmSetIndex sets a[b] to the copy of c
+	vmSetIndex
+	// vmField sets a to the copy of the field c of b, vmFieldRef doesn't copy it
+	vmField
+	vmFieldRef
+	// vmPackage sets a to the copy of the package value of the constant b
+	vmPackage
+	// vmEval evaluates the instruction tree of the escape b into c registers from a, vmExec drops the values
+	vmEval
+	vmExec
+	// vmCall calls the function of the call b with the arguments from c, the results go to the registers from a,
+	// vmCallValue calls the function value d
+	vmCall
+	vmCallValue
+	// vmJump goes to a, vmJumpIfFalse goes to b if a is false
+	vmJump
+	vmJumpIfFalse
+	// vmRange starts the range loop of the kind c over b, the state of the loop is in a and the register after it,
+	// vmNext sets the iteration values from b or goes to c when the loop of a is over
+	vmRange
+	vmNext
+	vmReturn
+	// vmTailCall starts the function again with the arguments from a
+	vmTailCall
+	vmMissingReturn
+	// vmCell sets a to the cell of the value b the closures and the pointers share, vmLoad sets a to the value
+	// of the cell b, vmStore sets the cell a to b, the value is copied if c is set
+	vmCell
+	vmLoad
+	vmStore
+	// vmSelect runs the select statement b over the channels and the values sent from c, the value received and ok
+	// go to a and the register after it, the machine goes on with the body of the case chosen
+	vmSelect
+	// vmRangeFunc calls the function b with the yield function running the body from d, the iteration values are
+	// in a and the register after it, the body ends at the jump before c, where the loop goes on after the call
+	vmRangeFunc
+	// vmDepth checks the depth of the calls for the inlined call of the function of the constant b
+	vmDepth
+)
+
+var vmOpcodeNames = [...]string{
+	vmMove: "move", vmCopy: "copy", vmZero: "zero",
+	vmAdd: "add", vmSub: "sub", vmMul: "mul", vmDiv: "div", vmOr: "or", vmAnd: "and",
+	vmEq: "eq", vmNe: "ne", vmLt: "lt", vmLe: "le", vmGt: "gt", vmGe: "ge", vmNot: "not",
+	vmConvert: "convert", vmBox: "box", vmLen: "len",
+	vmIndex: "index", vmIndexRef: "index-ref", vmSetIndex: "set-index", vmField: "field", vmFieldRef: "field-ref",
+	vmPackage: "package", vmEval: "eval", vmExec: "exec", vmCall: "call", vmCallValue: "call-value",
+	vmJump: "jump", vmJumpIfFalse: "jump-if-false", vmRange: "range", vmNext: "next",
+	vmReturn: "return", vmTailCall: "tail-call", vmMissingReturn: "missing-return",
+	vmCell: "cell", vmLoad: "load", vmStore: "store", vmSelect: "select", vmRangeFunc: "range-func", vmDepth: "depth",
+}
+
+var vmCompareOpcodes = map[string]vmOpcode{"==": vmEq, "!=": vmNe, "<": vmLt, "<=": vmLe, ">": vmGt, ">=": vmGe}
+
+type vmInstruction struct {
+	op vmOpcode
+	// the statements and the iterations the instruction starts, they are counted by the step budget.
+	// The optimized instruction may start several: the ones before it are removed
+	steps      int32
+	a, b, c, d int32
+}
+
+// vmCallSite is the call of the function known statically or of the function value.
+type vmCallSite struct {
+	function Function
+	// the compiled function, its call doesn't leave the machine
+	compiled *vmFunction
+	// the count of the results the call gives, -1 if they are dropped
+	args, results int
+}
+
+// vmEscape is the expression or the statement the machine doesn't compile, it runs as the instruction tree
+// with the variables it uses.
+type vmEscape struct {
+	instruction Instruction
+	names       []string
+	registers   []int32
+	// the registers of the cells, the tree shares the variable instead of getting its copy
+	cells []bool
+}
+
+// vmSelectStatement is the select statement, the default case has no channel.
+type vmSelectStatement struct {
+	sends    []bool
+	fallback int
+	// the bodies of the cases
+	targets []int
+}
+
+// vmFunction is the function compiled into the bytecode. Its registers are the parameters, the results,
+// the variables and the temporaries of the statements.
+type vmFunction struct {
+	program   *Program
+	function  *IntrpretatedFunction
+	code      []vmInstruction
+	constants []vmValue
+	types     []Type
+	// the zero values of the results, the ones of the kind any are made for each call
+	zeros     []vmValue
+	calls     []vmCallSite
+	escapes   []vmEscape
+	selects   []vmSelectStatement
+	registers int
+	// the variables the function literal has captured, their cells are in the registers after the results
+	free []string
+	// the results the closures share, they are in the cells
+	resultCells []bool
+	// the register of the deferred calls, -1 if the function defers nothing,
+	// and the return the function which has recovered from the panic returns by
+	defers int32
+	exit   int
+}
+
+// vmCompiler is the state of the function being compiled.
+type vmCompiler struct {
+	*vmFunction
+	// the registers of the variables visible in the scopes, the innermost scope is the last
+	scopes []map[string]int32
+	// the types of the variables, the values of the variables of the unknown types are copied too
+	variables map[int32]Type
+	// the names of the variables kept in the cells and the registers holding the cells
+	shared map[string]bool
+	cells  map[int32]bool
+	// the variables the function doesn't declare, they are captured by the function literal
+	missing []string
+	// the registers below live hold the variables, the ones from live to next are the temporaries of the statement
+	live, next int32
+	// the constants of the basic types are in the pool once
+	constantIndex map[any]int32
+	labels        map[string]int
+	gotos         []vmGoto
+	// the breaks of the loops being compiled, the innermost loop is the last
+	loops [][]int
+	// why the function can't be compiled, empty if it can
+	unsupported string
+}
+
+type vmGoto struct {
+	jump  int
+	label string
+}
+
+// compileVM compiles the body of the function, the function the machine can't run gets no bytecode.
+func (prog *Program) compileVM(function *IntrpretatedFunction) *vmFunction {
+	c := prog.compileBytecode(function, nil)
+	if len(c.missing) != 0 {
+		// the variables the function literal has captured are known after the first pass
+		c = prog.compileBytecode(function, c.missing)
+		if len(c.missing) != 0 {
+			c.fail("variable %v not declarated", c.missing[0])
+		}
+	}
+
+	if c.unsupported != "" {
+		prog.dumpBytecode(function.Name(), "bytecode", c.vmFunction, c.unsupported)
+		return nil
+	}
+
+	prog.dumpBytecode(function.Name(), "bytecode", c.vmFunction, "")
+	return c.vmFunction
+}
+
+// compileBytecode compiles the function whose captured variables are free.
+func (prog *Program) compileBytecode(function *IntrpretatedFunction, free []string) *vmCompiler {
+	c := &vmCompiler{
+		vmFunction:    &vmFunction{program: prog, function: function, free: free, defers: -1},
+		scopes:        []map[string]int32{{}},
+		variables:     map[int32]Type{},
+		shared:        sharedNames(function.instructions, false),
+		cells:         map[int32]bool{},
+		constantIndex: map[any]int32{},
+		labels:        map[string]int{},
+	}
+
+	for _, input := range function.inputVariables {
+		c.declare(input.Name, c.alloc(1), input.Type)
+	}
+	for i, output := range function.outputVariables {
+		register := c.alloc(1)
+		c.declare(resultName(i), register, output.Type)
+		c.declare(output.Name, register, output.Type)
+		c.zeros = append(c.zeros, unboxed(NewVariable(output.Type)))
+		c.resultCells = append(c.resultCells, c.cells[register])
+	}
+	if !slices.Contains(c.resultCells, true) {
+		c.resultCells = nil
+	}
+	for _, name := range free {
+		register := c.alloc(1)
+		c.declare(name, register, nil)
+		c.cells[register] = true
+	}
+	if defers(function.instructions) {
+		c.defers = c.alloc(1)
+		c.declare("", c.defers, nil)
+		c.cells[c.defers] = true
+	}
+	// the parameters and the results the closures share are moved into the cells
+	for register := range int32(len(function.inputVariables) + len(function.outputVariables)) {
+		c.wrap(register)
+	}
+
+	for _, instruction := range function.instructions {
+		// the body of the function shares the scope with the parameters
+		if block, ok := instruction.(*BlockInstruction); ok {
+			for _, instruction := range block.instructions {
+				c.statement(instruction)
+			}
+			continue
+		}
+
+		c.statement(instruction)
+	}
+	if len(function.outputVariables) != 0 {
+		c.emit(vmMissingReturn, 0, 0, 0)
+	} else {
+		c.emit(vmReturn, 0, 0, 0)
+	}
+	if c.defers >= 0 {
+		c.exit = c.emit(vmReturn, 0, 0, 0)
+	}
+
+	for _, jump := range c.gotos {
+		target, ok := c.labels[jump.label]
+		if !ok {
+			c.fail("goto %v out of the function", jump.label)
+			break
+		}
+		c.code[jump.jump].a = int32(target)
+	}
+
+	return c
+}
+
+// sharedNames are the names of the variables whose addresses are taken and of the ones the function literals use,
+// all the names used if all is set. Such variables are kept in the cells the pointers and the closures share.
+func sharedNames(instructions []Instruction, all bool) map[string]bool {
+	res := map[string]bool{}
+	var visit func(instruction Instruction, all bool)
+	visit = func(instruction Instruction, all bool) {
+		switch instr := instruction.(type) {
+		case *FunctionLiteralInstruction:
+			for _, instruction := range instr.function.instructions {
+				visit(instruction, true)
+			}
+			return
+		case *VariableAddressInstruction:
+			res[instr.variableName] = true
+		case *VariableUsingInstruction:
+			res[instr.variableName] = res[instr.variableName] || all
+		case *AssigmentInstruction:
+			res[instr.varName] = res[instr.varName] || all
+		}
+
+		for _, child := range children(instruction) {
+			visit(child, all)
+		}
+	}
+	for _, instruction := range instructions {
+		visit(instruction, all)
+	}
+
+	return res
+}
+
+// defers reports whether the function has the defer statement, the ones of its function literals are theirs.
+func defers(instructions []Instruction) bool {
+	for _, instruction := range instructions {
+		if _, ok := instruction.(*DeferInstruction); ok || defers(children(instruction)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *vmCompiler) fail(format string, args ...any) {
+	if c.unsupported == "" {
+		c.unsupported = fmt.Sprintf(format, args...)
+	}
+}
+
+func (c *vmCompiler) emit(op vmOpcode, a, b, c2 int32) int {
+	c.code = append(c.code, vmInstruction{op: op, a: a, b: b, c: c2})
+	return len(c.code) - 1
+}
+
+// land makes the jump go to the next instruction.
+func (c *vmCompiler) land(jump int) {
+	switch c.code[jump].op {
+	case vmJump:
+		c.code[jump].a = int32(len(c.code))
+	case vmJumpIfFalse:
+		c.code[jump].b = int32(len(c.code))
+	case vmNext:
+		c.code[jump].c = int32(len(c.code))
+	}
+}
+
+// alloc allocates the temporary registers.
+func (c *vmCompiler) alloc(n int) int32 {
+	res := c.next
+	c.next += int32(n)
+	c.registers = max(c.registers, int(c.next))
+	return res
+}
+
+// declare makes the register hold the variable till the end of the scope,
+// the register of the shared variable holds its cell.
+func (c *vmCompiler) declare(name string, register int32, Type Type) {
+	c.live = max(c.live, register+1)
+	c.variables[register] = Type
+	c.cells[register] = name != "" && c.shared[name]
+	if name != "" {
+		c.scopes[len(c.scopes)-1][name] = register
+	}
+}
+
+// wrap moves the value of the shared variable into its cell, the closures made after it see the new variable.
+func (c *vmCompiler) wrap(register int32) {
+	if c.cells[register] {
+		c.emit(vmCell, register, register, 0)
+	}
+}
+
+func (c *vmCompiler) lookup(name string) (int32, bool) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if register, ok := c.scopes[i][name]; ok {
+			return register, true
+		}
+	}
+
+	return 0, false
+}
+
+// resolve is the register of the variable, the variable the function doesn't declare is captured by it.
+func (c *vmCompiler) resolve(name string) (int32, bool) {
+	register, ok := c.lookup(name)
+	if !ok && !slices.Contains(c.missing, name) {
+		c.missing = append(c.missing, name)
+	}
+	return register, ok
+}
+
+// enter enters the scope, leave frees the registers of its variables.
+func (c *vmCompiler) enter() int32 {
+	c.scopes = append(c.scopes, map[string]int32{})
+	return c.live
+}
+
+func (c *vmCompiler) leave(live int32) {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.live, c.next = live, live
+}
+
+// constant is the operand of the constant, the values of the basic types are in the pool once.
+func (c *vmCompiler) constant(value any) int32 {
+	switch value.(type) {
+	case int, int32, uint8, float64, complex128, string, bool, nil:
+		if index, ok := c.constantIndex[value]; ok {
+			return index
+		}
+		c.constants = append(c.constants, unboxed(value))
+		c.constantIndex[value] = int32(-len(c.constants))
+		return int32(-len(c.constants))
+	}
+
+	c.constants = append(c.constants, unboxed(value))
+	return int32(-len(c.constants))
+}
+
+func (c *vmCompiler) typeOperand(Type Type) int32 {
+	c.types = append(c.types, Type)
+	return int32(len(c.types) - 1)
+}
+
+// copied reports whether the values of the type are copied when they are assigned.
+func copied(Type Type) bool {
+	if Type == nil {
+		return true
+	}
+
+	switch Type.Underlying().(type) {
+	case *StructType, *ArrayType, *InterfaceType:
+		return true
+	}
+	return false
+}
+
+// statement compiles the statement, the temporaries of the statement are free after it.
+func (c *vmCompiler) statement(instruction Instruction) {
+	start := len(c.code)
+	c.compileStatement(instruction)
+	if start < len(c.code) {
+		c.code[start].steps = 1
+	}
+	c.next = c.live
+}
+
+func (c *vmCompiler) compileStatement(instruction Instruction) {
+	switch instr := instruction.(type) {
+	case *BlockInstruction:
+		live := c.enter()
+		for _, instruction := range instr.instructions {
+			c.statement(instruction)
+		}
+		c.leave(live)
+	case *LabeledInstruction:
+		c.labels[instr.label] = len(c.code)
+		c.compileStatement(instr.instruction)
+	case *IFInstruction:
+		condition := c.read(instr.statment)
+		otherwise := c.emit(vmJumpIfFalse, condition, 0, 0)
+		c.next = c.live
+		c.statement(instr.than)
+		if instr.otherwise != nil {
+			end := c.emit(vmJump, 0, 0, 0)
+			c.land(otherwise)
+			c.statement(instr.otherwise)
+			c.land(end)
+		} else {
+			c.land(otherwise)
+		}
+	case *FORInstruction:
+		start := len(c.code)
+		exit := -1
+		if instr.statment != nil {
+			condition := c.read(instr.statment)
+			exit = c.emit(vmJumpIfFalse, condition, 0, 0)
+			c.next = c.live
+		}
+		c.loops = append(c.loops, nil)
+		c.statement(instr.than)
+		c.emit(vmJump, int32(start), 0, 0)
+
+		if exit >= 0 {
+			c.land(exit)
+		}
+		c.landBreaks()
+	case *RangeInstruction:
+		if instr.kind == rangeFunction {
+			c.compileRangeFunction(instr)
+			return
+		}
+		c.compileRange(instr)
+	case *SelectInstruction:
+		c.compileSelect(instr)
+	case *BreakInstruction:
+		if len(c.loops) == 0 {
+			c.fail("break out of the loop")
+			return
+		}
+		c.loops[len(c.loops)-1] = append(c.loops[len(c.loops)-1], c.emit(vmJump, 0, 0, 0))
+	case *GotoInstruction:
+		c.gotos = append(c.gotos, vmGoto{jump: c.emit(vmJump, 0, 0, 0), label: instr.label})
+	case *ReturnInstruction:
+		c.compileReturn(instr)
+	case *EmptyInstruction:
+	case *DefineVariableInstruction:
+		register := c.alloc(1)
+		if instr.value == nil {
+			c.emit(vmZero, register, c.typeOperand(instr.Type), 0)
+		} else {
+			c.compileExpression(instr.value, register)
+		}
+		c.declare(instr.Name, register, instr.Type)
+		c.wrap(register)
+	case *AssigmentInstruction:
+		register, ok := c.resolve(instr.varName)
+		if !ok {
+			return
+		}
+		if c.cells[register] {
+			value := c.alloc(1)
+			c.compileExpression(instr.instruction, value)
+			store := c.emit(vmStore, register, value, 0)
+			if !c.fresh(instr.instruction) {
+				c.code[store].c = 1
+			}
+			return
+		}
+		if c.fresh(instr.instruction) {
+			c.compileExpression(instr.instruction, register)
+			return
+		}
+		value := c.alloc(1)
+		c.compileExpression(instr.instruction, value)
+		c.emit(vmCopy, register, value, 0)
+	case *MultiAssigmentInstruction:
+		values := c.alloc(len(instr.temporaries))
+		switch {
+		case len(instr.values) == len(instr.temporaries):
+			for i, value := range instr.values {
+				c.compileExpression(value, values+int32(i))
+			}
+		case len(instr.values) == 1:
+			c.compileValues(instr.values[0], values, len(instr.temporaries))
+		default:
+			c.fail("%v values are assigned to %v variables", len(instr.values), len(instr.temporaries))
+		}
+		for i, name := range instr.temporaries {
+			c.declare(name, values+int32(i), nil)
+		}
+		for _, assigment := range instr.assigments {
+			c.compileStatement(assigment)
+		}
+	case *UnpackInstruction:
+		c.unpack(instr)
+	case *IndexAssigmentInstruction:
+		container := c.read(instr.container)
+		index := c.read(instr.index)
+		value := c.read(instr.instruction)
+		c.emit(vmSetIndex, container, index, value)
+	case *FunctionCallInstruction:
+		results := c.resultCount(instr.functionID)
+		c.compileCall(instr, c.alloc(max(results, 0)), results)
+	case *FunctionValueCallInstruction:
+		c.compileCallValue(instr, c.next, -1)
+	default:
+		c.emit(vmExec, 0, c.escape(instruction), -1)
+	}
+}
+
+// landBreaks makes the breaks of the innermost loop go to the next instruction.
+func (c *vmCompiler) landBreaks() {
+	for _, jump := range c.loops[len(c.loops)-1] {
+		c.land(jump)
+	}
+	c.loops = c.loops[:len(c.loops)-1]
+}
+
+// compileRange compiles the range loop, every iteration sets the iteration variables again.
+func (c *vmCompiler) compileRange(instr *RangeInstruction) {
+	live := c.enter()
+	state := c.alloc(2)
+	c.declare("", state+1, nil)
+	container := c.value(instr.container)
+	c.emit(vmRange, state, container, int32(instr.kind))
+	c.next = c.live
+
+	values := c.alloc(2)
+	for i, name := range instr.names {
+		c.declare(name, values+int32(i), nil)
+	}
+	next := c.emit(vmNext, state, values, 0)
+	c.code[next].steps = 1
+	c.wrap(values)
+	c.wrap(values + 1)
+	c.loops = append(c.loops, nil)
+	for _, assigment := range instr.assigments {
+		c.statement(assigment)
+	}
+	c.statement(instr.body)
+	c.emit(vmJump, int32(next), 0, 0)
+
+	c.land(next)
+	c.landBreaks()
+	c.leave(live)
+}
+
+// compileRangeFunction compiles the range loop over the function, the body runs in the calls of the yield function.
+func (c *vmCompiler) compileRangeFunction(instr *RangeInstruction) {
+	live := c.enter()
+	values := c.alloc(2)
+	c.declare("", values+1, nil)
+	function := c.value(instr.container)
+	at := c.emit(vmRangeFunc, values, function, 0)
+	c.next = c.live
+
+	c.code[at].d = int32(len(c.code))
+	for i, name := range instr.names {
+		if name != "" {
+			c.declare(name, values+int32(i), nil)
+			c.wrap(values + int32(i))
+		}
+	}
+	c.loops = append(c.loops, nil)
+	for _, assigment := range instr.assigments {
+		c.statement(assigment)
+	}
+	c.statement(instr.body)
+	end := c.emit(vmJump, 0, 0, 0)
+
+	c.land(end)
+	c.code[at].c = int32(len(c.code))
+	c.landBreaks()
+	c.leave(live)
+}
+
+// compileSelect compiles the select statement, the break leaves it.
+func (c *vmCompiler) compileSelect(instr *SelectInstruction) {
+	live := c.enter()
+	operands := c.alloc(2 * len(instr.cases))
+	sel := vmSelectStatement{sends: make([]bool, len(instr.cases)), fallback: -1, targets: make([]int, len(instr.cases))}
+	for i, selectCase := range instr.cases {
+		if selectCase.channel == nil {
+			sel.fallback = i
+			continue
+		}
+		c.compileExpression(selectCase.channel, operands+int32(2*i))
+		if selectCase.value != nil {
+			sel.sends[i] = true
+			c.compileExpression(selectCase.value, operands+int32(2*i+1))
+		}
+	}
+	received := c.alloc(2)
+	c.declare("", received+1, nil)
+	c.selects = append(c.selects, sel)
+	c.emit(vmSelect, received, int32(len(c.selects)-1), operands)
+
+	c.loops = append(c.loops, nil)
+	var ends []int
+	for i, selectCase := range instr.cases {
+		sel.targets[i] = len(c.code)
+		scope := c.enter()
+		for j, name := range selectCase.names {
+			if name != "" {
+				c.declare(name, received+int32(j), nil)
+				c.wrap(received + int32(j))
+			}
+		}
+		for _, assigment := range selectCase.assigments {
+			c.statement(assigment)
+		}
+		c.statement(selectCase.body)
+		ends = append(ends, c.emit(vmJump, 0, 0, 0))
+		c.leave(scope)
+	}
+
+	for _, end := range ends {
+		c.land(end)
+	}
+	c.landBreaks()
+	c.leave(live)
+}
+
+func (c *vmCompiler) compileReturn(instr *ReturnInstruction) {
+	if instr.tail && c.program.TailCalls {
+		call := instr.expressions[0].(*FunctionCallInstruction)
+		c.emit(vmTailCall, c.arguments(call.arguments), 0, 0)
+		return
+	}
+
+	results := int32(len(c.function.inputVariables))
+	count := len(c.function.outputVariables)
+	switch {
+	case len(instr.expressions) == 0:
+	case len(instr.expressions) == 1 && count == 1 && c.resultCells == nil:
+		c.compileExpression(instr.expressions[0], results)
+	case len(instr.expressions) == count:
+		// the results may be used by the expressions of the other ones
+		values := c.alloc(count)
+		for i, expression := range instr.expressions {
+			c.compileExpression(expression, values+int32(i))
+		}
+		c.setResults(values)
+	case len(instr.expressions) == 1:
+		values := c.alloc(count)
+		c.compileValues(instr.expressions[0], values, count)
+		c.setResults(values)
+	default:
+		c.fail("%v values are returned from %v results", len(instr.expressions), count)
+	}
+	c.emit(vmReturn, 0, 0, 0)
+}
+
+// setResults sets the results to the values from the register, the shared results are set in their cells.
+func (c *vmCompiler) setResults(values int32) {
+	results := int32(len(c.function.inputVariables))
+	for i := range int32(len(c.function.outputVariables)) {
+		if c.resultCells != nil && c.resultCells[i] {
+			c.emit(vmStore, results+i, values+i, 0)
+			continue
+		}
+		c.emit(vmMove, results+i, values+i, 0)
+	}
+}
+
+// unpack sets the temporaries to the results of the call, the arguments after it use them.
+func (c *vmCompiler) unpack(instr *UnpackInstruction) {
+	values := c.alloc(len(instr.temporaries))
+	c.compileValues(instr.value, values, len(instr.temporaries))
+	for i, name := range instr.temporaries {
+		c.declare(name, values+int32(i), nil)
+	}
+}
+
+// fresh reports whether the value of the expression is not shared with a variable or a container,
+// so it is assigned without the copy.
+func (c *vmCompiler) fresh(instruction Instruction) bool {
+	switch instr := instruction.(type) {
+	case *IntUsingInstruction, *FloatUsingInstruction, *StringUsingInstruction, *BoolUsingInstruction,
+		*RuneUsingInstruction, *NilUsingInstruction, *ConstantInstruction, *PackageValueInstruction,
+		*AddInstruction, *SubInstruction, *MulInstruction, *DivInstruction, *OrInstruction, *AndInstruction,
+		*NotInstruction, *CompareInstruction, *LenInstruction, *ConvertInstruction, *BoxInstruction,
+		*FunctionCallInstruction, *FunctionValueCallInstruction:
+		return true
+	case *VariableUsingInstruction:
+		return !instr.reference
+	case *IndexInstruction:
+		return !instr.reference
+	case *FieldInstruction:
+		return !instr.reference
+	}
+
+	return false
+}
+
+// read gives the operand the operation only reads: the constant, the register of the variable or the temporary.
+func (c *vmCompiler) read(instruction Instruction) int32 {
+	switch instr := instruction.(type) {
+	case *VariableUsingInstruction:
+		if register, ok := c.lookup(instr.variableName); ok && !c.cells[register] {
+			return register
+		}
+	case *IntUsingInstruction:
+		return c.constant(instr.integer)
+	case *FloatUsingInstruction:
+		return c.constant(instr.float)
+	case *StringUsingInstruction:
+		return c.constant(instr.str)
+	case *BoolUsingInstruction:
+		return c.constant(instr.boolVal)
+	case *RuneUsingInstruction:
+		return c.constant(instr.value)
+	case *NilUsingInstruction:
+		return c.constant(nil)
+	}
+
+	register := c.alloc(1)
+	c.compileExpression(instruction, register)
+	return register
+}
+
+// value gives the operand the operation keeps: the variables of the types copied on the assignment are copied.
+func (c *vmCompiler) value(instruction Instruction) int32 {
+	if instr, ok := instruction.(*VariableUsingInstruction); ok && !instr.reference {
+		if register, ok := c.lookup(instr.variableName); ok && !c.cells[register] && copied(c.variables[register]) {
+			res := c.alloc(1)
+			c.emit(vmCopy, res, register, 0)
+			return res
+		}
+	}
+
+	return c.read(instruction)
+}
+
+// compileExpression compiles the expression of one value into the register,
+// only the last instruction of the expression sets the register.
+func (c *vmCompiler) compileExpression(instruction Instruction, dst int32) {
+	switch instr := instruction.(type) {
+	case *IntUsingInstruction, *FloatUsingInstruction, *StringUsingInstruction, *BoolUsingInstruction,
+		*RuneUsingInstruction, *NilUsingInstruction:
+		c.emit(vmMove, dst, c.read(instr), 0)
+	case *ConstantInstruction:
+		c.emit(vmCopy, dst, c.constant(instr.value), 0)
+	case *FunctionUsingInstruction:
+		c.emit(vmMove, dst, c.constant(c.program.functions[instr.functionID]), 0)
+	case *PackageValueInstruction:
+		c.emit(vmPackage, dst, c.constant(instr.value), 0)
+	case *VariableUsingInstruction:
+		register, ok := c.resolve(instr.variableName)
+		if !ok {
+			return
+		}
+		copy := !instr.reference && copied(c.variables[register])
+		switch {
+		case c.cells[register]:
+			load := c.emit(vmLoad, dst, register, 0)
+			if copy {
+				c.code[load].c = 1
+			}
+		case copy:
+			c.emit(vmCopy, dst, register, 0)
+		default:
+			c.emit(vmMove, dst, register, 0)
+		}
+	case *AddInstruction:
+		c.compileArithmetic(vmAdd, instr.instructions, dst)
+	case *SubInstruction:
+		c.compileArithmetic(vmSub, instr.instructions, dst)
+	case *MulInstruction:
+		c.compileArithmetic(vmMul, instr.instructions, dst)
+	case *DivInstruction:
+		c.compileArithmetic(vmDiv, instr.instructions, dst)
+	case *OrInstruction:
+		c.compileShortCircuit(instr.instructions, true, dst)
+	case *AndInstruction:
+		c.compileShortCircuit(instr.instructions, false, dst)
+	case *NotInstruction:
+		c.emit(vmNot, dst, c.read(instr.instruction), 0)
+	case *CompareInstruction:
+		op, ok := vmCompareOpcodes[instr.compareType]
+		if !ok {
+			c.fail("comparison %v", instr.compareType)
+			return
+		}
+		lhv := c.read(instr.lhv)
+		rhv := c.read(instr.rhv)
+		c.emit(op, dst, lhv, rhv)
+	case *ConvertInstruction:
+		c.emit(vmConvert, dst, c.value(instr.instruction), c.typeOperand(instr.Type))
+	case *BoxInstruction:
+		c.emit(vmBox, dst, c.value(instr.instruction), c.typeOperand(instr.Type))
+	case *LenInstruction:
+		c.emit(vmLen, dst, c.read(instr.instruction), 0)
+	case *IndexInstruction:
+		container := c.read(instr.container)
+		index := c.read(instr.index)
+		op := vmIndex
+		if instr.reference {
+			op = vmIndexRef
+		}
+		at := c.emit(op, dst, container, index)
+		c.code[at].d = c.typeOperand(instr.Type)
+	case *FieldInstruction:
+		op := vmField
+		if instr.reference {
+			op = vmFieldRef
+		}
+		c.emit(op, dst, c.read(instr.structure), int32(instr.index))
+	case *FunctionCallInstruction:
+		c.compileCall(instr, dst, 1)
+	case *FunctionValueCallInstruction:
+		c.compileCallValue(instr, dst, 1)
+	default:
+		c.emit(vmEval, dst, c.escape(instruction), 1)
+	}
+}
+
+// compileValues compiles the expression of several values into the registers from dst.
+func (c *vmCompiler) compileValues(instruction Instruction, dst int32, count int) {
+	switch instr := instruction.(type) {
+	case *FunctionCallInstruction:
+		c.compileCall(instr, dst, count)
+	case *FunctionValueCallInstruction:
+		c.compileCallValue(instr, dst, count)
+	default:
+		if count == 1 {
+			c.compileExpression(instruction, dst)
+			return
+		}
+		c.emit(vmEval, dst, c.escape(instruction), int32(count))
+	}
+}
+
+// compileArithmetic evaluates the operands and combines them from the first one.
+func (c *vmCompiler) compileArithmetic(op vmOpcode, instructions []Instruction, dst int32) {
+	operands := make([]int32, len(instructions))
+	for i, instruction := range instructions {
+		operands[i] = c.read(instruction)
+	}
+
+	if len(operands) == 1 {
+		c.emit(vmMove, dst, operands[0], 0)
+		return
+	}
+	res := operands[0]
+	for i, operand := range operands[1:] {
+		target := dst
+		if i < len(operands)-2 {
+			target = c.alloc(1)
+		}
+		c.emit(op, target, res, operand)
+		res = target
+	}
+}
+
+// compileShortCircuit compiles && and ||, the operands after the one which has the value of the result are jumped over.
+func (c *vmCompiler) compileShortCircuit(instructions []Instruction, result bool, dst int32) {
+	res := c.alloc(1)
+	var ends []int
+	for i, instruction := range instructions {
+		c.compileExpression(instruction, res)
+		if i == len(instructions)-1 {
+			break
+		}
+
+		condition := res
+		if result {
+			condition = c.alloc(1)
+			c.emit(vmNot, condition, res, 0)
+		}
+		ends = append(ends, c.emit(vmJumpIfFalse, condition, 0, 0))
+	}
+	for _, end := range ends {
+		c.land(end)
+	}
+	c.emit(vmMove, dst, res, 0)
+}
+
+// resultCount is the count of the results of the function, -1 if it is not known.
+func (c *vmCompiler) resultCount(functionID int) int {
+	function, ok := c.program.functions[functionID].(TypedFunction)
+	if !ok {
+		return -1
+	}
+
+	return len(Results(function.Signature().Result))
+}
+
+// compileCall compiles the call of the function, the count of the results is checked if it is known.
+func (c *vmCompiler) compileCall(instr *FunctionCallInstruction, dst int32, results int) {
+	function := c.program.functions[instr.functionID]
+	if count := c.resultCount(instr.functionID); count >= 0 && results >= 0 && count != results {
+		c.fail("%v gives %v values, %v are used", function.Name(), count, results)
+		return
+	}
+
+	args := c.arguments(instr.arguments)
+	c.calls = append(c.calls, vmCallSite{function: function, args: c.argumentCount(instr.arguments), results: results})
+	c.emit(vmCall, dst, int32(len(c.calls)-1), args)
+}
+
+func (c *vmCompiler) compileCallValue(instr *FunctionValueCallInstruction, dst int32, results int) {
+	function := c.read(instr.function)
+	args := c.arguments(instr.arguments)
+	c.calls = append(c.calls, vmCallSite{args: c.argumentCount(instr.arguments), results: results})
+	at := c.emit(vmCallValue, dst, int32(len(c.calls)-1), args)
+	c.code[at].d = function
+}
+
+// arguments evaluates the arguments into the registers from the one returned,
+// the unpacking of the results of the call defines the temporaries the arguments after it use.
+func (c *vmCompiler) arguments(arguments []Instruction) int32 {
+	res := c.alloc(c.argumentCount(arguments))
+	i := int32(0)
+	for _, argument := range arguments {
+		if unpack, ok := argument.(*UnpackInstruction); ok {
+			c.unpack(unpack)
+			continue
+		}
+
+		c.compileExpression(argument, res+i)
+		i++
+	}
+
+	return res
+}
+
+func (c *vmCompiler) argumentCount(arguments []Instruction) int {
+	res := 0
+	for _, argument := range arguments {
+		if _, ok := argument.(*UnpackInstruction); !ok {
+			res++
+		}
+	}
+
+	return res
+}
+
+// escape adds the instruction tree which runs as it is. The tree gets the copies of the variables it uses,
+// it shares the ones in the cells: the closures it makes, the pointers it takes and the calls it defers.
+func (c *vmCompiler) escape(instruction Instruction) int32 {
+	escape := vmEscape{instruction: instruction}
+	// the temporaries of the unpacking are the variables of the tree itself
+	local := map[string]bool{}
+	seen := map[string]bool{}
+	add := func(name string, register int32) {
+		if !seen[name] {
+			seen[name] = true
+			escape.names = append(escape.names, name)
+			escape.registers = append(escape.registers, register)
+			escape.cells = append(escape.cells, c.cells[register])
+		}
+	}
+	shared := func(name string) {
+		register, ok := c.resolve(name)
+		switch {
+		case !ok:
+		case !c.cells[register]:
+			c.fail("variable %v is not in the cell", name)
+		default:
+			add(name, register)
+		}
+	}
+
+	var visit func(instruction Instruction)
+	visit = func(instruction Instruction) {
+		switch instr := instruction.(type) {
+		case *VariableUsingInstruction:
+			name := instr.variableName
+			if local[name] || seen[name] {
+				return
+			}
+			if register, ok := c.resolve(name); ok {
+				add(name, register)
+			}
+		case *VariableAddressInstruction:
+			shared(instr.variableName)
+		case *FunctionLiteralInstruction:
+			// the closure shares the variables it captures
+			for _, name := range c.program.compileBytecode(instr.function, nil).missing {
+				if !local[name] {
+					shared(name)
+				}
+			}
+			return
+		case *DeferInstruction:
+			add("@defers", c.defers)
+		case *UnpackInstruction:
+			visit(instr.value)
+			for _, name := range instr.temporaries {
+				local[name] = true
+			}
+			return
+		case *AssigmentInstruction, *DefineVariableInstruction, *MultiAssigmentInstruction, *SelectInstruction,
+			*BlockInstruction, *IFInstruction, *FORInstruction, *RangeInstruction, *ReturnInstruction,
+			*BreakInstruction, *GotoInstruction, *LabeledInstruction:
+			c.fail("%v", describeInstruction(instruction))
+			return
+		}
+
+		for _, child := range children(instruction) {
+			visit(child)
+		}
+	}
+	visit(instruction)
+
+	c.escapes = append(c.escapes, escape)
+	return int32(len(c.escapes) - 1)
+}
+
+var instructionType = reflect.TypeOf((*Instruction)(nil)).Elem()
+
+// children are the instructions the instruction is made of, in the order of its fields.
+func children(instruction Instruction) []Instruction {
+	var res []Instruction
+	var visit func(v reflect.Value)
+	visit = func(v reflect.Value) {
+		switch {
+		case v.Type() == instructionType:
+			if !v.IsNil() {
+				res = append(res, v.Interface().(Instruction))
+			}
+		case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+			for i := range v.Len() {
+				visit(v.Index(i))
+			}
+		case v.Kind() == reflect.Struct:
+			v = addressable(v)
+			for i := range v.NumField() {
+				visit(field(v, i))
+			}
+		}
+	}
+
+	if v := reflect.ValueOf(instruction); v.Kind() == reflect.Pointer && !v.IsNil() {
+		visit(v.Elem())
+	}
+	return res
+}
+
+// dumpBytecode writes the bytecode of the function after the pass or why it can't be compiled, if the dump is requested.
+func (prog *Program) dumpBytecode(name, pass string, f *vmFunction, unsupported string) {
+	if prog.IRDump == nil {
+		return
+	}
+
+	fmt.Fprintf(prog.IRDump, "%v after %v:\n", name, pass)
+	if unsupported != "" {
+		fmt.Fprintf(prog.IRDump, "\tnot compiled: %v\n", unsupported)
+		return
+	}
+	f.dump(prog.IRDump, "\t")
+}
+
+func (f *vmFunction) dump(w io.Writer, indent string) {
+	for pc, in := range f.code {
+		fmt.Fprintf(w, "%v%4d %v %v\n", indent, pc, stepMarker(in.steps), f.describe(in))
+	}
+}
+
+// stepMarker marks the instruction which starts the statements in the dump.
+func stepMarker(steps int32) string {
+	switch steps {
+	case 0:
+		return " "
+	case 1:
+		return "*"
+	}
+	return fmt.Sprintf("*%v", steps)
+}
+
+func (f *vmFunction) describe(in vmInstruction) string {
+	name := vmOpcodeNames[in.op]
+	switch in.op {
+	case vmMove, vmCopy, vmNot, vmLen:
+		return fmt.Sprintf("%v %v, %v", name, f.operand(in.a), f.operand(in.b))
+	case vmZero:
+		return fmt.Sprintf("%v %v, %v", name, f.operand(in.a), f.types[in.b])
+	case vmConvert, vmBox:
+		return fmt.Sprintf("%v %v, %v, %v", name, f.operand(in.a), f.operand(in.b), f.types[in.c])
+	case vmField, vmFieldRef:
+		return fmt.Sprintf("%v %v, %v, %v", name, f.operand(in.a), f.operand(in.b), in.c)
+	case vmPackage:
+		return fmt.Sprintf("%v %v, %v", name, f.operand(in.a), f.constants[-1-in.b].ref.(*PackageValue).Value)
+	case vmEval, vmExec:
+		return fmt.Sprintf("%v %v, %v, %v", name, f.operand(in.a), describeInstruction(f.escapes[in.b].instruction), in.c)
+	case vmCall:
+		call := f.calls[in.b]
+		return fmt.Sprintf("%v %v, %v(%v args from %v)", name, f.operand(in.a), call.function.Name(), call.args, f.operand(in.c))
+	case vmCallValue:
+		return fmt.Sprintf("%v %v, %v(%v args from %v)", name, f.operand(in.a), f.operand(in.d), f.calls[in.b].args, f.operand(in.c))
+	case vmJump:
+		return fmt.Sprintf("%v %v", name, in.a)
+	case vmJumpIfFalse:
+		return fmt.Sprintf("%v %v, %v", name, f.operand(in.a), in.b)
+	case vmRange:
+		return fmt.Sprintf("%v %v, %v, kind %v", name, f.operand(in.a), f.operand(in.b), in.c)
+	case vmNext:
+		return fmt.Sprintf("%v %v, %v, %v", name, f.operand(in.a), f.operand(in.b), in.c)
+	case vmReturn, vmMissingReturn:
+		return name
+	case vmCell, vmLoad, vmStore:
+		if in.c != 0 {
+			return fmt.Sprintf("%v %v, %v, copy", name, f.operand(in.a), f.operand(in.b))
+		}
+		return fmt.Sprintf("%v %v, %v", name, f.operand(in.a), f.operand(in.b))
+	case vmSelect:
+		return fmt.Sprintf("%v %v, cases from %v to %v", name, f.operand(in.a), f.operand(in.c), f.selects[in.b].targets)
+	case vmRangeFunc:
+		return fmt.Sprintf("%v %v, %v, body %v, %v", name, f.operand(in.a), f.operand(in.b), in.d, in.c)
+	case vmTailCall:
+		return fmt.Sprintf("%v %v", name, f.operand(in.a))
+	case vmDepth:
+		return fmt.Sprintf("%v %v", name, f.operand(in.b))
+	}
+
+	return fmt.Sprintf("%v %v, %v, %v", name, f.operand(in.a), f.operand(in.b), f.operand(in.c))
+}
+
+func (f *vmFunction) operand(operand int32) string {
+	if operand >= 0 {
+		return fmt.Sprintf("r%v", operand)
+	}
+
+	switch value := f.constants[-1-operand].boxed().(type) {
+	case string:
+		return fmt.Sprintf("%q", value)
+	case Function:
+		return value.Name()
+	default:
+		return fmt.Sprint(value)
+	}
+}
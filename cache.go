@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Cache is the directory of the compiled scripts, the image of the script is found by the hash of its source.
+// The nil cache keeps nothing.
+type Cache struct {
+	dir string
+	// the interpreter which has made the images, the images of the other builds are not used
+	executable string
+}
+
+// OpenCache opens the cache in the directory, the user cache directory is used by default.
+// The cache is nil when there is no place for it.
+func OpenCache(dir string) *Cache {
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(userDir, "gointerpreter")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	info, err := os.Stat(executable)
+	if err != nil {
+		return nil
+	}
+
+	return &Cache{
+		dir:        dir,
+		executable: fmt.Sprintf("%v %v %v", executable, info.Size(), info.ModTime().UnixNano()),
+	}
+}
+
+// key is the hash of the files of the main package, the rest of the sources is checked by the image itself.
+func (c *Cache) key(path string) (string, error) {
+	fileNames := []string{path}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		fileNames, err = packageFiles(path)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	hash := sha256.New()
+	fmt.Fprintln(hash, imageVersion, c.executable)
+	for _, fileName := range fileNames {
+		fileName, err := filepath.Abs(fileName)
+		if err != nil {
+			return "", err
+		}
+		source, err := os.ReadFile(fileName)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintln(hash, fileName, len(source))
+		hash.Write(source)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Load loads the compiled script into the new program, the program is nil when the script is not cached
+// or its sources have changed.
+func (c *Cache) Load(path string, newProgram func() *Program) *Program {
+	if c == nil {
+		return nil
+	}
+
+	key, err := c.key(path)
+	if err != nil {
+		return nil
+	}
+	file, err := os.Open(filepath.Join(c.dir, key+".gob"))
+	if err != nil {
+		return nil
+	}
+	img, err := readImage(file)
+	file.Close()
+	if err != nil || !img.fresh() {
+		return nil
+	}
+
+	program := newProgram()
+	if program.Load(img) != nil {
+		return nil
+	}
+
+	return program
+}
+
+// Save keeps the compiled script, the externals are found before the script is compiled.
+// The image is written aside and then renamed, so the other interpreters never see it half written.
+func (c *Cache) Save(path string, program *Program, externals *externals, loader *PackageLoader) error {
+	if c == nil {
+		return nil
+	}
+
+	key, err := c.key(path)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(c.dir, 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = program.Save(file, c.dir, externals, loader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filepath.Join(c.dir, key+".gob"))
+}
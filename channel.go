@@ -0,0 +1,147 @@
+package main
+
+import "errors"
+
+// ChannelValue is the value of a channel. The unbuffered channel keeps the value sent
+// until the receiver takes it, the sender waits for the receiver meanwhile.
+type ChannelValue struct {
+	Elem     Type
+	capacity int
+	buffer   []any
+	// the count of the values sent and received, the sender waits for its value to be received
+	sent, received int
+	// the count of the goroutines waiting for a value
+	receivers int
+	closed    bool
+}
+
+// channelKey is the object the goroutines waiting for the channel wait for,
+// the senders and the receivers wait apart.
+type channelKey struct {
+	channel *ChannelValue
+	send    bool
+}
+
+var errSendOnClosed = errors.New("send on closed channel")
+
+func NewChannel(elem Type, capacity int) *ChannelValue {
+	return &ChannelValue{Elem: elem, capacity: capacity}
+}
+
+// Len is the count of the values in the buffer, the value sent to the unbuffered channel is not in the buffer.
+func (ch *ChannelValue) Len() int {
+	if ch.capacity == 0 {
+		return 0
+	}
+
+	return len(ch.buffer)
+}
+
+// canSend reports whether the value can be sent without waiting: there is room in the buffer
+// or, for the unbuffered channel, the receiver waits. The send on the closed channel panics right away.
+func (ch *ChannelValue) canSend() bool {
+	if ch.closed {
+		return true
+	}
+	if ch.capacity == 0 {
+		return ch.receivers > len(ch.buffer)
+	}
+
+	return len(ch.buffer) < ch.capacity
+}
+
+// canReceive reports whether the value can be received without waiting.
+func (ch *ChannelValue) canReceive() bool {
+	return ch.closed || len(ch.buffer) != 0
+}
+
+// send sends the value, the goroutine waits for the room in the buffer and, if the channel is unbuffered, for the receiver.
+func (prog *Program) send(ch *ChannelValue, value any) error {
+	if ch == nil {
+		return prog.wait()
+	}
+
+	for !ch.closed && len(ch.buffer) >= max(ch.capacity, 1) {
+		if err := prog.wait(channelKey{ch, true}); err != nil {
+			return err
+		}
+	}
+	if ch.closed {
+		return errSendOnClosed
+	}
+
+	ch.buffer = append(ch.buffer, value)
+	ch.sent++
+	prog.notify(channelKey{ch, false})
+	if ch.capacity != 0 {
+		return nil
+	}
+
+	for sent := ch.sent; ch.received < sent; {
+		// the value not received is dropped when the channel is closed
+		if ch.closed {
+			return errSendOnClosed
+		}
+		if err := prog.wait(channelKey{ch, true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trySend sends the value if the buffer has room for it, otherwise the value is dropped.
+func (prog *Program) trySend(ch *ChannelValue, value any) {
+	if ch.closed || len(ch.buffer) >= max(ch.capacity, 1) {
+		return
+	}
+
+	ch.buffer = append(ch.buffer, value)
+	ch.sent++
+	prog.notify(channelKey{ch, false})
+}
+
+// receive receives the value, it is the zero value and false when the channel is closed and empty.
+func (prog *Program) receive(ch *ChannelValue) (any, bool, error) {
+	if ch == nil {
+		return nil, false, prog.wait()
+	}
+
+	for len(ch.buffer) == 0 {
+		if ch.closed {
+			return NewVariable(ch.Elem), false, nil
+		}
+
+		ch.receivers++
+		// the sender of the unbuffered channel may send when the receiver waits
+		prog.notify(channelKey{ch, true})
+		err := prog.wait(channelKey{ch, false})
+		ch.receivers--
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	value := ch.buffer[0]
+	ch.buffer = ch.buffer[1:]
+	ch.received++
+	prog.notify(channelKey{ch, true})
+	return value, true, nil
+}
+
+// close closes the channel, the goroutines waiting for it go on.
+func (prog *Program) close(ch *ChannelValue) error {
+	if ch == nil {
+		return errors.New("close of nil channel")
+	}
+	if ch.closed {
+		return errors.New("close of closed channel")
+	}
+
+	ch.closed = true
+	if ch.capacity == 0 {
+		ch.buffer = nil
+	}
+	prog.notify(channelKey{ch, true})
+	prog.notify(channelKey{ch, false})
+	return nil
+}
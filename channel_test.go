@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antlr4-go/antlr/v4"
+)
+
+// compileScript compiles the source as the main package of the program.
+func compileScript(t testing.TB, source string) *Program {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	err := os.WriteFile(path, []byte(source), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewPackageLoader()
+	_, err = loader.LoadMain(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	program := NewProgram()
+	for _, pkg := range loader.Packages {
+		if pkg.Std != nil {
+			pkg.Std.Declare(program, pkg)
+		}
+	}
+
+	var errs []error
+	for _, pkg := range loader.Packages {
+		for _, file := range pkg.Files {
+			typeListner := NewGoTypeListener(program, pkg, file)
+			antlr.ParseTreeWalkerDefault.Walk(typeListner, file.Tree)
+			errs = append(errs, typeListner.Errors...)
+		}
+	}
+	for _, pkg := range loader.Packages {
+		for _, file := range pkg.Files {
+			declarationListner := NewGoDeclarationListener(program, pkg, file)
+			antlr.ParseTreeWalkerDefault.Walk(declarationListner, file.Tree)
+			errs = append(errs, declarationListner.Errors...)
+		}
+	}
+	for _, pkg := range loader.Packages {
+		for _, file := range pkg.Files {
+			for _, function := range file.Tree.AllFunctionDefinition() {
+				if IsGeneric(function) {
+					continue
+				}
+
+				compileListner := NewGoCompilerListener(program, pkg, file)
+				antlr.ParseTreeWalkerDefault.Walk(compileListner, function)
+				errs = append(errs, compileListner.Errors...)
+			}
+		}
+	}
+	errs = append(errs, program.CompileInstances()...)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	return program
+}
+
+// captureOutput runs the function with the standard output written into the buffer.
+func captureOutput(t *testing.T, run func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	output := make(chan string)
+	go func() {
+		var res bytes.Buffer
+		io.Copy(&res, reader)
+		output <- res.String()
+	}()
+
+	run()
+	writer.Close()
+	return <-output
+}
+
+var backends = []string{BackendCode, BackendClosures, BackendVM}
+
+const pipeline = `package main
+
+import "fmt"
+
+func stage(in <-chan int, out chan<- int) {
+	for v := range in {
+		out <- v + 1;
+	}
+	close(out);
+}
+
+func main() {
+	first := make(chan int);
+	in := first;
+	for range 100 {
+		out := make(chan int);
+		go stage(in, out);
+		in = out;
+	}
+	go func() {
+		for i := range 3 {
+			first <- i * 1000;
+		}
+		close(first);
+	}();
+	for v := range in {
+		fmt.Println(v);
+	}
+}
+`
+
+const choices = `package main
+
+func main() {
+	a := make(chan string, 100);
+	b := make(chan string, 100);
+	for range 100 {
+		a <- "a";
+		b <- "b";
+	}
+	res := "";
+	for range 100 {
+		select {
+		case v := <-a:
+			res = res + v;
+		case v := <-b:
+			res = res + v;
+		}
+	}
+	println(res);
+}
+`
+
+func TestChannels(t *testing.T) {
+	for _, backend := range backends {
+		t.Run("pipeline/"+backend, func(t *testing.T) {
+			prog := compileScript(t, pipeline)
+			prog.Backend = backend
+
+			var err error
+			output := captureOutput(t, func() {
+				err = prog.Execute(context.Background())
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := "100\n1100\n2100\n"
+			if output != expected {
+				t.Errorf("the output is %q, %q is expected", output, expected)
+			}
+		})
+
+		t.Run("select/"+backend, func(t *testing.T) {
+			var outputs []string
+			for range 2 {
+				prog := compileScript(t, choices)
+				prog.Backend = backend
+
+				var err error
+				outputs = append(outputs, captureOutput(t, func() {
+					err = prog.Execute(context.Background())
+				}))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if outputs[0] != outputs[1] {
+				t.Errorf("the select statement has chosen %q and then %q", outputs[0], outputs[1])
+			}
+			if !strings.Contains(outputs[0], "a") || !strings.Contains(outputs[0], "b") {
+				t.Errorf("the select statement has always chosen the same case: %q", outputs[0])
+			}
+		})
+
+		for _, test := range []struct {
+			name string
+			body string
+		}{
+			{"unbuffered", "ch := make(chan int);\n\tch <- 1;"},
+			{"full", "ch := make(chan int, 1);\n\tch <- 1;\n\tch <- 2;"},
+			{"nil", "var ch chan int;\n\t<-ch;"},
+			{"empty select", "select {\n\t}"},
+		} {
+			t.Run("deadlock/"+test.name+"/"+backend, func(t *testing.T) {
+				prog := compileScript(t, "package main\n\nfunc main() {\n\t"+test.body+"\n}\n")
+				prog.Backend = backend
+
+				err := prog.Execute(context.Background())
+				if err != errDeadlock {
+					t.Errorf("the error is %T %q, %q is expected", err, err, errDeadlock)
+				}
+			})
+		}
+
+		t.Run("closed/"+backend, func(t *testing.T) {
+			prog := compileScript(t, "package main\n\nfunc main() {\n\tch := make(chan int);\n\tclose(ch);\n\tch <- 1;\n}\n")
+			prog.Backend = backend
+
+			err := prog.Execute(context.Background())
+			if err == nil || err.Error() != "send on closed channel" || fatal(err) {
+				t.Errorf("the error is %T %q, the panic \"send on closed channel\" is expected", err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,872 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// the backends run the lowered code
+const (
+	// BackendCode dispatches on the operations of the code and executes the instruction trees,
+	// the values are boxed in any, it is the reference the other backends are compared with
+	BackendCode = "code"
+	// BackendClosures compiles the code once into the Go closures specialized by the types of the operands,
+	// the values are boxed in any like in the instruction trees
+	BackendClosures = "closures"
+	// BackendVM compiles the functions into the bytecode of the register machine, the registers hold the tagged
+	// values, the functions it can't compile run on the closures
+	BackendVM = "vm"
+)
+
+// frame is the state of the code while it runs. The scopes share the map of the variables:
+// the variables the scope shadows are restored when it is left, so entering the scope copies nothing.
+type frame struct {
+	variables map[string]*any
+	// the cells of the variables by the slots the closures are compiled with, the map is kept
+	// for the instructions which are executed as they are
+	cells    []*any
+	shadowed []shadowed
+	// the count of the shadowed variables at the entry of each scope
+	scopes []int
+}
+
+// shadowed is the variable declared before the scope, the nil cell means that there was no variable.
+type shadowed struct {
+	name string
+	cell *any
+}
+
+// define declares the variable in the current scope.
+func (f *frame) define(name string, cell *any) {
+	f.shadow(name)
+	f.variables[name] = cell
+}
+
+// shadow keeps the variable the current scope declares again, it is restored when the scope is left.
+func (f *frame) shadow(name string) {
+	if len(f.scopes) != 0 {
+		f.shadowed = append(f.shadowed, shadowed{name: name, cell: f.variables[name]})
+	}
+}
+
+// declare keeps the variables the statement declares, the statement defines them in the map itself.
+func (f *frame) declare(instruction Instruction) {
+	switch instr := instruction.(type) {
+	case *DefineVariableInstruction:
+		f.shadow(instr.Name)
+	case *MultiAssigmentInstruction:
+		for _, assigment := range instr.assigments {
+			f.declare(assigment)
+		}
+	}
+}
+
+// leave leaves the scopes deeper than the depth.
+func (f *frame) leave(depth int) {
+	if depth >= len(f.scopes) {
+		return
+	}
+
+	mark := f.scopes[depth]
+	for i := len(f.shadowed) - 1; i >= mark; i-- {
+		if variable := f.shadowed[i]; variable.cell != nil {
+			f.variables[variable.name] = variable.cell
+		} else {
+			delete(f.variables, variable.name)
+		}
+	}
+	f.shadowed = f.shadowed[:mark]
+	f.scopes = f.scopes[:depth]
+}
+
+// step runs the operation and tells the next one, it is returned when the function is left by the return statement.
+type step func(f *frame) (next int, err error)
+
+const returned = -1
+
+// eval computes the value of the expression without the stack.
+type eval func(f *frame) (any, error)
+
+// closureCompiler is the state of the code being compiled into the closures.
+type closureCompiler struct {
+	*Code
+	// the slots of the variables declared in the scopes of the code, the innermost scope is the last
+	scopes []map[string]int
+	// the slots of the variables declared out of the code, they are looked up by the name once per run
+	outer map[string]int
+	slots int
+}
+
+// compile compiles the code into the closures, the code runs them instead of its operations.
+func (c *Code) compile() {
+	compiler := &closureCompiler{Code: c, outer: map[string]int{}}
+	steps := make([]step, len(c.ops))
+	for pc := range c.ops {
+		compiler.enter(c.ops[pc].depth)
+		steps[pc] = compiler.compileOp(pc)
+	}
+	slots := compiler.slots
+	// the frames of the runs which are over, the code of the loop body runs for each iteration
+	var frames []*frame
+
+	c.compiled = func(variables map[string]*any) (bool, error) {
+		f := &frame{cells: make([]*any, slots)}
+		if n := len(frames); n != 0 {
+			f, frames = frames[n-1], frames[:n-1]
+		}
+		f.variables = variables
+
+		stacklen := len(c.program.stack)
+		defer func() {
+			c.program.stack = c.program.stack[:stacklen]
+			clear(f.cells)
+			*f = frame{cells: f.cells, shadowed: f.shadowed[:0], scopes: f.scopes[:0]}
+			frames = append(frames, f)
+		}()
+
+		for pc := 0; pc < len(steps); {
+			if err := c.program.step(); err != nil {
+				return false, err
+			}
+			next, err := steps[pc](f)
+			if err != nil {
+				return false, err
+			}
+			if next == returned {
+				return true, nil
+			}
+			pc = next
+		}
+
+		return false, nil
+	}
+}
+
+// enter makes the scopes the operation at the depth sees, the deeper scopes are left.
+func (c *closureCompiler) enter(depth int) {
+	c.scopes = c.scopes[:min(len(c.scopes), depth+1)]
+	for len(c.scopes) <= depth {
+		c.scopes = append(c.scopes, map[string]int{})
+	}
+}
+
+// declare gives the slot to the variable declared in the current scope.
+func (c *closureCompiler) declare(name string) int {
+	c.slots++
+	c.scopes[len(c.scopes)-1][name] = c.slots - 1
+	return c.slots - 1
+}
+
+// variable compiles the access to the cell of the variable by its slot,
+// the variable declared out of the code is looked up in the map when it is reached first.
+func (c *closureCompiler) variable(name string) func(f *frame) (*any, error) {
+	slot, ok := c.outer[name]
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if declared, found := c.scopes[i][name]; found {
+			slot, ok = declared, true
+			break
+		}
+	}
+	if !ok {
+		slot = c.slots
+		c.slots++
+		c.outer[name] = slot
+	}
+
+	return func(f *frame) (*any, error) {
+		if cell := f.cells[slot]; cell != nil {
+			return cell, nil
+		}
+
+		cell, ok := f.variables[name]
+		if !ok {
+			return nil, fmt.Errorf("variable %v not declarated", name)
+		}
+		f.cells[slot] = cell
+		return cell, nil
+	}
+}
+
+func (c *closureCompiler) compileOp(pc int) step {
+	op := c.ops[pc]
+	jump := func(f *frame) (int, error) {
+		f.leave(op.depth)
+		return op.target, nil
+	}
+
+	switch op.code {
+	case opExec:
+		return c.compileStatement(pc, op.instruction)
+	case opJump:
+		return jump
+	case opJumpIfFalse:
+		condition := c.compileExpression(op.instruction)
+		return func(f *frame) (int, error) {
+			value, err := condition(f)
+			if err != nil {
+				return 0, err
+			}
+
+			res, ok := value.(bool)
+			if !ok {
+				return 0, fmt.Errorf("statement: %v(type: %v) is not bool", value, reflect.TypeOf(value))
+			}
+			if !res {
+				return jump(f)
+			}
+			return pc + 1, nil
+		}
+	case opEnterScope:
+		c.scopes = append(c.scopes, map[string]int{})
+		return func(f *frame) (int, error) {
+			f.scopes = append(f.scopes, len(f.shadowed))
+			return pc + 1, nil
+		}
+	case opLeaveScope:
+		return func(f *frame) (int, error) {
+			f.leave(len(f.scopes) - 1)
+			return pc + 1, nil
+		}
+	case opReturn:
+		return c.compileReturn(op.instruction.(*ReturnInstruction))
+	case opTailCall:
+		instr := op.instruction.(*ReturnInstruction)
+		return func(f *frame) (int, error) {
+			return 0, c.tailCall(instr, f.variables)
+		}
+	case opBreak:
+		return func(f *frame) (int, error) {
+			return 0, BreakError{}
+		}
+	case opGoto:
+		return func(f *frame) (int, error) {
+			return 0, GotoError{label: op.label}
+		}
+	}
+
+	return func(f *frame) (int, error) {
+		return pc + 1, nil
+	}
+}
+
+// compileStatement compiles the statement, the statements which are not specialized are executed as they are.
+func (c *closureCompiler) compileStatement(pc int, instruction Instruction) step {
+	switch instr := instruction.(type) {
+	case *DefineVariableInstruction, *AssigmentInstruction:
+		assign := c.compileAssigment(instr)
+		return func(f *frame) (int, error) {
+			if err := assign(f); err != nil {
+				return c.unwind(f, pc, err)
+			}
+			return pc + 1, nil
+		}
+	case *MultiAssigmentInstruction:
+		// all the values are evaluated into the temporaries before the assignments use them
+		temporaries := make([]int, len(instr.temporaries))
+		for i, name := range instr.temporaries {
+			temporaries[i] = c.declare(name)
+		}
+		assigments := make([]func(f *frame) error, len(instr.assigments))
+		for i, assigment := range instr.assigments {
+			assigments[i] = c.compileAssigment(assigment)
+		}
+		return func(f *frame) (int, error) {
+			values, err := unpackValues(c.program, instr.values, len(instr.temporaries), f.variables)
+			for i := 0; err == nil && i < len(temporaries); i++ {
+				value := values[i]
+				f.cells[temporaries[i]] = &value
+				f.variables[instr.temporaries[i]] = &value
+			}
+			for i := 0; err == nil && i < len(assigments); i++ {
+				err = assigments[i](f)
+			}
+			if err != nil {
+				return c.unwind(f, pc, err)
+			}
+			return pc + 1, nil
+		}
+	case *RangeInstruction:
+		if instr.kind != rangeFunction {
+			return c.compileRange(pc, instr)
+		}
+	case *FunctionCallInstruction:
+		call := c.compileCall(instr)
+		return func(f *frame) (int, error) {
+			_, err := call(f)
+			if err != nil {
+				return c.unwind(f, pc, err)
+			}
+			return pc + 1, nil
+		}
+	}
+
+	return func(f *frame) (int, error) {
+		stacklen := len(c.program.stack)
+		err := instruction.Execute(f.variables)
+		c.program.stack = c.program.stack[:stacklen]
+		if err != nil {
+			return c.unwind(f, pc, err)
+		}
+		return pc + 1, nil
+	}
+}
+
+// compileAssigment compiles the assignment to the variable or the declaration of the variable in the current scope,
+// the other assignments are executed as they are.
+func (c *closureCompiler) compileAssigment(instruction Instruction) func(f *frame) error {
+	switch instr := instruction.(type) {
+	case *DefineVariableInstruction:
+		if instr.value == nil {
+			slot := c.declare(instr.Name)
+			return func(f *frame) error {
+				value := NewVariable(instr.Type)
+				f.define(instr.Name, &value)
+				f.cells[slot] = &value
+				return nil
+			}
+		}
+
+		// the value is compiled before the variable is declared, it sees the variable of the outer scope
+		value := c.compileExpression(instr.value)
+		slot := c.declare(instr.Name)
+		return func(f *frame) error {
+			res, err := value(f)
+			if err != nil {
+				return err
+			}
+
+			f.define(instr.Name, &res)
+			f.cells[slot] = &res
+			return nil
+		}
+	case *AssigmentInstruction:
+		value := c.compileExpression(instr.instruction)
+		variable := c.variable(instr.varName)
+		return func(f *frame) error {
+			res, err := value(f)
+			if err != nil {
+				return err
+			}
+
+			cell, err := variable(f)
+			if err != nil {
+				return fmt.Errorf("variable %v undefined", instr.varName)
+			}
+			*cell = CloneAny(res)
+			return nil
+		}
+	}
+
+	return func(f *frame) error {
+		return instruction.Execute(f.variables)
+	}
+}
+
+// unwind handles the error of the statement: the return of the range loop body leaves the function,
+// the goto from it goes on at the label if the label is in the code.
+func (c *closureCompiler) unwind(f *frame, pc int, err error) (int, error) {
+	switch jump := err.(type) {
+	case ReturnError:
+		return returned, nil
+	case GotoError:
+		target, ok := c.labels[jump.label]
+		if !ok {
+			return 0, err
+		}
+		f.leave(target.depth)
+		return target.pc, nil
+	}
+
+	return 0, err
+}
+
+// compileRange compiles the range loop, the iteration variables are declared in the variables of the loop
+// for the time of the iteration, each iteration has its own ones.
+func (c *closureCompiler) compileRange(pc int, instr *RangeInstruction) step {
+	container := c.compileExpression(instr.container)
+	body := instr.body.(*CodeInstruction).code
+
+	// iterate runs the body, done reports that the loop is over, the next operation is known then
+	iterate := func(f *frame, values ...any) (done bool, next int, err error) {
+		depth := len(f.scopes)
+		f.scopes = append(f.scopes, len(f.shadowed))
+		for i, name := range instr.names {
+			if name == "" || i >= len(values) {
+				continue
+			}
+
+			value := values[i]
+			f.define(name, &value)
+		}
+		for _, assigment := range instr.assigments {
+			if err = assigment.Execute(f.variables); err != nil {
+				break
+			}
+		}
+
+		left := false
+		if err == nil {
+			left, err = body.Run(f.variables)
+		}
+		f.leave(depth)
+
+		switch err.(type) {
+		case nil:
+			if left {
+				return true, returned, nil
+			}
+			return false, 0, nil
+		case BreakError:
+			return true, pc + 1, nil
+		}
+		next, err = c.unwind(f, pc, err)
+		return true, next, err
+	}
+
+	return func(f *frame) (int, error) {
+		value, err := container(f)
+		if err != nil {
+			return c.unwind(f, pc, err)
+		}
+
+		switch instr.kind {
+		case rangeSlice:
+			slice := elements(value)
+			for i := range slice {
+				if done, next, err := iterate(f, i, CloneAny(slice[i])); done {
+					return next, err
+				}
+			}
+		case rangeString:
+			for i, r := range value.(string) {
+				if done, next, err := iterate(f, i, r); done {
+					return next, err
+				}
+			}
+		case rangeMap:
+			for key, value := range value.(map[any]any) {
+				if done, next, err := iterate(f, key, CloneAny(value)); done {
+					return next, err
+				}
+			}
+		case rangeInt:
+			switch n := value.(type) {
+			case int:
+				for i := range n {
+					if done, next, err := iterate(f, i); done {
+						return next, err
+					}
+				}
+			case int32:
+				for i := range n {
+					if done, next, err := iterate(f, i); done {
+						return next, err
+					}
+				}
+			case uint8:
+				for i := range n {
+					if done, next, err := iterate(f, i); done {
+						return next, err
+					}
+				}
+			}
+		case rangeChannel:
+			ch, _ := value.(*ChannelValue)
+			for {
+				value, ok, err := c.program.receive(ch)
+				if err != nil {
+					return c.unwind(f, pc, err)
+				}
+				if !ok {
+					break
+				}
+				if done, next, err := iterate(f, value); done {
+					return next, err
+				}
+			}
+		}
+
+		return pc + 1, nil
+	}
+}
+
+// compileReturn sets the results right from the expressions if each of them has the single value,
+// the call with several results is returned through the stack.
+func (c *closureCompiler) compileReturn(instr *ReturnInstruction) step {
+	expressions := make([]eval, len(instr.expressions))
+	for i, expression := range instr.expressions {
+		if !c.singleValued(expression) {
+			return func(f *frame) (int, error) {
+				if err := instr.setResults(f.variables); err != nil {
+					return 0, err
+				}
+				return returned, nil
+			}
+		}
+		expressions[i] = c.compileExpression(expression)
+	}
+	results := make([]func(f *frame) (*any, error), len(expressions))
+	for i := range results {
+		results[i] = c.variable(resultName(i))
+	}
+
+	return func(f *frame) (int, error) {
+		// the results are set after all the expressions are evaluated: they may use the named results
+		values := make([]any, len(expressions))
+		for i, expression := range expressions {
+			value, err := expression(f)
+			if err != nil {
+				return 0, err
+			}
+			values[i] = value
+		}
+
+		for i, value := range values {
+			cell, err := results[i](f)
+			if err != nil {
+				return 0, err
+			}
+			*cell = value
+		}
+		return returned, nil
+	}
+}
+
+// singleValued reports that the expression is known to have exactly one value, the calls may have several.
+func (c *Code) singleValued(instruction Instruction) bool {
+	switch instr := instruction.(type) {
+	case *FunctionCallInstruction:
+		function, ok := c.program.functions[instr.functionID].(TypedFunction)
+		if !ok {
+			return false
+		}
+		result := function.Signature().Result
+		_, tuple := result.(*TupleType)
+		return result != nil && !tuple
+	case *ConstantInstruction, *IntUsingInstruction, *FloatUsingInstruction, *StringUsingInstruction, *BoolUsingInstruction, *RuneUsingInstruction,
+		*NilUsingInstruction, *VariableUsingInstruction, *PackageValueInstruction, *FunctionUsingInstruction, *FunctionLiteralInstruction,
+		*AddInstruction, *SubInstruction, *MulInstruction, *DivInstruction, *OrInstruction, *AndInstruction, *NotInstruction, *CompareInstruction,
+		*ConvertInstruction, *BoxInstruction, *IndexInstruction, *SliceInstruction, *FieldInstruction, *LenInstruction,
+		*SliceLiteralInstruction, *ArrayLiteralInstruction, *MapLiteralInstruction, *StructLiteralInstruction:
+		return true
+	}
+
+	return false
+}
+
+// compileExpression compiles the expression, the expressions which are not specialized are executed on the stack.
+func (c *closureCompiler) compileExpression(instruction Instruction) eval {
+	switch instr := instruction.(type) {
+	case *ConstantInstruction:
+		value := instr.value
+		return func(f *frame) (any, error) {
+			return CloneAny(value), nil
+		}
+	case *IntUsingInstruction:
+		return constant(instr.integer)
+	case *FloatUsingInstruction:
+		return constant(instr.float)
+	case *StringUsingInstruction:
+		return constant(instr.str)
+	case *BoolUsingInstruction:
+		return constant(instr.boolVal)
+	case *RuneUsingInstruction:
+		return constant(instr.value)
+	case *VariableUsingInstruction:
+		variable := c.variable(instr.variableName)
+		return func(f *frame) (any, error) {
+			cell, err := variable(f)
+			if err != nil {
+				return nil, err
+			}
+
+			if instr.reference {
+				return *cell, nil
+			}
+			return CloneAny(*cell), nil
+		}
+	case *AddInstruction:
+		return c.compileArithmetic(instr.instructions, instr.Type, c.program.add, add[int], add[float64])
+	case *SubInstruction:
+		return c.compileArithmetic(instr.instructions, instr.Type, SubAny, sub[int], sub[float64])
+	case *MulInstruction:
+		return c.compileArithmetic(instr.instructions, instr.Type, MulAny, mul[int], mul[float64])
+	case *DivInstruction:
+		return c.compileArithmetic(instr.instructions, instr.Type, DivAny, nil, div[float64])
+	case *OrInstruction:
+		return c.compileShortCircuit(instr.instructions, OrAny, true)
+	case *AndInstruction:
+		return c.compileShortCircuit(instr.instructions, AndAny, false)
+	case *NotInstruction:
+		operand := c.compileExpression(instr.instruction)
+		return func(f *frame) (any, error) {
+			value, err := operand(f)
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := value.(bool); ok {
+				return !value, nil
+			}
+			return NotAny(value)
+		}
+	case *ConvertInstruction:
+		operand := c.compileExpression(instr.instruction)
+		return func(f *frame) (any, error) {
+			value, err := operand(f)
+			if err != nil {
+				return nil, err
+			}
+			return ConvertAny(value, instr.Type), nil
+		}
+	case *CompareInstruction:
+		return c.compileCompare(instr)
+	case *FunctionCallInstruction:
+		if !c.singleValued(instr) {
+			break
+		}
+
+		call := c.compileCall(instr)
+		return func(f *frame) (any, error) {
+			res, err := call(f)
+			if err != nil {
+				return nil, err
+			}
+			return res[0], nil
+		}
+	}
+
+	return func(f *frame) (any, error) {
+		stacklen := len(c.program.stack)
+		err := instruction.Execute(f.variables)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(c.program.stack) != stacklen+1 {
+			return nil, fmt.Errorf("wrong count of return values of statement")
+		}
+		value := c.program.stack[stacklen]
+		c.program.stack = c.program.stack[:stacklen]
+		return value, nil
+	}
+}
+
+func constant[T int | int32 | float64 | string | bool](value T) eval {
+	return func(f *frame) (any, error) {
+		return value, nil
+	}
+}
+
+// compileCall compiles the call of the function, the arguments with several values are evaluated on the stack.
+func (c *closureCompiler) compileCall(instr *FunctionCallInstruction) func(f *frame) ([]any, error) {
+	function := c.program.functions[instr.functionID]
+	arguments := make([]eval, len(instr.arguments))
+	for i, argument := range instr.arguments {
+		if !c.singleValued(argument) {
+			return func(f *frame) ([]any, error) {
+				stacklen := len(c.program.stack)
+				for _, argument := range instr.arguments {
+					if err := argument.Execute(f.variables); err != nil {
+						return nil, err
+					}
+				}
+				args := slices.Clone(c.program.stack[stacklen:])
+				c.program.stack = c.program.stack[:stacklen]
+
+				return function.Call(args...)
+			}
+		}
+		arguments[i] = c.compileExpression(argument)
+	}
+
+	return func(f *frame) ([]any, error) {
+		args := make([]any, len(arguments))
+		for i, argument := range arguments {
+			value, err := argument(f)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		}
+
+		return function.Call(args...)
+	}
+}
+
+func add[T int | float64](a, b T) T { return a + b }
+func sub[T int | float64](a, b T) T { return a - b }
+func mul[T int | float64](a, b T) T { return a * b }
+func div[T int | float64](a, b T) T { return a / b }
+
+// compileArithmetic compiles the operation on several operands, the operands are evaluated and combined from the first one.
+// The int and float64 operands are combined without the generic operation.
+func (c *closureCompiler) compileArithmetic(instructions []Instruction, Type Type, generic func(val1, val2 any) (any, error), ints func(a, b int) int, floats func(a, b float64) float64) eval {
+	operands := make([]eval, len(instructions))
+	for i, instruction := range instructions {
+		operands[i] = c.compileExpression(instruction)
+	}
+
+	combine := generic
+	if basicType, ok := underlyingBasic(Type); ok {
+		switch basicType.reflectType.Kind() {
+		case reflect.Int:
+			// the integer division goes to the generic operation, which reports the division by zero
+			if ints != nil {
+				combine = specialize(ints, generic)
+			}
+		case reflect.Float64:
+			combine = specialize(floats, generic)
+		}
+	}
+
+	if len(operands) == 2 {
+		lhv, rhv := operands[0], operands[1]
+		return func(f *frame) (any, error) {
+			val1, err := lhv(f)
+			if err != nil {
+				return nil, err
+			}
+			val2, err := rhv(f)
+			if err != nil {
+				return nil, err
+			}
+			return combine(val1, val2)
+		}
+	}
+
+	return func(f *frame) (any, error) {
+		values := make([]any, len(operands))
+		for i, operand := range operands {
+			value, err := operand(f)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+
+		res := values[0]
+		for _, value := range values[1:] {
+			var err error
+			res, err = combine(res, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+}
+
+// compileShortCircuit compiles && and ||, the operands after the one which has the value of the result are not evaluated.
+func (c *closureCompiler) compileShortCircuit(instructions []Instruction, generic func(val1, val2 any) (any, error), result bool) eval {
+	operands := make([]eval, len(instructions))
+	for i, instruction := range instructions {
+		operands[i] = c.compileExpression(instruction)
+	}
+
+	return func(f *frame) (any, error) {
+		var res any
+		for i, operand := range operands {
+			value, err := operand(f)
+			if err != nil {
+				return nil, err
+			}
+
+			if i == 0 {
+				res = value
+			} else if res, err = generic(res, value); err != nil {
+				return nil, err
+			}
+			if res, ok := res.(bool); ok && res == result {
+				return res, nil
+			}
+		}
+		return res, nil
+	}
+}
+
+// specialize makes the operation on the values of the type, the values of the other types go to the generic operation.
+func specialize[T int | float64](operation func(a, b T) T, generic func(val1, val2 any) (any, error)) func(val1, val2 any) (any, error) {
+	return func(val1, val2 any) (any, error) {
+		if a, ok := val1.(T); ok {
+			if b, ok := val2.(T); ok {
+				return operation(a, b), nil
+			}
+		}
+		return generic(val1, val2)
+	}
+}
+
+func underlyingBasic(Type Type) (*BasicType, bool) {
+	if Type == nil {
+		return nil, false
+	}
+
+	basicType, ok := Type.Underlying().(*BasicType)
+	return basicType, ok
+}
+
+// compileCompare compiles the comparison, the ordered operands are compared without the generic comparison.
+func (c *closureCompiler) compileCompare(instr *CompareInstruction) eval {
+	lhv, rhv := c.compileExpression(instr.lhv), c.compileExpression(instr.rhv)
+	compareType := instr.compareType
+	compare := func(val1, val2 any) (any, error) {
+		return CompareAny(val1, val2, compareType)
+	}
+	if basicType, ok := underlyingBasic(instr.Type); ok {
+		switch basicType.reflectType.Kind() {
+		case reflect.Int:
+			compare = ordered[int](compareType, compare)
+		case reflect.Int32:
+			compare = ordered[int32](compareType, compare)
+		case reflect.Uint8:
+			compare = ordered[uint8](compareType, compare)
+		case reflect.Float64:
+			compare = ordered[float64](compareType, compare)
+		case reflect.String:
+			compare = ordered[string](compareType, compare)
+		}
+	}
+
+	return func(f *frame) (any, error) {
+		val1, err := lhv(f)
+		if err != nil {
+			return nil, err
+		}
+		val2, err := rhv(f)
+		if err != nil {
+			return nil, err
+		}
+		return compare(val1, val2)
+	}
+}
+
+// ordered makes the comparison of the values of the ordered type, the values of the other types go to the generic comparison.
+func ordered[T int | int32 | uint8 | float64 | string](compareType string, generic func(val1, val2 any) (any, error)) func(val1, val2 any) (any, error) {
+	var compare func(a, b T) bool
+	switch compareType {
+	case "==":
+		compare = func(a, b T) bool { return a == b }
+	case "!=":
+		compare = func(a, b T) bool { return a != b }
+	case "<":
+		compare = func(a, b T) bool { return a < b }
+	case "<=":
+		compare = func(a, b T) bool { return a <= b }
+	case ">":
+		compare = func(a, b T) bool { return a > b }
+	case ">=":
+		compare = func(a, b T) bool { return a >= b }
+	default:
+		return generic
+	}
+
+	return func(val1, val2 any) (any, error) {
+		if a, ok := val1.(T); ok {
+			if b, ok := val2.(T); ok {
+				return compare(a, b), nil
+			}
+		}
+		return generic(val1, val2)
+	}
+}
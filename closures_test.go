@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// scopes shadows the variables in the nested scopes, by the short variable declarations of the several variables too.
+const scopes = `package main
+
+import "fmt"
+
+func pair(n int) (int, int) {
+	return n, n * 2;
+}
+
+func shadow(x int) int {
+	y := x;
+	if x > 0 {
+		x := x + 10;
+		a, y := pair(x);
+		fmt.Println(a, y);
+	}
+	if y > 0 {
+		x, z := pair(y);
+		y = x + z;
+	}
+	return x + y;
+}
+
+func main() {
+	fmt.Println(shadow(1));
+	var fs []func() int;
+	for i := range 3 {
+		v := i * 10;
+		fs = append(fs, func() int {
+			return v;
+		});
+	}
+	for _, f := range fs {
+		fmt.Println(f());
+	}
+	n := 0;
+loop:
+	if n < 3 {
+		k := n;
+		n = k + 1;
+		goto loop;
+	}
+	fmt.Println(n);
+}
+`
+
+const scopesOutput = "11 22\n4\n0\n10\n20\n3\n"
+
+func TestScopes(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			prog := compileScript(t, scopes)
+			prog.Backend = backend
+
+			var err error
+			output := captureOutput(t, func() {
+				err = prog.Execute(context.Background())
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output != scopesOutput {
+				t.Errorf("the output is %q, %q is expected", output, scopesOutput)
+			}
+		})
+	}
+}
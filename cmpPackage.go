@@ -0,0 +1,30 @@
+package main
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "cmp",
+		Path:    "cmp",
+		Declare: declareCmp,
+	})
+}
+
+func declareCmp(prog *Program, pkg *Package) {
+	terms := make([]*Term, 0)
+	for _, Type := range []Type{IntType, Int32Type, Uint8Type, Float64Type, StringType} {
+		terms = append(terms, &Term{Tilde: true, Type: Type})
+	}
+	ordered := prog.declareType(pkg, "Ordered", &InterfaceType{Terms: terms})
+
+	prog.declareGeneric(pkg, "Compare", []string{"T"}, []Type{ordered}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		T := typeArgs[0]
+		return signature([]Type{T, T}, IntType), func(args ...any) ([]any, error) {
+			return []any{compareKeys(T, args[0], args[1])}, nil
+		}
+	})
+	prog.declareGeneric(pkg, "Less", []string{"T"}, []Type{ordered}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		T := typeArgs[0]
+		return signature([]Type{T, T}, BoolType), func(args ...any) ([]any, error) {
+			return []any{compareKeys(T, args[0], args[1]) < 0}, nil
+		}
+	})
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+type opcode int
+
+const (
+	// opExec runs the statement, the values it leaves on the stack are dropped
+	opExec opcode = iota
+	opJump
+	// opJumpIfFalse pops the condition, the code goes on at the target if it is false
+	opJumpIfFalse
+	opEnterScope
+	opLeaveScope
+	// opReturn sets the results and leaves the function
+	opReturn
+	// opBreak and opGoto leave the code for the enclosing one, which has the loop or the label
+	opBreak
+	opGoto
+	// opNop is left by the optimizer, it is removed with the dead code
+	opNop
+	// opTailCall returns the call of the function itself, the function starts again with the arguments of the call
+	opTailCall
+)
+
+type op struct {
+	code        opcode
+	instruction Instruction
+	// the jump target and the count of the scopes there
+	target, depth int
+	label         string
+}
+
+// position is the place in the code the jump goes to.
+type position struct {
+	pc, depth int
+}
+
+// Code is the function body lowered into the flat list of the operations,
+// the control flow statements are the jumps between them, the expressions stay instruction trees.
+type Code struct {
+	program *Program
+	ops     []op
+	labels  map[string]position
+	// the bodies of the range loops and of the cases of the select statements
+	bodies []*Code
+	// the closures the code is compiled into, nil if the operations are dispatched
+	compiled func(variables map[string]*any) (bool, error)
+}
+
+// Lower lowers the bodies of all the functions of the program and optimizes them.
+func (prog *Program) Lower() {
+	var compiled []*vmFunction
+	for _, function := range prog.functions {
+		if function, ok := function.(*IntrpretatedFunction); ok && function.code == nil {
+			// the bytecode is compiled from the instruction trees, before the passes over the code change them
+			if prog.Backend == BackendVM {
+				function.vm = prog.compileVM(function)
+				if function.vm != nil {
+					compiled = append(compiled, function.vm)
+				}
+			}
+			function.code = prog.lower(function.instructions, true)
+			prog.optimize(function.Name(), function.code)
+			if prog.TailCalls {
+				function.code.eliminateTailCalls()
+				prog.dumpCode(function.Name(), "tailcalls", function.code)
+			}
+			if prog.Backend == BackendClosures || prog.Backend == BackendVM && function.vm == nil {
+				function.code.walk((*Code).compile)
+			}
+		}
+	}
+
+	// the calls between the compiled functions don't leave the machine
+	for _, function := range prog.functions {
+		function, ok := function.(*IntrpretatedFunction)
+		if !ok || function.vm == nil {
+			continue
+		}
+		for i, call := range function.vm.calls {
+			if callee, ok := call.function.(*IntrpretatedFunction); ok {
+				function.vm.calls[i].compiled = callee.vm
+			}
+		}
+	}
+	// the inlined functions are known once the calls are linked
+	for _, f := range compiled {
+		prog.optimizeVM(f)
+	}
+}
+
+// lowering is the state of the code being lowered.
+type lowering struct {
+	*Code
+	depth int
+	// the loops being lowered, the innermost is the last
+	loops []*loop
+}
+
+// loop is the loop being lowered, its break statements jump to the end of the loop.
+type loop struct {
+	depth  int
+	breaks []int
+}
+
+// lower lowers the statements, the body of the function shares the scope with the parameters.
+func (prog *Program) lower(instructions []Instruction, functionBody bool) *Code {
+	l := &lowering{Code: &Code{program: prog, labels: map[string]position{}}}
+	for _, instruction := range instructions {
+		if block, ok := instruction.(*BlockInstruction); ok && functionBody {
+			for _, instruction := range block.instructions {
+				l.lower(instruction)
+			}
+			continue
+		}
+
+		l.lower(instruction)
+	}
+
+	// the goto to the label out of the code is left to the enclosing code
+	for i := range l.ops {
+		if l.ops[i].code != opGoto {
+			continue
+		}
+		if target, ok := l.labels[l.ops[i].label]; ok {
+			l.ops[i] = op{code: opJump, target: target.pc, depth: target.depth}
+		}
+	}
+
+	return l.Code
+}
+
+func (l *lowering) emit(res op) int {
+	res.depth = l.depth
+	l.ops = append(l.ops, res)
+	return len(l.ops) - 1
+}
+
+// land makes the jump go to the next operation.
+func (l *lowering) land(jump int) {
+	l.ops[jump].target = len(l.ops)
+}
+
+func (l *lowering) lower(instruction Instruction) {
+	switch instr := instruction.(type) {
+	case *BlockInstruction:
+		l.emit(op{code: opEnterScope})
+		l.depth++
+		for _, instruction := range instr.instructions {
+			l.lower(instruction)
+		}
+		l.depth--
+		l.emit(op{code: opLeaveScope})
+	case *LabeledInstruction:
+		l.labels[instr.label] = position{pc: len(l.ops), depth: l.depth}
+		l.lower(instr.instruction)
+	case *IFInstruction:
+		otherwise := l.emit(op{code: opJumpIfFalse, instruction: instr.statment})
+		l.lower(instr.than)
+		if instr.otherwise != nil {
+			end := l.emit(op{code: opJump})
+			l.land(otherwise)
+			l.lower(instr.otherwise)
+			l.land(end)
+		} else {
+			l.land(otherwise)
+		}
+	case *FORInstruction:
+		start := len(l.ops)
+		exit := -1
+		if instr.statment != nil {
+			exit = l.emit(op{code: opJumpIfFalse, instruction: instr.statment})
+		}
+		l.loops = append(l.loops, &loop{depth: l.depth})
+		l.lower(instr.than)
+		l.emit(op{code: opJump, target: start})
+
+		if exit >= 0 {
+			l.land(exit)
+		}
+		for _, jump := range l.loops[len(l.loops)-1].breaks {
+			l.land(jump)
+		}
+		l.loops = l.loops[:len(l.loops)-1]
+	case *RangeInstruction:
+		// the body runs per iteration, its break and return leave the range instruction
+		body := l.program.lower([]Instruction{instr.body}, false)
+		l.bodies = append(l.bodies, body)
+		lowered := *instr
+		lowered.body = &CodeInstruction{code: body}
+		l.emit(op{code: opExec, instruction: &lowered})
+	case *SelectInstruction:
+		// the bodies of the cases are lowered like the ones of the range loops
+		lowered := *instr
+		lowered.cases = slices.Clone(instr.cases)
+		for i := range lowered.cases {
+			body := l.program.lower([]Instruction{lowered.cases[i].body}, false)
+			l.bodies = append(l.bodies, body)
+			lowered.cases[i].body = &CodeInstruction{code: body}
+		}
+		l.emit(op{code: opExec, instruction: &lowered})
+	case *BreakInstruction:
+		if len(l.loops) == 0 {
+			l.emit(op{code: opBreak})
+			return
+		}
+		// the scopes of the loop body are left
+		loop := l.loops[len(l.loops)-1]
+		jump := l.emit(op{code: opJump})
+		l.ops[jump].depth = loop.depth
+		loop.breaks = append(loop.breaks, jump)
+	case *GotoInstruction:
+		l.emit(op{code: opGoto, label: instr.label})
+	case *ReturnInstruction:
+		l.emit(op{code: opReturn, instruction: instr})
+	default:
+		l.emit(op{code: opExec, instruction: instr})
+	}
+}
+
+// Run runs the code in the scope of the variables, returned reports that the return statement has left the function.
+func (c *Code) Run(variables map[string]*any) (returned bool, err error) {
+	if c.compiled != nil {
+		return c.compiled(variables)
+	}
+
+	stacklen := len(c.program.stack)
+	defer func() {
+		c.program.stack = c.program.stack[:stacklen]
+	}()
+
+	// the scopes share the map of the variables like the ones of the compiled code
+	f := &frame{variables: variables}
+	for pc := 0; pc < len(c.ops); pc++ {
+		if err := c.program.step(); err != nil {
+			return false, err
+		}
+		op := &c.ops[pc]
+
+		switch op.code {
+		case opExec:
+			f.declare(op.instruction)
+			err := op.instruction.Execute(variables)
+			c.program.stack = c.program.stack[:stacklen]
+			if err == nil {
+				continue
+			}
+
+			switch jump := err.(type) {
+			case ReturnError:
+				return true, nil
+			case GotoError:
+				target, ok := c.labels[jump.label]
+				if !ok {
+					return false, err
+				}
+				f.leave(target.depth)
+				pc = target.pc - 1
+				continue
+			}
+			return false, err
+		case opJump:
+			f.leave(op.depth)
+			pc = op.target - 1
+		case opJumpIfFalse:
+			condition, err := c.condition(op.instruction, variables)
+			if err != nil {
+				return false, err
+			}
+			if !condition {
+				f.leave(op.depth)
+				pc = op.target - 1
+			}
+		case opEnterScope:
+			f.scopes = append(f.scopes, len(f.shadowed))
+		case opLeaveScope:
+			f.leave(len(f.scopes) - 1)
+		case opReturn:
+			err := op.instruction.(*ReturnInstruction).setResults(variables)
+			return err == nil, err
+		case opTailCall:
+			return false, c.tailCall(op.instruction.(*ReturnInstruction), variables)
+		case opBreak:
+			return false, BreakError{}
+		case opGoto:
+			return false, GotoError{label: op.label}
+		}
+	}
+
+	return false, nil
+}
+
+// tailCall evaluates the arguments of the self tail call, the function starts again with them.
+func (c *Code) tailCall(instr *ReturnInstruction, variables map[string]*any) error {
+	stacklen := len(c.program.stack)
+	for _, argument := range instr.expressions[0].(*FunctionCallInstruction).arguments {
+		err := argument.Execute(variables)
+		if err != nil {
+			return err
+		}
+	}
+	args := slices.Clone(c.program.stack[stacklen:])
+	c.program.stack = c.program.stack[:stacklen]
+
+	return TailCallError{args: args}
+}
+
+// condition evaluates the condition of the jump.
+func (c *Code) condition(instruction Instruction, variables map[string]*any) (bool, error) {
+	stacklen := len(c.program.stack)
+	err := instruction.Execute(variables)
+	if err != nil {
+		return false, err
+	}
+
+	if len(c.program.stack) != stacklen+1 {
+		return false, fmt.Errorf("wrong count of return values of statement")
+	}
+
+	value, ok := c.program.stack[stacklen].(bool)
+	c.program.stack = c.program.stack[:stacklen]
+	if !ok {
+		return false, fmt.Errorf("statement: %v(type: %v) is not bool", value, reflect.TypeOf(value))
+	}
+
+	return value, nil
+}
+
+// CodeInstruction runs the lowered code inside the instruction tree: the body of the range loop or of the case of the select statement.
+type CodeInstruction struct {
+	code *Code
+}
+
+func (instr *CodeInstruction) Execute(variables map[string]*any) error {
+	returned, err := instr.code.Run(variables)
+	if returned {
+		return ReturnError{}
+	}
+
+	return err
+}
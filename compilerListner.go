@@ -4,223 +4,2257 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/antlr4-go/antlr/v4"
 	"github.com/karetskiiVO/GOInterpreter/parser"
 	"golang.org/x/exp/slices"
 )
 
+type operandMode int
+
+const (
+	invalidOperand operandMode = iota
+	novalueOperand
+	valueOperand
+	variableOperand
+	constantOperand
+	typeOperand
+	packageOperand
+	builtinOperand
+	templateOperand
+	// the blank identifier, which may be only assigned to
+	blankOperand
+)
+
+// operand is what the compiler knows about the expression of an instruction.
+type operand struct {
+	mode operandMode
+	Type Type
+	text string
+
+	// the package of a package operand
+	pkg *Package
+	// the generic declaration of a template operand with the explicit type arguments
+	functionTemplate *FunctionTemplate
+	typeTemplate     *TypeTemplate
+	typeArgs         []Type
+	// the value of a literal constant
+	value any
+}
+
+// placeholderInstruction stands on the instruction stack for the operands
+// which are not values: types and packages.
+type placeholderInstruction struct {
+	text string
+}
+
+func (instr *placeholderInstruction) Execute(variables map[string]*any) error {
+	return fmt.Errorf("%v is not an expression", instr.text)
+}
+
 type GoCompilerListener struct {
 	*parser.BaseGoListener
 
-	instructionStack []Instruction
-	program          *Program
-	Errors           []error
+	instructionStack []Instruction
+	operands         map[Instruction]*operand
+	scopes           []map[string]Type
+	function         *IntrpretatedFunction
+	// the scope of the parameters of the function, the literals have their own
+	functionScope int
+	literals      int
+	temporaries   int
+	literalStates []literalState
+	// the enclosing blocks and the labels of the function
+	blocks []*blockState
+	labels *labelScope
+	// the range loops and the select statements the statement is in, their bodies are not the function body
+	ranges int
+	// the select statements being compiled, the innermost is the last
+	selects []*SelectInstruction
+	// the returns of the calls of the function itself, they are the tail calls if the function defers nothing
+	tailCalls []tailCall
+	deferred  bool
+
+	program  *Program
+	pkg      *Package
+	file     *SourceFile
+	resolver *TypeResolver
+	Errors   []error
+}
+
+func NewGoCompilerListener(program *Program, pkg *Package, file *SourceFile) *GoCompilerListener {
+	return &GoCompilerListener{
+		operands: map[Instruction]*operand{},
+		program:  program,
+		pkg:      pkg,
+		file:     file,
+		resolver: NewTypeResolver(program, pkg, file),
+	}
+}
+
+func (l *GoCompilerListener) errorf(format string, args ...any) {
+	l.Errors = append(l.Errors, fmt.Errorf(format, args...))
+}
+
+func (l *GoCompilerListener) push(instruction Instruction, op *operand) {
+	l.operands[instruction] = op
+	l.instructionStack = append(l.instructionStack, instruction)
+}
+
+func (l *GoCompilerListener) pop() (Instruction, *operand) {
+	instruction := l.instructionStack[len(l.instructionStack)-1]
+	l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
+
+	return instruction, l.operand(instruction)
+}
+
+// popN pops the last n instructions in the order they were pushed.
+func (l *GoCompilerListener) popN(n int) ([]Instruction, []*operand) {
+	instructions := slices.Clone(l.instructionStack[len(l.instructionStack)-n : len(l.instructionStack)])
+	l.instructionStack = l.instructionStack[:len(l.instructionStack)-n]
+
+	operands := make([]*operand, n)
+	for i, instruction := range instructions {
+		operands[i] = l.operand(instruction)
+	}
+
+	return instructions, operands
+}
+
+func (l *GoCompilerListener) operand(instruction Instruction) *operand {
+	if op, ok := l.operands[instruction]; ok {
+		return op
+	}
+
+	return &operand{mode: novalueOperand, Type: InvalidType}
+}
+
+func (l *GoCompilerListener) pushInvalid(text string) {
+	l.push(&placeholderInstruction{text: text}, &operand{mode: invalidOperand, Type: InvalidType, text: text})
+}
+
+func describe(op *operand) string {
+	switch op.mode {
+	case novalueOperand:
+		return op.text + " (no value)"
+	case typeOperand:
+		return op.text + " (type)"
+	case packageOperand:
+		return "package " + op.text
+	case builtinOperand:
+		return op.text + " (built-in function)"
+	case templateOperand:
+		return op.text + " (generic)"
+	case constantOperand:
+		if op.Type == UntypedNilType {
+			return "nil"
+		}
+		return fmt.Sprintf("%v (%v constant)", op.text, op.Type)
+	case variableOperand:
+		return fmt.Sprintf("%v (variable of type %v)", op.text, op.Type)
+	}
+
+	if IsUntyped(op.Type) {
+		return fmt.Sprintf("%v (%v value)", op.text, op.Type)
+	}
+	return fmt.Sprintf("%v (value of type %v)", op.text, op.Type)
+}
+
+// isValue reports an error if the operand can't be used as a value.
+func (l *GoCompilerListener) isValue(op *operand) bool {
+	switch op.mode {
+	case invalidOperand:
+		return false
+	case novalueOperand:
+		l.errorf("%v used as value", describe(op))
+	case typeOperand:
+		l.errorf("%v is not an expression", describe(op))
+	case packageOperand:
+		l.errorf("use of package %v without selector", op.text)
+	case builtinOperand:
+		l.errorf("%v must be called", describe(op))
+	case templateOperand:
+		l.errorf("cannot use generic %v without instantiation", op.text)
+	case blankOperand:
+		l.errorf("cannot use _ as value")
+	default:
+		if _, ok := op.Type.(*TupleType); ok {
+			l.errorf("multiple-value %v (value of type %v) in single-value context", op.text, op.Type)
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// convert wraps the instruction into the conversion if the representations of the types differ.
+func (l *GoCompilerListener) convert(instruction Instruction, from, to Type) Instruction {
+	if from == InvalidType || to == InvalidType {
+		return instruction
+	}
+
+	// the interface value keeps the type of the value
+	if IsInterface(to) && !IsInterface(from) && from != UntypedNilType {
+		if IsUntyped(from) {
+			instruction = l.convert(instruction, from, DefaultType(from))
+			from = DefaultType(from)
+		}
+		l.instantiateMethods(from)
+
+		return &BoxInstruction{
+			program:     l.program,
+			instruction: instruction,
+			Type:        from,
+		}
+	}
+	if IsInterface(from) && IsInterface(to) {
+		return instruction
+	}
+
+	if from != UntypedNilType && RuntimeType(from) == RuntimeType(to) {
+		return instruction
+	}
+
+	return &ConvertInstruction{
+		program:     l.program,
+		instruction: instruction,
+		Type:        to,
+	}
+}
+
+// instantiateMethods instantiates all the methods of the generic type,
+// the methods of the value in the interface may be called only at runtime.
+func (l *GoCompilerListener) instantiateMethods(Type Type) {
+	if pointerType, ok := Type.(*PointerType); ok {
+		Type = pointerType.Elem
+	}
+
+	namedType, ok := Type.(*NamedType)
+	if !ok || namedType.template == nil {
+		return
+	}
+
+	for name := range namedType.template.methods {
+		_, _, err := l.program.LookupMethod(namedType, name)
+		if err != nil {
+			l.Errors = append(l.Errors, err)
+		}
+	}
+}
+
+// assign checks that the value of the instruction can be assigned to a variable of the type.
+func (l *GoCompilerListener) assign(instruction Instruction, op *operand, Type Type, context string) Instruction {
+	if !l.isValue(op) {
+		return instruction
+	}
+
+	if !AssignableTo(op.Type, Type, l.program) {
+		l.errorf("cannot use %v as %v value in %v", describe(op), Type, context)
+		return instruction
+	}
+
+	if float, ok := op.value.(float64); ok && IsInteger(Type) && float != float64(int(float)) {
+		l.errorf("cannot use %v as %v value in %v (truncated)", describe(op), Type, context)
+		return instruction
+	}
+
+	return l.convert(instruction, op.Type, Type)
+}
+
+// defaultValue gives the untyped constants their default types, where there is no other type for them.
+func (l *GoCompilerListener) defaultValue(instruction Instruction, op *operand, context string) (Instruction, Type) {
+	if !l.isValue(op) {
+		return instruction, InvalidType
+	}
+
+	if op.Type == UntypedNilType {
+		l.errorf("use of untyped nil in %v", context)
+		return instruction, InvalidType
+	}
+
+	Type := DefaultType(op.Type)
+	return l.convert(instruction, op.Type, Type), Type
+}
+
+// unify brings the operands of a binary operation to the same type.
+func (l *GoCompilerListener) unify(instructions []Instruction, operands []*operand, text string) ([]Instruction, Type) {
+	var res Type
+	for _, op := range operands {
+		if !l.isValue(op) {
+			return instructions, InvalidType
+		}
+		if op.Type == InvalidType {
+			return instructions, InvalidType
+		}
+		if IsUntyped(op.Type) {
+			continue
+		}
+
+		if res == nil || IsInterface(op.Type) && AssignableTo(res, op.Type, l.program) {
+			// the value is compared with the interface value as the interface value
+			res = op.Type
+		} else if !Identical(res, op.Type) && !(IsInterface(res) && AssignableTo(op.Type, res, l.program)) {
+			l.errorf("invalid operation: %v (mismatched types %v and %v)", text, res, op.Type)
+			return instructions, InvalidType
+		}
+	}
+
+	if res == nil {
+		// all the operands are untyped constants, the float kind wins over the integer kind
+		for _, op := range operands {
+			if res == nil || Wider(res, op.Type) {
+				res = op.Type
+			}
+		}
+	}
+
+	instructions = slices.Clone(instructions)
+	for i, op := range operands {
+		if !AssignableTo(op.Type, res, l.program) {
+			l.errorf("invalid operation: %v (mismatched types %v and %v)", text, res, op.Type)
+			return instructions, InvalidType
+		}
+
+		instructions[i] = l.convert(instructions[i], op.Type, res)
+	}
+
+	return instructions, res
+}
+
+func (l *GoCompilerListener) EnterFunctionDefinition(ctx *parser.FunctionDefinitionContext) {
+	l.instructionStack = make([]Instruction, 0)
+
+	// the instances of the generic functions are set by the instantiation
+	if l.function == nil {
+		// the errors in the names are reported by the declaration listener
+		name, _ := l.resolver.FunctionName(ctx)
+		if id, ok := l.program.functionID[name]; ok {
+			l.function, _ = l.program.functions[id].(*IntrpretatedFunction)
+		}
+	}
+
+	if l.function != nil {
+		l.name(ctx.Block(), l.function.Name())
+	}
+
+	l.scopes = []map[string]Type{l.parameters(l.function)}
+	l.functionScope = 0
+	l.literals = 0
+	l.labels = newLabelScope()
+	l.tailCalls = nil
+	l.deferred = false
+}
+
+// parameters is the scope of the arguments and the named results of the function.
+func (l *GoCompilerListener) parameters(function *IntrpretatedFunction) map[string]Type {
+	params := map[string]Type{}
+	if function == nil {
+		return params
+	}
+
+	for _, variable := range append(slices.Clone(function.inputVariables), function.outputVariables...) {
+		if variable.Name != "" && variable.Name != "_" {
+			params[variable.Name] = variable.Type
+		}
+	}
+
+	return params
+}
+
+// name keeps the name of the function by its body, the instances of the generic function keep the name of the first one.
+func (l *GoCompilerListener) name(body parser.IBlockContext, name string) {
+	block, ok := body.(*parser.BlockContext)
+	if !ok {
+		return
+	}
+	if l.file.Functions == nil {
+		l.file.Functions = map[*parser.BlockContext]string{}
+	}
+	if _, ok := l.file.Functions[block]; !ok {
+		l.file.Functions[block] = name
+	}
+}
+
+// tailCall is the return of the call of the function itself.
+type tailCall struct {
+	instruction *ReturnInstruction
+	ctx         *parser.FunctionReturnContext
+}
+
+// literalState is the state of the enclosing function while the function literal is compiled.
+type literalState struct {
+	function         *IntrpretatedFunction
+	instructionStack []Instruction
+	functionScope    int
+	labels           *labelScope
+}
+
+func (l *GoCompilerListener) EnterFunctionLiteral(ctx *parser.FunctionLiteralContext) {
+	name := "func"
+	if l.function != nil {
+		name = l.function.Name() + ".func"
+	}
+	l.literals++
+
+	function := NewIntrpretatedFunction(name + strconv.Itoa(l.literals))
+	l.Errors = append(l.Errors, declareSignature(l.resolver, function, ctx)...)
+	l.name(ctx.Block(), function.Name())
+
+	l.literalStates = append(l.literalStates, literalState{
+		function:         l.function,
+		instructionStack: l.instructionStack,
+		functionScope:    l.functionScope,
+		labels:           l.labels,
+	})
+
+	l.function = function
+	l.instructionStack = make([]Instruction, 0)
+	l.scopes = append(l.scopes, l.parameters(function))
+	l.functionScope = len(l.scopes) - 1
+	l.labels = newLabelScope()
+}
+
+func (l *GoCompilerListener) ExitFunctionLiteral(ctx *parser.FunctionLiteralContext) {
+	function := l.function
+	function.instructions = l.instructionStack
+	l.checkLabels()
+
+	state := l.literalStates[len(l.literalStates)-1]
+	l.literalStates = l.literalStates[:len(l.literalStates)-1]
+
+	l.function = state.function
+	l.instructionStack = state.instructionStack
+	l.functionScope = state.functionScope
+	l.labels = state.labels
+	l.scopes = l.scopes[:len(l.scopes)-1]
+
+	err := l.program.RegisterFunction(function)
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		l.pushInvalid(ctx.GetText())
+		return
+	}
+
+	l.push(&FunctionLiteralInstruction{
+		program:  l.program,
+		function: function,
+	}, &operand{mode: valueOperand, Type: function.Signature(), text: "func literal"})
+}
+
+func (l *GoCompilerListener) ExitFunctionDefinition(ctx *parser.FunctionDefinitionContext) {
+	if l.function != nil {
+		l.function.instructions = l.instructionStack
+	}
+	l.function = nil
+	l.checkLabels()
+
+	// the deferred calls run when the function returns, so the function with them makes the ordinary calls
+	if l.deferred {
+		return
+	}
+	for _, call := range l.tailCalls {
+		call.instruction.tail = true
+		if l.file.TailCalls == nil {
+			l.file.TailCalls = map[*parser.FunctionReturnContext]bool{}
+		}
+		l.file.TailCalls[call.ctx] = true
+	}
+}
+
+func (l *GoCompilerListener) EnterBlock(ctx *parser.BlockContext) {
+	l.blocks = append(l.blocks, &blockState{
+		line:      ctx.GetStart().GetLine(),
+		statement: -1,
+	})
+
+	// the parameters are in the same scope as the function body
+	if isFunctionBody(ctx) {
+		return
+	}
+
+	l.scopes = append(l.scopes, map[string]Type{})
+}
+
+func (l *GoCompilerListener) ExitBlock(ctx *parser.BlockContext) {
+	l.blocks = l.blocks[:len(l.blocks)-1]
+	if !isFunctionBody(ctx) {
+		l.scopes = l.scopes[:len(l.scopes)-1]
+	}
+
+	l.instructionStack = append(l.instructionStack, l.block(len(ctx.AllLine())))
+}
+
+// block makes the block of the last statements compiled.
+func (l *GoCompilerListener) block(instructionCnt int) *BlockInstruction {
+	instructions := slices.Clone(l.instructionStack[len(l.instructionStack)-instructionCnt : len(l.instructionStack)])
+
+	labels := map[string]int{}
+	for i, instruction := range instructions {
+		for {
+			labeled, ok := instruction.(*LabeledInstruction)
+			if !ok {
+				break
+			}
+			labels[labeled.label] = i
+			instruction = labeled.instruction
+		}
+	}
+
+	l.instructionStack = l.instructionStack[:len(l.instructionStack)-instructionCnt]
+	return &BlockInstruction{
+		program:      l.program,
+		instructions: instructions,
+		labels:       labels,
+	}
+}
+
+// blockState is what the compiler knows about the block for the checks of goto.
+type blockState struct {
+	// the line where the block starts
+	line int
+	// the index of the current statement
+	statement int
+	// the statements declaring variables
+	declarations []declaration
+}
+
+type declaration struct {
+	statement int
+	line      int
+}
+
+// labelScope holds the labels of the function body, the literals have their own.
+type labelScope struct {
+	labels map[string]*label
+	gotos  []gotoStatement
+}
+
+func newLabelScope() *labelScope {
+	return &labelScope{
+		labels: map[string]*label{},
+	}
+}
+
+type label struct {
+	block     *blockState
+	statement int
+	line      int
+	used      bool
+}
+
+type gotoStatement struct {
+	label string
+	// the enclosing blocks with the indices of the statements containing the goto
+	blocks     []*blockState
+	statements []int
+}
+
+func (l *GoCompilerListener) EnterLine(ctx *parser.LineContext) {
+	switch ctx.GetParent().(type) {
+	case *parser.BlockContext, *parser.CommClauseContext:
+		l.blocks[len(l.blocks)-1].statement++
+	}
+}
+
+// declare marks the current statement as a declaration of variables.
+func (l *GoCompilerListener) declare(line int) {
+	if len(l.blocks) == 0 {
+		return
+	}
+
+	block := l.blocks[len(l.blocks)-1]
+	block.declarations = append(block.declarations, declaration{
+		statement: block.statement,
+		line:      line,
+	})
+}
+
+func (l *GoCompilerListener) EnterLabeledStatement(ctx *parser.LabeledStatementContext) {
+	name := ctx.NAME().GetText()
+	if previous, ok := l.labels.labels[name]; ok {
+		l.errorf("label %v already defined at line %v", name, previous.line)
+		return
+	}
+
+	block := l.blocks[len(l.blocks)-1]
+	l.labels.labels[name] = &label{
+		block:     block,
+		statement: block.statement,
+		line:      ctx.GetStart().GetLine(),
+	}
+}
+
+func (l *GoCompilerListener) ExitLabeledStatement(ctx *parser.LabeledStatementContext) {
+	var instruction Instruction = &EmptyInstruction{}
+	if ctx.Line() != nil {
+		instruction = l.instructionStack[len(l.instructionStack)-1]
+		l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
+	}
+
+	l.instructionStack = append(l.instructionStack, &LabeledInstruction{
+		label:       ctx.NAME().GetText(),
+		instruction: instruction,
+	})
+}
+
+func (l *GoCompilerListener) ExitGotoStatement(ctx *parser.GotoStatementContext) {
+	res := gotoStatement{
+		label:  ctx.NAME().GetText(),
+		blocks: slices.Clone(l.blocks),
+	}
+	for _, block := range l.blocks {
+		res.statements = append(res.statements, block.statement)
+	}
+	l.labels.gotos = append(l.labels.gotos, res)
+
+	l.instructionStack = append(l.instructionStack, &GotoInstruction{
+		label: res.label,
+	})
+}
+
+// checkLabels checks that every goto jumps to a label of an enclosing block
+// and does not skip the declarations of variables.
+func (l *GoCompilerListener) checkLabels() {
+	for _, jump := range l.labels.gotos {
+		target, ok := l.labels.labels[jump.label]
+		if !ok {
+			l.errorf("label %v not defined", jump.label)
+			continue
+		}
+		target.used = true
+
+		i := slices.Index(jump.blocks, target.block)
+		if i < 0 {
+			l.errorf("goto %v jumps into block starting at line %v", jump.label, target.block.line)
+			continue
+		}
+
+		for _, declaration := range target.block.declarations {
+			if declaration.statement > jump.statements[i] && declaration.statement < target.statement {
+				l.errorf("goto %v jumps over variable declaration at line %v", jump.label, declaration.line)
+				break
+			}
+		}
+	}
+
+	names := make([]string, 0, len(l.labels.labels))
+	for name := range l.labels.labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if !l.labels.labels[name].used {
+			l.errorf("label %v defined and not used", name)
+		}
+	}
+}
+
+func isFunctionBody(ctx *parser.BlockContext) bool {
+	switch ctx.GetParent().(type) {
+	case *parser.FunctionDefinitionContext, *parser.FunctionLiteralContext:
+		return true
+	}
+
+	return false
+}
+
+// lookupVariable looks for a variable from the innermost scope to the outermost one.
+func (l *GoCompilerListener) lookupVariable(name string) (Type, bool) {
+	for i := len(l.scopes) - 1; i >= 0; i-- {
+		if Type, ok := l.scopes[i][name]; ok {
+			return Type, true
+		}
+	}
+
+	return nil, false
+}
+
+func (l *GoCompilerListener) ExitVariableDefinition(ctx *parser.VariableDefinitionContext) {
+	name := ctx.NAME().GetText()
+	l.declare(ctx.GetStart().GetLine())
+
+	Type, err := l.resolver.Resolve(ctx.Typename())
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		Type = InvalidType
+	}
+
+	scope := l.scopes[len(l.scopes)-1]
+	if _, ok := scope[name]; ok {
+		l.errorf("%v redeclared in this block", name)
+	}
+	scope[name] = Type
+
+	l.instructionStack = append(l.instructionStack, &DefineVariableInstruction{
+		program: l.program,
+		Name:    name,
+		Type:    Type,
+	})
+}
+
+func (l *GoCompilerListener) ExitShortVariableDefinition(ctx *parser.ShortVariableDefinitionContext) {
+	values, valueOps := l.popN(len(ctx.AllExpression()))
+	names := ctx.AllNAME()
+	l.declare(ctx.GetStart().GetLine())
+	l.commaOk(values, valueOps, len(names))
+
+	types, ok := l.tuple(valueOps, len(names), "assignment mismatch: %v variables but %v values")
+	if !ok {
+		l.pushInvalid(ctx.GetText())
+		return
+	}
+
+	scope := l.scopes[len(l.scopes)-1]
+	declared := false
+	for _, name := range names {
+		if _, ok := scope[name.GetText()]; !ok && name.GetText() != "_" {
+			declared = true
+		}
+	}
+	if !declared {
+		l.errorf("no new variables on left side of :=")
+	}
+
+	if _, ok := valueOps[0].Type.(*TupleType); len(names) == 1 && !ok {
+		l.instructionStack = append(l.instructionStack, l.define(names[0].GetText(), values[0], valueOps[0]))
+		return
+	}
+
+	// all the values are evaluated before the variables are defined
+	res := &MultiAssigmentInstruction{
+		program:     l.program,
+		values:      values,
+		temporaries: make([]string, len(types)),
+		assigments:  make([]Instruction, len(names)),
+	}
+	for i, name := range names {
+		res.temporaries[i] = l.temporary()
+		res.assigments[i] = l.define(name.GetText(), &VariableUsingInstruction{
+			program:      l.program,
+			variableName: res.temporaries[i],
+		}, &operand{mode: valueOperand, Type: types[i], text: valueOps[min(i, len(valueOps)-1)].text})
+	}
+
+	l.instructionStack = append(l.instructionStack, res)
+}
+
+// define declares the variable of the short variable declaration,
+// the variables already declared in the same scope are assigned.
+func (l *GoCompilerListener) define(name string, instruction Instruction, op *operand) Instruction {
+	scope := l.scopes[len(l.scopes)-1]
+
+	if Type, ok := scope[name]; ok {
+		res, ok := l.assignTo(&VariableUsingInstruction{
+			program:      l.program,
+			variableName: name,
+		}, &operand{mode: variableOperand, Type: Type, text: name}, instruction, op)
+		if !ok {
+			return &placeholderInstruction{text: name}
+		}
+		return res
+	}
+
+	instruction, Type := l.defaultValue(instruction, op, "assignment")
+	if name == "_" {
+		return instruction
+	}
+	scope[name] = Type
+
+	return &DefineVariableInstruction{
+		program: l.program,
+		Name:    name,
+		Type:    Type,
+		value:   instruction,
+	}
+}
+
+func (l *GoCompilerListener) ExitVariableUsing(ctx *parser.VariableUsingContext) {
+	l.identifier(ctx.GetText())
+}
+
+// identifier resolves the name: variables, then the type parameters, the package members,
+// the imported packages and the predeclared identifiers.
+func (l *GoCompilerListener) identifier(name string) {
+	if name == "_" {
+		l.push(&placeholderInstruction{text: name}, &operand{mode: blankOperand, Type: InvalidType, text: name})
+		return
+	}
+
+	if Type, ok := l.lookupVariable(name); ok {
+		l.push(&VariableUsingInstruction{
+			program:      l.program,
+			variableName: name,
+		}, &operand{mode: variableOperand, Type: Type, text: name})
+		return
+	}
+
+	if Type, ok := l.resolver.typeArgs[name]; ok {
+		l.push(&placeholderInstruction{text: name}, &operand{mode: typeOperand, Type: Type, text: name})
+		return
+	}
+
+	if l.member(l.pkg, name, name) {
+		return
+	}
+
+	if pkg, ok := l.file.Imports[name]; ok {
+		l.push(&placeholderInstruction{text: name}, &operand{mode: packageOperand, Type: InvalidType, text: name, pkg: pkg})
+		return
+	}
+
+	_, isBuiltin := builtins[name]
+	if functionID, ok := l.program.functionID[name]; ok || isBuiltin {
+		l.push(&FunctionUsingInstruction{
+			program:    l.program,
+			functionID: functionID,
+		}, &operand{mode: builtinOperand, Type: InvalidType, text: name})
+		return
+	}
+
+	if Type, ok := l.program.types[name]; ok {
+		l.push(&placeholderInstruction{text: name}, &operand{mode: typeOperand, Type: Type, text: name})
+		return
+	}
+
+	if name == "nil" {
+		l.push(&NilUsingInstruction{
+			program: l.program,
+		}, &operand{mode: constantOperand, Type: UntypedNilType, text: name})
+		return
+	}
+
+	l.errorf("undefined: %v", name)
+	l.pushInvalid(name)
+}
+
+// member pushes the function, the type or the value declared in the package, if there is one.
+func (l *GoCompilerListener) member(pkg *Package, name, text string) bool {
+	if value, ok := l.program.values[pkg.QualifiedName(name)]; ok {
+		op := &operand{mode: valueOperand, Type: value.Type, text: text}
+		if value.Constant {
+			op.mode, op.value = constantOperand, value.Value
+			// the compiler knows the values of the rune constants as integers
+			if r, ok := value.Value.(int32); ok {
+				op.value = int(r)
+			}
+		}
+
+		l.push(&PackageValueInstruction{
+			program: l.program,
+			value:   value,
+		}, op)
+		return true
+	}
+
+	if functionID, ok := l.program.functionID[pkg.QualifiedName(name)]; ok {
+		l.pushFunction(functionID, text)
+		return true
+	}
+
+	if template, ok := l.program.functionTemplates[pkg.QualifiedName(name)]; ok {
+		l.push(&placeholderInstruction{text: text}, &operand{mode: templateOperand, Type: InvalidType, text: text, functionTemplate: template})
+		return true
+	}
+
+	if template, ok := l.program.typeTemplates[pkg.QualifiedName(name)]; ok {
+		l.push(&placeholderInstruction{text: text}, &operand{mode: templateOperand, Type: InvalidType, text: text, typeTemplate: template})
+		return true
+	}
+
+	Type, ok, err := l.resolver.lookup(pkg.QualifiedName(name))
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		l.pushInvalid(text)
+		return true
+	}
+	if ok {
+		l.push(&placeholderInstruction{text: text}, &operand{mode: typeOperand, Type: Type, text: text})
+		return true
+	}
+
+	return false
+}
+
+func (l *GoCompilerListener) pushFunction(functionID int, text string) {
+	var signature Type = InvalidType
+	if function, ok := l.program.functions[functionID].(TypedFunction); ok {
+		signature = function.Signature()
+	}
+
+	l.push(&FunctionUsingInstruction{
+		program:    l.program,
+		functionID: functionID,
+	}, &operand{mode: valueOperand, Type: signature, text: text})
+}
+
+// address makes the instruction which pushes the pointer to the addressable operand.
+func (l *GoCompilerListener) address(instruction Instruction, op *operand) (Instruction, bool) {
+	if op.mode != variableOperand {
+		return nil, false
+	}
+
+	switch instruction := instruction.(type) {
+	case *VariableUsingInstruction:
+		return &VariableAddressInstruction{
+			program:      l.program,
+			variableName: instruction.variableName,
+		}, true
+	case *FieldInstruction:
+		return &FieldAddressInstruction{
+			program:   l.program,
+			structure: instruction.structure,
+			index:     instruction.index,
+		}, true
+	case *DereferenceInstruction:
+		return instruction.pointer, true
+	}
+
+	return nil, false
+}
+
+// array gives the array the operand is or points to, it is used by reference to read and change its elements in place.
+// The elements are variables if the array is a variable or is pointed to.
+func (l *GoCompilerListener) array(container Instruction, op *operand) (Instruction, *ArrayType, operandMode, bool) {
+	if pointerType, ok := op.Type.Underlying().(*PointerType); ok {
+		arrayType, ok := pointerType.Elem.Underlying().(*ArrayType)
+		if !ok {
+			return nil, nil, 0, false
+		}
+
+		return &DereferenceInstruction{
+			program:   l.program,
+			pointer:   container,
+			reference: true,
+		}, arrayType, variableOperand, true
+	}
+
+	arrayType, ok := op.Type.Underlying().(*ArrayType)
+	if !ok {
+		return nil, nil, 0, false
+	}
+	mode := valueOperand
+	if op.mode == variableOperand {
+		mode = variableOperand
+	}
+
+	return reference(container), arrayType, mode, true
+}
+
+// reference makes the instruction push the value without copying it,
+// so the fields of the struct are changed in place.
+func reference(instruction Instruction) Instruction {
+	switch instruction := instruction.(type) {
+	case *VariableUsingInstruction:
+		res := *instruction
+		res.reference = true
+		return &res
+	case *IndexInstruction:
+		res := *instruction
+		res.reference = true
+		return &res
+	case *FieldInstruction:
+		res := *instruction
+		res.reference = true
+		return &res
+	case *DereferenceInstruction:
+		res := *instruction
+		res.reference = true
+		return &res
+	}
+
+	return instruction
+}
+
+func (l *GoCompilerListener) ExitSelectorExpression(ctx *parser.SelectorExpressionContext) {
+	instruction, op := l.pop()
+	name := ctx.NAME().GetText()
+	text := op.text + "." + name
+
+	if op.mode == invalidOperand {
+		l.pushInvalid(text)
+		return
+	}
+
+	if op.mode == packageOperand {
+		if !IsExported(name) {
+			l.errorf("name %v not exported by package %v", name, op.text)
+			l.pushInvalid(text)
+			return
+		}
+
+		if !l.member(op.pkg, name, text) {
+			l.errorf("undefined: %v", text)
+			l.pushInvalid(text)
+		}
+		return
+	}
+
+	if !l.isValue(op) {
+		l.pushInvalid(text)
+		return
+	}
+
+	base, pointer := op.Type, false
+	if pointerType, ok := base.Underlying().(*PointerType); ok {
+		base, pointer = pointerType.Elem, true
+	}
+
+	if structType, ok := base.Underlying().(*StructType); ok {
+		if index, ok := structType.FieldIndex(name, l.pkg); ok {
+			// the fields of the addressable structs are addressable
+			mode := valueOperand
+			if pointer || op.mode == variableOperand {
+				mode = variableOperand
+			}
+
+			l.push(&FieldInstruction{
+				program:   l.program,
+				structure: reference(instruction),
+				index:     index,
+			}, &operand{mode: mode, Type: structType.Fields[index].Type, text: text})
+			return
+		}
+	}
+
+	// the method of the interface is found by the dynamic type of the value
+	if iface, ok := op.Type.Underlying().(*InterfaceType); ok {
+		for _, method := range iface.Methods {
+			if method.Name == name {
+				l.push(&InterfaceMethodInstruction{
+					program:   l.program,
+					receiver:  instruction,
+					name:      name,
+					signature: method.Signature,
+				}, &operand{mode: valueOperand, Type: method.Signature, text: text})
+				return
+			}
+		}
+	}
+
+	if namedType, ok := base.(*NamedType); ok && (IsExported(name) || namedType.pkg == l.pkg) {
+		functionID, ok, err := l.program.LookupMethod(namedType, name)
+		if err != nil {
+			l.Errors = append(l.Errors, err)
+			l.pushInvalid(text)
+			return
+		}
+
+		if ok {
+			l.method(instruction, op, pointer, functionID, text)
+			return
+		}
+	}
+
+	l.errorf("%v undefined (type %v has no field or method %v)", text, op.Type, name)
+	l.pushInvalid(text)
+}
+
+// method pushes the method value, the receiver is addressed or dereferenced as the method wants.
+func (l *GoCompilerListener) method(receiver Instruction, op *operand, pointer bool, functionID int, text string) {
+	function, ok := l.program.functions[functionID].(TypedFunction)
+	if !ok {
+		l.pushInvalid(text)
+		return
+	}
+
+	signature := function.Signature()
+	_, wantsPointer := signature.Params[0].(*PointerType)
+
+	switch {
+	case wantsPointer && !pointer:
+		address, ok := l.address(receiver, op)
+		if !ok {
+			l.errorf("cannot call pointer method %v on %v", text, op.Type)
+			l.pushInvalid(text)
+			return
+		}
+		receiver = address
+	case !wantsPointer && pointer:
+		receiver = &DereferenceInstruction{
+			program: l.program,
+			pointer: receiver,
+		}
+	}
+
+	l.push(&MethodUsingInstruction{
+		program:    l.program,
+		receiver:   receiver,
+		functionID: functionID,
+	}, &operand{mode: valueOperand, Type: &FunctionType{Params: signature.Params[1:], Result: signature.Result}, text: text})
+}
+
+func (l *GoCompilerListener) ExitIndexElement(ctx *parser.IndexElementContext) {
+	if ctx.Typename() == nil {
+		return
+	}
+
+	Type, err := l.resolver.Resolve(ctx.Typename())
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		l.pushInvalid(ctx.GetText())
+		return
+	}
+
+	l.push(&placeholderInstruction{text: ctx.GetText()}, &operand{mode: typeOperand, Type: Type, text: ctx.GetText()})
+}
+
+func (l *GoCompilerListener) ExitLiteralType(ctx *parser.LiteralTypeContext) {
+	var Type Type
+	var err error
+	switch {
+	case ctx.SliceType() != nil:
+		Type, err = l.resolver.ResolveSliceType(ctx.SliceType())
+	case ctx.ArrayType() != nil:
+		Type, err = l.resolver.ResolveArrayType(ctx.ArrayType())
+	case ctx.MapType() != nil:
+		Type, err = l.resolver.ResolveMapType(ctx.MapType())
+	case ctx.ChannelType() != nil:
+		Type, err = l.resolver.ResolveChannelType(ctx.ChannelType())
+	case ctx.StructType() != nil:
+		Type, err = l.resolver.ResolveStructType(ctx.StructType())
+	default:
+		Type, err = l.resolver.ResolveInterfaceType(ctx.InterfaceType())
+	}
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		l.pushInvalid(ctx.GetText())
+		return
+	}
+
+	// the text of the tokens has no spaces, chan int would be chanint
+	text := ctx.GetText()
+	if ctx.ChannelType() != nil {
+		text = Type.String()
+	}
+	l.push(&placeholderInstruction{text: text}, &operand{mode: typeOperand, Type: Type, text: text})
+}
+
+func (l *GoCompilerListener) ExitIndexExpression(ctx *parser.IndexExpressionContext) {
+	indexes, indexOps := l.popN(len(ctx.AllIndexElement()))
+	container, containerOp := l.pop()
+	text := containerOp.text + ctx.GetText()
+
+	if containerOp.mode == templateOperand {
+		l.instantiate(containerOp, indexOps, text)
+		return
+	}
+
+	if len(indexes) != 1 {
+		l.errorf("invalid operation: more than one index in %v", text)
+		l.pushInvalid(text)
+		return
+	}
+	index, indexOp := indexes[0], indexOps[0]
+
+	if !l.isValue(containerOp) || !l.isValue(indexOp) {
+		l.pushInvalid(text)
+		return
+	}
+
+	if array, arrayType, mode, ok := l.array(container, containerOp); ok {
+		if !IsInteger(indexOp.Type) && indexOp.Type != InvalidType {
+			l.errorf("invalid argument: index %v must be integer", describe(indexOp))
+		}
+		if value, ok := indexOp.value.(int); ok && value < 0 {
+			l.errorf("invalid argument: index %v must not be negative", describe(indexOp))
+		} else if ok && value >= arrayType.Len {
+			l.errorf("invalid argument: index %v out of bounds [0:%v]", value, arrayType.Len)
+		}
+
+		l.push(&IndexInstruction{
+			program:   l.program,
+			container: array,
+			index:     l.convert(index, indexOp.Type, IntType),
+			Type:      arrayType.Elem,
+		}, &operand{mode: mode, Type: arrayType.Elem, text: text})
+		return
+	}
+
+	switch Type := containerOp.Type.Underlying().(type) {
+	case *SliceType:
+		if !IsInteger(indexOp.Type) && indexOp.Type != InvalidType {
+			l.errorf("invalid argument: index %v must be integer", describe(indexOp))
+		}
+
+		l.push(&IndexInstruction{
+			program:   l.program,
+			container: container,
+			index:     l.convert(index, indexOp.Type, IntType),
+			Type:      Type.Elem,
+		}, &operand{mode: variableOperand, Type: Type.Elem, text: text})
+	case *MapType:
+		l.push(&IndexInstruction{
+			program:   l.program,
+			container: container,
+			index:     l.assign(index, indexOp, Type.Key, "map index"),
+			Type:      Type.Elem,
+		}, &operand{mode: valueOperand, Type: Type.Elem, text: text})
+	default:
+		// the bytes of the string can't be changed
+		if IsString(Type) {
+			if !IsInteger(indexOp.Type) && indexOp.Type != InvalidType {
+				l.errorf("invalid argument: index %v must be integer", describe(indexOp))
+			}
+
+			l.push(&IndexInstruction{
+				program:   l.program,
+				container: l.convert(container, containerOp.Type, StringType),
+				index:     l.convert(index, indexOp.Type, IntType),
+				Type:      Uint8Type,
+			}, &operand{mode: valueOperand, Type: Uint8Type, text: text})
+			return
+		}
+
+		if containerOp.Type != InvalidType {
+			l.errorf("invalid operation: cannot index %v", describe(containerOp))
+		}
+		l.pushInvalid(text)
+	}
+}
+
+func (l *GoCompilerListener) ExitSliceExpression(ctx *parser.SliceExpressionContext) {
+	// the index is low, high or max by the count of the colons before it
+	var present [3]bool
+	colons := 0
+	for _, child := range ctx.GetChildren() {
+		switch child := child.(type) {
+		case antlr.TerminalNode:
+			if child.GetText() == ":" {
+				colons++
+			}
+		case parser.IExpressionContext:
+			present[colons] = true
+		}
+	}
+
+	var indices [3]Instruction
+	var indexOps [3]*operand
+	for i := len(present) - 1; i >= 0; i-- {
+		if present[i] {
+			indices[i], indexOps[i] = l.pop()
+		}
+	}
+	container, containerOp := l.pop()
+	text := containerOp.text + ctx.GetText()
+
+	if !l.isValue(containerOp) {
+		l.pushInvalid(text)
+		return
+	}
+
+	full := colons == 2
+	if full && !present[1] {
+		l.errorf("middle index required in 3-index slice")
+	}
+	if full && !present[2] {
+		l.errorf("final index required in 3-index slice")
+	}
+
+	Type := containerOp.Type
+	// the constant indices of the array must be within it
+	length := -1
+	switch {
+	case Type == InvalidType:
+	case IsString(Type):
+		if full {
+			l.errorf("invalid operation: 3-index slice of string")
+		}
+		// the substring of the untyped constant is the string
+		Type = DefaultType(Type)
+		container = l.convert(container, containerOp.Type, Type)
+	default:
+		// the slice of the array shares its elements, so the array must be a variable
+		if array, arrayType, mode, ok := l.array(container, containerOp); ok {
+			if mode != variableOperand {
+				l.errorf("cannot slice unaddressable value %v", describe(containerOp))
+				Type = InvalidType
+				break
+			}
+			container, Type, length = array, &SliceType{Elem: arrayType.Elem}, arrayType.Len
+			break
+		}
+
+		if _, ok := Type.Underlying().(*SliceType); !ok {
+			l.errorf("cannot slice %v", describe(containerOp))
+			Type = InvalidType
+		}
+	}
+
+	// the constant indices must be in order
+	previous := -1
+	for i, index := range indices {
+		if index == nil || !l.isValue(indexOps[i]) {
+			continue
+		}
+		if !IsInteger(indexOps[i].Type) && indexOps[i].Type != InvalidType {
+			l.errorf("invalid argument: index %v must be integer", describe(indexOps[i]))
+			Type = InvalidType
+			continue
+		}
+
+		if value, ok := indexOps[i].value.(int); ok {
+			if value < 0 {
+				l.errorf("invalid argument: index %v must not be negative", describe(indexOps[i]))
+			} else if length >= 0 && value > length {
+				l.errorf("invalid argument: index %v out of bounds [0:%v]", value, length+1)
+			} else if value < previous {
+				l.errorf("invalid slice indices: %v < %v", value, previous)
+			}
+			previous = value
+		}
+		indices[i] = l.convert(index, indexOps[i].Type, IntType)
+	}
+
+	if Type == InvalidType {
+		l.pushInvalid(text)
+		return
+	}
+
+	l.push(&SliceInstruction{
+		program:   l.program,
+		container: container,
+		indices:   indices,
+	}, &operand{mode: valueOperand, Type: Type, text: text})
+}
+
+// instantiate handles the explicit type arguments of the generic function or type,
+// the function gets the rest of the type arguments inferred from the call.
+func (l *GoCompilerListener) instantiate(templateOp *operand, typeOps []*operand, text string) {
+	typeArgs := slices.Clone(templateOp.typeArgs)
+	for _, op := range typeOps {
+		if op.mode == invalidOperand {
+			l.pushInvalid(text)
+			return
+		}
+		if op.mode != typeOperand {
+			l.errorf("%v is not a type", describe(op))
+			l.pushInvalid(text)
+			return
+		}
+
+		typeArgs = append(typeArgs, op.Type)
+	}
+
+	if templateOp.typeTemplate != nil {
+		Type, err := templateOp.typeTemplate.Instantiate(typeArgs)
+		if err != nil {
+			l.Errors = append(l.Errors, err)
+			l.pushInvalid(text)
+			return
+		}
+
+		l.push(&placeholderInstruction{text: text}, &operand{mode: typeOperand, Type: Type, text: text})
+		return
+	}
+
+	if len(typeArgs) < len(templateOp.functionTemplate.typeParams) {
+		res := *templateOp
+		res.typeArgs = typeArgs
+		res.text = text
+		l.push(&placeholderInstruction{text: text}, &res)
+		return
+	}
+
+	functionID, err := templateOp.functionTemplate.Instantiate(typeArgs)
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		l.pushInvalid(text)
+		return
+	}
+
+	l.pushFunction(functionID, text)
+}
+
+// infer instantiates the generic function with the type arguments inferred from the arguments of the call.
+func (l *GoCompilerListener) infer(templateOp *operand, argumentOps []*operand, spread bool) (int, bool) {
+	template := templateOp.functionTemplate
+
+	signature, typeParams, err := template.Signature()
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		return 0, false
+	}
+
+	bindings := map[*TypeParam]Type{}
+	for i, typeArg := range templateOp.typeArgs {
+		bindings[typeParams[i]] = typeArg
+	}
+
+	// the rest arguments of the variadic function are matched with the element of the slice
+	params := signature.Params
+	if signature.Variadic && !spread && len(argumentOps) >= len(params)-1 {
+		elem := params[len(params)-1].(*SliceType).Elem
+		params = slices.Clone(params[:len(params)-1])
+		for len(params) < len(argumentOps) {
+			params = append(params, elem)
+		}
+	}
+
+	if len(argumentOps) != len(params) {
+		l.errorf("wrong number of arguments in call to %v: have %v, want %v", templateOp.text, len(argumentOps), len(params))
+		return 0, false
+	}
+
+	for i, op := range argumentOps {
+		if !l.isValue(op) {
+			return 0, false
+		}
+		if IsUntyped(op.Type) || op.Type == InvalidType {
+			continue
+		}
+
+		if !inferTypeArgs(params[i], op.Type, bindings) {
+			l.errorf("type %v of %v does not match %v", op.Type, op.text, params[i])
+			return 0, false
+		}
+	}
+
+	// the untyped constants get their default types, if nothing else has defined the type parameter
+	untyped := map[*TypeParam]Type{}
+	for i, op := range argumentOps {
+		typeParam, ok := params[i].(*TypeParam)
+		if !ok || !IsUntyped(op.Type) || op.Type == UntypedNilType {
+			continue
+		}
+		if _, ok := bindings[typeParam]; ok {
+			continue
+		}
+
+		if Type, ok := untyped[typeParam]; !ok || Wider(Type, op.Type) {
+			untyped[typeParam] = op.Type
+		}
+	}
+	for typeParam, Type := range untyped {
+		bindings[typeParam] = DefaultType(Type)
+	}
+
+	typeArgs := make([]Type, len(typeParams))
+	for i, typeParam := range typeParams {
+		Type, ok := bindings[typeParam]
+		if !ok {
+			l.errorf("in call to %v, cannot infer %v", templateOp.text, typeParam)
+			return 0, false
+		}
+		typeArgs[i] = Type
+	}
+
+	functionID, err := template.Instantiate(typeArgs)
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		return 0, false
+	}
+
+	return functionID, true
+}
+
+func (l *GoCompilerListener) ExitCallExpression(ctx *parser.CallExpressionContext) {
+	arguments, argumentOps := l.popN(len(ctx.AllExpression()))
+	function, functionOp := l.pop()
+	text := functionOp.text + ctx.GetText()
+	spread := ctx.ELLIPSIS() != nil
+
+	switch functionOp.mode {
+	case invalidOperand:
+		l.pushInvalid(text)
+		return
+	case typeOperand:
+		if spread {
+			l.errorf("invalid use of ... in conversion to %v", functionOp.Type)
+		}
+
+		l.conversion(arguments, argumentOps, functionOp, text)
+		return
+	case builtinOperand:
+		if builtin, ok := builtins[functionOp.text]; ok {
+			instruction, op := builtin(l, arguments, argumentOps, spread, text)
+			l.push(instruction, op)
+			return
+		}
+
+		functionID := function.(*FunctionUsingInstruction).functionID
+		signature := l.program.functions[functionID].(GenericFunction).signature
+
+		// recover is the only one with the result
+		res := &operand{mode: novalueOperand, Type: InvalidType, text: text}
+		if signature.Result != nil {
+			res = &operand{mode: valueOperand, Type: signature.Result, text: text}
+		}
+		l.push(&FunctionCallInstruction{
+			program:    l.program,
+			functionID: functionID,
+			arguments:  l.arguments(arguments, argumentOps, signature, spread, functionOp.text),
+		}, res)
+		return
+	case templateOperand:
+		if functionOp.functionTemplate == nil {
+			l.errorf("cannot use generic type %v without instantiation", functionOp.typeTemplate)
+			l.pushInvalid(text)
+			return
+		}
+
+		functionID, ok := l.infer(functionOp, argumentOps, spread)
+		if !ok {
+			l.pushInvalid(text)
+			return
+		}
+
+		l.pushFunction(functionID, functionOp.text)
+		function, functionOp = l.pop()
+	}
+
+	if !l.isValue(functionOp) {
+		l.pushInvalid(text)
+		return
+	}
+
+	signature, ok := functionOp.Type.Underlying().(*FunctionType)
+	if !ok {
+		if functionOp.Type != InvalidType {
+			l.errorf("invalid operation: cannot call non-function %v", describe(functionOp))
+		}
+		l.pushInvalid(text)
+		return
+	}
+
+	arguments = l.arguments(arguments, argumentOps, signature, spread, functionOp.text)
+
+	res := &operand{mode: novalueOperand, Type: InvalidType, text: text}
+	if signature.Result != nil {
+		res = &operand{mode: valueOperand, Type: signature.Result, text: text}
+	}
+
+	switch function := function.(type) {
+	case *FunctionUsingInstruction:
+		l.push(&FunctionCallInstruction{
+			program:    l.program,
+			functionID: function.functionID,
+			arguments:  arguments,
+		}, res)
+	case *MethodUsingInstruction:
+		l.push(&FunctionCallInstruction{
+			program:    l.program,
+			functionID: function.functionID,
+			arguments:  append([]Instruction{function.receiver}, arguments...),
+		}, res)
+	default:
+		l.push(&FunctionValueCallInstruction{
+			program:   l.program,
+			function:  function,
+			arguments: arguments,
+		}, res)
+	}
+}
+
+// arguments checks the arguments of the call,
+// the rest arguments of the variadic function are packed into the slice.
+func (l *GoCompilerListener) arguments(arguments []Instruction, operands []*operand, signature *FunctionType, spread bool, name string) []Instruction {
+	// f(g()) passes the results of g as the arguments of f
+	if len(operands) == 1 && operands[0].mode == valueOperand && !spread {
+		if tuple, ok := operands[0].Type.(*TupleType); ok {
+			unpack := &UnpackInstruction{
+				program:     l.program,
+				value:       arguments[0],
+				temporaries: make([]string, len(tuple.Types)),
+			}
+
+			values := make([]Instruction, len(tuple.Types))
+			valueOps := make([]*operand, len(tuple.Types))
+			for i, Type := range tuple.Types {
+				unpack.temporaries[i] = l.temporary()
+				values[i] = &VariableUsingInstruction{
+					program:      l.program,
+					variableName: unpack.temporaries[i],
+				}
+				valueOps[i] = &operand{mode: valueOperand, Type: Type, text: operands[0].text}
+			}
+
+			// the unpacking pushes no values, so it goes before the arguments
+			return append([]Instruction{unpack}, l.arguments(values, valueOps, signature, spread, name)...)
+		}
+	}
+
+	params := signature.Params
+	context := "argument to " + name
+
+	if spread && !signature.Variadic {
+		l.errorf("cannot use ... in call to non-variadic %v", name)
+		return arguments
+	}
+
+	if signature.Variadic && !spread {
+		fixed := len(params) - 1
+		if len(arguments) < fixed {
+			l.errorf("not enough arguments in call to %v", name)
+			return arguments
+		}
+
+		res := make([]Instruction, 0, len(params))
+		for i := 0; i < fixed; i++ {
+			res = append(res, l.assign(arguments[i], operands[i], params[i], context))
+		}
+
+		// no rest arguments is the nil slice
+		if len(arguments) == fixed {
+			return append(res, l.convert(&NilUsingInstruction{program: l.program}, UntypedNilType, params[fixed]))
+		}
+
+		elem := params[fixed].(*SliceType).Elem
+		elements := make([]Instruction, 0, len(arguments)-fixed)
+		for i := fixed; i < len(arguments); i++ {
+			elements = append(elements, l.assign(arguments[i], operands[i], elem, context))
+		}
+
+		return append(res, &SliceLiteralInstruction{
+			program:  l.program,
+			elements: elements,
+		})
+	}
+
+	if len(arguments) < len(params) {
+		l.errorf("not enough arguments in call to %v", name)
+		return arguments
+	}
+	if len(arguments) > len(params) {
+		l.errorf("too many arguments in call to %v", name)
+		return arguments
+	}
+
+	res := make([]Instruction, len(arguments))
+	for i := range arguments {
+		res[i] = l.assign(arguments[i], operands[i], params[i], context)
+	}
+
+	return res
+}
+
+func (l *GoCompilerListener) conversion(arguments []Instruction, argumentOps []*operand, typeOp *operand, text string) {
+	if len(arguments) != 1 {
+		if len(arguments) == 0 {
+			l.errorf("missing argument in conversion to %v", typeOp.Type)
+		} else {
+			l.errorf("too many arguments in conversion to %v", typeOp.Type)
+		}
+		l.pushInvalid(text)
+		return
+	}
+
+	argument, op := arguments[0], argumentOps[0]
+	if !l.isValue(op) {
+		l.pushInvalid(text)
+		return
+	}
+
+	if !ConvertibleTo(op.Type, typeOp.Type, l.program) {
+		l.errorf("cannot convert %v to type %v", describe(op), typeOp.Type)
+		l.pushInvalid(text)
+		return
+	}
+
+	if float, ok := op.value.(float64); ok && IsInteger(typeOp.Type) && float != float64(int(float)) {
+		l.errorf("cannot convert %v to type %v (truncated)", describe(op), typeOp.Type)
+	}
+
+	l.push(l.convert(argument, op.Type, typeOp.Type), &operand{mode: valueOperand, Type: typeOp.Type, text: text})
+}
+
+func (l *GoCompilerListener) ExitUnaryExpression(ctx *parser.UnaryExpressionContext) {
+	if ctx.UnaryExpression() == nil {
+		return
+	}
+
+	instruction, op := l.pop()
+	operator := ctx.GetStart().GetText()
+	text := operator + op.text
+
+	if op.mode == invalidOperand {
+		l.pushInvalid(text)
+		return
+	}
+
+	if operator == "<-" {
+		// <-chan T in the expression is the channel type, as in the conversion (<-chan T)(ch)
+		if channelType, ok := op.Type.(*ChannelType); ok && op.mode == typeOperand && channelType.Dir == SendRecv {
+			Type := &ChannelType{Dir: RecvOnly, Elem: channelType.Elem}
+			l.push(&placeholderInstruction{text: Type.String()}, &operand{mode: typeOperand, Type: Type, text: Type.String()})
+			return
+		}
+
+		if !l.isValue(op) {
+			l.pushInvalid(text)
+			return
+		}
+
+		elem, ok := l.channelElem(op, true)
+		if !ok {
+			l.pushInvalid(text)
+			return
+		}
+
+		l.push(&ReceiveInstruction{
+			program: l.program,
+			channel: instruction,
+		}, &operand{mode: valueOperand, Type: elem, text: text})
+		return
+	}
+
+	if operator == "*" {
+		// *T in the expression is the pointer type, as in the conversion (*T)(p)
+		if op.mode == typeOperand {
+			l.push(&placeholderInstruction{text: text}, &operand{mode: typeOperand, Type: &PointerType{Elem: op.Type}, text: text})
+			return
+		}
+
+		if !l.isValue(op) {
+			l.pushInvalid(text)
+			return
+		}
+
+		pointerType, ok := op.Type.Underlying().(*PointerType)
+		if !ok {
+			if op.Type != InvalidType {
+				l.errorf("invalid operation: cannot indirect %v", describe(op))
+			}
+			l.pushInvalid(text)
+			return
+		}
+
+		l.push(&DereferenceInstruction{
+			program: l.program,
+			pointer: instruction,
+		}, &operand{mode: variableOperand, Type: pointerType.Elem, text: text})
+		return
+	}
+
+	if !l.isValue(op) {
+		l.pushInvalid(text)
+		return
+	}
+
+	// &T{} allocates a new variable for the composite literal
+	if _, ok := ctx.UnaryExpression().GetChild(0).(*parser.SimpleExpresionContext); ok {
+		simpleExpresion := ctx.UnaryExpression().SimpleExpresion()
+		if simpleExpresion.Operand().CompositeLiteral() != nil && simpleExpresion.GetChildCount() == 1 {
+			l.push(&NewPointerInstruction{
+				program:     l.program,
+				instruction: instruction,
+			}, &operand{mode: valueOperand, Type: &PointerType{Elem: op.Type}, text: text})
+			return
+		}
+	}
+
+	address, ok := l.address(instruction, op)
+	if !ok {
+		l.errorf("invalid operation: cannot take address of %v", describe(op))
+		l.pushInvalid(text)
+		return
+	}
+
+	l.push(address, &operand{mode: valueOperand, Type: &PointerType{Elem: op.Type}, text: text})
 }
 
-func NewGoCompilerListener(program *Program) *GoCompilerListener {
-	return &GoCompilerListener{
-		program: program,
+// channelElem checks that the values can be received from the channel or sent to it.
+func (l *GoCompilerListener) channelElem(op *operand, receive bool) (Type, bool) {
+	channelType, ok := op.Type.Underlying().(*ChannelType)
+	switch {
+	case op.Type == InvalidType:
+	case !ok && receive:
+		l.errorf("invalid operation: cannot receive from non-channel %v %v", op.Type, describe(op))
+	case !ok:
+		l.errorf("invalid operation: cannot send to non-channel %v %v", op.Type, describe(op))
+	case receive && channelType.Dir == SendOnly:
+		l.errorf("invalid operation: cannot receive from send-only channel %v %v", op.Type, describe(op))
+	case !receive && channelType.Dir == RecvOnly:
+		l.errorf("invalid operation: cannot send to receive-only channel %v %v", op.Type, describe(op))
+	default:
+		return channelType.Elem, true
 	}
-}
 
-func (l *GoCompilerListener) EnterFunctionDefinition(ctx *parser.FunctionDefinitionContext) {
-	l.instructionStack = make([]Instruction, 0)
+	return nil, false
 }
-func (l *GoCompilerListener) ExitFunctionDefinition(ctx *parser.FunctionDefinitionContext) {
-	function := l.program.functions[l.program.functionID[ctx.NAME().GetText()]]
 
-	if intrpretedFunction, ok := function.(*IntrpretatedFunction); ok {
-		intrpretedFunction.instructions = l.instructionStack
+// commaOk makes the receive the comma-ok one when its value and ok are assigned, as in v, ok := <-ch.
+func (l *GoCompilerListener) commaOk(values []Instruction, operands []*operand, n int) {
+	if len(values) != 1 || n != 2 {
+		return
 	}
-}
 
-func (l *GoCompilerListener) ExitVariableDefinition(ctx *parser.VariableDefinitionContext) {
-	Type, err := ReflectType(ctx.Typename().GetText())
+	receive, ok := values[0].(*ReceiveInstruction)
+	if !ok {
+		return
+	}
+
+	receive.commaOk = true
+	tuple := *operands[0]
+	tuple.Type = &TupleType{Types: []Type{operands[0].Type, BoolType}}
+	operands[0] = &tuple
+}
 
+func (l *GoCompilerListener) ExitCompositeLiteral(ctx *parser.CompositeLiteralContext) {
+	expressionCnt := 0
+	for _, keyedElement := range ctx.AllKeyedElement() {
+		expressionCnt += len(keyedElement.AllExpression())
+	}
+	instructions, operands := l.popN(expressionCnt)
+
+	var Type Type
+	var err error
+	switch {
+	case ctx.SliceType() != nil:
+		Type, err = l.resolver.ResolveSliceType(ctx.SliceType())
+	case ctx.ArrayType() != nil && ctx.ArrayType().ELLIPSIS() != nil:
+		// the length of [...]T is the count of the elements
+		Type, err = l.resolver.Resolve(ctx.ArrayType().Typename())
+		if err == nil {
+			Type = &ArrayType{Len: len(ctx.AllKeyedElement()), Elem: Type}
+		}
+	case ctx.ArrayType() != nil:
+		Type, err = l.resolver.ResolveArrayType(ctx.ArrayType())
+	case ctx.MapType() != nil:
+		Type, err = l.resolver.ResolveMapType(ctx.MapType())
+	case ctx.StructType() != nil:
+		Type, err = l.resolver.ResolveStructType(ctx.StructType())
+	default:
+		Type, err = l.resolver.ResolveQualifiedName(ctx.QualifiedName(), ctx.TypeArguments())
+	}
 	if err != nil {
 		l.Errors = append(l.Errors, err)
+		l.pushInvalid(ctx.GetText())
 		return
 	}
 
-	l.instructionStack = append(l.instructionStack, &DefineVariableInstruction{
-		Name: ctx.NAME().GetText(),
-		Type: Type,
-	})
+	op := &operand{mode: valueOperand, Type: Type, text: ctx.GetText()}
+
+	switch underlying := Type.Underlying().(type) {
+	case *SliceType:
+		for i, keyedElement := range ctx.AllKeyedElement() {
+			if len(keyedElement.AllExpression()) != 1 || keyedElement.NAME() != nil {
+				l.errorf("index keys are not supported in slice literals")
+				continue
+			}
+			instructions[i] = l.assign(instructions[i], operands[i], underlying.Elem, "slice literal")
+		}
+
+		l.push(&SliceLiteralInstruction{
+			program:  l.program,
+			elements: instructions,
+		}, op)
+	case *ArrayType:
+		for i, keyedElement := range ctx.AllKeyedElement() {
+			if len(keyedElement.AllExpression()) != 1 || keyedElement.NAME() != nil {
+				l.errorf("index keys are not supported in array literals")
+				continue
+			}
+			if i == underlying.Len {
+				l.errorf("index %v is out of bounds (>= %v)", i, underlying.Len)
+			}
+			instructions[i] = l.assign(instructions[i], operands[i], underlying.Elem, "array or slice literal")
+		}
+
+		l.push(&ArrayLiteralInstruction{
+			program:  l.program,
+			Type:     Type,
+			elements: instructions,
+		}, op)
+	case *MapType:
+		res := &MapLiteralInstruction{
+			program: l.program,
+		}
+
+		i := 0
+		for _, keyedElement := range ctx.AllKeyedElement() {
+			// the key looking as a field name is a variable or a constant
+			if keyedElement.NAME() != nil {
+				l.identifier(keyedElement.NAME().GetText())
+				key, keyOp := l.pop()
+
+				res.keys = append(res.keys, l.assign(key, keyOp, underlying.Key, "map literal"))
+				res.values = append(res.values, l.assign(instructions[i], operands[i], underlying.Elem, "map literal"))
+				i++
+				continue
+			}
+
+			if len(keyedElement.AllExpression()) != 2 {
+				l.errorf("missing key in map literal")
+				i++
+				continue
+			}
+
+			res.keys = append(res.keys, l.assign(instructions[i], operands[i], underlying.Key, "map literal"))
+			res.values = append(res.values, l.assign(instructions[i+1], operands[i+1], underlying.Elem, "map literal"))
+			i += 2
+		}
+
+		l.push(res, op)
+	case *StructType:
+		l.push(l.structLiteral(ctx, Type, underlying, instructions, operands), op)
+	default:
+		l.errorf("invalid composite literal type %v", Type)
+		l.pushInvalid(ctx.GetText())
+	}
 }
 
-func (l *GoCompilerListener) ExitCallExpression(ctx *parser.CallExpressionContext) {
-	functionID, ok := l.program.functionID[ctx.NAME().GetText()]
-	if !ok {
-		l.Errors = append(l.Errors, fmt.Errorf("function '%v' undefined", ctx.NAME().GetText()))
-		return
+// structLiteral matches the elements of the literal with the fields,
+// the elements are either all named by the fields or all positional.
+func (l *GoCompilerListener) structLiteral(ctx *parser.CompositeLiteralContext, Type Type, structType *StructType, instructions []Instruction, operands []*operand) Instruction {
+	res := &StructLiteralInstruction{
+		program: l.program,
+		Type:    Type,
+		fields:  make([]Instruction, len(structType.Fields)),
 	}
 
-	argumentsCnt := len(ctx.AllExpression())
+	keyedElements := ctx.AllKeyedElement()
+	if len(keyedElements) == 0 {
+		return res
+	}
 
-	instruction := &FunctionCallInstruction{
-		program:    l.program,
-		functionID: functionID,
-		arguments:  slices.Clone(l.instructionStack[len(l.instructionStack)-argumentsCnt : len(l.instructionStack)]),
+	if keyedElements[0].NAME() == nil {
+		if len(keyedElements) != len(structType.Fields) {
+			l.errorf("too few or too many values in struct literal of type %v", Type)
+			return res
+		}
+
+		for i, field := range structType.Fields {
+			if keyedElements[i].NAME() != nil || len(keyedElements[i].AllExpression()) != 1 {
+				l.errorf("mixture of field:value and value elements in struct literal")
+				return res
+			}
+			if !IsExported(field.Name) && structType.pkg != l.pkg {
+				l.errorf("implicit assignment to unexported field %v in struct literal of type %v", field.Name, Type)
+				return res
+			}
+
+			res.fields[i] = l.assign(instructions[i], operands[i], field.Type, "struct literal")
+		}
+		return res
+	}
+
+	for i, keyedElement := range keyedElements {
+		if keyedElement.NAME() == nil {
+			l.errorf("mixture of field:value and value elements in struct literal")
+			return res
+		}
+
+		name := keyedElement.NAME().GetText()
+		index, ok := structType.FieldIndex(name, l.pkg)
+		if !ok {
+			l.errorf("unknown field %v in struct literal of type %v", name, Type)
+			continue
+		}
+		if res.fields[index] != nil {
+			l.errorf("duplicate field name %v in struct literal", name)
+			continue
+		}
+
+		res.fields[index] = l.assign(instructions[i], operands[i], structType.Fields[index].Type, "struct literal")
 	}
 
-	l.instructionStack = append(l.instructionStack[:len(l.instructionStack)-argumentsCnt], instruction)
+	return res
 }
 
 func (l *GoCompilerListener) ExitStringUsing(ctx *parser.StringUsingContext) {
-	str := ctx.GetText()
-	l.instructionStack = append(l.instructionStack, &StringUsingInstruction{
+	text := ctx.GetText()
+	str, err := strconv.Unquote(text)
+	if err != nil {
+		l.errorf("invalid string literal %v", text)
+	}
+
+	l.push(&StringUsingInstruction{
 		program: l.program,
-		str:     str[1 : len(str)-1],
-	})
+		str:     str,
+	}, &operand{mode: constantOperand, Type: UntypedStringType, text: text, value: str})
 }
 
 func (l *GoCompilerListener) ExitNumberUsing(ctx *parser.NumberUsingContext) {
 	integer, err := strconv.Atoi(ctx.GetText())
 	if err != nil {
 		l.Errors = append(l.Errors, err)
-		return
 	}
 
-	l.instructionStack = append(l.instructionStack, &IntUsingInstruction{
+	l.push(&IntUsingInstruction{
 		program: l.program,
 		integer: integer,
-	})
+	}, &operand{mode: constantOperand, Type: UntypedIntType, text: ctx.GetText(), value: integer})
 }
 
-func (l *GoCompilerListener) ExitVariableUsing(ctx *parser.VariableUsingContext) {
-	l.instructionStack = append(l.instructionStack, &VariableUsingInstruction{
-		program:      l.program,
-		variableName: ctx.GetText(),
-	})
+func (l *GoCompilerListener) ExitRuneUsing(ctx *parser.RuneUsingContext) {
+	value, _, tail, err := strconv.UnquoteChar(ctx.GetText()[1:len(ctx.GetText())-1], '\'')
+	if err != nil || tail != "" {
+		l.errorf("invalid rune literal %v", ctx.GetText())
+	}
+
+	l.push(&RuneUsingInstruction{
+		program: l.program,
+		value:   value,
+	}, &operand{mode: constantOperand, Type: UntypedRuneType, text: ctx.GetText(), value: int(value)})
+}
+
+func (l *GoCompilerListener) ExitFloatUsing(ctx *parser.FloatUsingContext) {
+	float, err := strconv.ParseFloat(ctx.GetText(), 64)
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+	}
+
+	l.push(&FloatUsingInstruction{
+		program: l.program,
+		float:   float,
+	}, &operand{mode: constantOperand, Type: UntypedFloatType, text: ctx.GetText(), value: float})
 }
 
 func (l *GoCompilerListener) ExitBoolUsing(ctx *parser.BoolUsingContext) {
-	l.instructionStack = append(l.instructionStack, &BoolUsingInstruction{
+	l.push(&BoolUsingInstruction{
 		program: l.program,
 		boolVal: ctx.GetText() == "true",
-	})
+	}, &operand{mode: constantOperand, Type: UntypedBoolType, text: ctx.GetText(), value: ctx.GetText() == "true"})
 }
 
 func (l *GoCompilerListener) ExitAssigment(ctx *parser.AssigmentContext) {
-	instruction := l.instructionStack[len(l.instructionStack)-1]
-	l.instructionStack[len(l.instructionStack)-1] = &AssigmentInstruction{
-		program:     l.program,
-		varName:     ctx.NAME().GetText(),
-		instruction: instruction,
+	values, valueOps := l.popN(len(ctx.AllExpression()))
+	targets, targetOps := l.popN(len(ctx.AllAssigmentTarget()))
+	l.commaOk(values, valueOps, len(targets))
+
+	if _, ok := valueOps[0].Type.(*TupleType); len(targets) == 1 && len(values) == 1 && !ok {
+		res, ok := l.assignTo(targets[0], targetOps[0], values[0], valueOps[0])
+		if !ok {
+			l.pushInvalid(ctx.GetText())
+			return
+		}
+
+		l.instructionStack = append(l.instructionStack, res)
+		return
 	}
-}
 
-func (l *GoCompilerListener) ExitExpressionAdd(ctx *parser.ExpressionAddContext) {
-	argumentsCnt := len(ctx.AllExpressionSub())
-
-	if argumentsCnt == 1 {
+	// all the values are evaluated before they are assigned
+	types, ok := l.tuple(valueOps, len(targets), "assignment mismatch: %v variables but %v values")
+	if !ok {
+		l.pushInvalid(ctx.GetText())
 		return
 	}
 
-	instruction := &AddInstruction{
-		program:      l.program,
-		instructions: slices.Clone(l.instructionStack[len(l.instructionStack)-argumentsCnt : len(l.instructionStack)]),
+	res := &MultiAssigmentInstruction{
+		program:     l.program,
+		values:      values,
+		temporaries: make([]string, len(types)),
+		assigments:  make([]Instruction, 0, len(targets)),
 	}
+	for i, target := range targets {
+		res.temporaries[i] = l.temporary()
+
+		assigment, ok := l.assignTo(target, targetOps[i], &VariableUsingInstruction{
+			program:      l.program,
+			variableName: res.temporaries[i],
+		}, &operand{mode: valueOperand, Type: types[i], text: valueOps[min(i, len(valueOps)-1)].text})
+		if !ok {
+			l.pushInvalid(ctx.GetText())
+			return
+		}
+
+		res.assigments = append(res.assigments, assigment)
+	}
+
+	l.instructionStack = append(l.instructionStack, res)
+}
 
-	l.instructionStack = append(l.instructionStack[:len(l.instructionStack)-argumentsCnt], instruction)
+func (l *GoCompilerListener) temporary() string {
+	l.temporaries++
+	return "@tmp" + strconv.Itoa(l.temporaries)
 }
 
-func (l *GoCompilerListener) ExitExpressionSub(ctx *parser.ExpressionSubContext) {
-	argumentsCnt := len(ctx.AllExpressionMul())
+// tuple gives the types of the values: either all the values are single or there is
+// the only call with several results.
+func (l *GoCompilerListener) tuple(operands []*operand, n int, mismatch string) ([]Type, bool) {
+	if len(operands) == 1 {
+		if tuple, ok := operands[0].Type.(*TupleType); ok && operands[0].mode == valueOperand {
+			if len(tuple.Types) != n {
+				l.errorf(mismatch, n, len(tuple.Types))
+				return nil, false
+			}
+			return tuple.Types, true
+		}
+	}
 
-	if argumentsCnt == 1 {
-		return
+	if len(operands) != n {
+		l.errorf(mismatch, n, len(operands))
+		return nil, false
 	}
 
-	instruction := &SubInstruction{
-		program:      l.program,
-		instructions: slices.Clone(l.instructionStack[len(l.instructionStack)-argumentsCnt : len(l.instructionStack)]),
+	types := make([]Type, len(operands))
+	for i, op := range operands {
+		if !l.isValue(op) {
+			return nil, false
+		}
+		types[i] = op.Type
 	}
 
-	l.instructionStack = append(l.instructionStack[:len(l.instructionStack)-argumentsCnt], instruction)
+	return types, true
 }
 
-func (l *GoCompilerListener) ExitExpressionMul(ctx *parser.ExpressionMulContext) {
-	argumentsCnt := len(ctx.AllExpressionDiv())
+// assignTo makes the assignment of the value to the target.
+func (l *GoCompilerListener) assignTo(target Instruction, targetOp *operand, instruction Instruction, op *operand) (Instruction, bool) {
+	switch targetOp.mode {
+	case invalidOperand:
+		return nil, false
+	case blankOperand:
+		// the value is evaluated and dropped
+		instruction, _ = l.defaultValue(instruction, op, "assignment")
+		return instruction, true
+	}
 
-	if argumentsCnt == 1 {
-		return
+	switch target := target.(type) {
+	case *VariableUsingInstruction:
+		return &AssigmentInstruction{
+			program:     l.program,
+			varName:     target.variableName,
+			instruction: l.assign(instruction, op, targetOp.Type, "assignment"),
+		}, true
+	case *IndexInstruction:
+		// the elements of the strings and of the arrays which are not variables can't be changed
+		containerType := l.operand(target.container).Type.Underlying()
+		_, isMap := containerType.(*MapType)
+		if IsString(containerType) || !isMap && targetOp.mode != variableOperand {
+			l.errorf("cannot assign to %v (neither addressable nor a map index expression)", describe(targetOp))
+			return nil, false
+		}
+
+		return &IndexAssigmentInstruction{
+			program:     l.program,
+			container:   target.container,
+			index:       target.index,
+			instruction: l.assign(instruction, op, targetOp.Type, "assignment"),
+		}, true
 	}
 
-	instruction := &MulInstruction{
-		program:      l.program,
-		instructions: slices.Clone(l.instructionStack[len(l.instructionStack)-argumentsCnt : len(l.instructionStack)]),
+	address, ok := l.address(target, targetOp)
+	if !ok {
+		l.errorf("cannot assign to %v (neither addressable nor a map index expression)", describe(targetOp))
+		return nil, false
 	}
 
-	l.instructionStack = append(l.instructionStack[:len(l.instructionStack)-argumentsCnt], instruction)
+	return &StoreInstruction{
+		program:     l.program,
+		address:     address,
+		instruction: l.assign(instruction, op, targetOp.Type, "assignment"),
+	}, true
 }
 
-func (l *GoCompilerListener) ExitExpressionDiv(ctx *parser.ExpressionDivContext) {
-	argumentsCnt := len(ctx.AllExpressionLogic())
-
+// arithmetic builds the n-ary operation, check tells if the operator is defined on the type.
+func (l *GoCompilerListener) arithmetic(argumentsCnt int, operator string, check func(Type) bool, build func([]Instruction, Type) Instruction) {
 	if argumentsCnt == 1 {
 		return
 	}
 
-	instruction := &DivInstruction{
-		program:      l.program,
-		instructions: slices.Clone(l.instructionStack[len(l.instructionStack)-argumentsCnt : len(l.instructionStack)]),
+	instructions, operands := l.popN(argumentsCnt)
+	text := operands[0].text
+	for _, op := range operands[1:] {
+		text += operator + op.text
 	}
 
-	l.instructionStack = append(l.instructionStack[:len(l.instructionStack)-argumentsCnt], instruction)
-}
-
-func (l *GoCompilerListener) ExitExpressionLogic(ctx *parser.ExpressionLogicContext) {
-	if ctx.ExpressionLogic() == nil {
-		return
+	instructions, Type := l.unify(instructions, operands, text)
+	if Type != InvalidType && !check(Type) {
+		l.errorf("invalid operation: operator %v not defined on %v", operator, describe(operands[0]))
+		Type = InvalidType
 	}
 
-	instruction := &NotInstruction{
-		program:     l.program,
-		instruction: l.instructionStack[len(l.instructionStack)-1],
+	mode := valueOperand
+	if IsUntyped(Type) {
+		mode = constantOperand
 	}
-	l.instructionStack[len(l.instructionStack)-1] = instruction
-}
 
-func (l *GoCompilerListener) ExitExpressionLogicOr(ctx *parser.ExpressionLogicOrContext) {
-	argumentsCnt := len(ctx.AllExpressionLogicAnd())
+	l.push(build(instructions, Type), &operand{mode: mode, Type: Type, text: text})
+}
 
-	if argumentsCnt == 1 {
-		return
-	}
+func (l *GoCompilerListener) ExitExpressionAdd(ctx *parser.ExpressionAddContext) {
+	l.arithmetic(len(ctx.AllExpressionSub()), "+", func(Type Type) bool {
+		return IsNumeric(Type) || IsString(Type)
+	}, func(instructions []Instruction, Type Type) Instruction {
+		return &AddInstruction{
+			program:      l.program,
+			instructions: instructions,
+			Type:         Type,
+		}
+	})
+}
 
-	instruction := &DivInstruction{
-		program:      l.program,
-		instructions: slices.Clone(l.instructionStack[len(l.instructionStack)-argumentsCnt : len(l.instructionStack)]),
-	}
+func (l *GoCompilerListener) ExitExpressionSub(ctx *parser.ExpressionSubContext) {
+	l.arithmetic(len(ctx.AllExpressionMul()), "-", IsNumeric, func(instructions []Instruction, Type Type) Instruction {
+		return &SubInstruction{
+			program:      l.program,
+			instructions: instructions,
+			Type:         Type,
+		}
+	})
+}
 
-	l.instructionStack = append(l.instructionStack[:len(l.instructionStack)-argumentsCnt], instruction)
+func (l *GoCompilerListener) ExitExpressionMul(ctx *parser.ExpressionMulContext) {
+	l.arithmetic(len(ctx.AllExpressionDiv()), "*", IsNumeric, func(instructions []Instruction, Type Type) Instruction {
+		return &MulInstruction{
+			program:      l.program,
+			instructions: instructions,
+			Type:         Type,
+		}
+	})
 }
 
-func (l *GoCompilerListener) ExitExpressionLogicAnd(ctx *parser.ExpressionLogicAndContext) {
-	argumentsCnt := len(ctx.AllCompareExpression())
+func (l *GoCompilerListener) ExitExpressionDiv(ctx *parser.ExpressionDivContext) {
+	l.arithmetic(len(ctx.AllExpressionLogic()), "/", IsNumeric, func(instructions []Instruction, Type Type) Instruction {
+		return &DivInstruction{
+			program:      l.program,
+			instructions: instructions,
+			Type:         Type,
+		}
+	})
+}
 
-	if argumentsCnt == 1 {
+func (l *GoCompilerListener) ExitExpressionLogic(ctx *parser.ExpressionLogicContext) {
+	if ctx.ExpressionLogic() == nil {
 		return
 	}
 
-	instruction := &DivInstruction{
-		program:      l.program,
-		instructions: slices.Clone(l.instructionStack[len(l.instructionStack)-argumentsCnt : len(l.instructionStack)]),
+	instruction, op := l.pop()
+	res := &operand{mode: valueOperand, Type: op.Type, text: "!" + op.text}
+	if l.isValue(op) && !IsBoolean(op.Type) && op.Type != InvalidType {
+		l.errorf("invalid operation: operator ! not defined on %v", describe(op))
+		res.Type = InvalidType
 	}
 
-	l.instructionStack = append(l.instructionStack[:len(l.instructionStack)-argumentsCnt], instruction)
+	l.push(&NotInstruction{
+		program:     l.program,
+		instruction: instruction,
+	}, res)
 }
 
-func (l *GoCompilerListener) ExitBlock(ctx *parser.BlockContext) {
-	instructionCnt := len(ctx.AllLine())
-	instructions := slices.Clone(l.instructionStack[len(l.instructionStack)-instructionCnt : len(l.instructionStack)])
+func (l *GoCompilerListener) ExitExpressionLogicOr(ctx *parser.ExpressionLogicOrContext) {
+	l.arithmetic(len(ctx.AllExpressionLogicAnd()), "||", IsBoolean, func(instructions []Instruction, _ Type) Instruction {
+		return &OrInstruction{
+			program:      l.program,
+			instructions: instructions,
+		}
+	})
+}
 
-	l.instructionStack = append(l.instructionStack[:len(l.instructionStack)-instructionCnt], &BlockInstruction{
-		program:      l.program,
-		instructions: instructions,
+func (l *GoCompilerListener) ExitExpressionLogicAnd(ctx *parser.ExpressionLogicAndContext) {
+	l.arithmetic(len(ctx.AllCompareExpression()), "&&", IsBoolean, func(instructions []Instruction, _ Type) Instruction {
+		return &AndInstruction{
+			program:      l.program,
+			instructions: instructions,
+		}
 	})
 }
 
+// condition checks the condition of if and for statements.
+func (l *GoCompilerListener) condition(instruction Instruction, statement string) Instruction {
+	op := l.operand(instruction)
+	if !l.isValue(op) {
+		return instruction
+	}
+
+	if !IsBoolean(op.Type) && op.Type != InvalidType {
+		l.errorf("non-boolean condition in %v statement", statement)
+	}
+
+	return instruction
+}
+
 func (l *GoCompilerListener) ExitExpressionIF(ctx *parser.ExpressionIFContext) {
 	res := &IFInstruction{}
 	res.program = l.program
@@ -233,39 +2267,222 @@ func (l *GoCompilerListener) ExitExpressionIF(ctx *parser.ExpressionIFContext) {
 	res.than = l.instructionStack[len(l.instructionStack)-1]
 	l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
 
-	res.statment = l.instructionStack[len(l.instructionStack)-1]
+	res.statment = l.condition(l.instructionStack[len(l.instructionStack)-1], "if")
 	l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
 
 	l.instructionStack = append(l.instructionStack, res)
 }
 
 func (l *GoCompilerListener) ExitCompareExpression(ctx *parser.CompareExpressionContext) {
-	if len(ctx.AllSimpleExpresion()) == 1 {
+	if len(ctx.AllUnaryExpression()) == 1 {
 		return
 	}
 
-	res := &CompareInstruction{}
-	res.program = l.program
-	res.compareType = ctx.COMPARETOKEN().GetText()
+	compareType := ctx.COMPARETOKEN().GetText()
+	instructions, operands := l.popN(2)
+	res := &operand{mode: valueOperand, Type: UntypedBoolType, text: operands[0].text + compareType + operands[1].text}
+
+	// nil is compared only with the values of nillable types
+	for i := range operands {
+		other := operands[1-i]
+		if operands[i].Type == UntypedNilType && other.mode >= valueOperand && other.mode <= constantOperand && IsNillable(other.Type) {
+			if compareType != "==" && compareType != "!=" {
+				l.errorf("invalid operation: %v (operator %v not defined on nil)", res.text, compareType)
+			}
+
+			instructions[i] = l.convert(instructions[i], UntypedNilType, other.Type)
+			l.push(&CompareInstruction{
+				program:     l.program,
+				lhv:         instructions[0],
+				rhv:         instructions[1],
+				compareType: compareType,
+			}, res)
+			return
+		}
+	}
 
-	res.rhv = l.instructionStack[len(l.instructionStack)-1]
-	l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
+	instructions, Type := l.unify(instructions, operands, res.text)
+	switch {
+	case Type == InvalidType:
+	case (compareType == "==" || compareType == "!=") && !IsComparable(Type):
+		l.errorf("invalid operation: %v (%v cannot be compared)", res.text, Type)
+	case compareType != "==" && compareType != "!=" && !IsOrdered(Type):
+		l.errorf("invalid operation: %v (operator %v not defined on %v)", res.text, compareType, describe(operands[0]))
+	}
 
-	res.lhv = l.instructionStack[len(l.instructionStack)-1]
-	l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
+	l.push(&CompareInstruction{
+		program:     l.program,
+		lhv:         instructions[0],
+		rhv:         instructions[1],
+		compareType: compareType,
+		Type:        Type,
+	}, res)
+}
 
-	l.instructionStack = append(l.instructionStack, res)
+// the iteration variables are in the scope of the loop
+func (l *GoCompilerListener) EnterExpressionFOR(ctx *parser.ExpressionFORContext) {
+	l.scopes = append(l.scopes, map[string]Type{})
+	if ctx.RangeClause() != nil {
+		l.ranges++
+	}
+}
+
+func (l *GoCompilerListener) ExitRangeClause(ctx *parser.RangeClauseContext) {
+	container, containerOp := l.pop()
+	targets, targetOps := l.popN(len(ctx.AllAssigmentTarget()))
+	names := ctx.AllNAME()
+	text := "range " + containerOp.text
+
+	if !l.isValue(containerOp) {
+		l.pushInvalid(text)
+		return
+	}
+
+	res := &RangeInstruction{
+		program:   l.program,
+		container: container,
+	}
+
+	// the types of the iteration values, nil if there is no such value
+	var types [2]Type
+	switch underlying := containerOp.Type.Underlying().(type) {
+	case *SliceType:
+		res.kind = rangeSlice
+		types = [2]Type{IntType, underlying.Elem}
+	case *ArrayType:
+		// the array is copied once before the loop, like in Go
+		res.kind = rangeSlice
+		types = [2]Type{IntType, underlying.Elem}
+	case *PointerType:
+		array, arrayType, _, ok := l.array(container, containerOp)
+		if !ok {
+			l.errorf("cannot range over %v", describe(containerOp))
+			l.pushInvalid(text)
+			return
+		}
+
+		res.kind = rangeSlice
+		res.container = array
+		types = [2]Type{IntType, arrayType.Elem}
+	case *MapType:
+		res.kind = rangeMap
+		types = [2]Type{underlying.Key, underlying.Elem}
+	case *ChannelType:
+		if underlying.Dir == SendOnly {
+			l.errorf("cannot range over %v: receive from send-only channel %v", describe(containerOp), containerOp.Type)
+			l.pushInvalid(text)
+			return
+		}
+
+		res.kind = rangeChannel
+		types = [2]Type{underlying.Elem, nil}
+	case *FunctionType:
+		yield, ok := iterator(underlying)
+		if !ok {
+			l.errorf("cannot range over %v", describe(containerOp))
+			l.pushInvalid(text)
+			return
+		}
+
+		res.kind = rangeFunction
+		copy(types[:], yield.Params)
+	default:
+		switch {
+		case IsString(underlying):
+			res.kind = rangeString
+			res.container = l.convert(container, containerOp.Type, StringType)
+			types = [2]Type{IntType, Int32Type}
+		case IsInteger(underlying):
+			res.kind = rangeInt
+			res.container = l.convert(container, containerOp.Type, DefaultType(containerOp.Type))
+			types = [2]Type{DefaultType(containerOp.Type), nil}
+		default:
+			if containerOp.Type != InvalidType {
+				l.errorf("cannot range over %v", describe(containerOp))
+			}
+			l.pushInvalid(text)
+			return
+		}
+	}
+
+	count := len(names) + len(targets)
+	if count > 0 && types[count-1] == nil {
+		if types[0] == nil {
+			l.errorf("range over %v permits no iteration variables", describe(containerOp))
+		} else {
+			l.errorf("range over %v permits only one iteration variable", describe(containerOp))
+		}
+		l.pushInvalid(text)
+		return
+	}
+
+	scope := l.scopes[len(l.scopes)-1]
+	for i, name := range names {
+		if name.GetText() != "_" {
+			res.names[i] = name.GetText()
+			scope[name.GetText()] = types[i]
+		}
+	}
+
+	for i, target := range targets {
+		res.names[i] = l.temporary()
+
+		assigment, ok := l.assignTo(target, targetOps[i], &VariableUsingInstruction{
+			program:      l.program,
+			variableName: res.names[i],
+		}, &operand{mode: valueOperand, Type: types[i], text: text})
+		if !ok {
+			l.pushInvalid(text)
+			return
+		}
+		res.assigments = append(res.assigments, assigment)
+	}
+
+	l.push(res, &operand{mode: novalueOperand, Type: InvalidType, text: text})
+}
+
+// iterator gives the signature of the yield function of the range-over-func iterator.
+func iterator(Type *FunctionType) (*FunctionType, bool) {
+	if len(Type.Params) != 1 || Type.Result != nil {
+		return nil, false
+	}
+
+	yield, ok := Type.Params[0].Underlying().(*FunctionType)
+	if !ok || len(yield.Params) > 2 || yield.Variadic || yield.Result == nil || !IsBoolean(yield.Result) || IsUntyped(yield.Result) {
+		return nil, false
+	}
+
+	return yield, true
 }
 
 func (l *GoCompilerListener) ExitExpressionFOR(ctx *parser.ExpressionFORContext) {
-	res := &FORInstruction{}
-	res.program = l.program
+	l.scopes = l.scopes[:len(l.scopes)-1]
+	if ctx.RangeClause() != nil {
+		l.ranges--
+	}
 
-	res.than = l.instructionStack[len(l.instructionStack)-1]
+	body := l.instructionStack[len(l.instructionStack)-1]
 	l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
 
+	if ctx.RangeClause() != nil {
+		instruction, _ := l.pop()
+		res, ok := instruction.(*RangeInstruction)
+		if !ok {
+			l.pushInvalid(ctx.GetText())
+			return
+		}
+
+		res.body = body
+		l.instructionStack = append(l.instructionStack, res)
+		return
+	}
+
+	res := &FORInstruction{}
+	res.program = l.program
+	res.than = body
+
 	if ctx.Expression() != nil {
-		res.statment = l.instructionStack[len(l.instructionStack)-1]
+		res.statment = l.condition(l.instructionStack[len(l.instructionStack)-1], "for")
 		l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
 	}
 
@@ -277,14 +2494,319 @@ func (l *GoCompilerListener) ExitBreak(ctx *parser.BreakContext) {
 }
 
 func (l *GoCompilerListener) ExitFunctionReturn(ctx *parser.FunctionReturnContext) {
+	instructions, operands := l.popN(len(ctx.AllExpression()))
 	res := &ReturnInstruction{
 		program: l.program,
 	}
 
-	if ctx.Expression() != nil {
-		res.expression = l.instructionStack[len(l.instructionStack)-1]
-		l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
+	var results []InputVariable
+	if l.function != nil {
+		results = l.function.outputVariables
+	}
+
+	// the bare return returns the named results as they are
+	if len(instructions) == 0 {
+		if len(results) != 0 && results[0].Name == "" {
+			l.errorf("not enough return values\n\thave ()\n\twant %v", l.function.Signature().Result)
+		}
+		for _, result := range results {
+			if result.Name == "" || result.Name == "_" {
+				continue
+			}
+			if !l.inScope(result.Name, l.functionScope) {
+				l.errorf("result parameter %v not in scope at return", result.Name)
+			}
+		}
+
+		l.instructionStack = append(l.instructionStack, res)
+		return
+	}
+
+	if len(results) == 0 {
+		l.errorf("too many return values")
+		l.pushInvalid(ctx.GetText())
+		return
+	}
+
+	types, ok := l.tuple(operands, len(results), "wrong number of return values: want %v, have %v")
+	if !ok {
+		l.pushInvalid(ctx.GetText())
+		return
+	}
+
+	if len(instructions) == len(results) {
+		res.expressions = make([]Instruction, len(results))
+		for i := range results {
+			res.expressions[i] = l.assign(instructions[i], operands[i], results[i].Type, "return statement")
+		}
+
+		// the range loop body is not the function body, the return from it leaves the loop first
+		if call, ok := res.expressions[0].(*FunctionCallInstruction); ok && len(results) == 1 && l.ranges == 0 {
+			if id, ok := l.program.functionID[l.function.Name()]; ok && id == call.functionID {
+				l.tailCalls = append(l.tailCalls, tailCall{instruction: res, ctx: ctx})
+			}
+		}
+
+		l.instructionStack = append(l.instructionStack, res)
+		return
+	}
+
+	// the results of the call are stored to be converted one by one
+	multi := &MultiAssigmentInstruction{
+		program:     l.program,
+		values:      instructions,
+		temporaries: make([]string, len(results)),
+		assigments:  []Instruction{res},
+	}
+	res.expressions = make([]Instruction, len(results))
+	for i := range results {
+		multi.temporaries[i] = l.temporary()
+		res.expressions[i] = l.assign(&VariableUsingInstruction{
+			program:      l.program,
+			variableName: multi.temporaries[i],
+		}, &operand{mode: valueOperand, Type: types[i], text: operands[0].text}, results[i].Type, "return statement")
+	}
+
+	l.instructionStack = append(l.instructionStack, multi)
+}
+
+// inScope reports whether the name isn't shadowed in the scopes deeper than the scope.
+func (l *GoCompilerListener) inScope(name string, scope int) bool {
+	for i := len(l.scopes) - 1; i > scope; i-- {
+		if _, ok := l.scopes[i][name]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (l *GoCompilerListener) ExitDeferStatement(ctx *parser.DeferStatementContext) {
+	if len(l.literalStates) == 0 {
+		l.deferred = true
+	}
+
+	instruction, op := l.pop()
+	if op.mode == invalidOperand {
+		l.pushInvalid(ctx.GetText())
+		return
+	}
+
+	switch call := instruction.(type) {
+	case *FunctionCallInstruction:
+		l.instructionStack = append(l.instructionStack, &DeferInstruction{
+			program:    l.program,
+			functionID: call.functionID,
+			arguments:  call.arguments,
+		})
+	case *FunctionValueCallInstruction:
+		l.instructionStack = append(l.instructionStack, &DeferInstruction{
+			program:   l.program,
+			function:  call.function,
+			arguments: call.arguments,
+		})
+	case *BuiltinCallInstruction, *CloseInstruction:
+		if op.mode != novalueOperand {
+			l.errorf("defer discards result of %v", op.text)
+			l.pushInvalid(ctx.GetText())
+			return
+		}
+
+		function, arguments := l.builtinFunction(call)
+		l.instructionStack = append(l.instructionStack, &DeferInstruction{
+			program:   l.program,
+			function:  function,
+			arguments: arguments,
+		})
+	default:
+		if op.mode == novalueOperand || op.mode == valueOperand {
+			l.errorf("defer discards result of %v", op.text)
+		} else {
+			l.errorf("expression in defer must be function call")
+		}
+		l.pushInvalid(ctx.GetText())
+	}
+}
+
+func (l *GoCompilerListener) ExitGoStatement(ctx *parser.GoStatementContext) {
+	instruction, op := l.pop()
+	if op.mode == invalidOperand {
+		l.pushInvalid(ctx.GetText())
+		return
+	}
+
+	switch call := instruction.(type) {
+	case *FunctionCallInstruction:
+		l.instructionStack = append(l.instructionStack, &GoInstruction{
+			program:    l.program,
+			functionID: call.functionID,
+			arguments:  call.arguments,
+		})
+	case *FunctionValueCallInstruction:
+		l.instructionStack = append(l.instructionStack, &GoInstruction{
+			program:   l.program,
+			function:  call.function,
+			arguments: call.arguments,
+		})
+	case *BuiltinCallInstruction, *CloseInstruction:
+		if op.mode != novalueOperand {
+			l.errorf("go discards result of %v", op.text)
+			l.pushInvalid(ctx.GetText())
+			return
+		}
+
+		function, arguments := l.builtinFunction(call)
+		l.instructionStack = append(l.instructionStack, &GoInstruction{
+			program:   l.program,
+			function:  function,
+			arguments: arguments,
+		})
+	default:
+		if op.mode == novalueOperand || op.mode == valueOperand {
+			l.errorf("go discards result of %v", op.text)
+		} else {
+			l.errorf("expression in go must be function call")
+		}
+		l.pushInvalid(ctx.GetText())
+	}
+}
+
+// builtinFunction makes the call of the built-in function without results the call of the function value.
+func (l *GoCompilerListener) builtinFunction(instruction Instruction) (Instruction, []Instruction) {
+	if closeCall, ok := instruction.(*CloseInstruction); ok {
+		return &BuiltinFunctionInstruction{program: l.program, name: "close"}, []Instruction{closeCall.channel}
+	}
+
+	call := instruction.(*BuiltinCallInstruction)
+	return &BuiltinFunctionInstruction{program: l.program, name: call.name, Type: call.Type}, call.arguments
+}
+
+func (l *GoCompilerListener) ExitSendStatement(ctx *parser.SendStatementContext) {
+	values, operands := l.popN(2)
+	text := operands[0].text + " <- " + operands[1].text
+	if !l.isValue(operands[0]) {
+		l.pushInvalid(text)
+		return
+	}
+
+	elem, ok := l.channelElem(operands[0], false)
+	if !ok {
+		l.pushInvalid(text)
+		return
+	}
+
+	l.instructionStack = append(l.instructionStack, &SendInstruction{
+		program: l.program,
+		channel: values[0],
+		value:   l.assign(values[1], operands[1], elem, "send"),
+	})
+}
+
+// the bodies of the cases run apart from the function body, like the ones of the range loops
+func (l *GoCompilerListener) EnterSelectStatement(ctx *parser.SelectStatementContext) {
+	l.selects = append(l.selects, &SelectInstruction{program: l.program})
+	l.ranges++
+}
+
+func (l *GoCompilerListener) ExitSelectStatement(ctx *parser.SelectStatementContext) {
+	res := l.selects[len(l.selects)-1]
+	l.selects = l.selects[:len(l.selects)-1]
+	l.ranges--
+
+	first := 0
+	for _, clause := range ctx.AllCommClause() {
+		if clause.CommCase().GetText() != "default" {
+			continue
+		}
+		if first != 0 {
+			l.errorf("multiple defaults (first at line %v)", first)
+			break
+		}
+		first = clause.GetStart().GetLine()
 	}
 
 	l.instructionStack = append(l.instructionStack, res)
 }
+
+// the case has its own scope, the variables of the value received are in it
+func (l *GoCompilerListener) EnterCommClause(ctx *parser.CommClauseContext) {
+	l.blocks = append(l.blocks, &blockState{
+		line:      ctx.GetStart().GetLine(),
+		statement: -1,
+	})
+	l.scopes = append(l.scopes, map[string]Type{})
+}
+
+func (l *GoCompilerListener) ExitCommClause(ctx *parser.CommClauseContext) {
+	l.blocks = l.blocks[:len(l.blocks)-1]
+	l.scopes = l.scopes[:len(l.scopes)-1]
+
+	cases := l.selects[len(l.selects)-1].cases
+	cases[len(cases)-1].body = l.block(len(ctx.AllLine()))
+}
+
+func (l *GoCompilerListener) ExitCommCase(ctx *parser.CommCaseContext) {
+	res := l.selects[len(l.selects)-1]
+	switch {
+	case ctx.ReceiveClause() != nil:
+		// the receive clause has added the case
+		return
+	case ctx.SendStatement() != nil:
+		instruction := l.instructionStack[len(l.instructionStack)-1]
+		l.instructionStack = l.instructionStack[:len(l.instructionStack)-1]
+
+		// the invalid send has been reported, the case is left empty
+		send, _ := instruction.(*SendInstruction)
+		if send == nil {
+			send = &SendInstruction{}
+		}
+		res.cases = append(res.cases, selectCase{channel: send.channel, value: send.value})
+		return
+	}
+
+	res.cases = append(res.cases, selectCase{})
+}
+
+func (l *GoCompilerListener) ExitReceiveClause(ctx *parser.ReceiveClauseContext) {
+	value, valueOp := l.pop()
+	targets, targetOps := l.popN(len(ctx.AllAssigmentTarget()))
+	names := ctx.AllNAME()
+	res := l.selects[len(l.selects)-1]
+
+	scope := l.scopes[len(l.scopes)-1]
+	receive, ok := value.(*ReceiveInstruction)
+	if !ok {
+		if valueOp.mode != invalidOperand {
+			l.errorf("select case must be receive, send or assign recv")
+		}
+		// the variables are declared anyway, their uses are not reported again
+		for _, name := range names {
+			scope[name.GetText()] = InvalidType
+		}
+		res.cases = append(res.cases, selectCase{})
+		return
+	}
+
+	c := selectCase{channel: receive.channel}
+	types := [2]Type{valueOp.Type, BoolType}
+	for i, name := range names {
+		if name.GetText() != "_" {
+			c.names[i] = name.GetText()
+			scope[name.GetText()] = types[i]
+		}
+	}
+
+	for i, target := range targets {
+		c.names[i] = l.temporary()
+
+		assigment, ok := l.assignTo(target, targetOps[i], &VariableUsingInstruction{
+			program:      l.program,
+			variableName: c.names[i],
+		}, &operand{mode: valueOperand, Type: types[i], text: valueOp.text})
+		if ok {
+			c.assigments = append(c.assigments, assigment)
+		}
+	}
+
+	res.cases = append(res.cases, c)
+}
@@ -1,58 +1,241 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/karetskiiVO/GOInterpreter/parser"
 )
 
 type GoDeclarationListener struct {
 	*parser.BaseGoListener
 
-	program *Program
-	Errors  []error
+	program  *Program
+	pkg      *Package
+	resolver *TypeResolver
+	Errors   []error
 }
 
-func NewGoDeclarationListener(program *Program) *GoDeclarationListener {
+func NewGoDeclarationListener(program *Program, pkg *Package, file *SourceFile) *GoDeclarationListener {
 	return &GoDeclarationListener{
-		program: program,
+		program:  program,
+		pkg:      pkg,
+		resolver: NewTypeResolver(program, pkg, file),
+	}
+}
+
+func (l *GoDeclarationListener) EnterTypeDefinition(ctx *parser.TypeDefinitionContext) {
+	// the generic types are resolved per instance
+	if ctx.TypeParameters() != nil {
+		return
+	}
+
+	namedType := l.program.types[l.pkg.QualifiedName(ctx.NAME().GetText())].(*NamedType)
+
+	rhs, err := l.resolver.resolve(ctx.Typename())
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		rhs = InvalidType
+	}
+
+	for Type := rhs; Type != nil; {
+		next, ok := Type.(*NamedType)
+		if !ok {
+			break
+		}
+		if next == namedType {
+			l.Errors = append(l.Errors, fmt.Errorf("invalid recursive type %v", namedType))
+			rhs = InvalidType
+			break
+		}
+		Type = next.rhs
+	}
+
+	namedType.rhs = rhs
+}
+
+func (l *GoDeclarationListener) EnterTypeAlias(ctx *parser.TypeAliasContext) {
+	// aliases are resolved on the first use, the ones without usages are resolved here
+	_, _, err := l.resolver.LookupName(ctx.NAME().GetText())
+	if err != nil {
+		l.Errors = append(l.Errors, err)
 	}
 }
 
 func (l *GoDeclarationListener) EnterFunctionDefinition(ctx *parser.FunctionDefinitionContext) {
-	res := NewIntrpretatedFunction(ctx.NAME().GetText())
+	if IsGeneric(ctx) {
+		l.declareTemplate(ctx)
+		return
+	}
 
-	if ctx.Arguments() != nil {
-		for i := range ctx.Arguments().AllNAME() {
-			varName := ctx.Arguments().AllNAME()[i].GetText()
-			varType := ctx.Arguments().AllTypename()[i].NAME().GetText()
+	name, err := l.resolver.FunctionName(ctx)
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+	}
 
-			inputVariable := InputVariable{}
+	res, errs := declareFunction(l.resolver, name, ctx)
+	l.Errors = append(l.Errors, errs...)
 
-			inputVariable.Name = varName
+	if _, ok, _ := l.resolver.lookup(name); ok {
+		l.Errors = append(l.Errors, fmt.Errorf("%v redeclared in this block", ctx.NAME().GetText()))
+	}
 
-			var err error
-			inputVariable.Type, err = ReflectType(varType)
+	err = l.program.RegisterFunction(res)
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+	}
+}
+
+func (l *GoDeclarationListener) declareTemplate(ctx *parser.FunctionDefinitionContext) {
+	name := ctx.NAME().GetText()
+
+	if ctx.Receiver() == nil {
+		err := l.program.RegisterFunctionTemplate(l.pkg.QualifiedName(name), NewFunctionTemplate(l.resolver, ctx))
+		if err != nil {
+			l.Errors = append(l.Errors, err)
+		}
+		if _, ok, _ := l.resolver.lookup(l.pkg.QualifiedName(name)); ok {
+			l.Errors = append(l.Errors, fmt.Errorf("%v redeclared in this block", name))
+		}
+		return
+	}
+
+	if ctx.TypeParameters() != nil {
+		l.Errors = append(l.Errors, fmt.Errorf("methods cannot have type parameters"))
+		return
+	}
+
+	template, typeParams, err := l.resolver.GenericReceiver(ctx.Receiver())
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+		return
+	}
+	if _, ok := template.methods[name]; ok {
+		l.Errors = append(l.Errors, fmt.Errorf("method %v.%v already declared", template.name, name))
+		return
+	}
+
+	template.methods[name] = NewMethodTemplate(l.resolver, ctx, template, typeParams)
+}
+
+// declareFunction resolves the signature of the function, the receiver of a method is its first argument.
+func declareFunction(resolver *TypeResolver, name string, ctx parser.IFunctionDefinitionContext) (*IntrpretatedFunction, []error) {
+	errors := make([]error, 0)
+	res := NewIntrpretatedFunction(name)
+
+	if ctx.Receiver() != nil {
+		receiverType, err := resolver.Resolve(ctx.Receiver().Typename())
+		if err != nil {
+			errors = append(errors, err)
+			receiverType = InvalidType
+		}
+
+		err = res.RegisterArgument(InputVariable{
+			Name: ctx.Receiver().NAME().GetText(),
+			Type: receiverType,
+		})
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return res, append(errors, declareSignature(resolver, res, ctx)...)
+}
+
+// signatureContext is the signature of the function definition or the function literal.
+type signatureContext interface {
+	Arguments() parser.IArgumentsContext
+	Results() parser.IResultsContext
+}
+
+// declareSignature registers the arguments and the results of the function.
+func declareSignature(resolver *TypeResolver, function *IntrpretatedFunction, ctx signatureContext) []error {
+	errors := make([]error, 0)
+
+	if ctx.Arguments() != nil {
+		arguments, variadic, errs := declareArguments(resolver, ctx.Arguments())
+		errors = append(errors, errs...)
+
+		for _, argument := range arguments {
+			err := function.RegisterArgument(argument)
 			if err != nil {
-				l.Errors = append(l.Errors, err)
+				errors = append(errors, err)
 			}
+		}
+		function.variadic = variadic
+	}
+
+	results := ctx.Results()
+	if results == nil {
+		return errors
+	}
+
+	if results.Arguments() != nil {
+		named, variadic, errs := declareArguments(resolver, results.Arguments())
+		errors = append(errors, errs...)
+		if variadic {
+			errors = append(errors, fmt.Errorf("can only use ... with final parameter in list"))
+		}
 
-			err = res.RegisterArgument(inputVariable)
+		for _, result := range named {
+			err := function.RegisterResult(result)
 			if err != nil {
-				l.Errors = append(l.Errors, err)
+				errors = append(errors, err)
 			}
 		}
+		return errors
 	}
 
-	if ctx.Typename() != nil {
-		var err error
-		res.returnType, err = ReflectType(ctx.Typename().GetText())
+	typenames := []parser.ITypenameContext{results.Typename()}
+	if results.Typename() == nil {
+		typenames = make([]parser.ITypenameContext, 0)
+		if results.TypeList() != nil {
+			typenames = results.TypeList().AllTypename()
+		}
+	}
 
+	for _, typename := range typenames {
+		Type, err := resolver.Resolve(typename)
 		if err != nil {
-			l.Errors = append(l.Errors, err)
+			errors = append(errors, err)
+			Type = InvalidType
 		}
+
+		function.RegisterResult(InputVariable{Type: Type})
 	}
 
-	err := l.program.RegisterFunction(res)
-	if err != nil {
-		l.Errors = append(l.Errors, err)
+	return errors
+}
+
+// declareArguments resolves the list of the parameters, the names in a group share the type.
+func declareArguments(resolver *TypeResolver, ctx parser.IArgumentsContext) ([]InputVariable, bool, []error) {
+	errors := make([]error, 0)
+	res := make([]InputVariable, 0)
+	variadic := false
+
+	declarations := ctx.AllParameterDeclaration()
+	for i, declaration := range declarations {
+		Type, err := resolver.Resolve(declaration.Typename())
+		if err != nil {
+			errors = append(errors, err)
+			Type = InvalidType
+		}
+
+		if declaration.ELLIPSIS() != nil {
+			if i != len(declarations)-1 || len(declaration.AllNAME()) != 1 {
+				errors = append(errors, fmt.Errorf("can only use ... with final parameter in list"))
+			}
+
+			Type = &SliceType{Elem: Type}
+			variadic = true
+		}
+
+		for _, name := range declaration.AllNAME() {
+			res = append(res, InputVariable{
+				Name: name.GetText(),
+				Type: Type,
+			})
+		}
 	}
+
+	return res, variadic, errors
 }
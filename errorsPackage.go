@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "errors",
+		Path:    "errors",
+		Declare: declareErrors,
+	})
+}
+
+var (
+	isSignature        = &FunctionType{Params: []Type{ErrorType}, Result: BoolType}
+	asSignature        = &FunctionType{Params: []Type{AnyType}, Result: BoolType}
+	unwrapSignature    = &FunctionType{Params: []Type{}, Result: ErrorType}
+	unwrapAllSignature = &FunctionType{Params: []Type{}, Result: &SliceType{Elem: ErrorType}}
+)
+
+func declareErrors(prog *Program, pkg *Package) {
+	errorString := prog.declareType(pkg, "errorString", hostStruct(pkg, &Field{Name: "s", Type: StringType}))
+	prog.errorStringType = &PointerType{Elem: errorString}
+	prog.declareMethod(errorString, "Error", signature([]Type{prog.errorStringType}, StringType), hostGetter(0))
+
+	errors := &SliceType{Elem: ErrorType}
+	joinError := prog.declareType(pkg, "joinError", hostStruct(pkg, &Field{Name: "errs", Type: errors}))
+	joinErrorType := &PointerType{Elem: joinError}
+	prog.declareMethod(joinError, "Error", signature([]Type{joinErrorType}, StringType), func(args ...any) ([]any, error) {
+		errs, err := hostGetter(0)(args...)
+		if err != nil {
+			return nil, err
+		}
+
+		messages := make([]string, 0)
+		for _, err := range errs[0].([]any) {
+			message, err := prog.ErrorMessage(err)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		}
+		return []any{strings.Join(messages, "\n")}, nil
+	})
+	prog.declareMethod(joinError, "Unwrap", signature([]Type{joinErrorType}, errors), hostGetter(0))
+
+	prog.RegisterValue(pkg.QualifiedName("ErrUnsupported"), &PackageValue{Type: ErrorType, Value: prog.NewError("unsupported operation")})
+
+	prog.declareFunction(pkg, "New", signature([]Type{StringType}, ErrorType), func(args ...any) ([]any, error) {
+		return []any{prog.NewError(args[0].(string))}, nil
+	})
+
+	prog.declareFunction(pkg, "Unwrap", signature([]Type{ErrorType}, ErrorType), func(args ...any) ([]any, error) {
+		method, ok := prog.errorMethod(args[0], "Unwrap", unwrapSignature)
+		if !ok {
+			return []any{nil}, nil
+		}
+		return method.Call()
+	})
+
+	prog.declareFunction(pkg, "Join", variadic([]Type{ErrorType}, ErrorType), func(args ...any) ([]any, error) {
+		errs := make([]any, 0)
+		for _, err := range args[0].([]any) {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) == 0 {
+			return []any{nil}, nil
+		}
+
+		return []any{InterfaceValue{Type: joinErrorType, Value: NewPointer(NewStructValue(errs))}}, nil
+	})
+
+	prog.declareFunction(pkg, "Is", signature([]Type{ErrorType, ErrorType}, BoolType), func(args ...any) ([]any, error) {
+		err, target := args[0], args[1]
+		if err == nil || target == nil {
+			return []any{err == target}, nil
+		}
+
+		res, e := prog.errorsIs(err, target, IsComparable(target.(InterfaceValue).Type))
+		return []any{res}, e
+	})
+
+	prog.declareFunction(pkg, "As", signature([]Type{ErrorType, AnyType}, BoolType), func(args ...any) ([]any, error) {
+		// the nil error matches nothing, the target isn't checked then, like in Go
+		if args[0] == nil {
+			return []any{false}, nil
+		}
+
+		target, ok := args[1].(InterfaceValue)
+		if !ok {
+			return nil, fmt.Errorf("errors: target cannot be nil")
+		}
+
+		pointerType, ok := target.Type.Underlying().(*PointerType)
+		if !ok || isNilPointer(target.Value) {
+			return nil, fmt.Errorf("errors: target must be a non-nil pointer")
+		}
+		if !IsInterface(pointerType.Elem) && Implements(pointerType.Elem, ErrorType, prog) != nil {
+			return nil, fmt.Errorf("errors: *target must be interface or implement error")
+		}
+
+		res, err := prog.errorsAs(args[0], target, pointerType.Elem)
+		return []any{res}, err
+	})
+}
+
+// NewError makes the error value the way errors.New does.
+func (prog *Program) NewError(text string) any {
+	return InterfaceValue{Type: prog.errorStringType, Value: NewPointer(NewStructValue(text))}
+}
+
+// ErrorMessage calls the Error method of the error value.
+func (prog *Program) ErrorMessage(err any) (string, error) {
+	method, ok := prog.errorMethod(err, "Error", stringerSignature)
+	if !ok {
+		return "", fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+	}
+
+	res, e := method.Call()
+	if e != nil {
+		return "", e
+	}
+	return res[0].(string), nil
+}
+
+// errorMethod finds the method of the dynamic type of the error value.
+func (prog *Program) errorMethod(err any, name string, signature *FunctionType) (Function, bool) {
+	iface, ok := err.(InterfaceValue)
+	if !ok {
+		return nil, false
+	}
+
+	return prog.DynamicMethod(iface.Type, iface.Value, name, signature)
+}
+
+// unwrap gives the errors the error wraps and reports whether it wraps several errors.
+func (prog *Program) unwrap(err any) ([]any, bool, error) {
+	if method, ok := prog.errorMethod(err, "Unwrap", unwrapSignature); ok {
+		res, e := method.Call()
+		if e != nil || res[0] == nil {
+			return nil, false, e
+		}
+		return res, false, nil
+	}
+
+	if method, ok := prog.errorMethod(err, "Unwrap", unwrapAllSignature); ok {
+		res, e := method.Call()
+		if e != nil {
+			return nil, true, e
+		}
+		return res[0].([]any), true, nil
+	}
+
+	return nil, false, nil
+}
+
+// errorsIs looks for the target in the tree of the errors the error wraps.
+func (prog *Program) errorsIs(err, target any, targetComparable bool) (bool, error) {
+	for {
+		if targetComparable {
+			if equal, e := EqualAny(err, target); e == nil && equal.(bool) {
+				return true, nil
+			}
+		}
+
+		if method, ok := prog.errorMethod(err, "Is", isSignature); ok {
+			res, e := method.Call(target)
+			if e != nil || res[0].(bool) {
+				return e == nil, e
+			}
+		}
+
+		wrapped, several, e := prog.unwrap(err)
+		if e != nil || len(wrapped) == 0 {
+			return false, e
+		}
+		if !several {
+			err = wrapped[0]
+			continue
+		}
+
+		for _, err := range wrapped {
+			if err == nil {
+				continue
+			}
+			if ok, e := prog.errorsIs(err, target, targetComparable); ok || e != nil {
+				return ok, e
+			}
+		}
+		return false, nil
+	}
+}
+
+// errorsAs looks for the error of the target type in the tree of the errors the error wraps,
+// the found error is stored to the variable the target points to.
+func (prog *Program) errorsAs(err any, target InterfaceValue, targetType Type) (bool, error) {
+	for {
+		iface, ok := err.(InterfaceValue)
+		if !ok {
+			return false, nil
+		}
+
+		if AssignableTo(iface.Type, targetType, prog) {
+			cell := target.Value.(*any)
+			if IsInterface(targetType) {
+				*cell = iface
+			} else {
+				*cell = CloneAny(iface.Value)
+			}
+			return true, nil
+		}
+
+		if method, ok := prog.errorMethod(err, "As", asSignature); ok {
+			res, e := method.Call(target)
+			if e != nil || res[0].(bool) {
+				return e == nil, e
+			}
+		}
+
+		wrapped, several, e := prog.unwrap(err)
+		if e != nil || len(wrapped) == 0 {
+			return false, e
+		}
+		if !several {
+			err = wrapped[0]
+			continue
+		}
+
+		for _, err := range wrapped {
+			if ok, e := prog.errorsAs(err, target, targetType); ok || e != nil {
+				return ok, e
+			}
+		}
+		return false, nil
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "fmt",
+		Path:    "fmt",
+		Imports: []string{"errors", "io"},
+		Declare: declareFmt,
+	})
+}
+
+func declareFmt(prog *Program, pkg *Package) {
+	writer := prog.lookupStdType("io", "Writer")
+
+	prog.declareType(pkg, "Stringer", &InterfaceType{Methods: []*Method{
+		{Name: "String", Signature: stringerSignature},
+	}})
+	prog.declareType(pkg, "GoStringer", &InterfaceType{Methods: []*Method{
+		{Name: "GoString", Signature: stringerSignature},
+	}})
+
+	// the print functions differ by the way they format the operands and by where they write
+	printers := []struct {
+		suffix string
+		format func(args []any) string
+	}{
+		{"print", prog.Sprint},
+		{"println", prog.Sprintln},
+	}
+	printf := func(args []any) string {
+		return prog.Sprintf(args[0].(string), args[1].([]any))
+	}
+
+	for _, printer := range printers {
+		format := printer.format
+		prog.declareFunction(pkg, "P"+printer.suffix[1:], variadic([]Type{AnyType}, IntType, ErrorType), func(args ...any) ([]any, error) {
+			return prog.writeStdout(format(args[0].([]any)))
+		})
+		prog.declareFunction(pkg, "S"+printer.suffix, variadic([]Type{AnyType}, StringType), func(args ...any) ([]any, error) {
+			return []any{format(args[0].([]any))}, nil
+		})
+		prog.declareFunction(pkg, "F"+printer.suffix, variadic([]Type{writer, AnyType}, IntType, ErrorType), func(args ...any) ([]any, error) {
+			return prog.Write(args[0], format(args[1].([]any)))
+		})
+	}
+
+	prog.declareFunction(pkg, "Printf", variadic([]Type{StringType, AnyType}, IntType, ErrorType), func(args ...any) ([]any, error) {
+		return prog.writeStdout(printf(args))
+	})
+	prog.declareFunction(pkg, "Sprintf", variadic([]Type{StringType, AnyType}, StringType), func(args ...any) ([]any, error) {
+		return []any{printf(args)}, nil
+	})
+	prog.declareFunction(pkg, "Fprintf", variadic([]Type{writer, StringType, AnyType}, IntType, ErrorType), func(args ...any) ([]any, error) {
+		return prog.Write(args[0], printf(args[1:]))
+	})
+
+	declareErrorf(prog, pkg)
+}
+
+// writeStdout writes the output of the print functions, the results are the ones of Fprint.
+func (prog *Program) writeStdout(str string) ([]any, error) {
+	n, err := os.Stdout.WriteString(str)
+	if err != nil {
+		return []any{n, prog.NewError(fmt.Sprint(err))}, nil
+	}
+
+	return []any{n, nil}, nil
+}
+
+// declareErrorf declares Errorf with the errors it makes: the error wrapping the operand of %w,
+// the error wrapping the several operands and the error wrapping nothing.
+func declareErrorf(prog *Program, pkg *Package) {
+	wrapError := prog.declareType(pkg, "wrapError", hostStruct(pkg,
+		&Field{Name: "msg", Type: StringType},
+		&Field{Name: "err", Type: ErrorType},
+	))
+	wrapErrorType := &PointerType{Elem: wrapError}
+	prog.declareMethod(wrapError, "Error", signature([]Type{wrapErrorType}, StringType), hostGetter(0))
+	prog.declareMethod(wrapError, "Unwrap", signature([]Type{wrapErrorType}, ErrorType), hostGetter(1))
+
+	errors := &SliceType{Elem: ErrorType}
+	wrapErrors := prog.declareType(pkg, "wrapErrors", hostStruct(pkg,
+		&Field{Name: "msg", Type: StringType},
+		&Field{Name: "errs", Type: errors},
+	))
+	wrapErrorsType := &PointerType{Elem: wrapErrors}
+	prog.declareMethod(wrapErrors, "Error", signature([]Type{wrapErrorsType}, StringType), hostGetter(0))
+	prog.declareMethod(wrapErrors, "Unwrap", signature([]Type{wrapErrorsType}, errors), hostGetter(1))
+
+	prog.declareFunction(pkg, "Errorf", variadic([]Type{StringType, AnyType}, ErrorType), func(args ...any) ([]any, error) {
+		operands := args[1].([]any)
+		msg, wrapped := prog.Errorf(args[0].(string), operands)
+
+		switch len(wrapped) {
+		case 0:
+			return []any{prog.NewError(msg)}, nil
+		case 1:
+			return []any{InterfaceValue{
+				Type:  wrapErrorType,
+				Value: NewPointer(NewStructValue(msg, prog.errorOperand(operands[wrapped[0]]))),
+			}}, nil
+		}
+
+		errs := make([]any, 0, len(wrapped))
+		for i, argNum := range wrapped {
+			if i > 0 && wrapped[i-1] == argNum {
+				continue
+			}
+			if err := prog.errorOperand(operands[argNum]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return []any{InterfaceValue{
+			Type:  wrapErrorsType,
+			Value: NewPointer(NewStructValue(msg, errs)),
+		}}, nil
+	})
+}
+
+// errorOperand gives the operand of %w, if it is an error.
+func (prog *Program) errorOperand(operand any) any {
+	iface, ok := operand.(InterfaceValue)
+	if !ok {
+		return nil
+	}
+
+	if _, ok := prog.DynamicMethod(iface.Type, iface.Value, "Error", stringerSignature); !ok {
+		return nil
+	}
+
+	return iface
+}
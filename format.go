@@ -0,0 +1,962 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// printer formats the values of the script the way the fmt package of Go does.
+// The script values don't know their types, so every value is printed with its type:
+// the arguments are the interface values, which keep their dynamic types.
+type printer struct {
+	program *Program
+	buf     strings.Builder
+
+	// the operand being printed, the value of a struct field or an element
+	// is passed to the methods only if it is not reached through an unexported field
+	argType  Type
+	argValue any
+	hasArg   bool
+
+	flags printFlags
+
+	// the format of the arguments is broken, the methods of the arguments are not called
+	erroring bool
+	// Errorf wraps the arguments of %w
+	wrapErrs    bool
+	wrappedErrs []int
+	// the arguments are accessed out of the order
+	reordered  bool
+	goodArgNum bool
+}
+
+type printFlags struct {
+	plus, minus, sharp, space, zero bool
+	// %+v and %#v
+	plusV, sharpV bool
+
+	width, prec               int
+	widthPresent, precPresent bool
+}
+
+func newPrinter(program *Program) *printer {
+	return &printer{program: program}
+}
+
+// stringerSignature is the signature of the Error, String and GoString methods.
+var stringerSignature = &FunctionType{Params: []Type{}, Result: StringType}
+
+// spec rebuilds the directive with the current flags, the basic values are formatted by the host.
+func (p *printer) spec(verb rune) string {
+	var spec strings.Builder
+	spec.WriteByte('%')
+	if p.flags.plus || p.flags.plusV {
+		spec.WriteByte('+')
+	}
+	if p.flags.minus {
+		spec.WriteByte('-')
+	}
+	if p.flags.sharp || p.flags.sharpV {
+		spec.WriteByte('#')
+	}
+	if p.flags.space {
+		spec.WriteByte(' ')
+	}
+	if p.flags.zero {
+		spec.WriteByte('0')
+	}
+	if p.flags.widthPresent {
+		spec.WriteString(strconv.Itoa(p.flags.width))
+	}
+	if p.flags.precPresent {
+		spec.WriteByte('.')
+		spec.WriteString(strconv.Itoa(p.flags.prec))
+	}
+	spec.WriteRune(verb)
+
+	return spec.String()
+}
+
+// host formats the value of the basic type with the current flags.
+func (p *printer) host(verb rune, value any) {
+	fmt.Fprintf(&p.buf, p.spec(verb), value)
+}
+
+// padString writes the string padded to the width, the precision is ignored.
+func (p *printer) padString(str string) {
+	flags := p.flags
+	p.flags = printFlags{minus: flags.minus, zero: flags.zero, width: flags.width, widthPresent: flags.widthPresent}
+	p.host('s', str)
+	p.flags = flags
+}
+
+// fmt0x64 writes the hexadecimal number, with or without the leading 0x.
+func (p *printer) fmt0x64(value uint64, leading0x bool) {
+	flags := p.flags
+	p.flags.sharp, p.flags.sharpV, p.flags.plusV = leading0x, false, false
+	p.host('x', value)
+	p.flags = flags
+}
+
+func (p *printer) doPrint(args []any) {
+	prevString := false
+	for i, arg := range args {
+		isString := arg != nil && IsString(arg.(InterfaceValue).Type)
+		// the spaces are added between the operands when neither is a string
+		if i > 0 && !isString && !prevString {
+			p.buf.WriteByte(' ')
+		}
+		p.printArg(arg, 'v')
+		prevString = isString
+	}
+}
+
+func (p *printer) doPrintln(args []any) {
+	for i, arg := range args {
+		if i > 0 {
+			p.buf.WriteByte(' ')
+		}
+		p.printArg(arg, 'v')
+	}
+	p.buf.WriteByte('\n')
+}
+
+func (p *printer) doPrintf(format string, args []any) {
+	end := len(format)
+	argNum := 0
+	// the previous item of the format was an index like [3]
+	afterIndex := false
+	p.reordered = false
+
+formatLoop:
+	for i := 0; i < end; {
+		p.goodArgNum = true
+		lasti := i
+		for i < end && format[i] != '%' {
+			i++
+		}
+		if i > lasti {
+			p.buf.WriteString(format[lasti:i])
+		}
+		if i >= end {
+			break
+		}
+
+		// process one verb
+		i++
+		p.flags = printFlags{}
+
+	simpleFormat:
+		for ; i < end; i++ {
+			c := format[i]
+			switch c {
+			case '#':
+				p.flags.sharp = true
+			case '0':
+				// only the padding to the left is done with zeros
+				p.flags.zero = !p.flags.minus
+			case '+':
+				p.flags.plus = true
+			case '-':
+				p.flags.minus = true
+				p.flags.zero = false
+			case ' ':
+				p.flags.space = true
+			default:
+				// the simple verbs without the width, the precision and the argument indexes
+				if 'a' <= c && c <= 'z' && argNum < len(args) {
+					if c == 'w' {
+						p.wrappedErrs = append(p.wrappedErrs, argNum)
+					}
+					if c == 'v' || c == 'w' {
+						p.flags.sharpV, p.flags.sharp = p.flags.sharp, false
+						p.flags.plusV, p.flags.plus = p.flags.plus, false
+					}
+					p.printArg(args[argNum], rune(c))
+					argNum++
+					i++
+					continue formatLoop
+				}
+				break simpleFormat
+			}
+		}
+
+		argNum, i, afterIndex = p.argNumber(argNum, format, i, len(args))
+
+		if i < end && format[i] == '*' {
+			i++
+			p.flags.width, p.flags.widthPresent, argNum = intFromArg(args, argNum)
+			if !p.flags.widthPresent {
+				p.buf.WriteString("%!(BADWIDTH)")
+			}
+
+			// the negative width pads to the right
+			if p.flags.width < 0 {
+				p.flags.width = -p.flags.width
+				p.flags.minus = true
+				p.flags.zero = false
+			}
+			afterIndex = false
+		} else {
+			p.flags.width, p.flags.widthPresent, i = parsenum(format, i, end)
+			if afterIndex && p.flags.widthPresent {
+				// %[3]2d
+				p.goodArgNum = false
+			}
+		}
+
+		if i+1 < end && format[i] == '.' {
+			i++
+			if afterIndex {
+				// %[3].2d
+				p.goodArgNum = false
+			}
+			argNum, i, afterIndex = p.argNumber(argNum, format, i, len(args))
+			if i < end && format[i] == '*' {
+				i++
+				p.flags.prec, p.flags.precPresent, argNum = intFromArg(args, argNum)
+				if p.flags.prec < 0 {
+					p.flags.prec = 0
+					p.flags.precPresent = false
+				}
+				if !p.flags.precPresent {
+					p.buf.WriteString("%!(BADPREC)")
+				}
+				afterIndex = false
+			} else {
+				p.flags.prec, p.flags.precPresent, i = parsenum(format, i, end)
+				if !p.flags.precPresent {
+					p.flags.prec = 0
+					p.flags.precPresent = true
+				}
+			}
+		}
+
+		if !afterIndex {
+			argNum, i, afterIndex = p.argNumber(argNum, format, i, len(args))
+		}
+
+		if i >= end {
+			p.buf.WriteString("%!(NOVERB)")
+			break
+		}
+
+		verb, size := rune(format[i]), 1
+		if verb >= utf8.RuneSelf {
+			verb, size = utf8.DecodeRuneInString(format[i:])
+		}
+		i += size
+
+		switch {
+		case verb == '%':
+			// the percent doesn't take an operand and ignores the width and the precision
+			p.buf.WriteByte('%')
+		case !p.goodArgNum:
+			p.buf.WriteString("%!" + string(verb) + "(BADINDEX)")
+		case argNum >= len(args):
+			p.buf.WriteString("%!" + string(verb) + "(MISSING)")
+		default:
+			if verb == 'w' {
+				p.wrappedErrs = append(p.wrappedErrs, argNum)
+			}
+			if verb == 'v' || verb == 'w' {
+				p.flags.sharpV, p.flags.sharp = p.flags.sharp, false
+				p.flags.plusV, p.flags.plus = p.flags.plus, false
+			}
+			p.printArg(args[argNum], verb)
+			argNum++
+		}
+	}
+
+	// the extra arguments are not reported if they are accessed out of the order
+	if !p.reordered && argNum < len(args) {
+		p.flags = printFlags{}
+		p.buf.WriteString("%!(EXTRA ")
+		for i, arg := range args[argNum:] {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			if arg == nil {
+				p.buf.WriteString("<nil>")
+			} else {
+				p.buf.WriteString(reflectString(arg.(InterfaceValue).Type))
+				p.buf.WriteByte('=')
+				p.printArg(arg, 'v')
+			}
+		}
+		p.buf.WriteByte(')')
+	}
+}
+
+// tooLarge reports whether the width or the precision is too large to be a real one.
+func tooLarge(x int) bool {
+	const max int = 1e6
+	return x > max || x < -max
+}
+
+// parsenum parses the decimal number of the format.
+func parsenum(s string, start, end int) (num int, isnum bool, newi int) {
+	if start >= end {
+		return 0, false, end
+	}
+	for newi = start; newi < end && '0' <= s[newi] && s[newi] <= '9'; newi++ {
+		if tooLarge(num) {
+			return 0, false, end
+		}
+		num = num*10 + int(s[newi]-'0')
+		isnum = true
+	}
+
+	return num, isnum, newi
+}
+
+// parseArgNumber parses the argument index [n], the indexes start with one.
+func parseArgNumber(format string) (index int, width int, ok bool) {
+	if len(format) < 3 {
+		return 0, 1, false
+	}
+
+	for i := 1; i < len(format); i++ {
+		if format[i] == ']' {
+			width, ok, newi := parsenum(format, 1, i)
+			if !ok || newi != i {
+				return 0, i + 1, false
+			}
+			return width - 1, i + 1, true
+		}
+	}
+
+	return 0, 1, false
+}
+
+func (p *printer) argNumber(argNum int, format string, i int, numArgs int) (int, int, bool) {
+	if len(format) <= i || format[i] != '[' {
+		return argNum, i, false
+	}
+
+	p.reordered = true
+	index, width, ok := parseArgNumber(format[i:])
+	if ok && 0 <= index && index < numArgs {
+		return index, i + width, true
+	}
+	p.goodArgNum = false
+
+	return argNum, i + width, ok
+}
+
+// intFromArg takes the width or the precision given by *.
+func intFromArg(args []any, argNum int) (int, bool, int) {
+	if argNum >= len(args) {
+		return 0, false, argNum
+	}
+
+	num, isInt := 0, false
+	if arg, ok := args[argNum].(InterfaceValue); ok && IsInteger(arg.Type) {
+		switch value := arg.Value.(type) {
+		case int:
+			num, isInt = value, true
+		case int32:
+			num, isInt = int(value), true
+		case uint8:
+			num, isInt = int(value), true
+		}
+	}
+	if tooLarge(num) {
+		num, isInt = 0, false
+	}
+
+	return num, isInt, argNum + 1
+}
+
+// printArg prints the operand of the print functions, nil is the nil interface.
+func (p *printer) printArg(arg any, verb rune) {
+	if arg == nil {
+		p.argType, p.argValue, p.hasArg = nil, nil, false
+		switch verb {
+		case 'T', 'v':
+			p.padString("<nil>")
+		default:
+			p.badVerb(verb)
+		}
+		return
+	}
+
+	iface := arg.(InterfaceValue)
+	p.argType, p.argValue, p.hasArg = iface.Type, iface.Value, true
+
+	// the type and the address of the value are printed without its methods
+	switch verb {
+	case 'T':
+		p.fmtS(reflectString(iface.Type))
+		return
+	case 'p':
+		p.fmtPointer(iface.Type, iface.Value, 'p')
+		return
+	}
+
+	// the unnamed slice of bytes is printed as the bytes
+	if slice, ok := iface.Type.(*SliceType); ok && slice.Elem == Uint8Type {
+		p.fmtBytes(iface.Value.([]any), verb, "[]byte")
+		return
+	}
+
+	if !p.handleMethods(verb) {
+		p.printValue(iface.Type, iface.Value, verb, 0, false)
+	}
+}
+
+// handleMethods prints the value with its Error, String or GoString method, if it has the one.
+func (p *printer) handleMethods(verb rune) bool {
+	if p.erroring {
+		return false
+	}
+
+	if verb == 'w' {
+		// %w may be used only by Errorf with the error operand
+		if _, ok := p.method("Error", stringerSignature); !ok || !p.wrapErrs {
+			p.badVerb(verb)
+			return true
+		}
+		verb = 'v'
+	}
+
+	if p.flags.sharpV {
+		if method, ok := p.method("GoString", stringerSignature); ok {
+			p.callMethod(method, "GoString", verb, func(str string) {
+				p.fmtS(str)
+			})
+			return true
+		}
+		return false
+	}
+
+	switch verb {
+	case 'v', 's', 'x', 'X', 'q':
+		for _, name := range []string{"Error", "String"} {
+			if method, ok := p.method(name, stringerSignature); ok {
+				p.callMethod(method, name, verb, func(str string) {
+					p.fmtString(str, verb)
+				})
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// method finds the method of the operand.
+func (p *printer) method(name string, signature *FunctionType) (Function, bool) {
+	if !p.hasArg {
+		return nil, false
+	}
+
+	return p.program.DynamicMethod(p.argType, p.argValue, name, signature)
+}
+
+// callMethod prints the result of the method, the panic in the method is printed instead of the result.
+func (p *printer) callMethod(method Function, name string, verb rune, print func(str string)) {
+	res, err := method.Call()
+	if err == nil {
+		print(res[0].(string))
+		return
+	}
+
+	// the likeliest cause is the nil pointer receiver
+	if _, ok := p.argType.(*PointerType); ok && isNilPointer(p.argValue) {
+		p.buf.WriteString("<nil>")
+		return
+	}
+
+	flags := p.flags
+	p.flags = printFlags{}
+	p.buf.WriteString("%!" + string(verb) + "(PANIC=" + name + " method: " + err.Error() + ")")
+	p.flags = flags
+}
+
+func isNilPointer(value any) bool {
+	cell, _ := value.(*any)
+	return cell == nil
+}
+
+// badVerb prints the operand, which can't be formatted with the verb, with its type.
+func (p *printer) badVerb(verb rune) {
+	p.erroring = true
+	p.buf.WriteString("%!" + string(verb) + "(")
+	if p.hasArg {
+		p.buf.WriteString(reflectString(p.argType))
+		p.buf.WriteByte('=')
+		p.printValue(p.argType, p.argValue, 'v', 0, false)
+	} else {
+		p.buf.WriteString("<nil>")
+	}
+	p.buf.WriteByte(')')
+	p.erroring = false
+}
+
+// fmtS writes the string truncated to the precision and padded to the width.
+func (p *printer) fmtS(str string) {
+	flags := p.flags
+	p.flags = printFlags{
+		minus: flags.minus, zero: flags.zero,
+		width: flags.width, widthPresent: flags.widthPresent,
+		prec: flags.prec, precPresent: flags.precPresent,
+	}
+	p.host('s', str)
+	p.flags = flags
+}
+
+func (p *printer) fmtString(str string, verb rune) {
+	switch verb {
+	case 'v', 's', 'x', 'X', 'q':
+		p.host(verb, str)
+	default:
+		p.badVerb(verb)
+	}
+}
+
+// fmtBytes prints the slice of bytes, the verbs for strings print it as the string.
+func (p *printer) fmtBytes(bytes []any, verb rune, typeString string) {
+	switch verb {
+	case 'v', 'd':
+		if p.flags.sharpV {
+			p.buf.WriteString(typeString)
+			if bytes == nil {
+				p.buf.WriteString("(nil)")
+				return
+			}
+			p.buf.WriteByte('{')
+			for i, b := range bytes {
+				if i > 0 {
+					p.buf.WriteString(", ")
+				}
+				p.fmt0x64(uint64(b.(uint8)), true)
+			}
+			p.buf.WriteByte('}')
+			return
+		}
+
+		p.buf.WriteByte('[')
+		for i, b := range bytes {
+			if i > 0 {
+				p.buf.WriteByte(' ')
+			}
+			p.host(verb, b)
+		}
+		p.buf.WriteByte(']')
+	case 's', 'x', 'X', 'q':
+		hostBytes := make([]byte, len(bytes))
+		for i, b := range bytes {
+			hostBytes[i] = b.(uint8)
+		}
+		p.host(verb, hostBytes)
+	default:
+		p.printValue(&SliceType{Elem: Uint8Type}, bytes, verb, 0, false)
+	}
+}
+
+// fmtPointer prints the address of the value of the reference type.
+func (p *printer) fmtPointer(Type Type, value any, verb rune) {
+	var address uintptr
+	switch Type.Underlying().(type) {
+	case *PointerType, *SliceType, *MapType, *FunctionType, *ChannelType:
+		address = addressOf(value)
+	default:
+		p.badVerb(verb)
+		return
+	}
+
+	switch verb {
+	case 'v':
+		if p.flags.sharpV {
+			p.buf.WriteString("(" + reflectString(Type) + ")(")
+			if address == 0 {
+				p.buf.WriteString("nil")
+			} else {
+				p.fmt0x64(uint64(address), true)
+			}
+			p.buf.WriteByte(')')
+		} else if address == 0 {
+			p.padString("<nil>")
+		} else {
+			p.fmt0x64(uint64(address), !p.flags.sharp)
+		}
+	case 'p':
+		p.fmt0x64(uint64(address), !p.flags.sharp)
+	case 'b', 'o', 'd', 'x', 'X':
+		p.host(verb, uint64(address))
+	default:
+		p.badVerb(verb)
+	}
+}
+
+// addressOf is the address of the host value the reference is represented with.
+func addressOf(value any) uintptr {
+	if value == nil {
+		return 0
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func:
+		return reflectValue.Pointer()
+	case reflect.Struct:
+		// the function values made by the interpreter
+		if closure, ok := value.(Closure); ok {
+			return reflect.ValueOf(closure.variables).Pointer()
+		}
+	}
+
+	return reflect.ValueOf(&value).Pointer()
+}
+
+// printValue prints the value of the type, the values reached through
+// the unexported fields are printed without their methods.
+func (p *printer) printValue(Type Type, value any, verb rune, depth int, unexported bool) {
+	p.argType, p.argValue, p.hasArg = Type, value, true
+	if depth > 0 && !unexported && !IsInterface(Type) && p.handleMethods(verb) {
+		return
+	}
+
+	switch underlying := Type.Underlying().(type) {
+	case *BasicType:
+		p.printBasic(underlying, value, verb)
+	case *MapType:
+		p.printMap(Type, underlying, value.(map[any]any), verb, depth, unexported)
+	case *StructType:
+		p.printStruct(Type, underlying, value.(*StructValue), verb, depth, unexported)
+	case *InterfaceType:
+		if value == nil {
+			if p.flags.sharpV {
+				p.buf.WriteString(reflectString(Type) + "(nil)")
+			} else {
+				p.buf.WriteString("<nil>")
+			}
+			return
+		}
+		iface := value.(InterfaceValue)
+		p.printValue(iface.Type, iface.Value, verb, depth+1, unexported)
+	case *SliceType:
+		p.printSlice(Type, underlying, value.([]any), verb, depth, unexported)
+	case *ArrayType:
+		p.printSlice(Type, &SliceType{Elem: underlying.Elem}, value.(*ArrayValue).elems, verb, depth, unexported)
+	case *PointerType:
+		// the pointer to the composite value is printed as &{...}, only at the top level to avoid the loops
+		if cell, ok := value.(*any); ok && cell != nil && depth == 0 {
+			switch underlying.Elem.Underlying().(type) {
+			case *SliceType, *ArrayType, *StructType, *MapType:
+				p.buf.WriteByte('&')
+				p.printValue(underlying.Elem, *cell, verb, depth+1, unexported)
+				return
+			}
+		}
+		p.fmtPointer(Type, value, verb)
+	case *FunctionType, *ChannelType:
+		p.fmtPointer(Type, value, verb)
+	default:
+		p.badVerb(verb)
+	}
+}
+
+// printBasic checks the verb and formats the value of the basic type with the host formatting.
+func (p *printer) printBasic(Type *BasicType, value any, verb rune) {
+	verbs := ""
+	switch {
+	case IsBoolean(Type):
+		verbs = "tv"
+	case IsInteger(Type):
+		verbs = "bcdoOqxXUv"
+	case IsFloat(Type), IsComplex(Type):
+		verbs = "bgGxXfFeEv"
+	case IsString(Type):
+		verbs = "vsxXq"
+	}
+
+	if !strings.ContainsRune(verbs, verb) {
+		p.badVerb(verb)
+		return
+	}
+
+	p.host(verb, value)
+}
+
+func (p *printer) printMap(Type Type, mapType *MapType, value map[any]any, verb rune, depth int, unexported bool) {
+	if p.flags.sharpV {
+		p.buf.WriteString(reflectString(Type))
+		if value == nil {
+			p.buf.WriteString("(nil)")
+			return
+		}
+		p.buf.WriteByte('{')
+	} else {
+		p.buf.WriteString("map[")
+	}
+
+	for i, key := range sortedKeys(mapType.Key, value) {
+		if i > 0 {
+			if p.flags.sharpV {
+				p.buf.WriteString(", ")
+			} else {
+				p.buf.WriteByte(' ')
+			}
+		}
+		p.printValue(mapType.Key, key, verb, depth+1, unexported)
+		p.buf.WriteByte(':')
+		p.printValue(mapType.Elem, value[key], verb, depth+1, unexported)
+	}
+
+	if p.flags.sharpV {
+		p.buf.WriteByte('}')
+	} else {
+		p.buf.WriteByte(']')
+	}
+}
+
+func (p *printer) printStruct(Type Type, structType *StructType, value *StructValue, verb rune, depth int, unexported bool) {
+	if p.flags.sharpV {
+		p.buf.WriteString(reflectString(Type))
+	}
+	p.buf.WriteByte('{')
+	for i, field := range structType.Fields {
+		if i > 0 {
+			if p.flags.sharpV {
+				p.buf.WriteString(", ")
+			} else {
+				p.buf.WriteByte(' ')
+			}
+		}
+		if p.flags.plusV || p.flags.sharpV {
+			p.buf.WriteString(field.Name + ":")
+		}
+		p.printValue(field.Type, *value.fields[i], verb, depth+1, unexported || !IsExported(field.Name))
+	}
+	p.buf.WriteByte('}')
+}
+
+func (p *printer) printSlice(Type Type, sliceType *SliceType, value []any, verb rune, depth int, unexported bool) {
+	switch verb {
+	case 's', 'q', 'x', 'X':
+		if kind, ok := basicKind(sliceType.Elem); ok && kind == Uint8 {
+			p.fmtBytes(value, verb, reflectString(Type))
+			return
+		}
+	}
+
+	if p.flags.sharpV {
+		p.buf.WriteString(reflectString(Type))
+		if value == nil {
+			p.buf.WriteString("(nil)")
+			return
+		}
+		p.buf.WriteByte('{')
+		for i, elem := range value {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.printValue(sliceType.Elem, elem, verb, depth+1, unexported)
+		}
+		p.buf.WriteByte('}')
+		return
+	}
+
+	p.buf.WriteByte('[')
+	for i, elem := range value {
+		if i > 0 {
+			p.buf.WriteByte(' ')
+		}
+		p.printValue(sliceType.Elem, elem, verb, depth+1, unexported)
+	}
+	p.buf.WriteByte(']')
+}
+
+// sortedKeys sorts the keys of the map the way the fmt package does, so the maps are printed the same every time.
+func sortedKeys(Type Type, value map[any]any) []any {
+	keys := make([]any, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return compareKeys(Type, keys[i], keys[j]) < 0
+	})
+
+	return keys
+}
+
+func compareKeys(Type Type, a, b any) int {
+	switch Type := Type.Underlying().(type) {
+	case *BasicType:
+		switch a := a.(type) {
+		case int:
+			return cmp.Compare(a, b.(int))
+		case int32:
+			return cmp.Compare(a, b.(int32))
+		case uint8:
+			return cmp.Compare(a, b.(uint8))
+		case float64:
+			return cmp.Compare(a, b.(float64))
+		case complex128:
+			if c := cmp.Compare(real(a), real(b.(complex128))); c != 0 {
+				return c
+			}
+			return cmp.Compare(imag(a), imag(b.(complex128)))
+		case string:
+			return cmp.Compare(a, b.(string))
+		case bool:
+			switch {
+			case a == b.(bool):
+				return 0
+			case a:
+				return 1
+			}
+			return -1
+		}
+	case *PointerType:
+		return cmp.Compare(addressOf(a), addressOf(b))
+	case *StructType:
+		for i, field := range Type.Fields {
+			if c := compareKeys(field.Type, *a.(*StructValue).fields[i], *b.(*StructValue).fields[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case *ArrayType:
+		for i, elem := range a.(*ArrayValue).elems {
+			if c := compareKeys(Type.Elem, elem, b.(*ArrayValue).elems[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case *InterfaceType:
+		// nil goes first, then the values are ordered by their types
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		case b == nil:
+			return 1
+		}
+		a, b := a.(InterfaceValue), b.(InterfaceValue)
+		if !Identical(a.Type, b.Type) {
+			return cmp.Compare(reflectString(a.Type), reflectString(b.Type))
+		}
+		return compareKeys(a.Type, a.Value, b.Value)
+	}
+
+	return 0
+}
+
+// reflectString is the name of the type the way the reflect package of Go prints it.
+func reflectString(t Type) string {
+	switch t := t.(type) {
+	case *NamedType:
+		if t.pkg == nil {
+			return t.name
+		}
+		if t.template != nil {
+			typeArgs := make([]string, len(t.typeArgs))
+			for i, typeArg := range t.typeArgs {
+				typeArgs[i] = reflectString(typeArg)
+			}
+			return t.pkg.Name + "." + t.template.name + "[" + strings.Join(typeArgs, ",") + "]"
+		}
+		return t.pkg.Name + "." + t.name
+	case *BasicType:
+		return DefaultType(t).String()
+	case *SliceType:
+		return "[]" + reflectString(t.Elem)
+	case *ArrayType:
+		return "[" + strconv.Itoa(t.Len) + "]" + reflectString(t.Elem)
+	case *MapType:
+		return "map[" + reflectString(t.Key) + "]" + reflectString(t.Elem)
+	case *PointerType:
+		return "*" + reflectString(t.Elem)
+	case *ChannelType:
+		return strings.TrimSuffix(t.String(), t.Elem.String()) + reflectString(t.Elem)
+	case *StructType:
+		if len(t.Fields) == 0 {
+			return "struct {}"
+		}
+		fields := make([]string, len(t.Fields))
+		for i, field := range t.Fields {
+			fields[i] = field.Name + " " + reflectString(field.Type)
+			if field.Tag != "" {
+				fields[i] += " " + strconv.Quote(field.Tag)
+			}
+		}
+		return "struct { " + strings.Join(fields, "; ") + " }"
+	case *InterfaceType:
+		if len(t.Methods) == 0 {
+			return "interface {}"
+		}
+		methods := make([]string, len(t.Methods))
+		for i, method := range t.Methods {
+			methods[i] = method.Name + strings.TrimPrefix(reflectString(method.Signature), "func")
+		}
+		sort.Strings(methods)
+		return "interface { " + strings.Join(methods, "; ") + " }"
+	case *FunctionType:
+		params := make([]string, len(t.Params))
+		for i, param := range t.Params {
+			params[i] = reflectString(param)
+		}
+		if t.Variadic {
+			params[len(params)-1] = "..." + reflectString(t.Params[len(t.Params)-1].(*SliceType).Elem)
+		}
+		res := "func(" + strings.Join(params, ", ") + ")"
+		results := Results(t.Result)
+		switch len(results) {
+		case 0:
+		case 1:
+			res += " " + reflectString(results[0])
+		default:
+			types := make([]string, len(results))
+			for i, result := range results {
+				types[i] = reflectString(result)
+			}
+			res += " (" + strings.Join(types, ", ") + ")"
+		}
+		return res
+	}
+
+	return t.String()
+}
+
+// Sprint formats the operands of the print functions, they are the values of the type any.
+func (prog *Program) Sprint(args []any) string {
+	p := newPrinter(prog)
+	p.doPrint(args)
+	return p.buf.String()
+}
+
+func (prog *Program) Sprintln(args []any) string {
+	p := newPrinter(prog)
+	p.doPrintln(args)
+	return p.buf.String()
+}
+
+func (prog *Program) Sprintf(format string, args []any) string {
+	p := newPrinter(prog)
+	p.doPrintf(format, args)
+	return p.buf.String()
+}
+
+// Errorf formats the message of the error and gives the indexes of the operands wrapped with %w,
+// the indexes are sorted if the operands are accessed out of the order.
+func (prog *Program) Errorf(format string, args []any) (string, []int) {
+	p := newPrinter(prog)
+	p.wrapErrs = true
+	p.doPrintf(format, args)
+
+	if p.reordered {
+		sort.Ints(p.wrappedErrs)
+	}
+
+	return p.buf.String(), p.wrappedErrs
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
-	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/karetskiiVO/GOInterpreter/goshim"
 )
 
 type Function interface {
@@ -22,37 +25,124 @@ func (BreakError) Error() string {
 	panic("BreakError must be handled it is not error")
 }
 
+// GotoError unwinds the blocks up to the one with the label.
+type GotoError struct {
+	label string
+}
+
+func (GotoError) Error() string {
+	panic("GotoError must be handled it is not error")
+}
+
+// FatalError stops the program at once, the deferred calls don't run, like the fatal errors of the Go runtime.
+type FatalError struct {
+	msg string
+}
+
+func (e FatalError) Error() string {
+	return e.msg
+}
+
+// TailCallError starts the function again with the arguments of its self tail call.
+type TailCallError struct {
+	args []any
+}
+
+func (TailCallError) Error() string {
+	panic("TailCallError must be handled it is not error")
+}
+
+// PanicError is the panic of the script, recover gives the value the panic was called with.
+type PanicError struct {
+	value any
+	msg   string
+}
+
+func (e PanicError) Error() string {
+	return e.msg
+}
+
+// StackOverflowError is the runtime panic of the call deeper than the program allows.
+type StackOverflowError struct {
+	// the functions from main to the one which has not been called
+	Chain []string
+}
+
+func (e StackOverflowError) Error() string {
+	return "runtime error: stack overflow"
+}
+
+// Trace is the call chain of the overflow, the innermost call first like in the traces of Go.
+func (e StackOverflowError) Trace() string {
+	return goshim.Trace(e.Chain)
+}
+
+// TypedFunction is the function the type checker knows the signature of.
+type TypedFunction interface {
+	Function
+	Signature() *FunctionType
+}
+
+// GenericFunction is the function implemented by the interpreter,
+// the signature describes its arguments and results for the type checker.
 type GenericFunction struct {
-	name    string
-	handler func(args ...any) error
+	name      string
+	signature *FunctionType
+	handler   func(args ...any) ([]any, error)
 }
 
 func (gf GenericFunction) Call(args ...any) ([]any, error) {
-	return []any{}, gf.handler(args...)
+	return gf.handler(args...)
 }
 
 func (gf GenericFunction) Name() string {
 	return gf.name
 }
 
+func (gf GenericFunction) Signature() *FunctionType {
+	return gf.signature
+}
+
+// BoundMethod is a method value: the method together with the receiver it was selected from.
+type BoundMethod struct {
+	function Function
+	receiver any
+}
+
+func (bm BoundMethod) Call(args ...any) ([]any, error) {
+	return bm.function.Call(append([]any{bm.receiver}, args...)...)
+}
+
+func (bm BoundMethod) Name() string {
+	return bm.function.Name()
+}
+
 type InputVariable struct {
 	Name string
-	Type reflect.Type
+	Type Type
 }
 
 type IntrpretatedFunction struct {
 	inputVariables []InputVariable
-	returnType     reflect.Type
+	// the results are variables too, the unnamed results have empty names
+	outputVariables []InputVariable
+	// the last input variable is the slice of the rest arguments
+	variadic bool
 
 	name         string
 	instructions []Instruction
+	// the instructions lowered for the execution
+	code *Code
+	// the bytecode of the function, nil if the function runs the code
+	vm *vmFunction
 }
 
 func NewIntrpretatedFunction(name string) *IntrpretatedFunction {
 	return &IntrpretatedFunction{
-		inputVariables: make([]InputVariable, 0),
-		name:           name,
-		instructions:   make([]Instruction, 0),
+		inputVariables:  make([]InputVariable, 0),
+		outputVariables: make([]InputVariable, 0),
+		name:            name,
+		instructions:    make([]Instruction, 0),
 	}
 }
 
@@ -60,70 +150,288 @@ func (f *IntrpretatedFunction) Name() string {
 	return f.name
 }
 
+// Signature is the type of the function, the receiver of a method is its first parameter.
+func (f *IntrpretatedFunction) Signature() *FunctionType {
+	res := &FunctionType{
+		Params:   make([]Type, len(f.inputVariables)),
+		Variadic: f.variadic,
+	}
+
+	for i, inputVariable := range f.inputVariables {
+		res.Params[i] = inputVariable.Type
+	}
+
+	results := make([]Type, len(f.outputVariables))
+	for i, outputVariable := range f.outputVariables {
+		results[i] = outputVariable.Type
+	}
+	res.Result = NewResult(results)
+
+	return res
+}
+
+// resultName is the name of the variable of the result which can't be shadowed,
+// the named result is accessible by its own name too.
+func resultName(i int) string {
+	if i < len(resultNames) {
+		return resultNames[i]
+	}
+
+	return "@result" + strconv.Itoa(i)
+}
+
+// resultNames are the names of the results of the most functions, they are looked up on every return.
+var resultNames = func() []string {
+	res := make([]string, 8)
+	for i := range res {
+		res[i] = "@result" + strconv.Itoa(i)
+	}
+	return res
+}()
+
 func (f *IntrpretatedFunction) Call(args ...any) ([]any, error) {
-	if len(args) != len(f.inputVariables) {
-		return nil, fmt.Errorf(
-			"missmatch betweent count of arguments in function %v, given: %v expected: %v",
-			f.name,
-			len(args),
-			len(f.inputVariables),
-		)
+	return f.call(nil, args)
+}
+
+// call runs the function, the function literal sees the variables it has captured.
+func (f *IntrpretatedFunction) call(captured map[string]*any, args []any) ([]any, error) {
+	if f.vm != nil {
+		return f.vm.run(captured, args)
 	}
-	variables := make(map[string]any)
 
-	if f.returnType != nil {
-		variables["@result"] = nil
+	prog := f.code.program
+	depth := len(prog.calls)
+	if prog.MaxCallDepth > 0 && depth >= prog.MaxCallDepth {
+		return nil, StackOverflowError{Chain: append(slices.Clone(prog.calls), f.name)}
 	}
+	prog.calls = append(prog.calls, f.name)
+	defer func() {
+		prog.calls = prog.calls[:depth]
+	}()
 
-	for i, inputVariable := range f.inputVariables {
-		if reflect.TypeOf(args[i]) != f.inputVariables[i].Type {
-			return nil, fmt.Errorf(
-				"missmatch type for argument %v, given: %v expected: %v",
-				inputVariable.Name,
-				reflect.TypeOf(args[i]).Name(),
-				inputVariable.Type.Name(),
-			)
+	variables, defers, err := f.frame(captured, args)
+	if err != nil {
+		return nil, err
+	}
+	returned, err := f.code.Run(variables)
+	for {
+		tail, ok := err.(TailCallError)
+		if !ok {
+			break
+		}
+
+		variables, defers, err = f.frame(captured, tail.args)
+		if err != nil {
+			return nil, err
 		}
+		returned, err = f.code.Run(variables)
+	}
+
+	// the deferred calls run in the reverse order after the results are set, so they may change the named results,
+	// they may recover the panic of the function
+	recovered, err := prog.deferred((*defers).([]DeferredCall), depth, err)
+	if recovered {
+		// the recovered function returns the results it has
+		returned = true
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		variables[inputVariable.Name] = CloneAny(args[i])
+	if len(f.outputVariables) != 0 && !returned {
+		return nil, fmt.Errorf("missing return in function %v", f.name)
 	}
 
-	for _, instruction := range f.instructions {
-		err := instruction.Execute(variables)
+	res := make([]any, len(f.outputVariables))
+	for i := range f.outputVariables {
+		res[i] = *variables[resultName(i)]
+	}
 
-		if err == nil {
+	return res, nil
+}
+
+// deferred runs the deferred calls of the function returning with the error, the panic of the deferred call
+// replaces the one of the function. The depth is the count of the calls below the function,
+// the result reports that the deferred call has recovered the panic.
+func (prog *Program) deferred(calls []DeferredCall, depth int, err error) (bool, error) {
+	if fatal(err) {
+		return false, err
+	}
+
+	var panicking *panicState
+	if err != nil && len(calls) != 0 {
+		panicking = &panicState{err: err, depth: depth + 2}
+		prog.panics = append(prog.panics, panicking)
+		defer func() {
+			prog.panics = prog.panics[:len(prog.panics)-1]
+		}()
+	}
+	for i := len(calls) - 1; i >= 0; i-- {
+		_, deferredErr := calls[i].function.Call(calls[i].args...)
+		if fatal(deferredErr) {
+			return false, deferredErr
+		}
+		if deferredErr == nil {
 			continue
 		}
-		if _, ok := err.(ReturnError); ok {
-			break
+
+		err = deferredErr
+		if panicking == nil {
+			panicking = &panicState{depth: depth + 2}
+			prog.panics = append(prog.panics, panicking)
+			defer func() {
+				prog.panics = prog.panics[:len(prog.panics)-1]
+			}()
 		}
+		panicking.err, panicking.recovered = err, false
+	}
+	if panicking != nil && panicking.recovered {
+		return true, nil
+	}
 
-		return nil, err
+	return false, err
+}
+
+// frame makes the variables of the call: the captured ones, the results, the deferred calls and the parameters.
+func (f *IntrpretatedFunction) frame(captured map[string]*any, args []any) (map[string]*any, *any, error) {
+	if err := f.checkArguments(len(args)); err != nil {
+		return nil, nil, err
+	}
+	variables := make(map[string]*any)
+	for name, cell := range captured {
+		variables[name] = cell
 	}
 
-	var res []any
-	if f.returnType != nil {
-		if reflect.TypeOf(variables["@result"]) != f.returnType {
-			return nil, fmt.Errorf(
-				"wrong return type expected: %v, has: %v",
-				f.returnType,
-				reflect.TypeOf(variables["@result"]))
+	for i, outputVariable := range f.outputVariables {
+		result := NewVariable(outputVariable.Type)
+		variables[resultName(i)] = &result
+		if outputVariable.Name != "" {
+			variables[outputVariable.Name] = &result
 		}
+	}
 
-		res = append(res, variables["@result"])
+	var defers any = []DeferredCall(nil)
+	variables["@defers"] = &defers
+
+	for i, inputVariable := range f.inputVariables {
+		value := CloneAny(args[i])
+		variables[inputVariable.Name] = &value
 	}
 
-	return res, nil
+	return variables, &defers, nil
+}
+
+func (f *IntrpretatedFunction) checkArguments(count int) error {
+	if count != len(f.inputVariables) {
+		return fmt.Errorf(
+			"missmatch betweent count of arguments in function %v, given: %v expected: %v",
+			f.name,
+			count,
+			len(f.inputVariables),
+		)
+	}
+
+	return nil
+}
+
+// panicState is the panic the deferred calls of the function run with.
+type panicState struct {
+	err error
+	// the count of the calls while the deferred function runs, recover works only when it is called by that function
+	depth     int
+	recovered bool
+}
+
+// recover stops the panic when the deferred function calls it, the value is the one the panic was called with.
+func (prog *Program) recover() any {
+	if len(prog.panics) == 0 {
+		return nil
+	}
+	panicking := prog.panics[len(prog.panics)-1]
+	if panicking.recovered || panicking.depth != len(prog.calls) {
+		return nil
+	}
+
+	panicking.recovered = true
+	if err, ok := panicking.err.(PanicError); ok {
+		return err.value
+	}
+	if err, ok := panicking.err.(valueError); ok {
+		return err.value
+	}
+	// the runtime errors are recovered as the error values, the program without errors has only the message
+	if prog.errorStringType == nil {
+		return panicking.err.Error()
+	}
+	return prog.NewError(panicking.err.Error())
 }
 
 func (f *IntrpretatedFunction) RegisterArgument(argument InputVariable) error {
-	for _, inputVariable := range f.inputVariables {
-		if inputVariable.Name == argument.Name {
-			return fmt.Errorf("variable %v double declared", argument.Name)
-		}
+	err := f.checkDuplicate(argument)
+	if err != nil {
+		return err
 	}
 
 	f.inputVariables = append(f.inputVariables, argument)
 
 	return nil
 }
+
+func (f *IntrpretatedFunction) RegisterResult(result InputVariable) error {
+	err := f.checkDuplicate(result)
+	if err != nil {
+		return err
+	}
+
+	f.outputVariables = append(f.outputVariables, result)
+
+	return nil
+}
+
+func (f *IntrpretatedFunction) checkDuplicate(variable InputVariable) error {
+	if variable.Name == "" || variable.Name == "_" {
+		return nil
+	}
+
+	for _, inputVariable := range append(slices.Clone(f.inputVariables), f.outputVariables...) {
+		if inputVariable.Name == variable.Name {
+			return fmt.Errorf("variable %v double declared", variable.Name)
+		}
+	}
+
+	return nil
+}
+
+// HostFunction is the function value made by the interpreter while the program runs.
+type HostFunction struct {
+	name string
+	call func(args ...any) ([]any, error)
+}
+
+func (hf HostFunction) Call(args ...any) ([]any, error) {
+	return hf.call(args...)
+}
+
+func (hf HostFunction) Name() string {
+	return hf.name
+}
+
+// DeferredCall is the call made when the function returns, its arguments are evaluated by the defer statement.
+type DeferredCall struct {
+	function Function
+	args     []any
+}
+
+// Closure is the function literal together with the variables it has captured.
+type Closure struct {
+	function  *IntrpretatedFunction
+	variables map[string]*any
+}
+
+func (c Closure) Call(args ...any) ([]any, error) {
+	return c.function.call(c.variables, args)
+}
+
+func (c Closure) Name() string {
+	return c.function.Name()
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antlr4-go/antlr/v4"
+	"github.com/karetskiiVO/GOInterpreter/parser"
+)
+
+// Generic declarations are not compiled by themselves: every instantiation gets its own
+// copy of the declaration with the type parameters bound to the type arguments.
+// So the bodies of the generic functions are checked only for the used type arguments.
+
+type TypeTemplate struct {
+	name     string
+	ctx      parser.ITypeDefinitionContext
+	resolver *TypeResolver
+
+	typeParams  []string
+	constraints []parser.ITypeConstraintContext
+
+	instances map[string]*NamedType
+	methods   map[string]*FunctionTemplate
+}
+
+func NewTypeTemplate(resolver *TypeResolver, ctx parser.ITypeDefinitionContext) *TypeTemplate {
+	res := &TypeTemplate{
+		name:      ctx.NAME().GetText(),
+		ctx:       ctx,
+		resolver:  resolver,
+		instances: map[string]*NamedType{},
+		methods:   map[string]*FunctionTemplate{},
+	}
+	res.typeParams, res.constraints = typeParameters(ctx.TypeParameters())
+
+	return res
+}
+
+func (t *TypeTemplate) String() string {
+	return typeParametersString(t.name, t.typeParams, t.constraints)
+}
+
+func (t *TypeTemplate) Instantiate(typeArgs []Type) (*NamedType, error) {
+	err := checkTypeArgumentsCount("type "+t.name, t.typeParams, typeArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	name := t.name + typeArgumentsString(typeArgs)
+	if res, ok := t.instances[name]; ok {
+		return res, nil
+	}
+
+	resolver := t.resolver.Bind(t.typeParams, typeArgs)
+	err = checkConstraints(resolver, t.constraints, typeArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := NewNamedType(name, t.resolver.pkg)
+	res.template = t
+	res.typeArgs = typeArgs
+
+	// the instance is registered before its definition is resolved, so it may refer to itself
+	if !hasTypeParams(typeArgs) {
+		t.instances[name] = res
+	}
+
+	res.rhs, err = resolver.resolve(t.ctx.Typename())
+	if err != nil {
+		delete(t.instances, name)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// FunctionTemplate is a generic function or a method of a generic type.
+type FunctionTemplate struct {
+	name     string
+	ctx      parser.IFunctionDefinitionContext
+	resolver *TypeResolver
+
+	typeParams  []string
+	constraints []parser.ITypeConstraintContext
+
+	// the generic type of a method, the constraints are checked by the type
+	typeTemplate *TypeTemplate
+	// the generic function of a standard package has no source, its instances are host functions
+	host *hostTemplate
+
+	instances map[string]int
+}
+
+type hostTemplate struct {
+	program     *Program
+	pkg         *Package
+	constraints []Type
+	// instantiate gives the signature and the handler of the instance,
+	// the type arguments are the type parameters when the signature is used to infer them
+	instantiate func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error))
+}
+
+func NewFunctionTemplate(resolver *TypeResolver, ctx parser.IFunctionDefinitionContext) *FunctionTemplate {
+	res := &FunctionTemplate{
+		name:      ctx.NAME().GetText(),
+		ctx:       ctx,
+		resolver:  resolver,
+		instances: map[string]int{},
+	}
+	res.typeParams, res.constraints = typeParameters(ctx.TypeParameters())
+
+	return res
+}
+
+func NewMethodTemplate(resolver *TypeResolver, ctx parser.IFunctionDefinitionContext, typeTemplate *TypeTemplate, typeParams []string) *FunctionTemplate {
+	return &FunctionTemplate{
+		name:         ctx.NAME().GetText(),
+		ctx:          ctx,
+		resolver:     resolver,
+		typeParams:   typeParams,
+		typeTemplate: typeTemplate,
+		instances:    map[string]int{},
+	}
+}
+
+func (t *FunctionTemplate) String() string {
+	if t.host != nil {
+		params := make([]string, len(t.typeParams))
+		for i := range t.typeParams {
+			params[i] = t.typeParams[i] + " " + t.host.constraints[i].String()
+		}
+		return t.name + "[" + strings.Join(params, ", ") + "]"
+	}
+
+	return typeParametersString(t.name, t.typeParams, t.constraints)
+}
+
+// Signature is the type of the function with the type parameters in it, it is used to infer the type arguments.
+func (t *FunctionTemplate) Signature() (*FunctionType, []*TypeParam, error) {
+	typeParams := make([]*TypeParam, len(t.typeParams))
+	typeArgs := make([]Type, len(t.typeParams))
+	for i, name := range t.typeParams {
+		typeParams[i] = &TypeParam{name: name, constraint: AnyType}
+		typeArgs[i] = typeParams[i]
+	}
+
+	if t.host != nil {
+		for i, constraint := range t.host.constraints {
+			typeParams[i].constraint = constraint
+		}
+		signature, _ := t.host.instantiate(typeArgs)
+		return signature, typeParams, nil
+	}
+
+	resolver := t.resolver.Bind(t.typeParams, typeArgs)
+
+	for i, constraint := range t.constraints {
+		var err error
+		typeParams[i].constraint, err = resolver.ResolveConstraint(constraint)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	function, errs := declareFunction(resolver, t.name, t.ctx)
+	if len(errs) != 0 {
+		return nil, nil, errs[0]
+	}
+
+	return function.Signature(), typeParams, nil
+}
+
+// Instantiate declares the instance of the function, its body is compiled later by CompileInstances.
+func (t *FunctionTemplate) Instantiate(typeArgs []Type) (int, error) {
+	name := t.name + typeArgumentsString(typeArgs)
+	if t.typeTemplate != nil {
+		name = t.typeTemplate.name + typeArgumentsString(typeArgs) + "." + t.name
+	} else {
+		err := checkTypeArgumentsCount(t.name, t.typeParams, typeArgs)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if id, ok := t.instances[name]; ok {
+		return id, nil
+	}
+
+	if t.host != nil {
+		return t.instantiateHost(name, typeArgs)
+	}
+
+	resolver := t.resolver.Bind(t.typeParams, typeArgs)
+	err := checkConstraints(resolver, t.constraints, typeArgs)
+	if err != nil {
+		return 0, err
+	}
+
+	function, errs := declareFunction(resolver, resolver.pkg.QualifiedName(name), t.ctx)
+	if len(errs) != 0 {
+		return 0, errs[0]
+	}
+
+	err = resolver.program.RegisterFunction(function)
+	if err != nil {
+		return 0, err
+	}
+	id := resolver.program.functionID[function.Name()]
+	t.instances[name] = id
+
+	resolver.program.instances = append(resolver.program.instances, &instance{
+		function: function,
+		ctx:      t.ctx,
+		resolver: resolver,
+	})
+
+	return id, nil
+}
+
+// instantiateHost registers the host function of the instance of the standard generic function.
+func (t *FunctionTemplate) instantiateHost(name string, typeArgs []Type) (int, error) {
+	h := t.host
+	for i, constraint := range h.constraints {
+		err := Satisfies(typeArgs[i], constraint, h.program)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	signature, handler := h.instantiate(typeArgs)
+	function := GenericFunction{
+		name:      h.pkg.QualifiedName(name),
+		signature: signature,
+		handler:   handler,
+	}
+	err := h.program.RegisterFunction(function)
+	if err != nil {
+		return 0, err
+	}
+
+	id := h.program.functionID[function.Name()]
+	t.instances[name] = id
+	h.program.hostInstances[function.Name()] = hostInstance{template: h.pkg.QualifiedName(t.name), typeArgs: typeArgs}
+	return id, nil
+}
+
+// hostInstance is how the instance of the standard generic function is made.
+type hostInstance struct {
+	template string
+	typeArgs []Type
+}
+
+type instance struct {
+	function *IntrpretatedFunction
+	ctx      parser.IFunctionDefinitionContext
+	resolver *TypeResolver
+}
+
+// CompileInstances compiles the bodies of the instantiated functions,
+// the instances may instantiate other generic functions while they are compiled.
+func (prog *Program) CompileInstances() []error {
+	errors := make([]error, 0)
+
+	for len(prog.instances) != 0 {
+		instance := prog.instances[0]
+		prog.instances = prog.instances[1:]
+
+		compileListner := NewGoCompilerListener(prog, instance.resolver.pkg, instance.resolver.file)
+		compileListner.resolver = instance.resolver
+		compileListner.function = instance.function
+		antlr.ParseTreeWalkerDefault.Walk(compileListner, instance.ctx)
+
+		for _, err := range compileListner.Errors {
+			errors = append(errors, fmt.Errorf("in instance %v: %w", instance.function.Name(), err))
+		}
+	}
+
+	return errors
+}
+
+// LookupMethod looks for the method of the named type, the methods of the generic types are instantiated on demand.
+func (prog *Program) LookupMethod(namedType *NamedType, name string) (int, bool, error) {
+	// the predeclared types have no declared methods
+	if namedType.pkg == nil {
+		return 0, false, nil
+	}
+
+	if id, ok := prog.functionID[namedType.MethodName(name)]; ok {
+		return id, true, nil
+	}
+
+	if namedType.template == nil {
+		return 0, false, nil
+	}
+	method, ok := namedType.template.methods[name]
+	if !ok {
+		return 0, false, nil
+	}
+
+	id, err := method.Instantiate(namedType.typeArgs)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+// MethodSignature is the type of the method without the receiver. The method sets follow Go:
+// the methods with pointer receivers belong only to the pointer type.
+func (prog *Program) MethodSignature(t Type, name string) (*FunctionType, bool) {
+	if iface, ok := t.Underlying().(*InterfaceType); ok {
+		for _, method := range iface.Methods {
+			if method.Name == name {
+				return method.Signature, true
+			}
+		}
+		return nil, false
+	}
+
+	pointer := false
+	if pointerType, ok := t.(*PointerType); ok {
+		t = pointerType.Elem
+		pointer = true
+	}
+
+	namedType, ok := t.(*NamedType)
+	if !ok {
+		return nil, false
+	}
+
+	id, ok, err := prog.LookupMethod(namedType, name)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	function, ok := prog.functions[id].(TypedFunction)
+	if !ok {
+		return nil, false
+	}
+
+	signature := function.Signature()
+	if _, ok := signature.Params[0].(*PointerType); ok && !pointer {
+		return nil, false
+	}
+
+	return &FunctionType{Params: signature.Params[1:], Result: signature.Result}, true
+}
+
+// DynamicMethod finds the method of the dynamic type of the interface value and binds it to the value.
+// The methods with value receivers of the pointer type dereference the pointer when they are called.
+func (prog *Program) DynamicMethod(t Type, value any, name string, signature *FunctionType) (Function, bool) {
+	actual, ok := prog.MethodSignature(t, name)
+	if !ok || !Identical(actual, signature) {
+		return nil, false
+	}
+
+	base, pointer := t, false
+	if pointerType, ok := t.(*PointerType); ok {
+		base, pointer = pointerType.Elem, true
+	}
+	namedType, ok := base.(*NamedType)
+	if !ok {
+		return nil, false
+	}
+
+	id, ok, err := prog.LookupMethod(namedType, name)
+	if err != nil || !ok {
+		return nil, false
+	}
+	function := prog.functions[id]
+
+	if _, wantsPointer := function.(TypedFunction).Signature().Params[0].(*PointerType); pointer && !wantsPointer {
+		return HostFunction{
+			name: function.Name(),
+			call: func(args ...any) ([]any, error) {
+				cell, _ := value.(*any)
+				if cell == nil {
+					return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+				}
+				return function.Call(append([]any{CloneAny(*cell)}, args...)...)
+			},
+		}, true
+	}
+
+	return BoundMethod{function: function, receiver: value}, true
+}
+
+// IsGeneric reports whether the function is compiled only when it is instantiated.
+func IsGeneric(ctx parser.IFunctionDefinitionContext) bool {
+	if ctx.TypeParameters() != nil {
+		return true
+	}
+	if ctx.Receiver() == nil {
+		return false
+	}
+
+	base, _ := receiverTypename(ctx.Receiver().Typename())
+	return base.TypeArguments() != nil
+}
+
+// receiverTypename strips the pointer and the parentheses from the receiver type.
+func receiverTypename(ctx parser.ITypenameContext) (parser.ITypenameContext, bool) {
+	pointer := false
+	for {
+		switch {
+		case ctx.PointerType() != nil && !pointer:
+			pointer = true
+			ctx = ctx.PointerType().Typename()
+		case ctx.Typename() != nil:
+			ctx = ctx.Typename()
+		default:
+			return ctx, pointer
+		}
+	}
+}
+
+func typeParameters(ctx parser.ITypeParametersContext) ([]string, []parser.ITypeConstraintContext) {
+	names := make([]string, 0)
+	constraints := make([]parser.ITypeConstraintContext, 0)
+	if ctx == nil {
+		return names, constraints
+	}
+
+	for _, declaration := range ctx.AllTypeParameterDeclaration() {
+		for _, name := range declaration.AllNAME() {
+			names = append(names, name.GetText())
+			constraints = append(constraints, declaration.TypeConstraint())
+		}
+	}
+
+	return names, constraints
+}
+
+func typeParametersString(name string, typeParams []string, constraints []parser.ITypeConstraintContext) string {
+	params := make([]string, len(typeParams))
+	for i := range typeParams {
+		params[i] = typeParams[i]
+		if i < len(constraints) {
+			params[i] += " " + constraints[i].GetText()
+		}
+	}
+
+	return name + "[" + strings.Join(params, ", ") + "]"
+}
+
+func typeArgumentsString(typeArgs []Type) string {
+	args := make([]string, len(typeArgs))
+	for i, typeArg := range typeArgs {
+		args[i] = typeArg.String()
+	}
+
+	return "[" + strings.Join(args, ",") + "]"
+}
+
+func checkTypeArgumentsCount(name string, typeParams []string, typeArgs []Type) error {
+	if len(typeArgs) < len(typeParams) {
+		return fmt.Errorf("not enough type arguments for %v: have %v, want %v", name, len(typeArgs), len(typeParams))
+	}
+	if len(typeArgs) > len(typeParams) {
+		return fmt.Errorf("too many type arguments for %v: have %v, want %v", name, len(typeArgs), len(typeParams))
+	}
+
+	return nil
+}
+
+func checkConstraints(resolver *TypeResolver, constraints []parser.ITypeConstraintContext, typeArgs []Type) error {
+	for i, constraint := range constraints {
+		constraintType, err := resolver.ResolveConstraint(constraint)
+		if err != nil {
+			return err
+		}
+
+		err = Satisfies(typeArgs[i], constraintType, resolver.program)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasTypeParams(types []Type) bool {
+	for _, t := range types {
+		switch t := t.(type) {
+		case *TypeParam:
+			return true
+		case *SliceType:
+			if hasTypeParams([]Type{t.Elem}) {
+				return true
+			}
+		case *ArrayType:
+			if hasTypeParams([]Type{t.Elem}) {
+				return true
+			}
+		case *PointerType:
+			if hasTypeParams([]Type{t.Elem}) {
+				return true
+			}
+		case *ChannelType:
+			if hasTypeParams([]Type{t.Elem}) {
+				return true
+			}
+		case *MapType:
+			if hasTypeParams([]Type{t.Key, t.Elem}) {
+				return true
+			}
+		case *FunctionType:
+			if hasTypeParams(t.Params) || hasTypeParams(Results(t.Result)) {
+				return true
+			}
+		case *NamedType:
+			if hasTypeParams(t.typeArgs) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// inferTypeArgs unifies the type of the parameter with the type of the argument.
+func inferTypeArgs(param, arg Type, bindings map[*TypeParam]Type) bool {
+	if typeParam, ok := param.(*TypeParam); ok {
+		if bound, ok := bindings[typeParam]; ok {
+			return Identical(bound, arg)
+		}
+
+		bindings[typeParam] = arg
+		return true
+	}
+
+	if namedParam, ok := param.(*NamedType); ok {
+		namedArg, ok := arg.(*NamedType)
+		if !ok || namedParam.template == nil {
+			return true
+		}
+		if namedParam.template != namedArg.template {
+			return false
+		}
+
+		for i := range namedParam.typeArgs {
+			if !inferTypeArgs(namedParam.typeArgs[i], namedArg.typeArgs[i], bindings) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// the named types are inferred by their underlying types
+	arg = arg.Underlying()
+
+	switch param := param.(type) {
+	case *SliceType:
+		arg, ok := arg.(*SliceType)
+		return ok && inferTypeArgs(param.Elem, arg.Elem, bindings)
+	case *ArrayType:
+		arg, ok := arg.(*ArrayType)
+		return ok && param.Len == arg.Len && inferTypeArgs(param.Elem, arg.Elem, bindings)
+	case *PointerType:
+		arg, ok := arg.(*PointerType)
+		return ok && inferTypeArgs(param.Elem, arg.Elem, bindings)
+	case *ChannelType:
+		// the bidirectional channel is passed as the directional one
+		arg, ok := arg.(*ChannelType)
+		return ok && (param.Dir == arg.Dir || arg.Dir == SendRecv) && inferTypeArgs(param.Elem, arg.Elem, bindings)
+	case *MapType:
+		arg, ok := arg.(*MapType)
+		return ok && inferTypeArgs(param.Key, arg.Key, bindings) && inferTypeArgs(param.Elem, arg.Elem, bindings)
+	case *FunctionType:
+		arg, ok := arg.(*FunctionType)
+		if !ok || len(param.Params) != len(arg.Params) || len(Results(param.Result)) != len(Results(arg.Result)) {
+			return false
+		}
+		for i := range param.Params {
+			if !inferTypeArgs(param.Params[i], arg.Params[i], bindings) {
+				return false
+			}
+		}
+		for i, result := range Results(param.Result) {
+			if !inferTypeArgs(result, Results(arg.Result)[i], bindings) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return true
+}
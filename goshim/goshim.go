@@ -0,0 +1,358 @@
+// Package goshim is the runtime of the scripts built into the native programs:
+// the calls the interpreter treats in its own way are made through it,
+// so the built program behaves the way the interpreted script does.
+package goshim
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config is what the interpreter runs the script with, it is fixed when the script is built.
+type Config struct {
+	// the files are accessed only under the root directory, the relative root is found from the directory
+	// of the program when it starts, so the program may be moved with its files, the empty root is the working directory
+	Root string
+	// the clock starts at 2009-11-10 23:00:00 UTC and moves only by sleeping, the timers still fire on the real clock
+	VirtualTime bool
+	// the depth of the calls of the script functions beyond which the stack overflows, 0 is no limit
+	MaxCallDepth int
+}
+
+var (
+	config     Config
+	virtualNow = time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	// the goroutines move the virtual clock one at a time
+	clock sync.Mutex
+)
+
+// Run runs the main function of the script, the panic is reported the way the interpreter reports it.
+func Run(c Config, main func()) {
+	config = c
+	if config.VirtualTime {
+		time.Local = time.UTC
+	}
+	root, err := programRoot(config.Root)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	config.Root = root
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "panic: %v\n\ngoroutine 1 [running]\n", r)
+			if overflow, ok := r.(StackOverflowError); ok {
+				fmt.Fprint(os.Stderr, "\n", Trace(overflow.Chain))
+			}
+			os.Exit(2)
+		}
+	}()
+	main()
+}
+
+// Fatal stops the program at once with the fatal error, the deferred calls don't run.
+func Fatal(msg string) {
+	fmt.Fprintln(os.Stderr, "fatal error:", msg)
+	os.Exit(2)
+}
+
+// programRoot is the root the program runs with, the relative one is under the directory of the program.
+func programRoot(root string) (string, error) {
+	if root == "" || filepath.IsAbs(root) {
+		return root, nil
+	}
+
+	program, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	program, err = filepath.EvalSymlinks(program)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(program), root), nil
+}
+
+// callStack is the calls of the goroutine.
+type callStack struct {
+	// the functions being called, the innermost is the last
+	calls []string
+	// the frame the tail call of the function itself replaces, -1 if there is no such call
+	tailFrame int
+}
+
+var (
+	mainCalls = &callStack{tailFrame: -1}
+	// the script has started a goroutine, the calls are counted by the goroutines from then on
+	concurrent atomic.Bool
+	// the calls of the goroutines but the main one by the ids of the goroutines
+	goroutineCalls   = map[uint64]*callStack{}
+	goroutineCallsMu sync.Mutex
+	mainGoroutine    uint64
+)
+
+// Go is called right before the go statement of the script.
+func Go() {
+	if !concurrent.Load() {
+		mainGoroutine = goroutineID()
+		concurrent.Store(true)
+	}
+}
+
+// goroutineID is the id of the running goroutine, the trace of its stack starts with it.
+func goroutineID() uint64 {
+	var buf [64]byte
+	trace := strings.TrimPrefix(string(buf[:runtime.Stack(buf[:], false)]), "goroutine ")
+	id, _ := strconv.ParseUint(trace[:strings.IndexByte(trace, ' ')], 10, 64)
+	return id
+}
+
+// currentCalls is the calls of the running goroutine, the goroutine looks them up by its id
+// once the script has started the goroutines.
+func currentCalls() (*callStack, uint64) {
+	if !concurrent.Load() {
+		return mainCalls, 0
+	}
+	id := goroutineID()
+	if id == mainGoroutine {
+		return mainCalls, id
+	}
+
+	goroutineCallsMu.Lock()
+	defer goroutineCallsMu.Unlock()
+	stack, ok := goroutineCalls[id]
+	if !ok {
+		stack = &callStack{tailFrame: -1}
+		goroutineCalls[id] = stack
+	}
+	return stack, id
+}
+
+// Enter counts the call of the script function, the result is the frame of the call, which Leave leaves.
+// The calls deeper than the limit panic with StackOverflowError, which the script may recover.
+func Enter(name string) int {
+	stack, _ := currentCalls()
+	if stack.tailFrame >= 0 && stack.tailFrame == len(stack.calls)-1 {
+		stack.calls[stack.tailFrame], stack.tailFrame = name, -1
+		return len(stack.calls) - 1
+	}
+	stack.tailFrame = -1
+
+	if config.MaxCallDepth > 0 && len(stack.calls) >= config.MaxCallDepth {
+		panic(StackOverflowError{Chain: append(append([]string(nil), stack.calls...), name)})
+	}
+	stack.calls = append(stack.calls, name)
+	return len(stack.calls) - 1
+}
+
+// Leave leaves the frame of the call and the frames it has replaced,
+// the goroutine which leaves its first call is over.
+func Leave(frame int) {
+	stack, id := currentCalls()
+	stack.calls = stack.calls[:frame]
+	if frame == 0 && stack != mainCalls {
+		goroutineCallsMu.Lock()
+		delete(goroutineCalls, id)
+		goroutineCallsMu.Unlock()
+	}
+}
+
+// Tail marks the next call as the tail call of the function in the frame, it reuses the frame.
+// It wraps the last argument of the call, so it is evaluated after all the other ones.
+func Tail[T any](frame int, value T) T {
+	stack, _ := currentCalls()
+	stack.tailFrame = frame
+	return value
+}
+
+// StackOverflowError is the runtime panic of the call deeper than the program allows.
+type StackOverflowError struct {
+	// the functions from main to the one which has not been called
+	Chain []string
+}
+
+func (StackOverflowError) Error() string {
+	return "runtime error: stack overflow"
+}
+
+func (StackOverflowError) RuntimeError() {}
+
+// elidedFrames is the count of the calls shown at each end of the long call chain.
+const elidedFrames = 10
+
+// Trace is the call chain of the overflow, the innermost call first like in the traces of Go.
+// The interpreter writes its overflows with it too.
+func Trace(chain []string) string {
+	var res strings.Builder
+	for i := len(chain) - 1; i >= 0; i-- {
+		if i == len(chain)-1-elidedFrames && i >= elidedFrames {
+			fmt.Fprintf(&res, "...%v frames elided...\n", i-elidedFrames+1)
+			i = elidedFrames - 1
+		}
+		fmt.Fprintf(&res, "%v(...)\n", chain[i])
+	}
+
+	return res.String()
+}
+
+// Print is the builtin print, it writes to the standard output like the one of the interpreter.
+func Print(args ...any) {
+	fmt.Print(args...)
+}
+
+// Println is the builtin println, it writes to the standard output like the one of the interpreter.
+func Println(args ...any) {
+	fmt.Println(args...)
+}
+
+// Now is time.Now, the times of the scripts have no monotonic clock reading.
+func Now() time.Time {
+	if config.VirtualTime {
+		clock.Lock()
+		defer clock.Unlock()
+		return virtualNow
+	}
+
+	return time.Now().Round(0)
+}
+
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
+
+func Until(t time.Time) time.Duration {
+	return t.Sub(Now())
+}
+
+// Sleep is time.Sleep, the virtual clock jumps forward at once.
+// The goroutines of the built program don't wait for each other on the virtual clock, each sleep moves it on its own.
+func Sleep(d time.Duration) {
+	if !config.VirtualTime {
+		time.Sleep(d)
+		return
+	}
+
+	clock.Lock()
+	defer clock.Unlock()
+	if d > 0 {
+		virtualNow = virtualNow.Add(d)
+	}
+}
+
+func ReadFile(name string) ([]byte, error) {
+	path, err := resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	return data, ScriptPath(err, name)
+}
+
+func WriteFile(name string, data []byte, perm fs.FileMode) error {
+	path, err := resolve("open", name)
+	if err != nil {
+		return err
+	}
+
+	return ScriptPath(os.WriteFile(path, data, perm), name)
+}
+
+func Remove(name string) error {
+	path, err := resolve("remove", name)
+	if err != nil {
+		return err
+	}
+
+	return ScriptPath(os.Remove(path), name)
+}
+
+func Open(name string) (*os.File, error) {
+	path, err := resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	return file, ScriptPath(err, name)
+}
+
+// ErrPathEscapes is the error of the path out of the root.
+var ErrPathEscapes = errors.New("path escapes from parent")
+
+// resolve gives the path the file of the script is accessed by, the paths out of the root are rejected.
+// Under the working directory the file is accessed by its name, so the errors of the open file have the name the script has used.
+func resolve(op, name string) (string, error) {
+	if config.Root == "" {
+		_, err := Resolve(".", op, name)
+		return name, err
+	}
+
+	return Resolve(config.Root, op, name)
+}
+
+// ScriptPath replaces the host path in the error with the name the script has used.
+func ScriptPath(err error, name string) error {
+	var pathError *fs.PathError
+	if errors.As(err, &pathError) {
+		return &fs.PathError{Op: pathError.Op, Path: name, Err: pathError.Err}
+	}
+
+	return err
+}
+
+// Resolve gives the path of the file under the root, the paths out of the root are rejected.
+// The symbolic links are followed to check where the file really is.
+// The interpreter checks the paths of the scripts with it too, so the built programs see the same files.
+func Resolve(root, op, name string) (string, error) {
+	escapes := &fs.PathError{Op: op, Path: name, Err: ErrPathEscapes}
+	if !filepath.IsLocal(name) {
+		return "", escapes
+	}
+
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: errors.Unwrap(err)}
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	path, err := filepath.EvalSymlinks(filepath.Join(root, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		// the new file is checked by its directory, the dangling link may lead anywhere
+		if _, err := os.Lstat(filepath.Join(root, name)); err == nil {
+			return "", escapes
+		}
+
+		dir, err := filepath.EvalSymlinks(filepath.Dir(filepath.Join(root, name)))
+		if err != nil {
+			return "", &fs.PathError{Op: op, Path: name, Err: errors.Unwrap(err)}
+		}
+		path = filepath.Join(dir, filepath.Base(name))
+	} else if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: errors.Unwrap(err)}
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel != "." && !filepath.IsLocal(rel) {
+		return "", escapes
+	}
+
+	return path, nil
+}
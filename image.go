@@ -0,0 +1,716 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// imageVersion is the version of the format of the compiled program,
+// the images of the other versions are not loaded.
+const imageVersion = 4
+
+const imageMagic = "GOINTERPRETER IMAGE"
+
+// image is the compiled program: the bodies of the functions of the script without the source.
+// The standard packages are declared again when the image is loaded, the objects they have declared
+// are saved as the references to them, the rest of the program is saved as the graph of the values.
+type image struct {
+	Magic   string
+	Version int
+	// the standard packages in the order they are declared
+	Packages []string
+	// the fingerprint of the objects of the standard packages the references point to
+	Externals [sha256.Size]byte
+	// the source files the program is compiled from, the cached program is used while they are the same
+	Sources []imageSource
+	// the directories of the packages which are all the Go files in them
+	Dirs []string
+	// the directory of the main package relative to the directory of the image, the program may access the files in it by default,
+	// so the image moved with the script still finds them
+	Root      string
+	Functions []imageFunction
+	Nodes     []imageValue
+}
+
+type imageSource struct {
+	Path string
+	Hash [sha256.Size]byte
+}
+
+// imageFunction is the function of the script or the instance of the generic function of a standard package,
+// the instance is made again from the template and the type arguments.
+type imageFunction struct {
+	Function imageValue
+	Template string
+	TypeArgs []imageValue
+}
+
+type imageKind uint8
+
+const (
+	imageNil imageKind = iota
+	imageBool
+	imageInt
+	imageUint
+	imageFloat
+	imageComplex
+	imageString
+	// the node of the image the pointer points to
+	imagePointer
+	// the object of the standard package
+	imageExternal
+	// the function of the program known by the name
+	imageFunctionName
+	imageInterface
+	imageStruct
+	imageSlice
+	imageArray
+	// the keys and the values one after another
+	imageMap
+)
+
+// imageValue is the value of the graph, the fields are used by the kind.
+type imageValue struct {
+	Kind   imageKind
+	Int    int64
+	Uint   uint64
+	Float  float64
+	Imag   float64
+	String string
+	// the dynamic type of the interface value
+	Type  string
+	Ref   int
+	Elems []imageValue
+}
+
+// imageTypes are the dynamic types of the interface values the image may have.
+var imageTypes = func() map[string]reflect.Type {
+	res := map[string]reflect.Type{}
+	values := []any{
+		false, 0, int32(0), uint8(0), 0.0, 0i, "", []any(nil), map[any]any(nil), []byte(nil),
+
+		BasicType{}, NamedType{}, SliceType{}, ArrayType{}, MapType{}, ChannelType{}, PointerType{}, StructType{}, InterfaceType{},
+		TypeParam{}, FunctionType{}, TupleType{},
+
+		IntrpretatedFunction{}, GenericFunction{}, BoundMethod{}, HostFunction{}, Closure{},
+		StructValue{}, ArrayValue{}, InterfaceValue{}, PackageValue{},
+
+		DefineVariableInstruction{}, FunctionCallInstruction{}, StringUsingInstruction{}, IntUsingInstruction{},
+		RuneUsingInstruction{}, VariableUsingInstruction{}, BoolUsingInstruction{}, AssigmentInstruction{},
+		AddInstruction{}, MulInstruction{}, SubInstruction{}, DivInstruction{}, NotInstruction{}, OrInstruction{},
+		AndInstruction{}, BlockInstruction{}, IFInstruction{}, CompareInstruction{}, FORInstruction{},
+		RangeInstruction{}, BreakInstruction{}, GotoInstruction{}, LabeledInstruction{}, EmptyInstruction{},
+		ReturnInstruction{}, MultiAssigmentInstruction{}, UnpackInstruction{}, DeferInstruction{}, GoInstruction{},
+		FunctionLiteralInstruction{}, FloatUsingInstruction{}, NilUsingInstruction{}, ConstantInstruction{},
+		ConvertInstruction{}, BoxInstruction{}, PackageValueInstruction{}, FunctionUsingInstruction{},
+		MethodUsingInstruction{}, InterfaceMethodInstruction{}, FunctionValueCallInstruction{},
+		SliceLiteralInstruction{}, ArrayLiteralInstruction{}, MapLiteralInstruction{}, IndexInstruction{}, SliceInstruction{},
+		IndexAssigmentInstruction{}, FieldInstruction{}, FieldAddressInstruction{}, VariableAddressInstruction{},
+		DereferenceInstruction{}, StoreInstruction{}, NewPointerInstruction{}, StructLiteralInstruction{},
+		LenInstruction{}, BuiltinCallInstruction{}, AppendInstruction{}, SendInstruction{}, ReceiveInstruction{},
+		CloseInstruction{}, SelectInstruction{}, BuiltinFunctionInstruction{},
+	}
+	for _, value := range values {
+		t := reflect.TypeOf(value)
+		res[t.String()] = t
+		res[reflect.PointerTo(t).String()] = reflect.PointerTo(t)
+	}
+	return res
+}()
+
+var reflectTypeType = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+
+// compileTime reports that the values of the type are needed only while the program is compiled:
+// the parse trees and what refers to them. They are saved as nil.
+func compileTime(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	pkgPath := t.PkgPath()
+	return strings.HasSuffix(pkgPath, "/parser") || strings.HasPrefix(pkgPath, "github.com/antlr4-go/") ||
+		t == reflect.TypeOf(TypeResolver{}) || t == reflect.TypeOf(SourceFile{})
+}
+
+// field gives the field of the addressable struct, the unexported one too.
+func field(v reflect.Value, i int) reflect.Value {
+	f := v.Field(i)
+	return reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+}
+
+// addressable gives the copy of the value which can be taken apart by field.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+
+	res := reflect.New(v.Type()).Elem()
+	res.Set(v)
+	return res
+}
+
+// externals are the objects the standard packages have declared in the program,
+// they are found in the same order in any program with the same standard packages.
+type externals struct {
+	pointers []reflect.Value
+	// the count of the functions of the standard packages, the functions of the script follow them
+	functions int
+	// the names of the functions of the standard packages by their indices
+	names []string
+}
+
+// snapshot finds the objects of the program, which has only the standard packages declared yet.
+func (prog *Program) snapshot() *externals {
+	res := &externals{functions: len(prog.functions)}
+	seen := map[imageRef]bool{}
+
+	var visit func(v reflect.Value)
+	visit = func(v reflect.Value) {
+		if compileTime(v.Type()) {
+			return
+		}
+
+		switch v.Kind() {
+		case reflect.Pointer:
+			if v.IsNil() || seen[imageRefOf(v)] {
+				return
+			}
+			seen[imageRefOf(v)] = true
+			res.pointers = append(res.pointers, v)
+			// the values of the variables depend on the run: on the arguments, on the clock
+			if value, ok := v.Interface().(*PackageValue); ok {
+				visit(reflect.ValueOf(&value.Type).Elem())
+				return
+			}
+			visit(v.Elem())
+		case reflect.Interface:
+			if !v.IsNil() && v.Type() != reflectTypeType {
+				visit(v.Elem())
+			}
+		case reflect.Struct:
+			v = addressable(v)
+			for i := range v.NumField() {
+				visit(field(v, i))
+			}
+		case reflect.Slice, reflect.Array:
+			for i := range v.Len() {
+				visit(v.Index(i))
+			}
+		case reflect.Map:
+			for _, key := range sortedMapKeys(v) {
+				visit(key)
+				visit(v.MapIndex(key))
+			}
+		}
+	}
+
+	// the program is referred to by the instructions, it is not a part of the image
+	seen[imageRefOf(reflect.ValueOf(prog))] = true
+	res.pointers = append(res.pointers, reflect.ValueOf(prog))
+	for _, basicType := range []*BasicType{
+		InvalidType, BoolType, IntType, Int32Type, Uint8Type, Float64Type, Complex128Type, StringType,
+		UntypedBoolType, UntypedIntType, UntypedRuneType, UntypedFloatType, UntypedStringType, UntypedNilType,
+	} {
+		seen[imageRefOf(reflect.ValueOf(basicType))] = true
+		res.pointers = append(res.pointers, reflect.ValueOf(basicType))
+	}
+
+	// the functions are visited by the names, the order of the declaration doesn't matter
+	functions := make(map[string]Function, len(prog.functions))
+	for _, function := range prog.functions {
+		functions[function.Name()] = function
+		res.names = append(res.names, function.Name())
+	}
+	for _, root := range []any{functions, prog.types, prog.typeAliases, prog.values, prog.typeTemplates, prog.functionTemplates} {
+		visit(reflect.ValueOf(root))
+	}
+
+	return res
+}
+
+// fingerprint is the hash of the functions and the types of the objects, the images made with the other objects are not loaded.
+func (e *externals) fingerprint() [sha256.Size]byte {
+	hash := sha256.New()
+	for _, name := range e.names {
+		fmt.Fprintln(hash, name)
+	}
+	for _, pointer := range e.pointers {
+		fmt.Fprintln(hash, pointer.Type())
+	}
+
+	var res [sha256.Size]byte
+	hash.Sum(res[:0])
+	return res
+}
+
+type imageRef struct {
+	pointer unsafe.Pointer
+	Type    reflect.Type
+}
+
+func imageRefOf(v reflect.Value) imageRef {
+	return imageRef{pointer: v.UnsafePointer(), Type: v.Type()}
+}
+
+// sortedMapKeys gives the keys of the map in the same order each time.
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
+
+// imageError is the value which can't be saved into the image.
+type imageError struct {
+	msg string
+}
+
+type encoder struct {
+	program   *Program
+	externals map[imageRef]int
+	pointers  map[imageRef]int
+	nodes     []imageValue
+}
+
+// Save writes the image of the program into the directory dir, the externals are found before the script is compiled.
+func (prog *Program) Save(w io.Writer, dir string, externals *externals, loader *PackageLoader) (err error) {
+	e := &encoder{
+		program:   prog,
+		externals: map[imageRef]int{},
+		pointers:  map[imageRef]int{},
+	}
+	for i, pointer := range externals.pointers {
+		e.externals[imageRefOf(pointer)] = i
+	}
+
+	// the values which can't be saved stop the encoding at once
+	defer func() {
+		if r := recover(); r != nil {
+			imageErr, ok := r.(imageError)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("the program can't be saved: %v", imageErr.msg)
+		}
+	}()
+
+	res := image{
+		Magic:     imageMagic,
+		Version:   imageVersion,
+		Externals: externals.fingerprint(),
+	}
+	err = res.describe(loader, dir)
+	if err != nil {
+		return err
+	}
+	for _, function := range prog.functions[externals.functions:] {
+		if function, ok := function.(GenericFunction); ok {
+			res.Functions = append(res.Functions, e.hostInstance(function))
+			continue
+		}
+
+		res.Functions = append(res.Functions, imageFunction{Function: e.encode(reflect.ValueOf(&function).Elem())})
+	}
+	res.Nodes = e.nodes
+
+	writer := bufio.NewWriter(w)
+	err = gob.NewEncoder(writer).Encode(&res)
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// describe records the standard packages, the sources of the program and the directory of the script
+// relative to the directory of the image.
+func (img *image) describe(loader *PackageLoader, imageDir string) error {
+	if loader.moduleDir != "" {
+		err := img.addSource(filepath.Join(loader.moduleDir, "go.mod"))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, pkg := range loader.Packages {
+		if pkg.Std != nil {
+			img.Packages = append(img.Packages, pkg.Path)
+			continue
+		}
+
+		fileNames := make([]string, len(pkg.Files))
+		for i, file := range pkg.Files {
+			fileNames[i] = file.Name
+			err := img.addSource(file.Name)
+			if err != nil {
+				return err
+			}
+		}
+
+		// the new file of the package changes the program too
+		listed, err := packageFiles(pkg.Dir)
+		if err == nil && slices.Equal(listed, fileNames) {
+			dir, err := filepath.Abs(pkg.Dir)
+			if err != nil {
+				return err
+			}
+			img.Dirs = append(img.Dirs, dir)
+		}
+		if pkg.Path == MainPackagePath {
+			root, err := filepath.Abs(pkg.Dir)
+			if err != nil {
+				return err
+			}
+			imageDir, err := filepath.Abs(imageDir)
+			if err != nil {
+				return err
+			}
+			// the root on the other volume stays absolute
+			if rel, err := filepath.Rel(imageDir, root); err == nil {
+				root = rel
+			}
+			img.Root = root
+		}
+	}
+
+	return nil
+}
+
+func (img *image) addSource(fileName string) error {
+	path, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	img.Sources = append(img.Sources, imageSource{Path: path, Hash: sha256.Sum256(source)})
+	return nil
+}
+
+// fresh reports that the sources of the program have not changed since the image was made.
+func (img *image) fresh() bool {
+	hashes := make(map[string][sha256.Size]byte, len(img.Sources))
+	for _, src := range img.Sources {
+		source, err := os.ReadFile(src.Path)
+		if err != nil || sha256.Sum256(source) != src.Hash {
+			return false
+		}
+		hashes[src.Path] = src.Hash
+	}
+
+	for _, dir := range img.Dirs {
+		fileNames, err := packageFiles(dir)
+		if err != nil {
+			return false
+		}
+		for _, fileName := range fileNames {
+			if _, ok := hashes[fileName]; !ok {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// hostInstance saves the instance of the generic function of the standard package by its template and type arguments.
+func (e *encoder) hostInstance(function GenericFunction) imageFunction {
+	instance, ok := e.program.hostInstances[function.name]
+	if !ok {
+		panic(imageError{fmt.Sprintf("function %v is not the instance of the generic function", function.name)})
+	}
+
+	typeArgs := make([]imageValue, len(instance.typeArgs))
+	for i, typeArg := range instance.typeArgs {
+		typeArgs[i] = e.encode(reflect.ValueOf(&typeArg).Elem())
+	}
+	return imageFunction{Template: instance.template, TypeArgs: typeArgs}
+}
+
+func (e *encoder) encode(v reflect.Value) imageValue {
+	if compileTime(v.Type()) {
+		return imageValue{Kind: imageNil}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		res := imageValue{Kind: imageBool}
+		if v.Bool() {
+			res.Int = 1
+		}
+		return res
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return imageValue{Kind: imageInt, Int: v.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return imageValue{Kind: imageUint, Uint: v.Uint()}
+	case reflect.Float32, reflect.Float64:
+		return imageValue{Kind: imageFloat, Float: v.Float()}
+	case reflect.Complex64, reflect.Complex128:
+		return imageValue{Kind: imageComplex, Float: real(v.Complex()), Imag: imag(v.Complex())}
+	case reflect.String:
+		return imageValue{Kind: imageString, String: v.String()}
+	case reflect.Pointer:
+		if v.IsNil() {
+			return imageValue{Kind: imageNil}
+		}
+		if i, ok := e.externals[imageRefOf(v)]; ok {
+			return imageValue{Kind: imageExternal, Ref: i}
+		}
+		if i, ok := e.pointers[imageRefOf(v)]; ok {
+			return imageValue{Kind: imagePointer, Ref: i}
+		}
+
+		// the node is taken before the value is encoded, the value may refer to itself
+		i := len(e.nodes)
+		e.pointers[imageRefOf(v)] = i
+		e.nodes = append(e.nodes, imageValue{})
+		node := e.encode(v.Elem())
+		e.nodes[i] = node
+		return imageValue{Kind: imagePointer, Ref: i}
+	case reflect.Interface:
+		if v.IsNil() {
+			return imageValue{Kind: imageNil}
+		}
+		if v.Type() == reflectTypeType {
+			panic(imageError{"the host type " + v.Elem().String()})
+		}
+
+		dynamic := v.Elem()
+		// the host functions are the functions of the program, which is declared again
+		if function, ok := dynamic.Interface().(GenericFunction); ok {
+			if id, ok := e.program.functionID[function.name]; ok && id < len(e.program.functions) {
+				return imageValue{Kind: imageFunctionName, String: function.name}
+			}
+		}
+
+		name := dynamic.Type().String()
+		if _, ok := imageTypes[name]; !ok {
+			panic(imageError{"the value of the type " + name})
+		}
+		return imageValue{Kind: imageInterface, Type: name, Elems: []imageValue{e.encode(dynamic)}}
+	case reflect.Struct:
+		v = addressable(v)
+		res := imageValue{Kind: imageStruct, Elems: make([]imageValue, v.NumField())}
+		for i := range v.NumField() {
+			// the fields made again after the loading are saved as nil
+			if v.Type().Field(i).Tag.Get("image") == "-" {
+				continue
+			}
+			res.Elems[i] = e.encode(field(v, i))
+		}
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return imageValue{Kind: imageNil}
+		}
+		res := imageValue{Kind: imageSlice, Elems: make([]imageValue, v.Len())}
+		for i := range v.Len() {
+			res.Elems[i] = e.encode(v.Index(i))
+		}
+		return res
+	case reflect.Array:
+		res := imageValue{Kind: imageArray, Elems: make([]imageValue, v.Len())}
+		for i := range v.Len() {
+			res.Elems[i] = e.encode(v.Index(i))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return imageValue{Kind: imageNil}
+		}
+		res := imageValue{Kind: imageMap}
+		for _, key := range sortedMapKeys(v) {
+			res.Elems = append(res.Elems, e.encode(key), e.encode(v.MapIndex(key)))
+		}
+		return res
+	case reflect.Func:
+		if v.IsNil() {
+			return imageValue{Kind: imageNil}
+		}
+	}
+
+	panic(imageError{"the value of the type " + v.Type().String()})
+}
+
+type decoder struct {
+	program   *Program
+	externals []reflect.Value
+	nodes     []imageValue
+	pointers  map[int]reflect.Value
+}
+
+// readImage reads the image, the image of the other version is not read.
+func readImage(r io.Reader) (*image, error) {
+	img := &image{}
+	err := gob.NewDecoder(bufio.NewReader(r)).Decode(img)
+	if err != nil {
+		return nil, fmt.Errorf("the image can't be read: %v", err)
+	}
+	if img.Magic != imageMagic {
+		return nil, fmt.Errorf("the file is not the image of the program")
+	}
+	if img.Version != imageVersion {
+		return nil, fmt.Errorf("the image of the version %v can't be loaded, the version %v is expected", img.Version, imageVersion)
+	}
+
+	return img, nil
+}
+
+// Load loads the image into the new program, the sandbox and the clock of the program are set already.
+func (prog *Program) Load(img *image) (err error) {
+	for _, path := range img.Packages {
+		std, ok := stdPackages[path]
+		if !ok {
+			return fmt.Errorf("the image needs the unknown package %v", path)
+		}
+		std.Declare(prog, &Package{Name: std.Name, Path: std.Path, Std: std})
+	}
+	externals := prog.snapshot()
+	if externals.fingerprint() != img.Externals {
+		return fmt.Errorf("the image is made by the other version of the interpreter")
+	}
+
+	// the values which don't match the program stop the decoding at once
+	defer func() {
+		if r := recover(); r != nil {
+			imageErr, ok := r.(imageError)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("the image is broken: %v", imageErr.msg)
+		}
+	}()
+
+	d := &decoder{
+		program:   prog,
+		externals: externals.pointers,
+		nodes:     img.Nodes,
+		pointers:  map[int]reflect.Value{},
+	}
+	functionType := reflect.TypeOf((*Function)(nil)).Elem()
+	typeType := reflect.TypeOf((*Type)(nil)).Elem()
+	for i, function := range img.Functions {
+		if function.Template == "" {
+			err = prog.RegisterFunction(d.decode(function.Function, functionType).Interface().(Function))
+			if err != nil {
+				return err
+			}
+		} else {
+			template, ok := prog.functionTemplates[function.Template]
+			if !ok {
+				return fmt.Errorf("the image needs the unknown generic function %v", function.Template)
+			}
+			typeArgs := make([]Type, len(function.TypeArgs))
+			for i, typeArg := range function.TypeArgs {
+				typeArgs[i] = d.decode(typeArg, typeType).Interface().(Type)
+			}
+			_, err = template.Instantiate(typeArgs)
+			if err != nil {
+				return err
+			}
+		}
+
+		// the instructions call the functions by their indices
+		if len(prog.functions) != externals.functions+i+1 {
+			return fmt.Errorf("the image is broken: the function %v is out of its place", i)
+		}
+	}
+
+	return nil
+}
+
+func (d *decoder) decode(value imageValue, t reflect.Type) reflect.Value {
+	res := reflect.New(t).Elem()
+	switch value.Kind {
+	case imageNil:
+	case imageBool:
+		res.SetBool(value.Int != 0)
+	case imageInt:
+		res.SetInt(value.Int)
+	case imageUint:
+		res.SetUint(value.Uint)
+	case imageFloat:
+		res.SetFloat(value.Float)
+	case imageComplex:
+		res.SetComplex(complex(value.Float, value.Imag))
+	case imageString:
+		res.SetString(value.String)
+	case imageExternal:
+		if value.Ref >= len(d.externals) {
+			panic(imageError{"no object of the standard packages"})
+		}
+		d.set(res, d.externals[value.Ref])
+	case imageFunctionName:
+		id, ok := d.program.functionID[value.String]
+		if !ok {
+			panic(imageError{"no function " + value.String})
+		}
+		d.set(res, reflect.ValueOf(&d.program.functions[id]).Elem().Elem())
+	case imagePointer:
+		if pointer, ok := d.pointers[value.Ref]; ok {
+			d.set(res, pointer)
+			break
+		}
+		if t.Kind() != reflect.Pointer || value.Ref >= len(d.nodes) {
+			panic(imageError{"the pointer to the value of the type " + t.String()})
+		}
+
+		// the pointer is known before the value is decoded, the value may refer to itself
+		pointer := reflect.New(t.Elem())
+		d.pointers[value.Ref] = pointer
+		pointer.Elem().Set(d.decode(d.nodes[value.Ref], t.Elem()))
+		res.Set(pointer)
+	case imageInterface:
+		dynamic, ok := imageTypes[value.Type]
+		if !ok || len(value.Elems) != 1 {
+			panic(imageError{"the value of the type " + value.Type})
+		}
+		d.set(res, d.decode(value.Elems[0], dynamic))
+	case imageStruct:
+		if t.Kind() != reflect.Struct || t.NumField() != len(value.Elems) {
+			panic(imageError{"the value of the type " + t.String()})
+		}
+		for i, elem := range value.Elems {
+			field(res, i).Set(d.decode(elem, t.Field(i).Type))
+		}
+	case imageSlice:
+		res.Set(reflect.MakeSlice(t, len(value.Elems), len(value.Elems)))
+		for i, elem := range value.Elems {
+			res.Index(i).Set(d.decode(elem, t.Elem()))
+		}
+	case imageArray:
+		for i, elem := range value.Elems {
+			res.Index(i).Set(d.decode(elem, t.Elem()))
+		}
+	case imageMap:
+		res.Set(reflect.MakeMapWithSize(t, len(value.Elems)/2))
+		for i := 0; i+1 < len(value.Elems); i += 2 {
+			res.SetMapIndex(d.decode(value.Elems[i], t.Key()), d.decode(value.Elems[i+1], t.Elem()))
+		}
+	default:
+		panic(imageError{fmt.Sprintf("the value of the kind %v", value.Kind)})
+	}
+
+	return res
+}
+
+// set sets the decoded value, the value of the other type means that the image doesn't match the program.
+func (d *decoder) set(res reflect.Value, value reflect.Value) {
+	if !value.Type().AssignableTo(res.Type()) {
+		panic(imageError{fmt.Sprintf("the value of the type %v where %v is expected", value.Type(), res.Type())})
+	}
+	res.Set(value)
+}
@@ -9,20 +9,38 @@ import (
 )
 
 type Instruction interface {
-	Execute(variables map[string]any) error
+	Execute(variables map[string]*any) error
 }
 
 type DefineVariableInstruction struct {
-	Name string
-	Type reflect.Type
+	program *Program
+	Name    string
+	Type    Type
+	// the initial value, the variable without it is zeroed
+	value Instruction
 }
 
-func (instr *DefineVariableInstruction) Execute(variables map[string]any) error {
-	if _, ok := variables[instr.Name]; ok {
-		return fmt.Errorf("varible %v has already defined", instr.Name)
+func (instr *DefineVariableInstruction) Execute(variables map[string]*any) error {
+	if instr.value == nil {
+		// redeclarations are rejected by the compiler, so this is shadowing
+		value := NewVariable(instr.Type)
+		variables[instr.Name] = &value
+		return nil
+	}
+
+	stacklen := len(instr.program.stack)
+	err := instr.value.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
 	}
 
-	variables[instr.Name] = NewVariable(instr.Type)
+	value := instr.program.stack[stacklen]
+	instr.program.stack = instr.program.stack[:stacklen]
+	variables[instr.Name] = &value
 	return nil
 }
 
@@ -33,7 +51,7 @@ type FunctionCallInstruction struct {
 	arguments  []Instruction
 }
 
-func (instr *FunctionCallInstruction) Execute(variables map[string]any) error {
+func (instr *FunctionCallInstruction) Execute(variables map[string]*any) error {
 	stacklen := len(instr.program.stack)
 
 	for _, argument := range instr.arguments {
@@ -55,7 +73,7 @@ type StringUsingInstruction struct {
 	str     string
 }
 
-func (instr *StringUsingInstruction) Execute(variables map[string]any) error {
+func (instr *StringUsingInstruction) Execute(variables map[string]*any) error {
 	instr.program.stack = append(instr.program.stack, CloneAny(instr.str))
 	return nil
 }
@@ -65,23 +83,40 @@ type IntUsingInstruction struct {
 	integer int
 }
 
-func (instr *IntUsingInstruction) Execute(variables map[string]any) error {
+func (instr *IntUsingInstruction) Execute(variables map[string]*any) error {
 	instr.program.stack = append(instr.program.stack, instr.integer)
 	return nil
 }
 
+type RuneUsingInstruction struct {
+	program *Program
+	value   rune
+}
+
+func (instr *RuneUsingInstruction) Execute(variables map[string]*any) error {
+	instr.program.stack = append(instr.program.stack, instr.value)
+	return nil
+}
+
+// VariableUsingInstruction pushes the copy of the variable,
+// a reference is not copied: it is used to get the fields of the struct in place.
 type VariableUsingInstruction struct {
 	program      *Program
 	variableName string
+	reference    bool
 }
 
-func (instr *VariableUsingInstruction) Execute(variables map[string]any) error {
+func (instr *VariableUsingInstruction) Execute(variables map[string]*any) error {
 	val, ok := variables[instr.variableName]
 	if !ok {
 		return fmt.Errorf("variable %v not declarated", instr.variableName)
 	}
 
-	instr.program.stack = append(instr.program.stack, CloneAny(val))
+	if instr.reference {
+		instr.program.stack = append(instr.program.stack, *val)
+	} else {
+		instr.program.stack = append(instr.program.stack, CloneAny(*val))
+	}
 	return nil
 }
 
@@ -90,7 +125,7 @@ type BoolUsingInstruction struct {
 	boolVal bool
 }
 
-func (instr *BoolUsingInstruction) Execute(variables map[string]any) error {
+func (instr *BoolUsingInstruction) Execute(variables map[string]*any) error {
 	instr.program.stack = append(instr.program.stack, instr.boolVal)
 	return nil
 }
@@ -101,7 +136,7 @@ type AssigmentInstruction struct {
 	instruction Instruction
 }
 
-func (instr *AssigmentInstruction) Execute(variables map[string]any) error {
+func (instr *AssigmentInstruction) Execute(variables map[string]*any) error {
 	stacklen := len(instr.program.stack)
 	err := instr.instruction.Execute(variables)
 	if err != nil {
@@ -115,180 +150,120 @@ func (instr *AssigmentInstruction) Execute(variables map[string]any) error {
 	if !ok {
 		return fmt.Errorf("variable %v undefined", instr.varName)
 	}
-	if reflect.TypeOf(val) != reflect.TypeOf(instr.program.stack[stacklen]) {
-		return fmt.Errorf(
-			"mismatcn types expected: %v, actual: %v",
-			reflect.TypeOf(val),
-			reflect.TypeOf(instr.program.stack[stacklen]))
-	}
 
-	variables[instr.varName] = CloneAny(instr.program.stack[stacklen])
+	*val = CloneAny(instr.program.stack[stacklen])
 	instr.program.stack = instr.program.stack[:stacklen]
 	return nil
 }
 
-type AddInstruction struct {
-	program      *Program
-	instructions []Instruction
-}
-
-func (instr *AddInstruction) Execute(variables map[string]any) error {
-	stacklen := len(instr.program.stack)
-
-	for idx := range instr.instructions {
-		instruction := instr.instructions[len(instr.instructions)-1-idx]
+// fold evaluates the operands from the first one and pushes the value they are combined into from the first one.
+func fold(program *Program, variables map[string]*any, instructions []Instruction, operation func(val1, val2 any) (any, error)) error {
+	stacklen := len(program.stack)
 
+	for _, instruction := range instructions {
 		err := instruction.Execute(variables)
 		if err != nil {
 			return err
 		}
 	}
 
-	if len(instr.program.stack)-stacklen != len(instr.instructions) {
+	if len(program.stack)-stacklen != len(instructions) {
 		return fmt.Errorf(
 			"missmatch between return values expected: %v actual: %v",
-			len(instr.instructions),
-			len(instr.program.stack)-stacklen,
+			len(instructions),
+			len(program.stack)-stacklen,
 		)
 	}
 
-	for idx := 0; idx < len(instr.instructions)-1; idx++ {
-		val1 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-		val2 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-
-		sum, err := AddAny(val1, val2)
+	res := program.stack[stacklen]
+	for _, value := range program.stack[stacklen+1:] {
+		var err error
+		res, err = operation(res, value)
 		if err != nil {
 			return err
 		}
-		instr.program.stack = append(instr.program.stack, sum)
 	}
+	program.stack = append(program.stack[:stacklen], res)
 
 	return nil
 }
 
-type MulInstruction struct {
-	program      *Program
-	instructions []Instruction
-}
-
-func (instr *MulInstruction) Execute(variables map[string]any) error {
-	stacklen := len(instr.program.stack)
-
-	for idx := range instr.instructions {
-		instruction := instr.instructions[len(instr.instructions)-1-idx]
+// shortCircuit evaluates the operands of && and || from the first one,
+// the operands after the one which has the value of the result are not evaluated.
+func shortCircuit(program *Program, variables map[string]*any, instructions []Instruction, operation func(val1, val2 any) (any, error), result bool) error {
+	stacklen := len(program.stack)
 
+	for idx, instruction := range instructions {
 		err := instruction.Execute(variables)
 		if err != nil {
 			return err
 		}
-	}
-
-	if len(instr.program.stack)-stacklen != len(instr.instructions) {
-		return fmt.Errorf(
-			"missmatch between return values expected: %v actual: %v",
-			len(instr.instructions),
-			len(instr.program.stack)-stacklen,
-		)
-	}
 
-	for idx := 0; idx < len(instr.instructions)-1; idx++ {
-		val1 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-		val2 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
+		if expected := min(idx, 1) + 1; len(program.stack)-stacklen != expected {
+			return fmt.Errorf(
+				"missmatch between return values expected: %v actual: %v",
+				expected,
+				len(program.stack)-stacklen,
+			)
+		}
+		if idx > 0 {
+			res, err := operation(program.stack[stacklen], program.stack[stacklen+1])
+			if err != nil {
+				return err
+			}
+			program.stack = append(program.stack[:stacklen], res)
+		}
 
-		sum, err := MulAny(val1, val2)
-		if err != nil {
-			return err
+		if res, ok := program.stack[stacklen].(bool); ok && res == result {
+			return nil
 		}
-		instr.program.stack = append(instr.program.stack, sum)
 	}
 
 	return nil
 }
 
-type SubInstruction struct {
+type AddInstruction struct {
 	program      *Program
 	instructions []Instruction
+	// the type of the operands
+	Type Type
 }
 
-func (instr *SubInstruction) Execute(variables map[string]any) error {
-	stacklen := len(instr.program.stack)
-
-	for idx := range instr.instructions {
-		instruction := instr.instructions[len(instr.instructions)-1-idx]
-
-		err := instruction.Execute(variables)
-		if err != nil {
-			return err
-		}
-	}
+func (instr *AddInstruction) Execute(variables map[string]*any) error {
+	return fold(instr.program, variables, instr.instructions, instr.program.add)
+}
 
-	if len(instr.program.stack)-stacklen != len(instr.instructions) {
-		return fmt.Errorf(
-			"missmatch between return values expected: %v actual: %v",
-			len(instr.instructions),
-			len(instr.program.stack)-stacklen,
-		)
-	}
+type MulInstruction struct {
+	program      *Program
+	instructions []Instruction
+	// the type of the operands
+	Type Type
+}
 
-	for idx := 0; idx < len(instr.instructions)-1; idx++ {
-		val1 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-		val2 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
+func (instr *MulInstruction) Execute(variables map[string]*any) error {
+	return fold(instr.program, variables, instr.instructions, MulAny)
+}
 
-		sum, err := SubAny(val1, val2)
-		if err != nil {
-			return err
-		}
-		instr.program.stack = append(instr.program.stack, sum)
-	}
+type SubInstruction struct {
+	program      *Program
+	instructions []Instruction
+	// the type of the operands
+	Type Type
+}
 
-	return nil
+func (instr *SubInstruction) Execute(variables map[string]*any) error {
+	return fold(instr.program, variables, instr.instructions, SubAny)
 }
 
 type DivInstruction struct {
 	program      *Program
 	instructions []Instruction
+	// the type of the operands
+	Type Type
 }
 
-func (instr *DivInstruction) Execute(variables map[string]any) error {
-	stacklen := len(instr.program.stack)
-
-	for idx := range instr.instructions {
-		instruction := instr.instructions[len(instr.instructions)-1-idx]
-
-		err := instruction.Execute(variables)
-		if err != nil {
-			return err
-		}
-	}
-
-	if len(instr.program.stack)-stacklen != len(instr.instructions) {
-		return fmt.Errorf(
-			"missmatch between return values expected: %v actual: %v",
-			len(instr.instructions),
-			len(instr.program.stack)-stacklen,
-		)
-	}
-
-	for idx := 0; idx < len(instr.instructions)-1; idx++ {
-		val1 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-		val2 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-
-		sum, err := DivAny(val1, val2)
-		if err != nil {
-			return err
-		}
-		instr.program.stack = append(instr.program.stack, sum)
-	}
-
-	return nil
+func (instr *DivInstruction) Execute(variables map[string]*any) error {
+	return fold(instr.program, variables, instr.instructions, DivAny)
 }
 
 type NotInstruction struct {
@@ -296,7 +271,7 @@ type NotInstruction struct {
 	instruction Instruction
 }
 
-func (instr *NotInstruction) Execute(variables map[string]any) error {
+func (instr *NotInstruction) Execute(variables map[string]*any) error {
 	stacklen := len(instr.program.stack)
 
 	err := instr.instruction.Execute(variables)
@@ -330,40 +305,8 @@ type OrInstruction struct {
 	instructions []Instruction
 }
 
-func (instr *OrInstruction) Execute(variables map[string]any) error {
-	stacklen := len(instr.program.stack)
-
-	for idx := range instr.instructions {
-		instruction := instr.instructions[len(instr.instructions)-1-idx]
-
-		err := instruction.Execute(variables)
-		if err != nil {
-			return err
-		}
-	}
-
-	if len(instr.program.stack)-stacklen != len(instr.instructions) {
-		return fmt.Errorf(
-			"missmatch between return values expected: %v actual: %v",
-			len(instr.instructions),
-			len(instr.program.stack)-stacklen,
-		)
-	}
-
-	for idx := 0; idx < len(instr.instructions)-1; idx++ {
-		val1 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-		val2 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-
-		sum, err := OrAny(val1, val2)
-		if err != nil {
-			return err
-		}
-		instr.program.stack = append(instr.program.stack, sum)
-	}
-
-	return nil
+func (instr *OrInstruction) Execute(variables map[string]*any) error {
+	return shortCircuit(instr.program, variables, instr.instructions, OrAny, true)
 }
 
 type AndInstruction struct {
@@ -371,67 +314,42 @@ type AndInstruction struct {
 	instructions []Instruction
 }
 
-func (instr *AndInstruction) Execute(variables map[string]any) error {
-	stacklen := len(instr.program.stack)
-
-	for idx := range instr.instructions {
-		instruction := instr.instructions[len(instr.instructions)-1-idx]
-
-		err := instruction.Execute(variables)
-		if err != nil {
-			return err
-		}
-	}
-
-	if len(instr.program.stack)-stacklen != len(instr.instructions) {
-		return fmt.Errorf(
-			"missmatch between return values expected: %v actual: %v",
-			len(instr.instructions),
-			len(instr.program.stack)-stacklen,
-		)
-	}
-
-	for idx := 0; idx < len(instr.instructions)-1; idx++ {
-		val1 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-		val2 := instr.program.stack[len(instr.program.stack)-1]
-		instr.program.stack = instr.program.stack[:len(instr.program.stack)-1]
-
-		sum, err := AndAny(val1, val2)
-		if err != nil {
-			return err
-		}
-		instr.program.stack = append(instr.program.stack, sum)
-	}
-
-	return nil
+func (instr *AndInstruction) Execute(variables map[string]*any) error {
+	return shortCircuit(instr.program, variables, instr.instructions, AndAny, false)
 }
 
 type BlockInstruction struct {
 	program      *Program
 	instructions []Instruction
+	// the indices of the labeled instructions
+	labels map[string]int
 }
 
-func (instr *BlockInstruction) Execute(variables map[string]any) error {
+func (instr *BlockInstruction) Execute(variables map[string]*any) error {
 	stacklen := len(instr.program.stack)
 	blockVariables := maps.Clone(variables)
 
 	var err error = nil
 
-	for _, instruction := range instr.instructions {
-		err = instruction.Execute(blockVariables)
+	for i := 0; i < len(instr.instructions); i++ {
+		err = instr.instructions[i].Execute(blockVariables)
 
 		if _, ok := err.(ReturnError); ok {
 			break
 		}
+		if jump, ok := err.(GotoError); ok {
+			if target, ok := instr.labels[jump.label]; ok {
+				instr.program.stack = instr.program.stack[:stacklen]
+				err = nil
+				i = target - 1
+				continue
+			}
+		}
 		if err != nil {
 			return err
 		}
 	}
 
-	for variable := range variables {
-		variables[variable] = blockVariables[variable]
-	}
 	if stacklen > len(instr.program.stack) {
 		return fmt.Errorf("wrong stack size")
 	}
@@ -447,7 +365,7 @@ type IFInstruction struct {
 	otherwise Instruction
 }
 
-func (instr *IFInstruction) Execute(variables map[string]any) error {
+func (instr *IFInstruction) Execute(variables map[string]*any) error {
 	stacklen := len(instr.program.stack)
 	err := instr.statment.Execute(variables)
 	if err != nil {
@@ -483,9 +401,11 @@ type CompareInstruction struct {
 	program     *Program
 	lhv, rhv    Instruction
 	compareType string
+	// the type of the operands, nil for the comparison with nil
+	Type Type
 }
 
-func (instr *CompareInstruction) Execute(variables map[string]any) error {
+func (instr *CompareInstruction) Execute(variables map[string]*any) error {
 	stacklen := len(instr.program.stack)
 
 	var err error
@@ -523,7 +443,7 @@ type FORInstruction struct {
 	than     Instruction
 }
 
-func (instr *FORInstruction) Execute(variables map[string]any) error {
+func (instr *FORInstruction) Execute(variables map[string]*any) error {
 	statementValue := true
 	for {
 		if instr.statment != nil {
@@ -564,37 +484,1453 @@ func (instr *FORInstruction) Execute(variables map[string]any) error {
 	return nil
 }
 
+type rangeKind int
+
+const (
+	rangeSlice rangeKind = iota
+	rangeString
+	rangeMap
+	rangeInt
+	rangeFunction
+	rangeChannel
+)
+
+// RangeInstruction runs the body for each element of the container,
+// every iteration has its own iteration variables.
+type RangeInstruction struct {
+	program   *Program
+	kind      rangeKind
+	container Instruction
+	// the names of the iteration variables, the empty names aren't set
+	names [2]string
+	// the assignments of the iteration values to the existing variables
+	assigments []Instruction
+	body       Instruction
+}
+
+func (instr *RangeInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	err := instr.container.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	container := instr.program.stack[stacklen]
+	instr.program.stack = instr.program.stack[:stacklen]
+
+	switch instr.kind {
+	case rangeSlice:
+		slice := elements(container)
+		for i := range slice {
+			done, err := instr.iterate(variables, i, CloneAny(slice[i]))
+			if done {
+				return err
+			}
+		}
+	case rangeString:
+		for i, r := range container.(string) {
+			done, err := instr.iterate(variables, i, r)
+			if done {
+				return err
+			}
+		}
+	case rangeMap:
+		for key, value := range container.(map[any]any) {
+			done, err := instr.iterate(variables, key, CloneAny(value))
+			if done {
+				return err
+			}
+		}
+	case rangeInt:
+		switch n := container.(type) {
+		case int:
+			return iterateInt(instr, variables, n)
+		case int32:
+			return iterateInt(instr, variables, n)
+		case uint8:
+			return iterateInt(instr, variables, n)
+		}
+	case rangeFunction:
+		return instr.iterateFunction(variables, container)
+	case rangeChannel:
+		// the loop receives the values until the channel is closed
+		ch, _ := container.(*ChannelValue)
+		for {
+			value, ok, err := instr.program.receive(ch)
+			if err != nil || !ok {
+				return err
+			}
+			done, err := instr.iterate(variables, value)
+			if done {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// elements are the elements of the slice or the array the range loop goes over.
+func elements(container any) []any {
+	if array, ok := container.(*ArrayValue); ok {
+		return array.elems
+	}
+
+	slice, _ := container.([]any)
+	return slice
+}
+
+func iterateInt[T int | int32 | uint8](instr *RangeInstruction, variables map[string]*any, n T) error {
+	for i := T(0); i < n; i++ {
+		done, err := instr.iterate(variables, i)
+		if done {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// iterateFunction calls the iterator function with the yield function running the body.
+func (instr *RangeInstruction) iterateFunction(variables map[string]*any, container any) error {
+	function, ok := container.(Function)
+	if !ok {
+		return fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+	}
+
+	finished := false
+	var bodyErr error
+	_, err := function.Call(HostFunction{
+		name: "yield",
+		call: func(args ...any) ([]any, error) {
+			if finished {
+				return nil, fmt.Errorf("range function continued iteration after function for loop body returned false")
+			}
+
+			done, err := instr.iterate(variables, args...)
+			if done {
+				finished = true
+				bodyErr = err
+			}
+			return []any{!done}, nil
+		},
+	})
+
+	// the return from the body of the loop is the return from the function
+	if bodyErr != nil {
+		return bodyErr
+	}
+	return err
+}
+
+// iterate runs the body with the fresh iteration variables, done reports that the loop is over.
+func (instr *RangeInstruction) iterate(variables map[string]*any, values ...any) (bool, error) {
+	iteration := maps.Clone(variables)
+	for i, name := range instr.names {
+		if name == "" || i >= len(values) {
+			continue
+		}
+
+		value := values[i]
+		iteration[name] = &value
+	}
+
+	for _, assigment := range instr.assigments {
+		err := assigment.Execute(iteration)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	err := instr.body.Execute(iteration)
+	if _, ok := err.(BreakError); ok {
+		return true, nil
+	}
+
+	return err != nil, err
+}
+
 type BreakInstruction struct{}
 
-func (instr *BreakInstruction) Execute(variables map[string]any) error {
+func (instr *BreakInstruction) Execute(variables map[string]*any) error {
 	return BreakError{}
 }
 
+type GotoInstruction struct {
+	label string
+}
+
+func (instr *GotoInstruction) Execute(variables map[string]*any) error {
+	return GotoError{label: instr.label}
+}
+
+// LabeledInstruction marks the target of goto, the block jumps to it.
+type LabeledInstruction struct {
+	label       string
+	instruction Instruction
+}
+
+func (instr *LabeledInstruction) Execute(variables map[string]*any) error {
+	return instr.instruction.Execute(variables)
+}
+
+// EmptyInstruction is the empty statement.
+type EmptyInstruction struct{}
+
+func (instr *EmptyInstruction) Execute(variables map[string]*any) error {
+	return nil
+}
+
+// ReturnInstruction sets the results of the function, the bare return has no expressions.
 type ReturnInstruction struct {
-	program    *Program
-	expression Instruction
+	program     *Program
+	expressions []Instruction
+	// the return of the call of the function itself, it may run again in the same call
+	tail bool
 }
 
-func (instr *ReturnInstruction) Execute(variables map[string]any) error {
-	hasExpression := (instr.expression != nil)
-	_, hasReturnValue := variables["@result"]
-	if hasExpression != hasReturnValue {
-		return fmt.Errorf("has return value:%v expected:%v", hasExpression, hasReturnValue)
+func (instr *ReturnInstruction) Execute(variables map[string]*any) error {
+	err := instr.setResults(variables)
+	if err != nil {
+		return err
 	}
 
-	if hasReturnValue {
-		stacklen := len(instr.program.stack)
-		err := instr.expression.Execute(variables)
+	return ReturnError{}
+}
+
+func (instr *ReturnInstruction) setResults(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	for _, expression := range instr.expressions {
+		err := expression.Execute(variables)
 		if err != nil {
 			return err
 		}
+	}
 
-		if len(instr.program.stack) != stacklen+1 {
-			return fmt.Errorf("wrong count of return values of statement")
-		}
+	if len(instr.program.stack) != stacklen+len(instr.expressions) {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
 
-		variables["@result"] = instr.program.stack[len(instr.program.stack)-1]
+	for i, value := range instr.program.stack[stacklen:] {
+		*variables[resultName(i)] = value
 	}
+	instr.program.stack = instr.program.stack[:stacklen]
 
-	return ReturnError{}
+	return nil
+}
+
+// MultiAssigmentInstruction evaluates all the values into the temporary variables before the assignments.
+type MultiAssigmentInstruction struct {
+	program     *Program
+	values      []Instruction
+	temporaries []string
+	assigments  []Instruction
+}
+
+func (instr *MultiAssigmentInstruction) Execute(variables map[string]*any) error {
+	err := unpack(instr.program, instr.values, instr.temporaries, variables)
+	if err != nil {
+		return err
+	}
+
+	for _, assigment := range instr.assigments {
+		err := assigment.Execute(variables)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnpackInstruction stores the results of the call into the temporary variables, it pushes nothing.
+type UnpackInstruction struct {
+	program     *Program
+	value       Instruction
+	temporaries []string
+}
+
+func (instr *UnpackInstruction) Execute(variables map[string]*any) error {
+	return unpack(instr.program, []Instruction{instr.value}, instr.temporaries, variables)
+}
+
+func unpack(program *Program, values []Instruction, temporaries []string, variables map[string]*any) error {
+	res, err := unpackValues(program, values, len(temporaries), variables)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range temporaries {
+		value := res[i]
+		variables[name] = &value
+	}
+	return nil
+}
+
+// unpackValues evaluates the values, they are expected to leave the count of the values on the stack.
+func unpackValues(program *Program, values []Instruction, count int, variables map[string]*any) ([]any, error) {
+	stacklen := len(program.stack)
+	for _, value := range values {
+		err := value.Execute(variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(program.stack) != stacklen+count {
+		return nil, fmt.Errorf(
+			"missmatch between return values expected: %v actual: %v",
+			count,
+			len(program.stack)-stacklen,
+		)
+	}
+
+	res := slices.Clone(program.stack[stacklen:])
+	program.stack = program.stack[:stacklen]
+	return res, nil
+}
+
+// DeferInstruction evaluates the function and the arguments of the call, the call is made when the function returns.
+type DeferInstruction struct {
+	program *Program
+	// the function value, if the function isn't known statically
+	function   Instruction
+	functionID int
+	arguments  []Instruction
+}
+
+func (instr *DeferInstruction) Execute(variables map[string]*any) error {
+	function, args, err := evaluateCall(instr.program, instr.function, instr.functionID, instr.arguments, variables)
+	if err != nil {
+		return err
+	}
+
+	defers := variables["@defers"]
+	*defers = append((*defers).([]DeferredCall), DeferredCall{function: function, args: args})
+	return nil
+}
+
+// GoInstruction evaluates the function and the arguments of the call, the call is made by the new goroutine.
+type GoInstruction struct {
+	program *Program
+	// the function value, if the function isn't known statically
+	function   Instruction
+	functionID int
+	arguments  []Instruction
+}
+
+func (instr *GoInstruction) Execute(variables map[string]*any) error {
+	function, args, err := evaluateCall(instr.program, instr.function, instr.functionID, instr.arguments, variables)
+	if err != nil {
+		return err
+	}
+
+	instr.program.spawn(function, args)
+	return nil
+}
+
+// evaluateCall evaluates the function and the arguments of the call which is made later.
+func evaluateCall(program *Program, functionValue Instruction, functionID int, arguments []Instruction, variables map[string]*any) (Function, []any, error) {
+	stacklen := len(program.stack)
+
+	function := program.functions[functionID]
+	if functionValue != nil {
+		err := functionValue.Execute(variables)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var ok bool
+		function, ok = program.stack[stacklen].(Function)
+		if !ok {
+			return nil, nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+		}
+		program.stack = program.stack[:stacklen]
+	}
+
+	for _, argument := range arguments {
+		err := argument.Execute(variables)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	args := slices.Clone(program.stack[stacklen:])
+	program.stack = program.stack[:stacklen]
+
+	return function, args, nil
+}
+
+// evaluate evaluates the expression with the single value.
+func evaluate(program *Program, instruction Instruction, variables map[string]*any) (any, error) {
+	stacklen := len(program.stack)
+	err := instruction.Execute(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(program.stack) != stacklen+1 {
+		return nil, fmt.Errorf("wrong count of return values of statement")
+	}
+
+	value := program.stack[stacklen]
+	program.stack = program.stack[:stacklen]
+	return value, nil
+}
+
+// SendInstruction sends the value to the channel, the goroutine waits until the channel takes it.
+type SendInstruction struct {
+	program *Program
+	channel Instruction
+	value   Instruction
+}
+
+func (instr *SendInstruction) Execute(variables map[string]*any) error {
+	channel, err := evaluate(instr.program, instr.channel, variables)
+	if err != nil {
+		return err
+	}
+	value, err := evaluate(instr.program, instr.value, variables)
+	if err != nil {
+		return err
+	}
+
+	ch, _ := channel.(*ChannelValue)
+	return instr.program.send(ch, value)
+}
+
+// ReceiveInstruction receives the value from the channel, v, ok := <-ch also tells whether the value was sent.
+type ReceiveInstruction struct {
+	program *Program
+	channel Instruction
+	commaOk bool
+}
+
+func (instr *ReceiveInstruction) Execute(variables map[string]*any) error {
+	channel, err := evaluate(instr.program, instr.channel, variables)
+	if err != nil {
+		return err
+	}
+
+	ch, _ := channel.(*ChannelValue)
+	value, ok, err := instr.program.receive(ch)
+	if err != nil {
+		return err
+	}
+
+	instr.program.stack = append(instr.program.stack, value)
+	if instr.commaOk {
+		instr.program.stack = append(instr.program.stack, ok)
+	}
+	return nil
+}
+
+// CloseInstruction closes the channel.
+type CloseInstruction struct {
+	program *Program
+	channel Instruction
+}
+
+func (instr *CloseInstruction) Execute(variables map[string]*any) error {
+	channel, err := evaluate(instr.program, instr.channel, variables)
+	if err != nil {
+		return err
+	}
+
+	ch, _ := channel.(*ChannelValue)
+	return instr.program.close(ch)
+}
+
+// selectCase is the case of the select statement, the default case has no channel.
+type selectCase struct {
+	channel Instruction
+	// the value sent, nil if the case receives
+	value Instruction
+	// the names of the variables of the value received and of ok, the empty names aren't set
+	names [2]string
+	// the assignments of the value received and of ok to the existing variables
+	assigments []Instruction
+	body       Instruction
+}
+
+// SelectInstruction runs the case which can go on: one of them at random, if several can,
+// the default case, if none can, or the first one which can after the goroutine has waited.
+type SelectInstruction struct {
+	program *Program
+	cases   []selectCase
+}
+
+func (instr *SelectInstruction) Execute(variables map[string]*any) error {
+	// the channels and the values sent are evaluated once, in the order of the cases
+	channels := make([]*ChannelValue, len(instr.cases))
+	sends := make([]bool, len(instr.cases))
+	values := make([]any, len(instr.cases))
+	fallback := -1
+	for i, c := range instr.cases {
+		if c.channel == nil {
+			fallback = i
+			continue
+		}
+
+		channel, err := evaluate(instr.program, c.channel, variables)
+		if err != nil {
+			return err
+		}
+		channels[i], _ = channel.(*ChannelValue)
+
+		if c.value != nil {
+			sends[i] = true
+			values[i], err = evaluate(instr.program, c.value, variables)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	i, received, err := instr.program.choose(channels, sends, values, fallback)
+	if err != nil {
+		return err
+	}
+	return instr.run(variables, i, received)
+}
+
+// choose waits until one of the cases of the select statement can go on and makes its communication.
+// The channel of the default case, whose index is fallback, is nil, received is the value and ok of the receiving case.
+func (prog *Program) choose(channels []*ChannelValue, sends []bool, values []any, fallback int) (int, [2]any, error) {
+	// the timers due fire before the default case may be chosen
+	if fallback >= 0 {
+		prog.wakeUp()
+	}
+
+	for {
+		var ready []int
+		for i, ch := range channels {
+			if ch != nil && (sends[i] && ch.canSend() || !sends[i] && ch.canReceive()) {
+				ready = append(ready, i)
+			}
+		}
+		switch {
+		case len(ready) != 0:
+			i := ready[prog.scheduler.random.IntN(len(ready))]
+			if sends[i] {
+				return i, [2]any{}, prog.send(channels[i], values[i])
+			}
+			value, ok, err := prog.receive(channels[i])
+			return i, [2]any{value, ok}, err
+		case fallback >= 0:
+			return fallback, [2]any{}, nil
+		}
+
+		// the select without the channels blocks for good
+		var objects []any
+		for i, ch := range channels {
+			switch {
+			case ch == nil:
+			case sends[i]:
+				objects = append(objects, channelKey{ch, true})
+			default:
+				ch.receivers++
+				prog.notify(channelKey{ch, true})
+				objects = append(objects, channelKey{ch, false})
+			}
+		}
+		err := prog.wait(objects...)
+		for i, ch := range channels {
+			if ch != nil && !sends[i] {
+				ch.receivers--
+			}
+		}
+		if err != nil {
+			return 0, [2]any{}, err
+		}
+	}
+}
+
+// run runs the body of the case with the value received, the break leaves the select statement.
+func (instr *SelectInstruction) run(variables map[string]*any, i int, received [2]any) error {
+	c := instr.cases[i]
+	scope := maps.Clone(variables)
+	for i, name := range c.names {
+		if name != "" {
+			scope[name] = &received[i]
+		}
+	}
+	for _, assigment := range c.assigments {
+		if err := assigment.Execute(scope); err != nil {
+			return err
+		}
+	}
+
+	err := c.body.Execute(scope)
+	if _, ok := err.(BreakError); ok {
+		return nil
+	}
+	return err
+}
+
+// FunctionLiteralInstruction makes the closure, which shares the variables visible at its creation.
+type FunctionLiteralInstruction struct {
+	program  *Program
+	function *IntrpretatedFunction
+}
+
+func (instr *FunctionLiteralInstruction) Execute(variables map[string]*any) error {
+	instr.program.stack = append(instr.program.stack, Closure{
+		function:  instr.function,
+		variables: maps.Clone(variables),
+	})
+	return nil
+}
+
+type FloatUsingInstruction struct {
+	program *Program
+	float   float64
+}
+
+func (instr *FloatUsingInstruction) Execute(variables map[string]*any) error {
+	instr.program.stack = append(instr.program.stack, instr.float)
+	return nil
+}
+
+type NilUsingInstruction struct {
+	program *Program
+}
+
+func (instr *NilUsingInstruction) Execute(variables map[string]*any) error {
+	instr.program.stack = append(instr.program.stack, nil)
+	return nil
+}
+
+// ConstantInstruction pushes the value of the constant expression evaluated by the optimizer.
+type ConstantInstruction struct {
+	program *Program
+	value   any
+}
+
+func (instr *ConstantInstruction) Execute(variables map[string]*any) error {
+	instr.program.stack = append(instr.program.stack, CloneAny(instr.value))
+	return nil
+}
+
+// ConvertInstruction changes the representation of the value to the one of the type,
+// the conversions between types with the same representation are not emitted at all.
+type ConvertInstruction struct {
+	program     *Program
+	instruction Instruction
+	Type        Type
+}
+
+func (instr *ConvertInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	err := instr.instruction.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	instr.program.stack[stacklen] = ConvertAny(instr.program.stack[stacklen], instr.Type)
+	return nil
+}
+
+// BoxInstruction makes the interface value from the value of the concrete type.
+type BoxInstruction struct {
+	program     *Program
+	instruction Instruction
+	Type        Type
+}
+
+func (instr *BoxInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	err := instr.instruction.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	instr.program.stack[stacklen] = InterfaceValue{Type: instr.Type, Value: instr.program.stack[stacklen]}
+	return nil
+}
+
+// PackageValueInstruction pushes the variable or the constant of the standard package.
+type PackageValueInstruction struct {
+	program *Program
+	value   *PackageValue
+}
+
+func (instr *PackageValueInstruction) Execute(variables map[string]*any) error {
+	instr.program.stack = append(instr.program.stack, CloneAny(instr.value.Value))
+	return nil
+}
+
+type FunctionUsingInstruction struct {
+	program    *Program
+	functionID int
+}
+
+func (instr *FunctionUsingInstruction) Execute(variables map[string]*any) error {
+	instr.program.stack = append(instr.program.stack, instr.program.functions[instr.functionID])
+	return nil
+}
+
+type MethodUsingInstruction struct {
+	program    *Program
+	receiver   Instruction
+	functionID int
+}
+
+func (instr *MethodUsingInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	err := instr.receiver.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	instr.program.stack[stacklen] = BoundMethod{
+		function: instr.program.functions[instr.functionID],
+		receiver: instr.program.stack[stacklen],
+	}
+	return nil
+}
+
+// InterfaceMethodInstruction pushes the method value of the dynamic type of the interface value.
+type InterfaceMethodInstruction struct {
+	program   *Program
+	receiver  Instruction
+	name      string
+	signature *FunctionType
+}
+
+func (instr *InterfaceMethodInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	err := instr.receiver.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	receiver, ok := instr.program.stack[stacklen].(InterfaceValue)
+	if !ok {
+		return fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+	}
+
+	method, ok := instr.program.DynamicMethod(receiver.Type, receiver.Value, instr.name, instr.signature)
+	if !ok {
+		return fmt.Errorf("%v does not implement the method %v", reflectString(receiver.Type), instr.name)
+	}
+
+	instr.program.stack[stacklen] = method
+	return nil
+}
+
+type FunctionValueCallInstruction struct {
+	program *Program
+
+	function  Instruction
+	arguments []Instruction
+}
+
+func (instr *FunctionValueCallInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+
+	err := instr.function.Execute(variables)
+	if err != nil {
+		return err
+	}
+	for _, argument := range instr.arguments {
+		err := argument.Execute(variables)
+		if err != nil {
+			return err
+		}
+	}
+
+	function, ok := instr.program.stack[stacklen].(Function)
+	if !ok {
+		return fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+	}
+	args := slices.Clone(instr.program.stack[stacklen+1:])
+	instr.program.stack = instr.program.stack[:stacklen]
+
+	res, err := function.Call(args...)
+	instr.program.stack = append(instr.program.stack, res...)
+	return err
+}
+
+type SliceLiteralInstruction struct {
+	program  *Program
+	elements []Instruction
+}
+
+func (instr *SliceLiteralInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+
+	for _, element := range instr.elements {
+		err := element.Execute(variables)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(instr.program.stack)-stacklen != len(instr.elements) {
+		return fmt.Errorf(
+			"missmatch between return values expected: %v actual: %v",
+			len(instr.elements),
+			len(instr.program.stack)-stacklen,
+		)
+	}
+
+	res := slices.Clone(instr.program.stack[stacklen:])
+	instr.program.stack = append(instr.program.stack[:stacklen], res)
+	return nil
+}
+
+// ArrayLiteralInstruction makes the array, the elements which are not given are zero.
+type ArrayLiteralInstruction struct {
+	program  *Program
+	Type     Type
+	elements []Instruction
+}
+
+func (instr *ArrayLiteralInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	res := NewVariable(instr.Type).(*ArrayValue)
+
+	for i, element := range instr.elements {
+		err := element.Execute(variables)
+		if err != nil {
+			return err
+		}
+
+		if len(instr.program.stack) != stacklen+1 {
+			return fmt.Errorf("wrong count of return values of statement")
+		}
+
+		res.elems[i] = CloneAny(instr.program.stack[stacklen])
+		instr.program.stack = instr.program.stack[:stacklen]
+	}
+
+	instr.program.stack = append(instr.program.stack, res)
+	return nil
+}
+
+type MapLiteralInstruction struct {
+	program *Program
+	keys    []Instruction
+	values  []Instruction
+}
+
+func (instr *MapLiteralInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	res := make(map[any]any, len(instr.keys))
+
+	for i := range instr.keys {
+		err := instr.keys[i].Execute(variables)
+		if err != nil {
+			return err
+		}
+		err = instr.values[i].Execute(variables)
+		if err != nil {
+			return err
+		}
+
+		if len(instr.program.stack) != stacklen+2 {
+			return fmt.Errorf("wrong count of return values of statement")
+		}
+
+		res[instr.program.stack[stacklen]] = instr.program.stack[stacklen+1]
+		instr.program.stack = instr.program.stack[:stacklen]
+	}
+
+	instr.program.stack = append(instr.program.stack, res)
+	return nil
+}
+
+// IndexInstruction reads an element of a slice or a map,
+// Type is the element type to get the zero value of missing map keys.
+type IndexInstruction struct {
+	program   *Program
+	container Instruction
+	index     Instruction
+	Type      Type
+	reference bool
+}
+
+func (instr *IndexInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+
+	err := instr.container.Execute(variables)
+	if err != nil {
+		return err
+	}
+	err = instr.index.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+2 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	container, index := instr.program.stack[stacklen], instr.program.stack[stacklen+1]
+	instr.program.stack = instr.program.stack[:stacklen]
+	res, err := indexValue(container, index, instr.Type)
+	if err != nil {
+		return err
+	}
+
+	if !instr.reference {
+		res = CloneAny(res)
+	}
+	instr.program.stack = append(instr.program.stack, res)
+	return nil
+}
+
+// indexValue is the element of the container, the missing key of the map gives the zero value of the type.
+func indexValue(container, index any, Type Type) (any, error) {
+	if array, ok := container.(*ArrayValue); ok {
+		container = array.elems
+	}
+
+	switch container := container.(type) {
+	case []any:
+		idx := index.(int)
+		if idx < 0 || idx >= len(container) {
+			return nil, fmt.Errorf("runtime error: index out of range [%v] with length %v", idx, len(container))
+		}
+		return container[idx], nil
+	case map[any]any:
+		val, ok := container[index]
+		if !ok {
+			val = NewVariable(Type)
+		}
+		return val, nil
+	case string:
+		idx := index.(int)
+		if idx < 0 || idx >= len(container) {
+			return nil, fmt.Errorf("runtime error: index out of range [%v] with length %v", idx, len(container))
+		}
+		return container[idx], nil
+	}
+
+	return nil, fmt.Errorf("invalid operation: cannot index %v", reflect.TypeOf(container))
+}
+
+// SliceInstruction makes the slice or the substring, the omitted indices are nil.
+type SliceInstruction struct {
+	program   *Program
+	container Instruction
+	indices   [3]Instruction
+}
+
+func (instr *SliceInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+
+	err := instr.container.Execute(variables)
+	if err != nil {
+		return err
+	}
+	container := instr.program.stack[stacklen]
+	instr.program.stack = instr.program.stack[:stacklen]
+	// the slice of the array shares its elements
+	if array, ok := container.(*ArrayValue); ok {
+		container = array.elems
+	}
+
+	// the length or the capacity is the default high and max index
+	length := 0
+	switch container := container.(type) {
+	case []any:
+		length = cap(container)
+	case string:
+		length = len(container)
+	}
+	indices := [3]int{0, length, length}
+	if slice, ok := container.([]any); ok {
+		indices[1] = len(slice)
+	}
+
+	for i, index := range instr.indices {
+		if index == nil {
+			continue
+		}
+
+		err := index.Execute(variables)
+		if err != nil {
+			return err
+		}
+		if len(instr.program.stack) != stacklen+1 {
+			return fmt.Errorf("wrong count of return values of statement")
+		}
+
+		indices[i] = instr.program.stack[stacklen].(int)
+		instr.program.stack = instr.program.stack[:stacklen]
+	}
+
+	low, high, max := indices[0], indices[1], indices[2]
+	switch {
+	case max < 0 || max > length:
+		return fmt.Errorf("runtime error: slice bounds out of range [::%v] with capacity %v", max, length)
+	case high < 0 || high > max:
+		if _, ok := container.(string); ok {
+			return fmt.Errorf("runtime error: slice bounds out of range [:%v] with length %v", high, length)
+		}
+		if instr.indices[2] == nil {
+			return fmt.Errorf("runtime error: slice bounds out of range [:%v] with capacity %v", high, max)
+		}
+		return fmt.Errorf("runtime error: slice bounds out of range [:%v:%v]", high, max)
+	case low < 0 || low > high:
+		return fmt.Errorf("runtime error: slice bounds out of range [%v:%v]", low, high)
+	}
+
+	switch container := container.(type) {
+	case []any:
+		instr.program.stack = append(instr.program.stack, container[low:high:max])
+	case string:
+		instr.program.stack = append(instr.program.stack, container[low:high])
+	default:
+		return fmt.Errorf("invalid operation: cannot slice %v", reflect.TypeOf(container))
+	}
+
+	return nil
+}
+
+type IndexAssigmentInstruction struct {
+	program     *Program
+	container   Instruction
+	index       Instruction
+	instruction Instruction
+}
+
+func (instr *IndexAssigmentInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+
+	for _, instruction := range []Instruction{instr.container, instr.index, instr.instruction} {
+		err := instruction.Execute(variables)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(instr.program.stack) != stacklen+3 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	container, index, val := instr.program.stack[stacklen], instr.program.stack[stacklen+1], instr.program.stack[stacklen+2]
+	instr.program.stack = instr.program.stack[:stacklen]
+	return instr.program.setIndex(container, index, val)
+}
+
+// setIndex stores the copy of the value into the element of the container, the new key of the map is charged to the budget.
+func (prog *Program) setIndex(container, index, val any) error {
+	if array, ok := container.(*ArrayValue); ok {
+		container = array.elems
+	}
+
+	switch container := container.(type) {
+	case []any:
+		idx := index.(int)
+		if idx < 0 || idx >= len(container) {
+			return fmt.Errorf("runtime error: index out of range [%v] with length %v", idx, len(container))
+		}
+		container[idx] = CloneAny(val)
+	case map[any]any:
+		if container == nil {
+			return fmt.Errorf("assignment to entry in nil map")
+		}
+		if _, ok := container[index]; !ok {
+			err := prog.allocate(2 * slotSize)
+			if err != nil {
+				return err
+			}
+		}
+		container[index] = CloneAny(val)
+	default:
+		return fmt.Errorf("invalid operation: cannot index %v", reflect.TypeOf(container))
+	}
+
+	return nil
+}
+
+type FieldInstruction struct {
+	program   *Program
+	structure Instruction
+	index     int
+	reference bool
+}
+
+func (instr *FieldInstruction) Execute(variables map[string]*any) error {
+	field, err := fieldCell(instr.program, instr.structure, instr.index, variables)
+	if err != nil {
+		return err
+	}
+
+	if instr.reference {
+		instr.program.stack = append(instr.program.stack, *field)
+	} else {
+		instr.program.stack = append(instr.program.stack, CloneAny(*field))
+	}
+	return nil
+}
+
+// FieldAddressInstruction pushes the pointer to the field of the struct.
+type FieldAddressInstruction struct {
+	program   *Program
+	structure Instruction
+	index     int
+}
+
+func (instr *FieldAddressInstruction) Execute(variables map[string]*any) error {
+	field, err := fieldCell(instr.program, instr.structure, instr.index, variables)
+	if err != nil {
+		return err
+	}
+
+	instr.program.stack = append(instr.program.stack, field)
+	return nil
+}
+
+// fieldCell executes the struct instruction, the pointers to structs are dereferenced.
+func fieldCell(program *Program, structure Instruction, index int, variables map[string]*any) (*any, error) {
+	stacklen := len(program.stack)
+	err := structure.Execute(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(program.stack) != stacklen+1 {
+		return nil, fmt.Errorf("wrong count of return values of statement")
+	}
+
+	val := program.stack[stacklen]
+	program.stack = program.stack[:stacklen]
+	return structField(val, index)
+}
+
+// structField is the cell of the field of the struct or of the struct the pointer points to.
+func structField(val any, index int) (*any, error) {
+	if pointer, ok := val.(*any); ok || val == nil {
+		if pointer == nil {
+			return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+		}
+		val = *pointer
+	}
+
+	return val.(*StructValue).fields[index], nil
+}
+
+type VariableAddressInstruction struct {
+	program      *Program
+	variableName string
+}
+
+func (instr *VariableAddressInstruction) Execute(variables map[string]*any) error {
+	val, ok := variables[instr.variableName]
+	if !ok {
+		return fmt.Errorf("variable %v not declarated", instr.variableName)
+	}
+
+	instr.program.stack = append(instr.program.stack, val)
+	return nil
+}
+
+type DereferenceInstruction struct {
+	program   *Program
+	pointer   Instruction
+	reference bool
+}
+
+func (instr *DereferenceInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	err := instr.pointer.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	pointer, _ := instr.program.stack[stacklen].(*any)
+	if pointer == nil {
+		return fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+	}
+
+	if instr.reference {
+		instr.program.stack[stacklen] = *pointer
+	} else {
+		instr.program.stack[stacklen] = CloneAny(*pointer)
+	}
+	return nil
+}
+
+// StoreInstruction assigns the value by the pointer.
+type StoreInstruction struct {
+	program     *Program
+	address     Instruction
+	instruction Instruction
+}
+
+func (instr *StoreInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+
+	err := instr.address.Execute(variables)
+	if err != nil {
+		return err
+	}
+	err = instr.instruction.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+2 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	pointer, _ := instr.program.stack[stacklen].(*any)
+	if pointer == nil {
+		return fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+	}
+
+	*pointer = CloneAny(instr.program.stack[stacklen+1])
+	instr.program.stack = instr.program.stack[:stacklen]
+	return nil
+}
+
+// NewPointerInstruction moves the value to a new variable and pushes the pointer to it.
+type NewPointerInstruction struct {
+	program     *Program
+	instruction Instruction
+}
+
+func (instr *NewPointerInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	err := instr.instruction.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	value := instr.program.stack[stacklen]
+	instr.program.stack[stacklen] = &value
+	return nil
+}
+
+// StructLiteralInstruction fills the fields of the zero value, the omitted fields have no instructions.
+type StructLiteralInstruction struct {
+	program *Program
+	Type    Type
+	fields  []Instruction
+}
+
+func (instr *StructLiteralInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	res := NewVariable(instr.Type).(*StructValue)
+
+	for i, field := range instr.fields {
+		if field == nil {
+			continue
+		}
+
+		err := field.Execute(variables)
+		if err != nil {
+			return err
+		}
+
+		if len(instr.program.stack) != stacklen+1 {
+			return fmt.Errorf("wrong count of return values of statement")
+		}
+
+		*res.fields[i] = CloneAny(instr.program.stack[stacklen])
+		instr.program.stack = instr.program.stack[:stacklen]
+	}
+
+	instr.program.stack = append(instr.program.stack, res)
+	return nil
+}
+
+type LenInstruction struct {
+	program     *Program
+	instruction Instruction
+}
+
+func (instr *LenInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	err := instr.instruction.Execute(variables)
+	if err != nil {
+		return err
+	}
+
+	if len(instr.program.stack) != stacklen+1 {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+
+	res, err := length(instr.program.stack[stacklen])
+	if err != nil {
+		return err
+	}
+
+	instr.program.stack[stacklen] = res
+	return nil
+}
+
+// length is the result of the built-in len.
+func length(val any) (int, error) {
+	switch val := val.(type) {
+	case []any:
+		return len(val), nil
+	case *ArrayValue:
+		return len(val.elems), nil
+	case map[any]any:
+		return len(val), nil
+	case string:
+		return len(val), nil
+	case *ChannelValue:
+		return val.Len(), nil
+	case nil:
+		// the nil channel
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("invalid argument: %v for built-in len", reflect.TypeOf(val))
+}
+
+// BuiltinCallInstruction calls the implementation of the built-in function with the values of the arguments.
+type BuiltinCallInstruction struct {
+	program *Program
+	// the name of the implementation in builtinCalls and the type of the values it makes
+	name      string
+	Type      Type
+	arguments []Instruction
+	// the implementation is made again when the compiled program is loaded
+	call func(args []any) ([]any, error) `image:"-"`
+}
+
+func (instr *BuiltinCallInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+	for _, argument := range instr.arguments {
+		err := argument.Execute(variables)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(instr.program.stack)-stacklen != len(instr.arguments) {
+		return fmt.Errorf(
+			"missmatch between return values expected: %v actual: %v",
+			len(instr.arguments),
+			len(instr.program.stack)-stacklen,
+		)
+	}
+
+	args := slices.Clone(instr.program.stack[stacklen:])
+	instr.program.stack = instr.program.stack[:stacklen]
+
+	if instr.call == nil {
+		instr.call = builtinCalls[instr.name](instr.Type)
+	}
+	err := instr.program.allocate(builtinSize(instr.name, instr.Type, args))
+	if err != nil {
+		return err
+	}
+	res, err := instr.call(args)
+	if err != nil {
+		return err
+	}
+
+	instr.program.stack = append(instr.program.stack, res...)
+	return nil
+}
+
+// BuiltinFunctionInstruction makes the function value calling the built-in function,
+// the defer and the go statements call the built-in functions without results through it.
+type BuiltinFunctionInstruction struct {
+	program *Program
+	name    string
+	Type    Type
+}
+
+func (instr *BuiltinFunctionInstruction) Execute(variables map[string]*any) error {
+	var call func(args []any) ([]any, error)
+	if instr.name == "close" {
+		call = func(args []any) ([]any, error) {
+			ch, _ := args[0].(*ChannelValue)
+			return nil, instr.program.close(ch)
+		}
+	} else {
+		call = builtinCalls[instr.name](instr.Type)
+	}
+
+	instr.program.stack = append(instr.program.stack, HostFunction{
+		name: instr.name,
+		call: func(args ...any) ([]any, error) {
+			return call(args)
+		},
+	})
+	return nil
+}
+
+type AppendInstruction struct {
+	program  *Program
+	slice    Instruction
+	elements []Instruction
+	// the only element is the slice of the elements, as in append(s, t...)
+	spread bool
+}
+
+func (instr *AppendInstruction) Execute(variables map[string]*any) error {
+	stacklen := len(instr.program.stack)
+
+	err := instr.slice.Execute(variables)
+	if err != nil {
+		return err
+	}
+	for _, element := range instr.elements {
+		err := element.Execute(variables)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(instr.program.stack)-stacklen != len(instr.elements)+1 {
+		return fmt.Errorf(
+			"missmatch between return values expected: %v actual: %v",
+			len(instr.elements)+1,
+			len(instr.program.stack)-stacklen,
+		)
+	}
+
+	slice, _ := instr.program.stack[stacklen].([]any)
+	elements := instr.program.stack[stacklen+1:]
+	if instr.spread {
+		spread, _ := elements[0].([]any)
+		elements = make([]any, len(spread))
+		for i, element := range spread {
+			elements[i] = CloneAny(element)
+		}
+	}
+	err = instr.program.allocateAppend(slice, len(elements))
+	if err != nil {
+		return err
+	}
+	res := append(slice, elements...)
+	instr.program.stack = append(instr.program.stack[:stacklen], res)
+	return nil
 }
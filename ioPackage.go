@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "io",
+		Path:    "io",
+		Imports: []string{"errors"},
+		Declare: declareIO,
+	})
+
+	hostSentinels[io.EOF] = "io.EOF"
+	hostSentinels[io.ErrUnexpectedEOF] = "io.ErrUnexpectedEOF"
+}
+
+// writeSignature is the signature of the Write method of io.Writer.
+var writeSignature = &FunctionType{
+	Params: []Type{&SliceType{Elem: Uint8Type}},
+	Result: &TupleType{Types: []Type{IntType, ErrorType}},
+}
+
+// readSignature is the signature of the Read method of io.Reader.
+var readSignature = &FunctionType{
+	Params: []Type{&SliceType{Elem: Uint8Type}},
+	Result: &TupleType{Types: []Type{IntType, ErrorType}},
+}
+
+func declareIO(prog *Program, pkg *Package) {
+	prog.declareType(pkg, "Writer", &InterfaceType{Methods: []*Method{
+		{Name: "Write", Signature: writeSignature},
+	}})
+	prog.declareType(pkg, "Reader", &InterfaceType{Methods: []*Method{
+		{Name: "Read", Signature: readSignature},
+	}})
+
+	prog.RegisterValue(pkg.QualifiedName("EOF"), &PackageValue{Type: ErrorType, Value: prog.NewError(io.EOF.Error())})
+	prog.RegisterValue(pkg.QualifiedName("ErrUnexpectedEOF"), &PackageValue{Type: ErrorType, Value: prog.NewError(io.ErrUnexpectedEOF.Error())})
+}
+
+// Write writes the string to the io.Writer value with its Write method.
+func (prog *Program) Write(writer any, str string) ([]any, error) {
+	iface, ok := writer.(InterfaceValue)
+	if !ok {
+		return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+	}
+
+	method, ok := prog.DynamicMethod(iface.Type, iface.Value, "Write", writeSignature)
+	if !ok {
+		return nil, fmt.Errorf("%v does not implement io.Writer", reflectString(iface.Type))
+	}
+
+	return method.Call(StringToSlice(str, Uint8Type))
+}
+
+// hostReader is the io.Reader of the host which reads from the io.Reader value of the script.
+type hostReader struct {
+	prog *Program
+	read Function
+}
+
+// Reader makes the host reader of the io.Reader value, the nil reader fails when it is read.
+func (prog *Program) Reader(reader any) io.Reader {
+	res := hostReader{prog: prog}
+	if iface, ok := reader.(InterfaceValue); ok {
+		res.read, _ = prog.DynamicMethod(iface.Type, iface.Value, "Read", readSignature)
+	}
+
+	return res
+}
+
+func (r hostReader) Read(p []byte) (int, error) {
+	buf := make([]any, len(p))
+	for i := range buf {
+		buf[i] = uint8(0)
+	}
+
+	res := callScript(r.read, buf)
+	n := res[0].(int)
+	for i := range n {
+		p[i] = buf[i].(uint8)
+	}
+
+	return n, r.prog.toHostError(res[1])
+}
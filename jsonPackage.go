@@ -0,0 +1,642 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "json",
+		Path:    "encoding/json",
+		Imports: []string{"errors"},
+		Declare: declareJSON,
+	})
+
+	hostErrors[reflect.TypeOf(&json.SyntaxError{})] = func(prog *Program, err error) any {
+		return InterfaceValue{
+			Type:  prog.jsonSyntaxErrorType,
+			Value: NewPointer(NewStructValue(err.Error(), int(err.(*json.SyntaxError).Offset))),
+		}
+	}
+}
+
+func declareJSON(prog *Program, pkg *Package) {
+	byteSlice := &SliceType{Elem: Uint8Type}
+
+	syntaxError := prog.declareType(pkg, "SyntaxError", &StructType{Fields: []*Field{
+		{Name: "msg", Type: StringType},
+		{Name: "Offset", Type: IntType},
+	}, pkg: pkg})
+	prog.jsonSyntaxErrorType = &PointerType{Elem: syntaxError}
+	prog.declareMethod(syntaxError, "Error", signature([]Type{prog.jsonSyntaxErrorType}, StringType), hostGetter(0))
+
+	prog.jsonErrors = map[string]func(msg string) any{}
+	for _, name := range []string{"InvalidUnmarshalError", "UnmarshalTypeError", "UnsupportedTypeError", "UnsupportedValueError"} {
+		errorType := prog.declareType(pkg, name, hostStruct(pkg, &Field{Name: "msg", Type: StringType}))
+		errorPointer := &PointerType{Elem: errorType}
+		prog.declareMethod(errorType, "Error", signature([]Type{errorPointer}, StringType), hostGetter(0))
+
+		prog.jsonErrors[name] = func(msg string) any {
+			return InterfaceValue{Type: errorPointer, Value: NewPointer(NewStructValue(msg))}
+		}
+	}
+
+	prog.declareFunction(pkg, "Marshal", signature([]Type{AnyType}, byteSlice, ErrorType), func(args ...any) ([]any, error) {
+		data, err := prog.marshalJSON(args[0])
+		if err != nil {
+			return []any{[]any(nil), err}, nil
+		}
+		return []any{StringToSlice(data, Uint8Type), nil}, nil
+	})
+
+	prog.declareFunction(pkg, "MarshalIndent", signature([]Type{AnyType, StringType, StringType}, byteSlice, ErrorType), func(args ...any) ([]any, error) {
+		data, err := prog.marshalJSON(args[0])
+		if err != nil {
+			return []any{[]any(nil), err}, nil
+		}
+
+		var indented bytes.Buffer
+		json.Indent(&indented, []byte(data), args[1].(string), args[2].(string))
+		return []any{StringToSlice(indented.String(), Uint8Type), nil}, nil
+	})
+
+	prog.declareFunction(pkg, "Unmarshal", signature([]Type{byteSlice, AnyType}, ErrorType), func(args ...any) ([]any, error) {
+		return []any{prog.unmarshalJSON([]byte(SliceToString(args[0].([]any))), args[1])}, nil
+	})
+
+	prog.declareFunction(pkg, "Valid", signature([]Type{byteSlice}, BoolType), func(args ...any) ([]any, error) {
+		return []any{json.Valid([]byte(SliceToString(args[0].([]any))))}, nil
+	})
+}
+
+// jsonEncoder encodes the values of the script the way encoding/json encodes the Go values.
+type jsonEncoder struct {
+	prog *Program
+	buf  strings.Builder
+	// the pointers being encoded, the cycles are reported
+	seen map[*any]bool
+}
+
+// marshalJSON gives the encoding of the value or the error value of the json package.
+func (prog *Program) marshalJSON(value any) (string, any) {
+	encoder := &jsonEncoder{prog: prog, seen: map[*any]bool{}}
+	if iface, ok := value.(InterfaceValue); ok {
+		if err := encoder.encode(iface.Type, iface.Value); err != nil {
+			return "", err
+		}
+	} else {
+		encoder.buf.WriteString("null")
+	}
+
+	return encoder.buf.String(), nil
+}
+
+func (prog *Program) unsupportedType(Type Type) any {
+	return prog.jsonErrors["UnsupportedTypeError"]("json: unsupported type: " + reflectString(Type))
+}
+
+func (prog *Program) unsupportedValue(str string) any {
+	return prog.jsonErrors["UnsupportedValueError"]("json: unsupported value: " + str)
+}
+
+// quoteJSON quotes the string with the escapes of encoding/json, the HTML characters are escaped too.
+func quoteJSON(str string) string {
+	res, _ := json.Marshal(str)
+	return string(res)
+}
+
+func (e *jsonEncoder) encode(Type Type, value any) any {
+	switch underlying := Type.Underlying().(type) {
+	case *BasicType:
+		switch value := value.(type) {
+		case bool:
+			e.buf.WriteString(strconv.FormatBool(value))
+		case int:
+			e.buf.WriteString(strconv.Itoa(value))
+		case int32:
+			e.buf.WriteString(strconv.Itoa(int(value)))
+		case uint8:
+			e.buf.WriteString(strconv.Itoa(int(value)))
+		case float64:
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return e.prog.unsupportedValue(strconv.FormatFloat(value, 'g', -1, 64))
+			}
+			res, _ := json.Marshal(value)
+			e.buf.Write(res)
+		case string:
+			e.buf.WriteString(quoteJSON(value))
+		default:
+			return e.prog.unsupportedType(Type)
+		}
+	case *InterfaceType:
+		iface, ok := value.(InterfaceValue)
+		if !ok {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.encode(iface.Type, iface.Value)
+	case *PointerType:
+		cell, _ := value.(*any)
+		if cell == nil {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if e.seen[cell] {
+			return e.prog.unsupportedValue("encountered a cycle via " + reflectString(Type))
+		}
+		e.seen[cell] = true
+		defer delete(e.seen, cell)
+		return e.encode(underlying.Elem, *cell)
+	case *SliceType:
+		slice := value.([]any)
+		if slice == nil {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if kind, _ := basicKind(underlying.Elem); kind == Uint8 {
+			e.buf.WriteString(`"` + base64.StdEncoding.EncodeToString([]byte(SliceToString(slice))) + `"`)
+			return nil
+		}
+
+		e.buf.WriteByte('[')
+		for i, elem := range slice {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.encode(underlying.Elem, elem); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+	case *ArrayType:
+		// the arrays of the bytes are the arrays of the numbers, unlike the slices
+		e.buf.WriteByte('[')
+		for i, elem := range value.(*ArrayValue).elems {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.encode(underlying.Elem, elem); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+	case *MapType:
+		key := underlying.Key.Underlying()
+		if !IsString(key) && !IsInteger(key) {
+			return e.prog.unsupportedType(Type)
+		}
+		m := value.(map[any]any)
+		if m == nil {
+			e.buf.WriteString("null")
+			return nil
+		}
+
+		// the keys are sorted by their encodings
+		keys := make([]string, 0, len(m))
+		values := map[string]any{}
+		for k, v := range m {
+			str := fmt.Sprint(k)
+			if r, ok := k.(int32); ok {
+				str = strconv.Itoa(int(r))
+			}
+			keys = append(keys, str)
+			values[str] = v
+		}
+		sort.Strings(keys)
+
+		e.buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.buf.WriteString(quoteJSON(k) + ":")
+			if err := e.encode(underlying.Elem, values[k]); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+	case *StructType:
+		e.buf.WriteByte('{')
+		first := true
+		for _, field := range jsonFields(underlying) {
+			fieldValue := *value.(*StructValue).fields[field.index]
+			if field.omitEmpty && isEmptyJSON(fieldValue) {
+				continue
+			}
+
+			if !first {
+				e.buf.WriteByte(',')
+			}
+			first = false
+			e.buf.WriteString(quoteJSON(field.name) + ":")
+			if err := e.encode(field.Type, fieldValue); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+	default:
+		return e.prog.unsupportedType(Type)
+	}
+
+	return nil
+}
+
+// jsonField is the field of the struct encoded by the json package.
+type jsonField struct {
+	*Field
+	index     int
+	name      string
+	tagged    bool
+	omitEmpty bool
+}
+
+// jsonFields gives the exported fields with their names from the json tags,
+// the fields with the same name are dropped, unless only one of them is tagged.
+func jsonFields(structType *StructType) []*jsonField {
+	fields := make([]*jsonField, 0, len(structType.Fields))
+	count := map[string]int{}
+	tagged := map[string]int{}
+
+	for i, field := range structType.Fields {
+		if !IsExported(field.Name) {
+			continue
+		}
+
+		tag := reflect.StructTag(field.Tag).Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		res := &jsonField{Field: field, index: i, name: name, tagged: isValidJSONTag(name)}
+		if !res.tagged {
+			res.name = field.Name
+		}
+		for _, option := range strings.Split(options, ",") {
+			res.omitEmpty = res.omitEmpty || option == "omitempty"
+		}
+
+		count[res.name]++
+		if res.tagged {
+			tagged[res.name]++
+		}
+		fields = append(fields, res)
+	}
+
+	res := make([]*jsonField, 0, len(fields))
+	for _, field := range fields {
+		if count[field.name] == 1 || tagged[field.name] == 1 && field.tagged {
+			res = append(res, field)
+		}
+	}
+	return res
+}
+
+func isValidJSONTag(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, r := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", r):
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			return false
+		}
+	}
+	return true
+}
+
+func isEmptyJSON(value any) bool {
+	switch value := value.(type) {
+	case bool:
+		return !value
+	case int, int32, uint8, float64, string:
+		return reflect.ValueOf(value).IsZero()
+	case []any:
+		return len(value) == 0
+	case map[any]any:
+		return len(value) == 0
+	case nil:
+		return true
+	case *any:
+		return value == nil
+	}
+
+	return false
+}
+
+// jsonMember is the member of the JSON object, the decoder keeps the order of the members.
+type jsonMember struct {
+	key   string
+	value any
+}
+
+// parseJSON parses the valid JSON text into the objects with the ordered members,
+// the arrays, the numbers, the strings, the booleans and nil.
+func parseJSON(decoder *json.Decoder) any {
+	token, _ := decoder.Token()
+
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '[' {
+			res := make([]any, 0)
+			for decoder.More() {
+				res = append(res, parseJSON(decoder))
+			}
+			decoder.Token()
+			return res
+		}
+
+		res := make([]jsonMember, 0)
+		for decoder.More() {
+			key, _ := decoder.Token()
+			res = append(res, jsonMember{key: key.(string), value: parseJSON(decoder)})
+		}
+		decoder.Token()
+		return res
+	}
+
+	return token
+}
+
+// jsonDecoder stores the parsed JSON value to the variables of the script,
+// the mismatched values are skipped and the first mismatch is reported.
+type jsonDecoder struct {
+	prog *Program
+	// the name of the struct the decoded value is, it starts the paths of the fields in the errors
+	structName string
+	err        any
+}
+
+func (prog *Program) unmarshalJSON(data []byte, target any) any {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return prog.hostError(err)
+	}
+
+	iface, ok := target.(InterfaceValue)
+	if !ok {
+		return prog.jsonErrors["InvalidUnmarshalError"]("json: Unmarshal(nil)")
+	}
+	pointerType, ok := iface.Type.Underlying().(*PointerType)
+	if !ok {
+		return prog.jsonErrors["InvalidUnmarshalError"]("json: Unmarshal(non-pointer " + reflectString(iface.Type) + ")")
+	}
+	if isNilPointer(iface.Value) {
+		return prog.jsonErrors["InvalidUnmarshalError"]("json: Unmarshal(nil " + reflectString(iface.Type) + ")")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	d := &jsonDecoder{prog: prog}
+	if namedType, ok := pointerType.Elem.(*NamedType); ok {
+		if _, ok := namedType.Underlying().(*StructType); ok {
+			d.structName = namedType.name
+		}
+	}
+	d.decode(parseJSON(decoder), pointerType.Elem, iface.Value.(*any), nil)
+
+	return d.err
+}
+
+// jsonKind is the kind of the JSON value in the errors.
+func jsonKind(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case []any:
+		return "array"
+	}
+
+	return "object"
+}
+
+func (d *jsonDecoder) mismatch(kind string, Type Type, path []string) {
+	if d.err != nil {
+		return
+	}
+
+	msg := "json: cannot unmarshal " + kind + " into Go value of type " + reflectString(Type)
+	if len(path) != 0 {
+		msg = "json: cannot unmarshal " + kind + " into Go struct field " + d.structName + "." + strings.Join(path, ".") + " of type " + reflectString(Type)
+	}
+	d.err = d.prog.jsonErrors["UnmarshalTypeError"](msg)
+}
+
+func (d *jsonDecoder) decode(value any, Type Type, cell *any, path []string) {
+	if value == nil {
+		switch Type.Underlying().(type) {
+		case *InterfaceType, *PointerType, *MapType, *SliceType:
+			*cell = NewVariable(Type)
+		}
+		return
+	}
+
+	switch underlying := Type.Underlying().(type) {
+	case *PointerType:
+		if isNilPointer(*cell) {
+			*cell = NewPointer(NewVariable(underlying.Elem))
+		}
+		d.decode(value, underlying.Elem, (*cell).(*any), path)
+	case *InterfaceType:
+		if len(underlying.Methods) != 0 {
+			d.mismatch(jsonKind(value), Type, path)
+			return
+		}
+		*cell = jsonInterface(value)
+	case *BasicType:
+		d.decodeBasic(value, Type, cell, path)
+	case *SliceType:
+		if str, ok := value.(string); ok {
+			if kind, _ := basicKind(underlying.Elem); kind == Uint8 {
+				data, err := base64.StdEncoding.DecodeString(str)
+				if err != nil {
+					if d.err == nil {
+						d.err = d.prog.hostError(err)
+					}
+					return
+				}
+				*cell = StringToSlice(string(data), Uint8Type)
+				return
+			}
+		}
+
+		array, ok := value.([]any)
+		if !ok {
+			d.mismatch(jsonKind(value), Type, path)
+			return
+		}
+		res := make([]any, len(array))
+		for i, elem := range array {
+			res[i] = NewVariable(underlying.Elem)
+			d.decode(elem, underlying.Elem, &res[i], append(path, strconv.Itoa(i)))
+		}
+		*cell = res
+	case *ArrayType:
+		array, ok := value.([]any)
+		if !ok {
+			d.mismatch(jsonKind(value), Type, path)
+			return
+		}
+		// the extra elements are dropped and the missing ones are zero
+		res := NewVariable(Type).(*ArrayValue)
+		for i := range res.elems {
+			if i < len(array) {
+				d.decode(array[i], underlying.Elem, &res.elems[i], append(path, strconv.Itoa(i)))
+			}
+		}
+		*cell = res
+	case *MapType:
+		object, ok := value.([]jsonMember)
+		key := underlying.Key.Underlying()
+		if !ok || !IsString(key) && !IsInteger(key) {
+			d.mismatch(jsonKind(value), Type, path)
+			return
+		}
+		if *cell == nil || (*cell).(map[any]any) == nil {
+			*cell = map[any]any{}
+		}
+
+		m := (*cell).(map[any]any)
+		for _, member := range object {
+			var k any = member.key
+			if IsInteger(key) {
+				n, err := strconv.ParseInt(member.key, 10, 64)
+				if err != nil || !fitsInteger(n, key) {
+					d.mismatch("number "+member.key, underlying.Key, append(path, member.key))
+					continue
+				}
+				k = ConvertAny(int(n), key)
+			}
+
+			elem := NewVariable(underlying.Elem)
+			d.decode(member.value, underlying.Elem, &elem, append(path, member.key))
+			m[k] = elem
+		}
+	case *StructType:
+		object, ok := value.([]jsonMember)
+		if !ok {
+			d.mismatch(jsonKind(value), Type, path)
+			return
+		}
+
+		fields := jsonFields(underlying)
+		for _, member := range object {
+			field := lookupJSONField(fields, member.key)
+			if field == nil {
+				continue
+			}
+			d.decode(member.value, field.Type, (*cell).(*StructValue).fields[field.index], append(path, member.key))
+		}
+	default:
+		d.mismatch(jsonKind(value), Type, path)
+	}
+}
+
+// lookupJSONField finds the field by its name, the case is ignored if no name matches exactly.
+func lookupJSONField(fields []*jsonField, name string) *jsonField {
+	for _, field := range fields {
+		if field.name == name {
+			return field
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.name, name) {
+			return field
+		}
+	}
+
+	return nil
+}
+
+func (d *jsonDecoder) decodeBasic(value any, Type Type, cell *any, path []string) {
+	underlying := Type.Underlying()
+
+	switch value := value.(type) {
+	case bool:
+		if IsBoolean(underlying) {
+			*cell = value
+			return
+		}
+	case string:
+		if IsString(underlying) {
+			*cell = value
+			return
+		}
+	case json.Number:
+		switch {
+		case IsInteger(underlying):
+			n, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil || !fitsInteger(n, underlying) {
+				d.mismatch("number "+string(value), Type, path)
+				return
+			}
+			*cell = ConvertAny(int(n), underlying)
+			return
+		case IsFloat(underlying):
+			f, err := strconv.ParseFloat(string(value), 64)
+			if err != nil {
+				d.mismatch("number "+string(value), Type, path)
+				return
+			}
+			*cell = f
+			return
+		}
+	}
+
+	d.mismatch(jsonKind(value), Type, path)
+}
+
+// fitsInteger reports whether the integer type holds the value.
+func fitsInteger(n int64, Type Type) bool {
+	switch kind, _ := basicKind(Type); kind {
+	case Int32:
+		return n >= math.MinInt32 && n <= math.MaxInt32
+	case Uint8:
+		return n >= 0 && n <= math.MaxUint8
+	}
+
+	return true
+}
+
+// jsonInterface gives the value stored to the empty interface: float64, string, bool, []any or map[string]any.
+func jsonInterface(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		f, _ := value.Float64()
+		return InterfaceValue{Type: Float64Type, Value: f}
+	case string:
+		return InterfaceValue{Type: StringType, Value: value}
+	case bool:
+		return InterfaceValue{Type: BoolType, Value: value}
+	case []any:
+		res := make([]any, len(value))
+		for i, elem := range value {
+			res[i] = jsonInterface(elem)
+		}
+		return InterfaceValue{Type: &SliceType{Elem: AnyType}, Value: res}
+	case []jsonMember:
+		res := map[any]any{}
+		for _, member := range value {
+			res[member.key] = jsonInterface(member.value)
+		}
+		return InterfaceValue{Type: &MapType{Key: StringType, Elem: AnyType}, Value: res}
+	}
+
+	return nil
+}
@@ -1,65 +1,342 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/antlr4-go/antlr/v4"
 	"github.com/jessevdk/go-flags"
-	"github.com/karetskiiVO/GOInterpreter/parser"
 )
 
+// runOptions are the options the program runs with, the one of the script or the compiled one.
+type runOptions struct {
+	Root         string `long:"root" value-name:"DIR" description:"the directory the script may access the files in, the directory of the script by default"`
+	Optimization int    `short:"O" long:"optimize" value-name:"LEVEL" default:"1" description:"the optimization level of the code, 0 turns the optimizations off, 2 inlines the small functions"`
+	DumpIR       bool   `long:"dump-ir" description:"write the lowered code, the SSA form and the bytecode of the functions after each optimization pass to stderr"`
+	Backend      string `long:"backend" choice:"code" choice:"closures" choice:"vm" default:"vm" description:"how the lowered code runs: the dispatch over its operations, the Go closures compiled from it or the bytecode of the register machine"`
+	VirtualTime  bool   `long:"virtual-time" description:"run the script on the virtual clock which starts at 2009-11-10 23:00:00 UTC and moves only by sleeping"`
+	MaxCallDepth int    `long:"max-call-depth" value-name:"DEPTH" default:"100000" description:"the depth of the calls beyond which the stack overflows with the runtime panic, 0 is no limit"`
+	TailCalls    bool   `long:"tail-calls" description:"run the function which returns the call of itself in the same call, so the recursive loops don't grow the stack"`
+
+	MaxSteps int64         `long:"max-steps" value-name:"N" description:"the count of the operations the script may execute, 0 is no limit"`
+	Timeout  time.Duration `long:"timeout" value-name:"DURATION" description:"the wall-clock time the script may run, like 1.5s or 2m, 0 is no limit"`
+	MaxHeap  byteSize      `long:"max-heap" value-name:"SIZE" description:"the memory the script may allocate in bytes, like 65536, 512KB or 64MB, the memory the collector frees is not given back, 0 is no limit"`
+}
+
+// byteSize is the size in bytes with the optional KB, MB or GB suffix, the units are 1024 times bigger each.
+type byteSize uint64
+
+func (size *byteSize) UnmarshalFlag(value string) error {
+	number, unit := strings.ToUpper(value), uint64(1)
+	for i, suffix := range []string{"KB", "MB", "GB"} {
+		if strings.HasSuffix(number, suffix) {
+			number, unit = strings.TrimSuffix(number, suffix), 1<<(10*(i+1))
+			break
+		}
+	}
+
+	res, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %q", value)
+	}
+	*size = byteSize(res * unit)
+
+	return nil
+}
+
+// newProgram makes the program with the options, the first argument is the path of the script.
+func (options *runOptions) newProgram(args []string) *Program {
+	program := NewProgram()
+	program.Sandbox = Sandbox{
+		Args: args,
+		Root: options.Root,
+	}
+	if program.Sandbox.Root == "" {
+		program.Sandbox.Root = scriptDirectory(args[0])
+	}
+	program.Optimization = options.Optimization
+	program.Backend = options.Backend
+	program.MaxCallDepth = options.MaxCallDepth
+	program.TailCalls = options.TailCalls
+	program.MaxSteps = options.MaxSteps
+	program.MaxHeap = uint64(options.MaxHeap)
+	if options.DumpIR {
+		program.IRDump = os.Stderr
+	}
+	if options.VirtualTime {
+		program.Clock = NewVirtualClock()
+	}
+
+	return program
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "build":
+			os.Exit(build(os.Args[2:]))
+		case "compile":
+			os.Exit(compileImage(os.Args[2:]))
+		case "run":
+			os.Exit(runImage(os.Args[2:]))
+		}
+	}
+
 	var options struct {
+		runOptions
+		Cache   string `long:"cache" value-name:"DIR" description:"the directory the compiled scripts are kept in, the user cache directory by default"`
+		NoCache bool   `long:"no-cache" description:"compile the script each time it runs"`
+
 		Args struct {
-			SourceFileName string
-		} `positional-args:"yes" required:"1"`
+			SourcePath string   `positional-arg-name:"script" required:"yes"`
+			Rest       []string `positional-arg-name:"args"`
+		} `positional-args:"yes"`
 	}
 
-	flagsParser := flags.NewParser(&options, flags.Default&(^flags.PrintErrors))
+	// the arguments after the script are the arguments of the script
+	flagsParser := flags.NewParser(&options, flags.Default&(^flags.PrintErrors)|flags.PassAfterNonOption)
 	_, err := flagsParser.Parse()
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	input, err := antlr.NewFileStream(options.Args.SourceFileName)
+	args := append([]string{options.Args.SourcePath}, options.Args.Rest...)
+	newProgram := func() *Program {
+		return options.newProgram(args)
+	}
+
+	var cache *Cache
+	if !options.NoCache {
+		cache = OpenCache(options.Cache)
+	}
+	program := cache.Load(options.Args.SourcePath, newProgram)
+	if program == nil {
+		loader := NewPackageLoader()
+		_, err = loader.LoadMain(options.Args.SourcePath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		program = newProgram()
+		declare(program, loader)
+		externals := program.snapshot()
+		errs := compile(program, loader)
+		if len(errs) != 0 {
+			for _, err := range errs {
+				fmt.Println(err)
+			}
+
+			os.Exit(1)
+		}
+
+		// the script runs anyway when it can't be cached
+		cache.Save(options.Args.SourcePath, program, externals, loader)
+	}
+
+	os.Exit(options.execute(program))
+}
+
+// compileImage is the compile command: the script is compiled into the image, which the run command runs.
+func compileImage(args []string) int {
+	var options struct {
+		Output string `short:"o" long:"output" value-name:"FILE" required:"yes" description:"the file the compiled program is written to"`
+
+		Args struct {
+			SourcePath string `positional-arg-name:"script" required:"yes"`
+		} `positional-args:"yes"`
+	}
+
+	flagsParser := flags.NewNamedParser("compile", flags.Default&(^flags.PrintErrors))
+	flagsParser.AddGroup("Compile Options", "", &options)
+	_, err := flagsParser.ParseArgs(args)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		return 1
 	}
 
-	lexer := parser.NewGoLexer(input)
-	stream := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
-	parser := parser.NewGoParser(stream)
-
-	tree := parser.Program() // Начинаем с корневого узла
+	loader := NewPackageLoader()
+	_, err = loader.LoadMain(options.Args.SourcePath)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
 
 	program := NewProgram()
-
-	declarationListner := NewGoDeclarationListener(program)
-	antlr.ParseTreeWalkerDefault.Walk(declarationListner, tree)
-	if len(declarationListner.Errors) != 0 {
-		for _, err := range declarationListner.Errors {
+	declare(program, loader)
+	externals := program.snapshot()
+	errs := compile(program, loader)
+	if len(errs) != 0 {
+		for _, err := range errs {
 			fmt.Println(err)
 		}
+		return 1
+	}
 
-		os.Exit(1)
+	file, err := os.Create(options.Output)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	err = program.Save(file, filepath.Dir(options.Output), externals, loader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Remove(options.Output)
+		return 1
 	}
 
-	compileListner := NewGoCompilerListener(program)
-	antlr.ParseTreeWalkerDefault.Walk(compileListner, tree)
-	if len(compileListner.Errors) != 0 {
-		for _, err := range compileListner.Errors {
-			fmt.Println(err)
+	return 0
+}
+
+// runImage is the run command: the compiled program runs the way the script does.
+func runImage(args []string) int {
+	var options struct {
+		runOptions
+
+		Args struct {
+			ImagePath string   `positional-arg-name:"image" required:"yes"`
+			Rest      []string `positional-arg-name:"args"`
+		} `positional-args:"yes"`
+	}
+
+	flagsParser := flags.NewNamedParser("run", flags.Default&(^flags.PrintErrors)|flags.PassAfterNonOption)
+	flagsParser.AddGroup("Run Options", "", &options)
+	_, err := flagsParser.ParseArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	file, err := os.Open(options.Args.ImagePath)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	img, err := readImage(file)
+	file.Close()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	// the files are the ones of the script, not the ones next to the image
+	if options.Root == "" {
+		options.Root = img.Root
+		if !filepath.IsAbs(options.Root) {
+			options.Root = filepath.Join(filepath.Dir(options.Args.ImagePath), options.Root)
 		}
+	}
+	program := options.newProgram(append([]string{options.Args.ImagePath}, options.Args.Rest...))
+	err = program.Load(img)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
 
-		os.Exit(1)
+	return options.execute(program)
+}
+
+// execute runs the program within the time limit, the result is the exit code.
+func (options *runOptions) execute(program *Program) int {
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
 	}
 
-	err = program.Execute()
+	err := program.Execute(ctx)
+	if exit, ok := err.(ExitError); ok {
+		return exit.Code
+	}
+	// the panic ends the program the same way in main and in the other goroutines
+	goroutine := 1
+	if panicked, ok := err.(GoroutinePanicError); ok {
+		goroutine, err = panicked.Goroutine, panicked.Err
+	} else if fatal(err) {
+		fmt.Fprintln(os.Stderr, "fatal error:", err)
+		return 2
+	}
 	if err != nil {
-		fmt.Println("panic:", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "panic: %v\n\ngoroutine %v [running]\n", err, goroutine)
+		if overflow, ok := err.(StackOverflowError); ok {
+			fmt.Fprint(os.Stderr, "\n", overflow.Trace())
+		}
+		return 2
+	}
+
+	return 0
+}
+
+// declare declares the standard packages the program imports.
+func declare(program *Program, loader *PackageLoader) {
+	for _, pkg := range loader.Packages {
+		if pkg.Std != nil {
+			pkg.Std.Declare(program, pkg)
+		}
+	}
+}
+
+// compile compiles the program with the standard packages declared, the errors are the ones of the first failed pass.
+func compile(program *Program, loader *PackageLoader) []error {
+	typeErrors := make([]error, 0)
+	for _, pkg := range loader.Packages {
+		for _, file := range pkg.Files {
+			typeListner := NewGoTypeListener(program, pkg, file)
+			antlr.ParseTreeWalkerDefault.Walk(typeListner, file.Tree)
+			typeErrors = append(typeErrors, typeListner.Errors...)
+		}
+	}
+	if len(typeErrors) != 0 {
+		return typeErrors
 	}
+
+	declarationErrors := make([]error, 0)
+	for _, pkg := range loader.Packages {
+		for _, file := range pkg.Files {
+			declarationListner := NewGoDeclarationListener(program, pkg, file)
+			antlr.ParseTreeWalkerDefault.Walk(declarationListner, file.Tree)
+			declarationErrors = append(declarationErrors, declarationListner.Errors...)
+		}
+	}
+	if len(declarationErrors) != 0 {
+		return declarationErrors
+	}
+
+	compileErrors := make([]error, 0)
+	for _, pkg := range loader.Packages {
+		for _, file := range pkg.Files {
+			// the generic functions are compiled per instance
+			for _, function := range file.Tree.AllFunctionDefinition() {
+				if IsGeneric(function) {
+					continue
+				}
+
+				compileListner := NewGoCompilerListener(program, pkg, file)
+				antlr.ParseTreeWalkerDefault.Walk(compileListner, function)
+				compileErrors = append(compileErrors, compileListner.Errors...)
+			}
+		}
+	}
+	compileErrors = append(compileErrors, program.CompileInstances()...)
+
+	return compileErrors
+}
+
+// scriptDirectory is the directory of the script file or the directory of the package itself.
+func scriptDirectory(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path
+	}
+
+	return filepath.Dir(path)
 }
@@ -0,0 +1,79 @@
+package main
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "maps",
+		Path:    "maps",
+		Declare: declareMaps,
+	})
+}
+
+// declareMaps declares Keys and Values, they give the iterators over the map ordered by the keys,
+// so the scripts get the same output every time.
+func declareMaps(prog *Program, pkg *Package) {
+	typeParams := []string{"K", "V"}
+	constraints := []Type{ComparableType, AnyType}
+
+	prog.declareGeneric(pkg, "Keys", typeParams, constraints, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		K := typeArgs[0]
+		seq := seqType(K)
+		return signature([]Type{&MapType{Key: K, Elem: typeArgs[1]}}, seq), func(args ...any) ([]any, error) {
+			m := args[0].(map[any]any)
+			return []any{sequence("maps.Keys", seq, func() []any {
+				return sortedKeys(K, m)
+			})}, nil
+		}
+	})
+	prog.declareGeneric(pkg, "Values", typeParams, constraints, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		K, V := typeArgs[0], typeArgs[1]
+		seq := seqType(V)
+		return signature([]Type{&MapType{Key: K, Elem: V}}, seq), func(args ...any) ([]any, error) {
+			m := args[0].(map[any]any)
+			return []any{sequence("maps.Values", seq, func() []any {
+				res := sortedKeys(K, m)
+				for i, key := range res {
+					res[i] = CloneAny(m[key])
+				}
+				return res
+			})}, nil
+		}
+	})
+}
+
+// seqType is the type of the iterator over the values of the type, iter.Seq.
+func seqType(elem Type) *FunctionType {
+	return signature([]Type{signature([]Type{elem}, BoolType)})
+}
+
+// sequence makes the iterator over the values, they are taken when the iteration starts.
+func sequence(name string, seq *FunctionType, values func() []any) Function {
+	return GenericFunction{
+		name:      name + ".func1",
+		signature: seq,
+		handler: func(args ...any) (res []any, err error) {
+			defer catchScriptError(&err)
+
+			yield, _ := args[0].(Function)
+			for _, value := range values() {
+				if !callScript(yield, value)[0].(bool) {
+					break
+				}
+			}
+			return nil, nil
+		},
+	}
+}
+
+// collect gathers the values of the iterator.
+func collect(seq Function, elem Type) []any {
+	res := make([]any, 0)
+	callScript(seq, GenericFunction{
+		name:      "slices.Collect.func1",
+		signature: signature([]Type{elem}, BoolType),
+		handler: func(args ...any) ([]any, error) {
+			res = append(res, CloneAny(args[0]))
+			return []any{true}, nil
+		},
+	})
+	return res
+}
@@ -0,0 +1,96 @@
+package main
+
+import "math"
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "math",
+		Path:    "math",
+		Declare: declareMath,
+	})
+}
+
+func declareMath(prog *Program, pkg *Package) {
+	prog.declareHostFunctions(pkg, map[string]any{
+		"Abs":         math.Abs,
+		"Acos":        math.Acos,
+		"Asin":        math.Asin,
+		"Atan":        math.Atan,
+		"Atan2":       math.Atan2,
+		"Cbrt":        math.Cbrt,
+		"Ceil":        math.Ceil,
+		"Copysign":    math.Copysign,
+		"Cos":         math.Cos,
+		"Cosh":        math.Cosh,
+		"Dim":         math.Dim,
+		"Exp":         math.Exp,
+		"Exp2":        math.Exp2,
+		"Floor":       math.Floor,
+		"Frexp":       math.Frexp,
+		"Hypot":       math.Hypot,
+		"Inf":         math.Inf,
+		"IsInf":       math.IsInf,
+		"IsNaN":       math.IsNaN,
+		"Ldexp":       math.Ldexp,
+		"Log":         math.Log,
+		"Log10":       math.Log10,
+		"Log1p":       math.Log1p,
+		"Log2":        math.Log2,
+		"Max":         math.Max,
+		"Min":         math.Min,
+		"Mod":         math.Mod,
+		"Modf":        math.Modf,
+		"NaN":         math.NaN,
+		"Pow":         math.Pow,
+		"Pow10":       math.Pow10,
+		"Remainder":   math.Remainder,
+		"Round":       math.Round,
+		"RoundToEven": math.RoundToEven,
+		"Signbit":     math.Signbit,
+		"Sin":         math.Sin,
+		"Sinh":        math.Sinh,
+		"Sqrt":        math.Sqrt,
+		"Tan":         math.Tan,
+		"Tanh":        math.Tanh,
+		"Trunc":       math.Trunc,
+	})
+
+	// the constants which don't fit into int or float64 are not declared
+	for name, value := range map[string]float64{
+		"E":                      math.E,
+		"Pi":                     math.Pi,
+		"Phi":                    math.Phi,
+		"Sqrt2":                  math.Sqrt2,
+		"SqrtE":                  math.SqrtE,
+		"SqrtPi":                 math.SqrtPi,
+		"SqrtPhi":                math.SqrtPhi,
+		"Ln2":                    math.Ln2,
+		"Log2E":                  math.Log2E,
+		"Ln10":                   math.Ln10,
+		"Log10E":                 math.Log10E,
+		"MaxFloat32":             math.MaxFloat32,
+		"SmallestNonzeroFloat32": math.SmallestNonzeroFloat32,
+		"MaxFloat64":             math.MaxFloat64,
+		"SmallestNonzeroFloat64": math.SmallestNonzeroFloat64,
+	} {
+		prog.declareConstant(pkg, name, UntypedFloatType, value)
+	}
+
+	for name, value := range map[string]int{
+		"MaxInt":    math.MaxInt,
+		"MinInt":    math.MinInt,
+		"MaxInt8":   math.MaxInt8,
+		"MinInt8":   math.MinInt8,
+		"MaxInt16":  math.MaxInt16,
+		"MinInt16":  math.MinInt16,
+		"MaxInt32":  math.MaxInt32,
+		"MinInt32":  math.MinInt32,
+		"MaxInt64":  math.MaxInt64,
+		"MinInt64":  math.MinInt64,
+		"MaxUint8":  math.MaxUint8,
+		"MaxUint16": math.MaxUint16,
+		"MaxUint32": math.MaxUint32,
+	} {
+		prog.declareConstant(pkg, name, UntypedIntType, value)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// pass is the optimization of the lowered code, it runs if the optimization level is high enough.
+type pass struct {
+	name  string
+	level int
+	run   func(c *Code)
+}
+
+var passes = []pass{
+	{"fold", 1, (*Code).foldConstants},
+	{"branches", 1, (*Code).foldBranches},
+	{"jumps", 1, (*Code).threadJumps},
+	{"dead", 1, (*Code).eliminateDeadCode},
+}
+
+// optimize runs the passes of the optimization level on the code and the bodies of its range loops,
+// the code is dumped after each pass.
+func (prog *Program) optimize(name string, c *Code) {
+	prog.dumpCode(name, "lower", c)
+	for _, pass := range passes {
+		if pass.level > prog.Optimization {
+			continue
+		}
+
+		c.walk(pass.run)
+		prog.dumpCode(name, pass.name, c)
+	}
+}
+
+// walk runs the pass on the code and on the bodies of the range loops in it.
+func (c *Code) walk(run func(c *Code)) {
+	run(c)
+	for _, body := range c.bodies {
+		body.walk(run)
+	}
+}
+
+// foldConstants evaluates the expressions of the constants once, the expressions failing at run time are left as they are.
+func (c *Code) foldConstants() {
+	for i := range c.ops {
+		if c.ops[i].instruction != nil {
+			c.ops[i].instruction = c.fold(c.ops[i].instruction)
+		}
+	}
+}
+
+func (c *Code) fold(instruction Instruction) Instruction {
+	constant := true
+	children := func(instructions []Instruction) {
+		for i, child := range instructions {
+			instructions[i] = c.fold(child)
+			constant = constant && isConstant(instructions[i])
+		}
+	}
+
+	switch instr := instruction.(type) {
+	case *AddInstruction:
+		children(instr.instructions)
+	case *SubInstruction:
+		children(instr.instructions)
+	case *MulInstruction:
+		children(instr.instructions)
+	case *DivInstruction:
+		children(instr.instructions)
+	case *OrInstruction:
+		children(instr.instructions)
+	case *AndInstruction:
+		children(instr.instructions)
+	case *NotInstruction:
+		instr.instruction = c.fold(instr.instruction)
+		constant = isConstant(instr.instruction)
+	case *ConvertInstruction:
+		instr.instruction = c.fold(instr.instruction)
+		constant = isConstant(instr.instruction)
+	case *CompareInstruction:
+		instr.lhv, instr.rhv = c.fold(instr.lhv), c.fold(instr.rhv)
+		constant = isConstant(instr.lhv) && isConstant(instr.rhv)
+	case *BoxInstruction:
+		instr.instruction = c.fold(instr.instruction)
+		return instr
+	case *DefineVariableInstruction:
+		if instr.value != nil {
+			instr.value = c.fold(instr.value)
+		}
+		return instr
+	case *AssigmentInstruction:
+		instr.instruction = c.fold(instr.instruction)
+		return instr
+	case *FunctionCallInstruction:
+		children(instr.arguments)
+		return instr
+	case *ReturnInstruction:
+		children(instr.expressions)
+		return instr
+	default:
+		return instruction
+	}
+	if !constant {
+		return instruction
+	}
+
+	value, ok := c.evaluate(instruction)
+	if !ok {
+		return instruction
+	}
+
+	return &ConstantInstruction{program: c.program, value: value}
+}
+
+// evaluate runs the constant expression, the expression which fails, even by the panic of the host, is not evaluated.
+func (c *Code) evaluate(instruction Instruction) (res any, ok bool) {
+	stacklen := len(c.program.stack)
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+		c.program.stack = c.program.stack[:stacklen]
+	}()
+
+	if err := instruction.Execute(nil); err != nil || len(c.program.stack) != stacklen+1 {
+		return nil, false
+	}
+
+	return c.program.stack[stacklen], true
+}
+
+// isConstant reports that the instruction pushes the same basic value each time.
+func isConstant(instruction Instruction) bool {
+	switch instr := instruction.(type) {
+	case *ConstantInstruction, *IntUsingInstruction, *FloatUsingInstruction, *StringUsingInstruction, *BoolUsingInstruction, *RuneUsingInstruction:
+		return true
+	case *PackageValueInstruction:
+		return instr.value.Constant
+	}
+
+	return false
+}
+
+// foldBranches turns the conditional jumps on the constant conditions into the plain jumps or removes them.
+func (c *Code) foldBranches() {
+	for i := range c.ops {
+		jump := &c.ops[i]
+		if jump.code != opJumpIfFalse || !isConstant(jump.instruction) {
+			continue
+		}
+
+		condition, ok := c.evaluate(jump.instruction)
+		if !ok {
+			continue
+		}
+
+		if condition.(bool) {
+			*jump = op{code: opNop}
+		} else {
+			jump.code, jump.instruction = opJump, nil
+		}
+	}
+}
+
+// threadJumps makes the jumps to the plain jumps go right to their targets.
+func (c *Code) threadJumps() {
+	for i := range c.ops {
+		op := &c.ops[i]
+		if op.code != opJump && op.code != opJumpIfFalse {
+			continue
+		}
+
+		// the loop of the jumps is the endless loop, it is left as it is
+		for range c.ops {
+			if op.target >= len(c.ops) || c.ops[op.target].code != opJump {
+				break
+			}
+
+			next := c.ops[op.target]
+			op.target, op.depth = next.target, min(op.depth, next.depth)
+		}
+	}
+}
+
+// eliminateDeadCode removes the operations which are never reached and the empty ones.
+func (c *Code) eliminateDeadCode() {
+	reached := make([]bool, len(c.ops)+1)
+	queue := []int{0}
+	// the labels are the targets of the goto statements of the range loop bodies
+	for _, label := range c.labels {
+		queue = append(queue, label.pc)
+	}
+	for len(queue) > 0 {
+		pc := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if reached[pc] {
+			continue
+		}
+		reached[pc] = true
+		if pc == len(c.ops) {
+			continue
+		}
+
+		switch op := c.ops[pc]; op.code {
+		case opJump:
+			queue = append(queue, op.target)
+		case opJumpIfFalse:
+			queue = append(queue, pc+1, op.target)
+		case opReturn, opTailCall, opBreak, opGoto:
+		default:
+			queue = append(queue, pc+1)
+		}
+	}
+
+	// the removed operation is replaced by the next kept one
+	index := make([]int, len(c.ops)+1)
+	ops := make([]op, 0, len(c.ops))
+	for pc, op := range c.ops {
+		index[pc] = len(ops)
+		if reached[pc] && op.code != opNop {
+			ops = append(ops, op)
+		}
+	}
+	index[len(c.ops)] = len(ops)
+
+	for i := range ops {
+		if ops[i].code == opJump || ops[i].code == opJumpIfFalse {
+			ops[i].target = index[ops[i].target]
+		}
+	}
+	for name, label := range c.labels {
+		c.labels[name] = position{pc: index[label.pc], depth: label.depth}
+	}
+	c.ops = ops
+}
+
+// dumpCode writes the code after the pass, if the dump is requested.
+func (prog *Program) dumpCode(name, pass string, c *Code) {
+	if prog.IRDump == nil {
+		return
+	}
+
+	fmt.Fprintf(prog.IRDump, "%v after %v:\n", name, pass)
+	c.dump(prog.IRDump, "\t")
+}
+
+// eliminateTailCalls turns the returns the compiler has found to be the tail calls into them,
+// the bodies of the range loops have none: they can't start the function again.
+func (c *Code) eliminateTailCalls() {
+	for i, op := range c.ops {
+		if op.code == opReturn && op.instruction.(*ReturnInstruction).tail {
+			c.ops[i].code = opTailCall
+		}
+	}
+}
+
+func (c *Code) dump(w io.Writer, indent string) {
+	for pc, op := range c.ops {
+		fmt.Fprintf(w, "%v%4d  %v\n", indent, pc, op)
+		switch instr := op.instruction.(type) {
+		case *RangeInstruction:
+			instr.body.(*CodeInstruction).code.dump(w, indent+"\t")
+		case *SelectInstruction:
+			for _, c := range instr.cases {
+				c.body.(*CodeInstruction).code.dump(w, indent+"\t")
+			}
+		}
+	}
+}
+
+func (op op) String() string {
+	switch op.code {
+	case opExec:
+		return "exec " + describeInstruction(op.instruction)
+	case opJump:
+		return fmt.Sprintf("jump %v depth %v", op.target, op.depth)
+	case opJumpIfFalse:
+		return fmt.Sprintf("jump-if-false %v %v depth %v", describeInstruction(op.instruction), op.target, op.depth)
+	case opEnterScope:
+		return "enter"
+	case opLeaveScope:
+		return "leave"
+	case opReturn:
+		return "return " + describeInstructions(op.instruction.(*ReturnInstruction).expressions, ", ")
+	case opTailCall:
+		return "tail-call " + describeInstructions(op.instruction.(*ReturnInstruction).expressions, ", ")
+	case opBreak:
+		return "break"
+	case opGoto:
+		return "goto " + op.label
+	case opNop:
+		return "nop"
+	}
+
+	return fmt.Sprintf("op(%d)", op.code)
+}
+
+// describeInstruction writes the instruction tree for the dump of the code.
+func describeInstruction(instruction Instruction) string {
+	switch instr := instruction.(type) {
+	case *ConstantInstruction:
+		return fmt.Sprintf("%#v", instr.value)
+	case *IntUsingInstruction:
+		return fmt.Sprint(instr.integer)
+	case *FloatUsingInstruction:
+		return fmt.Sprint(instr.float)
+	case *StringUsingInstruction:
+		return fmt.Sprintf("%q", instr.str)
+	case *BoolUsingInstruction:
+		return fmt.Sprint(instr.boolVal)
+	case *RuneUsingInstruction:
+		return fmt.Sprintf("%q", instr.value)
+	case *VariableUsingInstruction:
+		return instr.variableName
+	case *AddInstruction:
+		return "(" + describeInstructions(instr.instructions, " + ") + ")"
+	case *SubInstruction:
+		return "(" + describeInstructions(instr.instructions, " - ") + ")"
+	case *MulInstruction:
+		return "(" + describeInstructions(instr.instructions, " * ") + ")"
+	case *DivInstruction:
+		return "(" + describeInstructions(instr.instructions, " / ") + ")"
+	case *OrInstruction:
+		return "(" + describeInstructions(instr.instructions, " || ") + ")"
+	case *AndInstruction:
+		return "(" + describeInstructions(instr.instructions, " && ") + ")"
+	case *NotInstruction:
+		return "!" + describeInstruction(instr.instruction)
+	case *CompareInstruction:
+		return "(" + describeInstruction(instr.lhv) + " " + instr.compareType + " " + describeInstruction(instr.rhv) + ")"
+	case *ConvertInstruction:
+		return fmt.Sprintf("%v(%v)", instr.Type, describeInstruction(instr.instruction))
+	case *DefineVariableInstruction:
+		if instr.value == nil {
+			return fmt.Sprintf("var %v %v", instr.Name, instr.Type)
+		}
+		return fmt.Sprintf("var %v = %v", instr.Name, describeInstruction(instr.value))
+	case *AssigmentInstruction:
+		return fmt.Sprintf("%v = %v", instr.varName, describeInstruction(instr.instruction))
+	case *FunctionCallInstruction:
+		return fmt.Sprintf("%v(%v)", instr.program.functions[instr.functionID].Name(), describeInstructions(instr.arguments, ", "))
+	case *RangeInstruction:
+		return fmt.Sprintf("range %v", describeInstruction(instr.container))
+	case *SendInstruction:
+		return fmt.Sprintf("%v <- %v", describeInstruction(instr.channel), describeInstruction(instr.value))
+	case *ReceiveInstruction:
+		return "<-" + describeInstruction(instr.channel)
+	}
+
+	return strings.TrimSuffix(reflect.TypeOf(instruction).Elem().Name(), "Instruction")
+}
+
+func describeInstructions(instructions []Instruction, sep string) string {
+	res := make([]string, len(instructions))
+	for i, instruction := range instructions {
+		res[i] = describeInstruction(instruction)
+	}
+
+	return strings.Join(res, sep)
+}
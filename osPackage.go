@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"syscall"
+
+	"github.com/karetskiiVO/GOInterpreter/goshim"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "os",
+		Path:    "os",
+		Imports: []string{"errors", "io"},
+		Declare: declareOS,
+	})
+
+	for _, sentinel := range osSentinels {
+		hostSentinels[sentinel.err] = "os." + sentinel.name
+	}
+	hostErrors[reflect.TypeOf(&fs.PathError{})] = func(prog *Program, err error) any {
+		pathError := err.(*fs.PathError)
+		return InterfaceValue{
+			Type:  prog.pathErrorType,
+			Value: NewPointer(NewStructValue(pathError.Op, pathError.Path, prog.hostError(pathError.Err))),
+		}
+	}
+	hostErrors[reflect.TypeOf(syscall.Errno(0))] = func(prog *Program, err error) any {
+		for _, sentinel := range osSentinels {
+			if errors.Is(err, sentinel.err) {
+				kind := prog.values["os."+sentinel.name].Value
+				return InterfaceValue{Type: prog.syscallErrorType, Value: NewPointer(NewStructValue(err.Error(), kind))}
+			}
+		}
+		return prog.NewError(err.Error())
+	}
+}
+
+// ExitError is the exit of the script by os.Exit, it stops the program like the fatal errors do.
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("exit status %v", e.Code)
+}
+
+// Sandbox is what the scripts can reach of the host system through the os package.
+type Sandbox struct {
+	Args []string
+	// the files are accessed only under the root directory
+	Root string
+}
+
+// resolve gives the host path of the file of the script, the paths out of the root are rejected.
+func (s *Sandbox) resolve(op, name string) (string, error) {
+	return goshim.Resolve(s.Root, op, name)
+}
+
+// osSentinels are the errors of the os package the errors of the host system are matched with.
+var osSentinels = []struct {
+	name string
+	err  error
+}{
+	{"ErrInvalid", fs.ErrInvalid},
+	{"ErrPermission", fs.ErrPermission},
+	{"ErrExist", fs.ErrExist},
+	{"ErrNotExist", fs.ErrNotExist},
+	{"ErrClosed", fs.ErrClosed},
+}
+
+func declareOS(prog *Program, pkg *Package) {
+	for _, sentinel := range osSentinels {
+		prog.RegisterValue(pkg.QualifiedName(sentinel.name), &PackageValue{Type: ErrorType, Value: prog.NewError(sentinel.err.Error())})
+	}
+
+	pathError := prog.declareType(pkg, "PathError", &StructType{Fields: []*Field{
+		{Name: "Op", Type: StringType},
+		{Name: "Path", Type: StringType},
+		{Name: "Err", Type: ErrorType},
+	}, pkg: pkg})
+	prog.pathErrorType = &PointerType{Elem: pathError}
+	prog.declareMethod(pathError, "Error", signature([]Type{prog.pathErrorType}, StringType), func(args ...any) ([]any, error) {
+		fields, err := hostMethod(func(receiver *StructValue, args ...any) []any {
+			return []any{*receiver.fields[0], *receiver.fields[1], *receiver.fields[2]}
+		})(args...)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := prog.ErrorMessage(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		return []any{fields[0].(string) + " " + fields[1].(string) + ": " + msg}, nil
+	})
+	prog.declareMethod(pathError, "Unwrap", signature([]Type{prog.pathErrorType}, ErrorType), hostGetter(2))
+
+	// the error of the host system is matched with the sentinel of its kind
+	syscallError := prog.declareType(pkg, "syscallError", hostStruct(pkg,
+		&Field{Name: "msg", Type: StringType},
+		&Field{Name: "kind", Type: ErrorType},
+	))
+	prog.syscallErrorType = &PointerType{Elem: syscallError}
+	prog.declareMethod(syscallError, "Error", signature([]Type{prog.syscallErrorType}, StringType), hostGetter(0))
+	prog.declareMethod(syscallError, "Is", signature([]Type{prog.syscallErrorType, ErrorType}, BoolType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		equal, err := EqualAny(*receiver.fields[1], args[0])
+		return []any{err == nil && equal.(bool)}
+	}))
+
+	declareFile(prog, pkg)
+
+	args := make([]any, len(prog.Sandbox.Args))
+	for i, arg := range prog.Sandbox.Args {
+		args[i] = arg
+	}
+	prog.RegisterValue(pkg.QualifiedName("Args"), &PackageValue{Type: &SliceType{Elem: StringType}, Value: args})
+
+	prog.declareFunction(pkg, "Exit", signature([]Type{IntType}), func(args ...any) ([]any, error) {
+		return nil, ExitError{Code: args[0].(int)}
+	})
+	prog.declareHostFunctions(pkg, map[string]any{
+		"Getenv":    os.Getenv,
+		"LookupEnv": os.LookupEnv,
+		"ReadFile": func(name string) ([]byte, error) {
+			path, err := prog.Sandbox.resolve("open", name)
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := os.ReadFile(path)
+			return data, goshim.ScriptPath(err, name)
+		},
+		"Remove": func(name string) error {
+			path, err := prog.Sandbox.resolve("remove", name)
+			if err != nil {
+				return err
+			}
+
+			return goshim.ScriptPath(os.Remove(path), name)
+		},
+		"WriteFile": func(name string, data []byte, perm int) error {
+			path, err := prog.Sandbox.resolve("open", name)
+			if err != nil {
+				return err
+			}
+
+			return goshim.ScriptPath(os.WriteFile(path, data, fs.FileMode(perm)), name)
+		},
+	})
+}
+
+func declareFile(prog *Program, pkg *Package) {
+	// the file keeps the descriptor of the host file
+	file := prog.declareType(pkg, "File", hostStruct(pkg,
+		&Field{Name: "fd", Type: IntType},
+		&Field{Name: "name", Type: StringType},
+	))
+	filePointer := &PointerType{Elem: file}
+	bytes := &SliceType{Elem: Uint8Type}
+
+	prog.declareMethod(file, "Write", signature([]Type{filePointer, bytes}, IntType, ErrorType), func(args ...any) ([]any, error) {
+		return prog.writeFile(args[0], SliceToString(args[1].([]any)))
+	})
+	prog.declareMethod(file, "WriteString", signature([]Type{filePointer, StringType}, IntType, ErrorType), func(args ...any) ([]any, error) {
+		return prog.writeFile(args[0], args[1].(string))
+	})
+
+	prog.declareMethod(file, "Name", signature([]Type{filePointer}, StringType), hostGetter(1))
+	prog.declareMethod(file, "Read", signature([]Type{filePointer, bytes}, IntType, ErrorType), func(args ...any) ([]any, error) {
+		_, file, err := prog.hostFile(args[0], "read")
+		if err != nil {
+			return []any{0, prog.hostError(err)}, nil
+		}
+
+		p := args[1].([]any)
+		buf := make([]byte, len(p))
+		n, err := file.Read(buf)
+		for i := range n {
+			p[i] = buf[i]
+		}
+		if err != nil {
+			return []any{n, prog.hostError(err)}, nil
+		}
+
+		return []any{n, nil}, nil
+	})
+
+	prog.declareMethod(file, "Close", signature([]Type{filePointer}, ErrorType), func(args ...any) ([]any, error) {
+		fd, file, err := prog.hostFile(args[0], "close")
+		if err != nil {
+			return []any{prog.hostError(err)}, nil
+		}
+
+		// the standard streams stay open for the interpreter
+		if fd <= 2 {
+			return []any{nil}, nil
+		}
+
+		delete(prog.hostFiles, fd)
+		if err := file.Close(); err != nil {
+			return []any{prog.hostError(err)}, nil
+		}
+
+		return []any{nil}, nil
+	})
+
+	prog.declareFunction(pkg, "Open", signature([]Type{StringType}, filePointer, ErrorType), func(args ...any) ([]any, error) {
+		name := args[0].(string)
+
+		path, err := prog.Sandbox.resolve("open", name)
+		if err != nil {
+			return []any{nil, prog.hostError(err)}, nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return []any{nil, prog.hostError(goshim.ScriptPath(err, name))}, nil
+		}
+
+		fd := prog.nextFD
+		prog.nextFD++
+		prog.hostFiles[fd] = file
+
+		return []any{NewPointer(NewStructValue(fd, name)), nil}, nil
+	})
+
+	for _, stream := range []struct {
+		name string
+		fd   int
+	}{{"Stdin", 0}, {"Stdout", 1}, {"Stderr", 2}} {
+		prog.RegisterValue(pkg.QualifiedName(stream.name), &PackageValue{
+			Type:  filePointer,
+			Value: NewPointer(NewStructValue(stream.fd, prog.hostFiles[stream.fd].Name())),
+		})
+	}
+}
+
+// hostFile gives the host file of the *os.File value, the errors are the ones of the os package.
+func (prog *Program) hostFile(receiver any, op string) (int, *os.File, error) {
+	if isNilPointer(receiver) {
+		return 0, nil, fs.ErrInvalid
+	}
+
+	fields := (*receiver.(*any)).(*StructValue).fields
+	fd, name := (*fields[0]).(int), (*fields[1]).(string)
+	file, ok := prog.hostFiles[fd]
+	if !ok {
+		return 0, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrClosed}
+	}
+
+	return fd, file, nil
+}
+
+func (prog *Program) writeFile(receiver any, str string) ([]any, error) {
+	_, file, err := prog.hostFile(receiver, "write")
+	if err != nil {
+		return []any{0, prog.hostError(err)}, nil
+	}
+
+	n, err := file.WriteString(str)
+	if err != nil {
+		return []any{n, prog.hostError(err)}, nil
+	}
+
+	return []any{n, nil}, nil
+}
+
+// closeFiles closes the files the script has left open, the standard streams stay open.
+func (prog *Program) closeFiles() {
+	for fd, file := range prog.hostFiles {
+		if fd > 2 {
+			file.Close()
+			delete(prog.hostFiles, fd)
+		}
+	}
+	clear(prog.hostScanners)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const exit = `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	defer fmt.Println("deferred");
+	os.Exit(3);
+}
+`
+
+const exitGoroutine = `package main
+
+import "os"
+
+func main() {
+	done := make(chan int);
+	go func() {
+		os.Exit(4);
+	}();
+	<-done;
+}
+`
+
+const leak = `package main
+
+import "os"
+
+func main() {
+	os.Stdout.Close();
+	os.Stdout.WriteString("open\n");
+	for range 3 {
+		os.Open("data.txt");
+	}
+}
+`
+
+func TestExit(t *testing.T) {
+	for _, backend := range backends {
+		for _, test := range []struct {
+			name   string
+			source string
+			code   int
+		}{{"main", exit, 3}, {"goroutine", exitGoroutine, 4}} {
+			t.Run(test.name+"/"+backend, func(t *testing.T) {
+				prog := compileScript(t, test.source)
+				prog.Backend = backend
+
+				var err error
+				output := captureOutput(t, func() {
+					err = prog.Execute(context.Background())
+				})
+				exited, ok := err.(ExitError)
+				if !ok || exited.Code != test.code {
+					t.Fatalf("the error is %T %q, the exit with %v is expected", err, err, test.code)
+				}
+				if output != "" {
+					t.Errorf("the deferred calls have run on exit: %q", output)
+				}
+			})
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			prog := compileScript(t, leak)
+			prog.Backend = backend
+			prog.Sandbox.Root = t.TempDir()
+			err := os.WriteFile(filepath.Join(prog.Sandbox.Root, "data.txt"), nil, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output := captureOutput(t, func() {
+				// the standard output of the script is the captured one
+				prog.hostFiles[1] = os.Stdout
+				err = prog.Execute(context.Background())
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output != "open\n" {
+				t.Errorf("the output is %q, the standard output is closed by the script", output)
+			}
+			if len(prog.hostFiles) != 3 {
+				t.Errorf("%v files are left open after the run", len(prog.hostFiles)-3)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/antlr4-go/antlr/v4"
+	"github.com/karetskiiVO/GOInterpreter/parser"
+)
+
+// MainPackagePath is the symbol prefix of the main package, the same way
+// the Go linker names it regardless of the module path.
+const MainPackagePath = "main"
+
+type Package struct {
+	Name  string
+	Path  string
+	Dir   string
+	Files []*SourceFile
+
+	// the standard package implemented by the interpreter, it has no files
+	Std *StdPackage
+}
+
+type SourceFile struct {
+	Name    string
+	Tree    parser.IProgramContext
+	Imports map[string]*Package
+	// the returns of the calls of the functions themselves, the built programs find them by it
+	TailCalls map[*parser.FunctionReturnContext]bool
+	// the names of the functions by their bodies, the built programs trace the calls by them
+	Functions map[*parser.BlockContext]string
+}
+
+func (pkg *Package) QualifiedName(name string) string {
+	return pkg.Path + "." + name
+}
+
+func IsExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+func ParseFile(fileName string) (parser.IProgramContext, error) {
+	input, err := antlr.NewFileStream(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	lexer := parser.NewGoLexer(input)
+	stream := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
+	goParser := parser.NewGoParser(stream)
+
+	return goParser.Program(), nil
+}
+
+// PackageLoader parses the main package and every local package it imports.
+// Local import paths are resolved against the module path from go.mod.
+type PackageLoader struct {
+	modulePath string
+	moduleDir  string
+
+	packages map[string]*Package
+	loading  map[string]bool
+
+	// Packages are stored in dependency order, the main package is the last one.
+	Packages []*Package
+}
+
+func NewPackageLoader() *PackageLoader {
+	return &PackageLoader{
+		packages: map[string]*Package{},
+		loading:  map[string]bool{},
+		Packages: make([]*Package, 0),
+	}
+}
+
+// LoadMain loads a single source file or a directory as the main package.
+func (l *PackageLoader) LoadMain(path string) (*Package, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path
+	var fileNames []string
+	if info.IsDir() {
+		fileNames, err = packageFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		dir = filepath.Dir(path)
+		fileNames = []string{path}
+	}
+
+	err = l.findModule(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, err := l.loadPackage(MainPackagePath, dir, fileNames)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.Name != "main" {
+		return nil, fmt.Errorf("package %v is not a main package", pkg.Name)
+	}
+
+	return pkg, nil
+}
+
+func (l *PackageLoader) importPackage(importPath string) (*Package, error) {
+	if pkg, ok := l.packages[importPath]; ok {
+		return pkg, nil
+	}
+	if l.loading[importPath] {
+		return nil, fmt.Errorf("import cycle not allowed: %v", importPath)
+	}
+	if std, ok := stdPackages[importPath]; ok {
+		return l.loadStdPackage(std)
+	}
+
+	if l.modulePath == "" {
+		return nil, fmt.Errorf("package %v is not found: there is no go.mod", importPath)
+	}
+	relativePath, ok := strings.CutPrefix(importPath, l.modulePath)
+	if !ok || (relativePath != "" && relativePath[0] != '/') {
+		return nil, fmt.Errorf("package %v is not in module %v", importPath, l.modulePath)
+	}
+
+	dir := filepath.Join(l.moduleDir, filepath.FromSlash(relativePath))
+	fileNames, err := packageFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, err := l.loadPackage(importPath, dir, fileNames)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.Name == "main" {
+		return nil, fmt.Errorf("import \"%v\" is a program, not an importable package", importPath)
+	}
+
+	return pkg, nil
+}
+
+func (l *PackageLoader) loadPackage(importPath, dir string, fileNames []string) (*Package, error) {
+	if len(fileNames) == 0 {
+		return nil, fmt.Errorf("no Go files in %v", dir)
+	}
+
+	l.loading[importPath] = true
+	defer delete(l.loading, importPath)
+
+	pkg := &Package{
+		Path:  importPath,
+		Dir:   dir,
+		Files: make([]*SourceFile, 0, len(fileNames)),
+	}
+
+	for _, fileName := range fileNames {
+		tree, err := ParseFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		packageName := tree.Package().NAME().GetText()
+		if pkg.Name == "" {
+			pkg.Name = packageName
+		} else if pkg.Name != packageName {
+			return nil, fmt.Errorf(
+				"found packages %v (%v) and %v (%v) in %v",
+				pkg.Name, filepath.Base(pkg.Files[0].Name),
+				packageName, filepath.Base(fileName),
+				dir,
+			)
+		}
+
+		file := &SourceFile{
+			Name:    fileName,
+			Tree:    tree,
+			Imports: map[string]*Package{},
+		}
+
+		for _, importDeclaration := range tree.AllImportDeclaration() {
+			for _, importSpec := range importDeclaration.AllImportSpec() {
+				err := l.importSpec(file, importSpec)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		pkg.Files = append(pkg.Files, file)
+	}
+
+	l.packages[importPath] = pkg
+	l.Packages = append(l.Packages, pkg)
+
+	return pkg, nil
+}
+
+// loadStdPackage adds the standard package after the standard packages it depends on.
+func (l *PackageLoader) loadStdPackage(std *StdPackage) (*Package, error) {
+	l.loading[std.Path] = true
+	defer delete(l.loading, std.Path)
+
+	for _, importPath := range std.Imports {
+		_, err := l.importPackage(importPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pkg := &Package{
+		Name: std.Name,
+		Path: std.Path,
+		Std:  std,
+	}
+
+	l.packages[std.Path] = pkg
+	l.Packages = append(l.Packages, pkg)
+
+	return pkg, nil
+}
+
+func (l *PackageLoader) importSpec(file *SourceFile, ctx parser.IImportSpecContext) error {
+	importPath, err := strconv.Unquote(ctx.STRING().GetText())
+	if err != nil {
+		return fmt.Errorf("%v: invalid import path %v", file.Name, ctx.STRING().GetText())
+	}
+
+	pkg, err := l.importPackage(importPath)
+	if err != nil {
+		return err
+	}
+
+	name := pkg.Name
+	if ctx.NAME() != nil {
+		name = ctx.NAME().GetText()
+	}
+	if _, ok := file.Imports[name]; ok {
+		return fmt.Errorf("%v: %v redeclared in this block", file.Name, name)
+	}
+
+	file.Imports[name] = pkg
+	return nil
+}
+
+// findModule looks for go.mod in dir and its parents.
+// A script outside of any module can still run, but can't import local packages.
+func (l *PackageLoader) findModule(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	for {
+		modulePath, err := readModulePath(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			l.modulePath = modulePath
+			l.moduleDir = dir
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func readModulePath(fileName string) (string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+
+		modulePath, err := strconv.Unquote(fields[1])
+		if err != nil {
+			modulePath = fields[1]
+		}
+		return modulePath, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("%v: no module declaration", fileName)
+}
+
+func packageFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fileNames := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		fileNames = append(fileNames, filepath.Join(dir, name))
+	}
+	sort.Strings(fileNames)
+
+	return fileNames, nil
+}
@@ -1,43 +1,144 @@
 package main
 
-import "fmt"
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
 
 type Program struct {
 	functions  []Function
 	functionID map[string]int
 
+	types       map[string]Type
+	typeAliases map[string]*TypeAlias
+	// the variables and the constants of the standard packages
+	values map[string]*PackageValue
+	// the types of the errors the standard packages make, nil until the package is declared
+	errorStringType     *PointerType
+	pathErrorType       *PointerType
+	syscallErrorType    *PointerType
+	jsonSyntaxErrorType *PointerType
+	// the constructors of the errors of the json package by their type names
+	jsonErrors map[string]func(msg string) any
+
+	typeTemplates     map[string]*TypeTemplate
+	functionTemplates map[string]*FunctionTemplate
+	// the instances of the generic functions waiting for compilation
+	instances []*instance
+	// the instances of the generic functions of the standard packages by their names,
+	// they are made again when the compiled program is loaded
+	hostInstances map[string]hostInstance
+
 	stack []any
+
+	// what the scripts can reach of the host system
+	Sandbox Sandbox
+	// the host files behind the descriptors of os.File, the standard streams are 0, 1 and 2
+	hostFiles map[int]*os.File
+	// the descriptor of the next opened file, the descriptors are not reused
+	nextFD int
+	// the host scanners behind the handles of bufio.Scanner, the zero handle is no scanner
+	hostScanners map[int]*bufio.Scanner
+	// the time the scripts see, the wall clock by default
+	Clock Clock
+
+	// the optimization level of the lowered code, 0 turns the optimizations off
+	Optimization int
+	// where the lowered code is written after each optimization pass, nil if it is not needed
+	IRDump io.Writer
+	// how the lowered code runs: BackendCode, BackendClosures or BackendVM
+	Backend string
+	// the machines which run the bytecode, free for the next call from the host
+	machines []*machine
+
+	// the depth of the calls of the script functions beyond which the stack overflows, 0 is no limit
+	MaxCallDepth int
+	// the function which returns the call of itself runs again in the same call instead
+	TailCalls bool
+	// the functions being called, the innermost is the last
+	calls []string
+	// the panics the deferred calls run with, the innermost is the last
+	panics []*panicState
+	// the goroutines, the calls and the panics above are the ones of the current goroutine
+	scheduler scheduler
+
+	// the count of the operations the script may execute, 0 is no limit
+	MaxSteps int64
+	// the bytes the script may allocate by make, append, the new keys of the maps and the string addition, 0 is no limit
+	MaxHeap uint64
+	// what the run has spent, the deadline and the cancellation come with the context of the run
+	budget budget
 }
 
+// DefaultMaxCallDepth is deep enough for the recursive scripts and keeps the host stack far from its limit.
+const DefaultMaxCallDepth = 100000
+
 func NewProgram() *Program {
 	res := &Program{
-		functions:  make([]Function, 0),
-		functionID: map[string]int{},
-		stack: make([]any, 0),
+		functions:         make([]Function, 0),
+		functionID:        map[string]int{},
+		types:             map[string]Type{},
+		typeAliases:       map[string]*TypeAlias{},
+		values:            map[string]*PackageValue{},
+		typeTemplates:     map[string]*TypeTemplate{},
+		functionTemplates: map[string]*FunctionTemplate{},
+		hostInstances:     map[string]hostInstance{},
+		stack:             make([]any, 0),
+		hostFiles:         map[int]*os.File{0: os.Stdin, 1: os.Stdout, 2: os.Stderr},
+		nextFD:            3,
+		hostScanners:      map[int]*bufio.Scanner{},
+		Clock:             wallClock{},
+		Backend:           BackendVM,
+		MaxCallDepth:      DefaultMaxCallDepth,
+		budget:            budget{ctx: context.Background()},
+	}
+
+	for _, basicType := range []*BasicType{BoolType, IntType, Int32Type, Uint8Type, Float64Type, Complex128Type, StringType} {
+		res.RegisterType(basicType.name, basicType)
 	}
+	res.RegisterType("rune", Int32Type)
+	res.RegisterType("byte", Uint8Type)
+	res.RegisterType("any", AnyType)
+	res.RegisterType("comparable", ComparableType)
+	res.RegisterType("error", ErrorType)
 
 	res.RegisterFunction(GenericFunction{
-		name: "print",
-		handler: func(args ...any) error {
-			fmt.Print(args...)
-			return nil
+		name:      "print",
+		signature: &FunctionType{Params: []Type{&SliceType{Elem: AnyType}}, Variadic: true},
+		handler: func(args ...any) ([]any, error) {
+			fmt.Print(UnboxAll(args[0].([]any))...)
+			return nil, nil
 		},
 	})
 	res.RegisterFunction(GenericFunction{
-		name: "println",
-		handler: func(args ...any) error {
-			fmt.Println(args...)
-			return nil
+		name:      "println",
+		signature: &FunctionType{Params: []Type{&SliceType{Elem: AnyType}}, Variadic: true},
+		handler: func(args ...any) ([]any, error) {
+			fmt.Println(UnboxAll(args[0].([]any))...)
+			return nil, nil
 		},
 	})
 	res.RegisterFunction(GenericFunction{
-		name: "panic",
-		handler: func(args ...any) error {
+		name:      "panic",
+		signature: &FunctionType{Params: []Type{AnyType}},
+		handler: func(args ...any) ([]any, error) {
 			if len(args) != 1 {
-				return fmt.Errorf("the \"panic\" function has an incorrect number of arguments")
+				return nil, fmt.Errorf("the \"panic\" function has an incorrect number of arguments")
 			}
 
-			return fmt.Errorf("%v", args[0])
+			// the errors and the stringers are printed with their methods
+			return nil, PanicError{value: args[0], msg: res.Sprint(args)}
+		},
+	})
+
+	res.RegisterFunction(GenericFunction{
+		name:      "recover",
+		signature: &FunctionType{Params: []Type{}, Result: AnyType},
+		handler: func(args ...any) ([]any, error) {
+			return []any{res.recover()}, nil
 		},
 	})
 
@@ -48,6 +149,9 @@ func (prog *Program) RegisterFunction(function Function) error {
 	if _, ok := prog.functionID[function.Name()]; ok {
 		return fmt.Errorf("function %v already defined", function.Name())
 	}
+	if _, ok := prog.functionTemplates[function.Name()]; ok {
+		return fmt.Errorf("function %v already defined", function.Name())
+	}
 
 	prog.functionID[function.Name()] = len(prog.functions)
 	prog.functions = append(prog.functions, function)
@@ -55,12 +159,71 @@ func (prog *Program) RegisterFunction(function Function) error {
 	return nil
 }
 
-func (prog *Program) Execute() error {
-	id, ok := prog.functionID["main"]
+func (prog *Program) RegisterFunctionTemplate(name string, template *FunctionTemplate) error {
+	if _, ok := prog.functionID[name]; ok {
+		return fmt.Errorf("function %v already defined", name)
+	}
+	if _, ok := prog.functionTemplates[name]; ok {
+		return fmt.Errorf("function %v already defined", name)
+	}
+
+	prog.functionTemplates[name] = template
+
+	return nil
+}
+
+func (prog *Program) typeDefined(name string) bool {
+	_, isType := prog.types[name]
+	_, isAlias := prog.typeAliases[name]
+	_, isTemplate := prog.typeTemplates[name]
+
+	return isType || isAlias || isTemplate
+}
+
+func (prog *Program) RegisterType(name string, Type Type) error {
+	if prog.typeDefined(name) {
+		return fmt.Errorf("type %v already defined", name)
+	}
+
+	prog.types[name] = Type
+
+	return nil
+}
+
+func (prog *Program) RegisterTypeAlias(name string, alias *TypeAlias) error {
+	if prog.typeDefined(name) {
+		return fmt.Errorf("type %v already defined", name)
+	}
+
+	prog.typeAliases[name] = alias
+
+	return nil
+}
+
+func (prog *Program) RegisterTypeTemplate(name string, template *TypeTemplate) error {
+	if prog.typeDefined(name) {
+		return fmt.Errorf("type %v already defined", name)
+	}
+
+	prog.typeTemplates[name] = template
+
+	return nil
+}
+
+// Execute runs the main function, the script is stopped when the context is done or it exceeds its budgets.
+func (prog *Program) Execute(ctx context.Context) error {
+	id, ok := prog.functionID[MainPackagePath+".main"]
 	if !ok {
 		return fmt.Errorf("there is no 'main'")
 	}
+
+	prog.Lower()
+	prog.start(ctx)
+	prog.startGoroutines()
 	res, err := prog.functions[id].Call()
+	// the program is over when main returns, the other goroutines don't run any more
+	prog.exit()
+	prog.closeFiles()
 	if len(res) != 0 {
 		return fmt.Errorf("'main' can't have return value")
 	}
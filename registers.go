@@ -0,0 +1,568 @@
+package main
+
+import "slices"
+
+// ssaNode is the registers the values share: the ones of the value, of the instruction setting several registers
+// with its extracts, of the arguments of the call the arguments computed right into them share,
+// or of the values coalesced with the phis.
+type ssaNode struct {
+	id    int
+	width int
+	// the first register, -1 till it is chosen. The fixed node has the register the machine sets or reads the value in
+	register int32
+	fixed    bool
+	// the arguments of the call, the count of the ones computed right into the node
+	pack    bool
+	members int
+	// the node it is coalesced into, nil if it is the one the values of both are in
+	parent     *ssaNode
+	neighbours map[*ssaNode]bool
+}
+
+func (n *ssaNode) find() *ssaNode {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// interferes reports whether the values of the nodes are live at once.
+func (n *ssaNode) interferes(other *ssaNode) bool {
+	for neighbour := range n.neighbours {
+		if neighbour.find() == other {
+			return true
+		}
+	}
+	return false
+}
+
+// conflicts reports whether the registers from the register are taken by the node live at once with this one.
+func (n *ssaNode) conflicts(register int32) bool {
+	for neighbour := range n.neighbours {
+		neighbour = neighbour.find()
+		if neighbour.register >= 0 && register < neighbour.register+int32(neighbour.width) &&
+			neighbour.register < register+int32(n.width) {
+			return true
+		}
+	}
+	return false
+}
+
+// ssaMove is the move of the value between the registers, the source may be the constant.
+type ssaMove struct {
+	dst, src int32
+}
+
+// ssaAllocation is the registers of the values of the function being compiled back into the bytecode.
+type ssaAllocation struct {
+	*ssaFunction
+	nodes []*ssaNode
+	// the node of the value and the register of the value in it
+	node   map[*ssaValue]*ssaNode
+	offset map[*ssaValue]int32
+	// the arguments of the calls and of the tail calls
+	packs           map[*ssaValue]*ssaNode
+	uses            map[*ssaValue]int
+	liveIn, liveOut map[*ssaBlock]map[*ssaNode]bool
+
+	code    []vmInstruction
+	escapes []vmEscape
+	// the register the cycles of the moves go through, -1 till it is needed
+	temp      int32
+	registers int32
+}
+
+// compile compiles the SSA form back into the bytecode of the function. The values get the registers
+// by the coloring of the graph of the values live at once, the phis get the registers of their arguments if they can.
+func (s *ssaFunction) compile() {
+	s.splitCriticalEdges()
+	a := &ssaAllocation{
+		ssaFunction: s,
+		node:        map[*ssaValue]*ssaNode{},
+		offset:      map[*ssaValue]int32{},
+		packs:       map[*ssaValue]*ssaNode{},
+		uses:        map[*ssaValue]int{},
+		liveIn:      map[*ssaBlock]map[*ssaNode]bool{},
+		liveOut:     map[*ssaBlock]map[*ssaNode]bool{},
+		temp:        -1,
+	}
+	a.makeNodes()
+	a.computeLiveness()
+	a.buildInterference()
+	a.coalesce()
+	a.precolorResults()
+	a.color()
+	a.emit()
+}
+
+// splitCriticalEdges adds the block on each edge from the block of several successors to the block of the phis,
+// the moves of the phis go to it.
+func (s *ssaFunction) splitCriticalEdges() {
+	for _, block := range slices.Clone(s.blocks) {
+		if len(block.succs) < 2 {
+			continue
+		}
+		for j, succ := range block.succs {
+			if len(succ.phis) == 0 {
+				continue
+			}
+
+			index := block.predIndex(j)
+			edge := s.block()
+			edge.values = []*ssaValue{s.value(edge, vmInstruction{op: vmJump})}
+			edge.preds, edge.succs = []*ssaBlock{block}, []*ssaBlock{succ}
+			block.succs[j], succ.preds[index] = edge, edge
+			if j == 0 {
+				// the block goes on to the first successor without the jump
+				s.blocks = slices.Insert(s.blocks, slices.Index(s.blocks, block)+1, edge)
+			} else {
+				s.blocks = append(s.blocks, edge)
+			}
+		}
+	}
+}
+
+func (a *ssaAllocation) newNode(width int) *ssaNode {
+	n := &ssaNode{id: len(a.nodes), width: width, register: -1, neighbours: map[*ssaNode]bool{}}
+	a.nodes = append(a.nodes, n)
+	return n
+}
+
+// packCount is the count of the arguments the instruction reads from the registers following each other.
+func (a *ssaAllocation) packCount(v *ssaValue) int {
+	switch v.in.op {
+	case vmCall, vmCallValue:
+		return a.calls[v.in.b].args
+	case vmTailCall:
+		return len(v.args)
+	}
+	return 0
+}
+
+// makeNodes makes the nodes of the values. The argument of the call used only by it and computed in its block
+// is computed right into the register the call reads it from.
+func (a *ssaAllocation) makeNodes() {
+	for _, block := range a.blocks {
+		for _, v := range slices.Concat(block.phis, block.values) {
+			for _, arg := range v.args {
+				a.uses[arg]++
+			}
+		}
+	}
+
+	for _, block := range a.blocks {
+		for _, v := range block.values {
+			count := a.packCount(v)
+			if count == 0 {
+				continue
+			}
+			pack := a.newNode(count)
+			pack.pack = true
+			a.packs[v] = pack
+			for i, arg := range v.args[:count] {
+				if arg.block == block && a.uses[arg] == 1 && arg.width == 1 && a.computed(arg) {
+					a.node[arg], a.offset[arg] = pack, int32(i)
+					pack.members++
+				}
+			}
+		}
+	}
+
+	for _, block := range a.blocks {
+		for _, v := range slices.Concat(block.phis, block.values) {
+			switch {
+			case v.in.op == ssaParam:
+				n := a.newNode(1)
+				n.register, n.fixed = v.in.b, true
+				a.node[v] = n
+			case v.width > 0 && a.node[v] == nil:
+				a.node[v] = a.newNode(v.width)
+			}
+		}
+	}
+}
+
+// computed reports whether the value is set by the instruction of the machine.
+func (a *ssaAllocation) computed(v *ssaValue) bool {
+	switch v.in.op {
+	case ssaPhi, ssaParam, ssaConstant, ssaExtract:
+		return false
+	}
+	return true
+}
+
+// place is the node of the value and the register of the value in it, the node of the constant is nil.
+func (a *ssaAllocation) place(v *ssaValue) (*ssaNode, int32) {
+	switch v.in.op {
+	case ssaConstant:
+		return nil, 0
+	case ssaExtract:
+		n, _ := a.place(v.args[0])
+		return n, v.in.b
+	}
+	return a.node[v], a.offset[v]
+}
+
+// defined is the node the instruction sets, nil if it sets none.
+func (a *ssaAllocation) defined(v *ssaValue) *ssaNode {
+	if v.width == 0 || v.in.op == ssaPhi {
+		return nil
+	}
+	return a.node[v]
+}
+
+// reads are the nodes the instruction reads besides the arguments of the call it reads from the node of them.
+func (a *ssaAllocation) reads(v *ssaValue) []*ssaNode {
+	var res []*ssaNode
+	for _, arg := range v.args {
+		if n, _ := a.place(arg); n != nil && !n.pack {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// liveOut are the nodes live at the end of the block: the ones live in its successors and the arguments of their phis.
+func (a *ssaAllocation) computeLiveOut(block *ssaBlock) map[*ssaNode]bool {
+	res := map[*ssaNode]bool{}
+	for j, succ := range block.succs {
+		for n := range a.liveIn[succ] {
+			res[n] = true
+		}
+		index := block.predIndex(j)
+		for _, phi := range succ.phis {
+			if n, _ := a.place(phi.args[index]); n != nil {
+				res[n] = true
+			}
+		}
+	}
+	return res
+}
+
+// computeLiveness finds the nodes live at the start and at the end of the blocks,
+// the nodes of the arguments of the calls are live in their blocks only.
+func (a *ssaAllocation) computeLiveness() {
+	for changed := true; changed; {
+		changed = false
+		for i := len(a.blocks) - 1; i >= 0; i-- {
+			block := a.blocks[i]
+			out := a.computeLiveOut(block)
+			live := make(map[*ssaNode]bool, len(out))
+			for n := range out {
+				live[n] = true
+			}
+			for j := len(block.values) - 1; j >= 0; j-- {
+				v := block.values[j]
+				if n := a.defined(v); n != nil && !n.pack {
+					delete(live, n)
+				}
+				for _, n := range a.reads(v) {
+					live[n] = true
+				}
+			}
+			for _, phi := range block.phis {
+				delete(live, a.node[phi])
+			}
+
+			a.liveOut[block] = out
+			if len(live) != len(a.liveIn[block]) {
+				a.liveIn[block], changed = live, true
+				continue
+			}
+			for n := range live {
+				if !a.liveIn[block][n] {
+					a.liveIn[block], changed = live, true
+					break
+				}
+			}
+		}
+	}
+}
+
+func interfere(n *ssaNode, live map[*ssaNode]bool) {
+	for other := range live {
+		if other != n {
+			n.neighbours[other] = true
+			other.neighbours[n] = true
+		}
+	}
+}
+
+// buildInterference connects the nodes live at once. The instruction reads its arguments before it sets its registers,
+// so the value may take the register of the argument it is computed of.
+func (a *ssaAllocation) buildInterference() {
+	for _, block := range a.blocks {
+		live := make(map[*ssaNode]bool, len(a.liveOut[block]))
+		for n := range a.liveOut[block] {
+			live[n] = true
+		}
+		// the arguments of the calls computed right into the node of them, which are not computed yet
+		pending := map[*ssaNode]int{}
+
+		for j := len(block.values) - 1; j >= 0; j-- {
+			v := block.values[j]
+			if n := a.defined(v); n != nil {
+				interfere(n, live)
+				if n.pack {
+					pending[n]--
+					if pending[n] == 0 {
+						delete(live, n)
+					}
+				} else {
+					delete(live, n)
+				}
+			}
+			for _, n := range a.reads(v) {
+				live[n] = true
+			}
+
+			// the arguments are moved into the node of them right before the call
+			if pack := a.packs[v]; pack != nil {
+				interfere(pack, live)
+				if pack.members > 0 {
+					live[pack], pending[pack] = true, pack.members
+				}
+			}
+		}
+
+		// the phis are set at once when the block is entered
+		for _, phi := range block.phis {
+			delete(live, a.node[phi])
+		}
+		for i, phi := range block.phis {
+			n := a.node[phi]
+			interfere(n, live)
+			for _, other := range block.phis[:i] {
+				interfere(n, map[*ssaNode]bool{a.node[other]: true})
+			}
+		}
+	}
+}
+
+// coalesce gives the phi the register of its argument if they are not live at once, the move of the argument is not needed then.
+func (a *ssaAllocation) coalesce() {
+	for _, block := range a.blocks {
+		for _, phi := range block.phis {
+			for _, arg := range phi.args {
+				n, _ := a.place(arg)
+				if n == nil || n.pack || n.width != 1 {
+					continue
+				}
+				n, p := n.find(), a.node[phi].find()
+				if n == p || n.fixed && p.fixed || n.interferes(p) {
+					continue
+				}
+
+				if n.fixed {
+					n, p = p, n
+				}
+				n.parent = p
+				for neighbour := range n.neighbours {
+					p.neighbours[neighbour] = true
+				}
+			}
+		}
+	}
+}
+
+// precolorResults computes the result used only by the return right into the register the machine returns it from.
+func (a *ssaAllocation) precolorResults() {
+	params := int32(len(a.function.inputVariables))
+	for _, block := range a.blocks {
+		if ret := block.terminator(); ret.in.op == vmReturn {
+			for i, arg := range ret.args {
+				if arg.block != block || a.uses[arg] != 1 || arg.width != 1 || !a.computed(arg) {
+					continue
+				}
+				n := a.node[arg].find()
+				if n.fixed || n.pack || n.conflicts(params+int32(i)) {
+					continue
+				}
+				n.register, n.fixed = params+int32(i), true
+			}
+		}
+	}
+}
+
+// color gives each node the first registers the nodes live at once with it don't take.
+func (a *ssaAllocation) color() {
+	a.registers = int32(a.frameSize())
+	for _, n := range a.nodes {
+		if n.parent != nil {
+			continue
+		}
+		if n.register < 0 {
+			n.register = 0
+			for n.conflicts(n.register) {
+				n.register++
+			}
+		}
+		a.registers = max(a.registers, n.register+int32(n.width))
+	}
+}
+
+// register is the operand of the value: its register or its constant.
+func (a *ssaAllocation) register(v *ssaValue) int32 {
+	n, offset := a.place(v)
+	if n == nil {
+		return v.in.b
+	}
+	return n.find().register + offset
+}
+
+func (a *ssaAllocation) emitInstruction(in vmInstruction) int {
+	a.code = append(a.code, in)
+	return len(a.code) - 1
+}
+
+// parallelMove moves the values as if all of them were read before the first one is set,
+// the cycle of the moves goes through the temporary register.
+func (a *ssaAllocation) parallelMove(moves []ssaMove) {
+	moves = slices.DeleteFunc(moves, func(move ssaMove) bool {
+		return move.dst == move.src
+	})
+
+	for len(moves) > 0 {
+		ready := slices.IndexFunc(moves, func(move ssaMove) bool {
+			return !slices.ContainsFunc(moves, func(other ssaMove) bool {
+				return other.src == move.dst
+			})
+		})
+		if ready >= 0 {
+			a.emitInstruction(vmInstruction{op: vmMove, a: moves[ready].dst, b: moves[ready].src})
+			moves = slices.Delete(moves, ready, ready+1)
+			continue
+		}
+
+		// every register set is read by another move, the first one is saved in the temporary register
+		if a.temp < 0 {
+			a.temp = a.registers
+			a.registers++
+		}
+		saved := moves[0].dst
+		a.emitInstruction(vmInstruction{op: vmMove, a: a.temp, b: saved})
+		for i := range moves {
+			if moves[i].src == saved {
+				moves[i].src = a.temp
+			}
+		}
+	}
+}
+
+// emit writes the bytecode of the blocks in their order, the jumps to the next block are left out.
+func (a *ssaAllocation) emit() {
+	starts := map[*ssaBlock]int{}
+	// the jumps and the blocks they go to
+	jumps := map[int]*ssaBlock{}
+	params := int32(len(a.function.inputVariables))
+
+	for i, block := range a.blocks {
+		starts[block] = len(a.code)
+		var next *ssaBlock
+		if i+1 < len(a.blocks) {
+			next = a.blocks[i+1]
+		}
+
+		for _, v := range block.values {
+			in := v.in
+			switch in.op {
+			case ssaParam:
+				continue
+			case vmMove, vmCopy, vmNot, vmLen, vmConvert, vmBox, vmField, vmFieldRef, vmCell, vmLoad:
+				in.a, in.b = a.register(v), a.register(v.args[0])
+			case vmZero, vmPackage:
+				in.a = a.register(v)
+			case vmAdd, vmSub, vmMul, vmDiv, vmOr, vmAnd, vmEq, vmNe, vmLt, vmLe, vmGt, vmGe, vmIndex, vmIndexRef:
+				in.a, in.b, in.c = a.register(v), a.register(v.args[0]), a.register(v.args[1])
+			case vmSetIndex:
+				in.a, in.b, in.c = a.register(v.args[0]), a.register(v.args[1]), a.register(v.args[2])
+			case vmStore:
+				in.a, in.b = a.register(v.args[0]), a.register(v.args[1])
+			case vmEval, vmExec:
+				escape := a.vmFunction.escapes[in.b]
+				escape.registers = make([]int32, len(v.args))
+				for i, arg := range v.args {
+					escape.registers[i] = a.register(arg)
+				}
+				a.escapes = append(a.escapes, escape)
+				in.b = int32(len(a.escapes) - 1)
+				if in.op == vmEval {
+					in.a = a.register(v)
+				}
+			case vmCall, vmCallValue, vmTailCall:
+				base := int32(0)
+				if pack := a.packs[v]; pack != nil {
+					base = pack.register
+					var moves []ssaMove
+					for i, arg := range v.args[:pack.width] {
+						if a.node[arg] != pack {
+							moves = append(moves, ssaMove{dst: base + int32(i), src: a.register(arg)})
+						}
+					}
+					a.parallelMove(moves)
+				}
+				switch in.op {
+				case vmTailCall:
+					in.a = base
+				case vmCallValue:
+					in.d = a.register(v.args[len(v.args)-1])
+					fallthrough
+				default:
+					in.c = base
+					if v.width > 0 {
+						in.a = a.register(v)
+					}
+				}
+			case vmRange:
+				in.a, in.b = a.register(v), a.register(v.args[0])
+			case vmJumpIfFalse:
+				in.a = a.register(v.args[0])
+			case vmNext:
+				in.a, in.b = a.register(v.args[0]), a.register(v)
+			case vmReturn:
+				moves := make([]ssaMove, len(v.args))
+				for i, arg := range v.args {
+					moves[i] = ssaMove{dst: params + int32(i), src: a.register(arg)}
+				}
+				a.parallelMove(moves)
+			}
+
+			if v.terminator() && len(block.succs) == 1 {
+				// the phis of the successor are set on the way to it
+				index := block.predIndex(0)
+				var moves []ssaMove
+				for _, phi := range block.succs[0].phis {
+					moves = append(moves, ssaMove{dst: a.register(phi), src: a.register(phi.args[index])})
+				}
+				a.parallelMove(moves)
+				if in.op == vmJump && block.succs[0] == next && in.steps == 0 {
+					continue
+				}
+			}
+
+			pc := a.emitInstruction(in)
+			switch in.op {
+			case vmJump:
+				jumps[pc] = block.succs[0]
+			case vmJumpIfFalse, vmNext:
+				jumps[pc] = block.succs[1]
+				if block.succs[0] != next {
+					jumps[a.emitInstruction(vmInstruction{op: vmJump})] = block.succs[0]
+				}
+			}
+		}
+	}
+
+	for pc, block := range jumps {
+		switch in := &a.code[pc]; in.op {
+		case vmJump:
+			in.a = int32(starts[block])
+		case vmJumpIfFalse:
+			in.b = int32(starts[block])
+		case vmNext:
+			in.c = int32(starts[block])
+		}
+	}
+
+	a.vmFunction.code, a.vmFunction.escapes, a.vmFunction.registers = a.code, a.escapes, int(a.registers)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// goroutine is the goroutine of the script. Each one runs on its own goroutine of the host,
+// but only one of them runs at a time: the running one passes the turn to the next one
+// when it blocks, when it sleeps and when it has run for a while, so the runs are reproducible.
+type goroutine struct {
+	id int
+	// the goroutine waits for its turn on the channel
+	turn chan struct{}
+	// closed when the goroutine of the host is over
+	done chan struct{}
+	// the state of the program the goroutine runs with, it is kept while the other goroutines run
+	stack  []any
+	calls  []string
+	panics []*panicState
+	// the time the sleeping goroutine wakes up at
+	wake time.Time
+	// the objects the blocked goroutine waits for, the first one notified wakes it up
+	waits []any
+}
+
+// scheduler is the state of the goroutines of the program.
+type scheduler struct {
+	main, current *goroutine
+	// the goroutines which may run in the order they get the turn
+	ready []*goroutine
+	// the sleeping goroutines, the one which wakes up first is the first
+	sleeping []*goroutine
+	// the blocked goroutines by the objects they wait for
+	waiting map[any][]*goroutine
+	// the timers which have not fired, the one which fires first is the first
+	timers []*timer
+	// the goroutines started by the script which are not over
+	live []*goroutine
+	// the count of the goroutines started, the main goroutine is the first
+	count int
+	// the error which ends the program, the main goroutine returns it when it gets the turn
+	failure error
+	// main has returned, the goroutines end as soon as they get the turn
+	exiting bool
+	// chooses the case of the select statement among the ones which can go on, the choices are the same in every run
+	random *rand.Rand
+}
+
+// timer calls its function when its time comes, the periodic one calls it again after each period.
+type timer struct {
+	when   time.Time
+	period time.Duration
+	fire   func(now time.Time)
+}
+
+// GoroutinePanicError is the panic the goroutine hasn't recovered, it ends the whole program.
+type GoroutinePanicError struct {
+	Goroutine int
+	Err       error
+}
+
+func (e GoroutinePanicError) Error() string {
+	return e.Err.Error()
+}
+
+func (e GoroutinePanicError) Unwrap() error {
+	return e.Err
+}
+
+// errDeadlock is the fatal error of the script whose goroutines are all blocked:
+// none of them can send or receive the value the other ones wait for.
+var errDeadlock = FatalError{"all goroutines are asleep - deadlock!"}
+
+// errExited ends the goroutine which is left when main returns, its deferred calls don't run.
+var errExited = FatalError{"the program has exited"}
+
+// startGoroutines makes the goroutine of the program the main one.
+func (prog *Program) startGoroutines() {
+	main := &goroutine{id: 1, turn: make(chan struct{}, 1)}
+	prog.scheduler = scheduler{main: main, current: main, waiting: map[any][]*goroutine{}, count: 1, random: rand.New(rand.NewPCG(1, 2))}
+}
+
+// spawn starts the goroutine which calls the function, it runs when it gets the turn.
+func (prog *Program) spawn(function Function, args []any) {
+	s := &prog.scheduler
+	s.count++
+	g := &goroutine{id: s.count, turn: make(chan struct{}, 1), done: make(chan struct{})}
+	s.live = append(s.live, g)
+	s.ready = append(s.ready, g)
+
+	go func() {
+		defer close(g.done)
+		<-g.turn
+		if s.exiting {
+			return
+		}
+
+		prog.resume(g)
+		_, err := function.Call(args...)
+		prog.end(g, err)
+	}()
+}
+
+// end ends the goroutine and passes the turn on, the panic of the goroutine ends the program.
+func (prog *Program) end(g *goroutine, err error) {
+	s := &prog.scheduler
+	s.live = slices.DeleteFunc(s.live, func(live *goroutine) bool {
+		return live == g
+	})
+	if s.exiting {
+		return
+	}
+
+	next := s.main
+	if err != nil {
+		if !fatal(err) {
+			err = GoroutinePanicError{Goroutine: g.id, Err: err}
+		}
+		s.failure = err
+	} else if next, err = prog.next(); err != nil {
+		s.failure, next = err, s.main
+	}
+	next.turn <- struct{}{}
+}
+
+// resume makes the goroutine which has got the turn the current one.
+func (prog *Program) resume(g *goroutine) {
+	prog.scheduler.current = g
+	prog.stack, prog.calls, prog.panics = g.stack, g.calls, g.panics
+}
+
+// park passes the turn from the current goroutine to the next one, the current one waits until it gets the turn back.
+// The goroutine which is not ready gets the turn when the other one makes it ready or when it wakes up.
+func (prog *Program) park() error {
+	s := &prog.scheduler
+	g := s.current
+	next, err := prog.next()
+	if err != nil {
+		if g == s.main {
+			return err
+		}
+		// the error ends the program, main ends it
+		s.failure, next = err, s.main
+	}
+
+	if next != g {
+		g.stack, g.calls, g.panics = prog.stack, prog.calls, prog.panics
+		next.turn <- struct{}{}
+		<-g.turn
+		if s.exiting {
+			return errExited
+		}
+		prog.resume(g)
+	}
+
+	if g == s.main && s.failure != nil {
+		return s.failure
+	}
+	return nil
+}
+
+// next is the goroutine which gets the turn: the first ready one,
+// when no one is ready, the clock goes on up to the time the first sleeping one wakes up at or the first timer fires at.
+func (prog *Program) next() (*goroutine, error) {
+	s := &prog.scheduler
+	for {
+		prog.wakeUp()
+		if len(s.ready) != 0 {
+			next := s.ready[0]
+			s.ready = s.ready[1:]
+			return next, nil
+		}
+		if len(s.sleeping) == 0 && len(s.timers) == 0 {
+			return nil, errDeadlock
+		}
+
+		var wake time.Time
+		if len(s.sleeping) != 0 {
+			wake = s.sleeping[0].wake
+		}
+		if len(s.timers) != 0 && (wake.IsZero() || s.timers[0].when.Before(wake)) {
+			wake = s.timers[0].when
+		}
+		if prog.Clock.Sleep(prog.budget.ctx, wake.Sub(prog.Clock.Now())) != nil {
+			return nil, prog.interrupted()
+		}
+	}
+}
+
+// wakeUp makes the sleeping goroutines ready and fires the timers when their time comes.
+func (prog *Program) wakeUp() {
+	s := &prog.scheduler
+	if len(s.sleeping) == 0 && len(s.timers) == 0 {
+		return
+	}
+
+	now := prog.Clock.Now()
+	for len(s.sleeping) != 0 && !s.sleeping[0].wake.After(now) {
+		s.ready = append(s.ready, s.sleeping[0])
+		s.sleeping = s.sleeping[1:]
+	}
+	for len(s.timers) != 0 && !s.timers[0].when.After(now) {
+		t := s.timers[0]
+		s.timers = s.timers[1:]
+		if t.period > 0 {
+			// the ticks missed while the program was busy are dropped
+			t.when = t.when.Add(t.period * (1 + now.Sub(t.when)/t.period))
+			prog.startTimer(t)
+		}
+		t.fire(now)
+	}
+}
+
+// startTimer starts the timer, it fires at its time.
+func (prog *Program) startTimer(t *timer) {
+	s := &prog.scheduler
+	// the timers which fire at the same time fire in the order they have been started
+	i := slices.IndexFunc(s.timers, func(other *timer) bool {
+		return other.when.After(t.when)
+	})
+	if i < 0 {
+		i = len(s.timers)
+	}
+	s.timers = slices.Insert(s.timers, i, t)
+}
+
+// stopTimer stops the timer, the result reports whether it had not fired yet.
+func (prog *Program) stopTimer(t *timer) bool {
+	s := &prog.scheduler
+	i := slices.Index(s.timers, t)
+	if i < 0 {
+		return false
+	}
+
+	s.timers = slices.Delete(s.timers, i, i+1)
+	return true
+}
+
+// yield lets the ready goroutines run, the current one runs again after them.
+func (prog *Program) yield() error {
+	s := &prog.scheduler
+	prog.wakeUp()
+	if len(s.ready) == 0 {
+		return nil
+	}
+
+	s.ready = append(s.ready, s.current)
+	return prog.park()
+}
+
+// sleep lets the other goroutines run for the duration.
+func (prog *Program) sleep(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	s := &prog.scheduler
+	g := s.current
+	g.wake = prog.Clock.Now().Add(d)
+	// the goroutines which wake up at the same time run in the order they have fallen asleep
+	i := slices.IndexFunc(s.sleeping, func(sleeping *goroutine) bool {
+		return sleeping.wake.After(g.wake)
+	})
+	if i < 0 {
+		i = len(s.sleeping)
+	}
+	s.sleeping = slices.Insert(s.sleeping, i, g)
+
+	return prog.park()
+}
+
+// wait blocks the current goroutine until the other one notifies the goroutines waiting for one of the objects,
+// the goroutine which waits for nothing is blocked for good.
+func (prog *Program) wait(objects ...any) error {
+	s := &prog.scheduler
+	g := s.current
+	g.waits = objects
+	for _, object := range objects {
+		s.waiting[object] = append(s.waiting[object], g)
+	}
+	return prog.park()
+}
+
+// notify makes the goroutines waiting for the object ready, they check again what they wait for.
+func (prog *Program) notify(object any) {
+	s := &prog.scheduler
+	for _, g := range s.waiting[object] {
+		// the goroutine waiting for the object twice is already ready
+		if g.waits == nil {
+			continue
+		}
+		// the goroutine stops waiting for the other objects too
+		for _, other := range g.waits {
+			if other == object {
+				continue
+			}
+			s.waiting[other] = slices.DeleteFunc(s.waiting[other], func(waiting *goroutine) bool {
+				return waiting == g
+			})
+			if len(s.waiting[other]) == 0 {
+				delete(s.waiting, other)
+			}
+		}
+		g.waits = nil
+		s.ready = append(s.ready, g)
+	}
+	delete(s.waiting, object)
+}
+
+// exit ends the goroutines left when main returns, they return from where they wait without running their deferred calls.
+func (prog *Program) exit() {
+	s := &prog.scheduler
+	s.exiting = true
+	stack, calls, panics := prog.stack, prog.calls, prog.panics
+	for _, g := range slices.Clone(s.live) {
+		g.turn <- struct{}{}
+		<-g.done
+	}
+	prog.stack, prog.calls, prog.panics = stack, calls, panics
+}
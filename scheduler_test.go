@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+const sleepers = `package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+func main() {
+	start := time.Now();
+	var wg sync.WaitGroup;
+	for _, d := range []int{3, 1, 2} {
+		wg.Add(1);
+		go func() {
+			defer wg.Done();
+			time.Sleep(time.Duration(d) * time.Hour);
+			fmt.Println(d, time.Since(start));
+		}();
+	}
+	wg.Wait();
+	fmt.Println("done", time.Since(start));
+}
+`
+
+const deadlock = `package main
+
+import "sync"
+
+func main() {
+	var mu sync.Mutex;
+	mu.Lock();
+	go func() {
+		mu.Lock();
+	}();
+	mu.Lock();
+}
+`
+
+const goroutinePanic = `package main
+
+import "sync"
+
+func main() {
+	var wg sync.WaitGroup;
+	wg.Add(1);
+	defer func() {
+		recover();
+	}();
+	go func() {
+		panic("boom");
+	}();
+	wg.Wait();
+}
+`
+
+const leftovers = `package main
+
+import "sync"
+
+func main() {
+	var mu sync.Mutex;
+	mu.Lock();
+	for range 10 {
+		go func() {
+			defer mu.Unlock();
+			mu.Lock();
+		}();
+	}
+	for range 10 {
+		go func() {
+			for {
+			}
+		}();
+	}
+}
+`
+
+const tickers = `package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func main() {
+	start := time.Now();
+	ticker := time.NewTicker(time.Hour);
+	timer := time.NewTimer(150 * time.Minute);
+	for range 2 {
+		<-ticker.C;
+		fmt.Println("tick", time.Since(start));
+	}
+	ticker.Reset(45 * time.Minute);
+	<-timer.C;
+	fmt.Println("timer", time.Since(start), timer.Stop());
+	<-ticker.C;
+	fmt.Println("tick", time.Since(start));
+	ticker.Stop();
+	select {
+	case <-ticker.C:
+		fmt.Println("the ticker has not stopped");
+	case <-time.After(time.Hour):
+		fmt.Println("after", time.Since(start));
+	}
+}
+`
+
+func TestTimers(t *testing.T) {
+	for _, backend := range backends {
+		t.Run("virtual clock/"+backend, func(t *testing.T) {
+			prog := compileScript(t, tickers)
+			prog.Backend = backend
+			prog.Clock = NewVirtualClock()
+
+			var err error
+			start := time.Now()
+			output := captureOutput(t, func() {
+				err = prog.Execute(context.Background())
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := "tick 1h0m0s\ntick 2h0m0s\ntimer 2h30m0s false\ntick 2h45m0s\nafter 3h45m0s\n"
+			if output != expected {
+				t.Errorf("the output is %q, %q is expected", output, expected)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("the timers have taken %v", elapsed)
+			}
+		})
+
+		t.Run("deadlock/"+backend, func(t *testing.T) {
+			prog := compileScript(t, "package main\n\nimport \"time\"\n\nfunc main() {\n\ttimer := time.NewTimer(time.Hour);\n\ttimer.Stop();\n\t<-timer.C;\n}\n")
+			prog.Backend = backend
+			prog.Clock = NewVirtualClock()
+
+			err := prog.Execute(context.Background())
+			if err != errDeadlock {
+				t.Errorf("the error is %T %q, %q is expected", err, err, errDeadlock)
+			}
+		})
+
+		t.Run("ticking forever/"+backend, func(t *testing.T) {
+			// the ticker keeps moving the virtual clock, the deadline of the run stops it
+			prog := compileScript(t, "package main\n\nimport \"time\"\n\nfunc main() {\n\ttime.NewTicker(time.Second);\n\tselect {\n\t}\n}\n")
+			prog.Backend = backend
+			prog.Clock = NewVirtualClock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err := prog.Execute(ctx)
+			if _, ok := err.(DeadlineError); !ok {
+				t.Errorf("the error is %T %q, DeadlineError is expected", err, err)
+			}
+		})
+	}
+}
+
+func TestGoroutines(t *testing.T) {
+	for _, backend := range backends {
+		t.Run("virtual clock/"+backend, func(t *testing.T) {
+			prog := compileScript(t, sleepers)
+			prog.Backend = backend
+			prog.Clock = NewVirtualClock()
+
+			var err error
+			start := time.Now()
+			output := captureOutput(t, func() {
+				err = prog.Execute(context.Background())
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := "1 1h0m0s\n2 2h0m0s\n3 3h0m0s\ndone 3h0m0s\n"
+			if output != expected {
+				t.Errorf("the output is %q, %q is expected", output, expected)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("the sleeping goroutines have taken %v", elapsed)
+			}
+		})
+
+		t.Run("deadlock/"+backend, func(t *testing.T) {
+			prog := compileScript(t, deadlock)
+			prog.Backend = backend
+
+			err := prog.Execute(context.Background())
+			if err != errDeadlock {
+				t.Errorf("the error is %T %q, %q is expected", err, err, errDeadlock)
+			}
+		})
+
+		t.Run("panic/"+backend, func(t *testing.T) {
+			prog := compileScript(t, goroutinePanic)
+			prog.Backend = backend
+
+			err := prog.Execute(context.Background())
+			panicked, ok := err.(GoroutinePanicError)
+			if !ok {
+				t.Fatalf("the error is %T %q, GoroutinePanicError is expected", err, err)
+			}
+			if panicked.Goroutine != 2 || panicked.Error() != "boom" {
+				t.Errorf("the panic is %q in the goroutine %v, \"boom\" in the goroutine 2 is expected", panicked, panicked.Goroutine)
+			}
+			if !fatal(err) {
+				t.Errorf("%q is recovered by main", err)
+			}
+		})
+
+		t.Run("exit/"+backend, func(t *testing.T) {
+			prog := compileScript(t, leftovers)
+			prog.Backend = backend
+
+			before := runtime.NumGoroutine()
+			err := prog.Execute(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the goroutines of the host end right after they have told they are over
+			for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+				runtime.Gosched()
+			}
+			if after := runtime.NumGoroutine(); after > before {
+				t.Errorf("%v goroutines are left after main has returned", after-before)
+			}
+		})
+	}
+}
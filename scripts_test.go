@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the outputs of the test scripts into their golden files")
+
+// asInterpreter is set for the test binary which runs as the interpreter itself.
+const asInterpreter = "GOINTERPRETER_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(asInterpreter) != "" {
+		main()
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// scriptRun is the run of the test script, its output is in the golden file next to the script.
+type scriptRun struct {
+	dir    string
+	golden string
+	flags  []string
+	args   []string
+	// the run has the flags of the build too, so the native program must give the same output
+	native bool
+}
+
+// scriptRuns is every script of the test directory, the runs with the wall clock use the virtual one,
+// so the outputs are the same each time.
+func scriptRuns(t *testing.T) []scriptRun {
+	t.Helper()
+
+	entries, err := os.ReadDir("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	special := map[string][]scriptRun{
+		"test20": {{golden: "expected", flags: []string{"--virtual-time"}, native: true}},
+		"test25": {
+			{golden: "expected", native: true},
+			{golden: "tail-calls", flags: []string{"--tail-calls"}, native: true},
+		},
+		"test26": {
+			{golden: "expected", native: true},
+			{golden: "spin", flags: []string{"--max-steps", "1000000"}, args: []string{"spin"}},
+			{golden: "timeout", flags: []string{"--timeout", "500ms"}, args: []string{"sleep"}},
+			{golden: "grow", flags: []string{"--max-heap", "64MB"}, args: []string{"grow"}},
+		},
+		// the timers of the native program fire on the real clock, so it doesn't run on the virtual one
+		"test30": {{golden: "expected", flags: []string{"--virtual-time"}}},
+	}
+
+	var runs []scriptRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dirRuns, ok := special[entry.Name()]
+		if !ok {
+			dirRuns = []scriptRun{{golden: "expected", native: true}}
+		}
+		for _, run := range dirRuns {
+			run.dir = filepath.Join("test", entry.Name())
+			runs = append(runs, run)
+		}
+	}
+
+	return runs
+}
+
+// scriptOutput is what the script has written and how it has exited.
+type scriptOutput struct {
+	stdout string
+	stderr string
+	code   int
+}
+
+// golden is the text of the golden file: the standard output, then the error output and the exit code if there are any.
+func (output scriptOutput) golden() string {
+	res := output.stdout
+	if output.stderr != "" {
+		res += "-- stderr --\n" + output.stderr
+	}
+	if output.code != 0 {
+		res += fmt.Sprintf("-- exit %v --\n", output.code)
+	}
+
+	return res
+}
+
+// firstError is the output with only the first line of the error output,
+// the native program writes the stacks of the goroutines after it.
+func (output scriptOutput) firstError() scriptOutput {
+	output.stderr, _, _ = strings.Cut(output.stderr, "\n")
+	return output
+}
+
+func runCommand(t *testing.T, cmd *exec.Cmd) scriptOutput {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitError *exec.ExitError
+	if err != nil && !errors.As(err, &exitError) {
+		t.Fatal(err)
+	}
+
+	return scriptOutput{stdout: stdout.String(), stderr: stderr.String(), code: cmd.ProcessState.ExitCode()}
+}
+
+// interpret runs the test binary as the interpreter.
+func interpret(t *testing.T, args ...string) scriptOutput {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), asInterpreter+"=1")
+	return runCommand(t, cmd)
+}
+
+func TestScripts(t *testing.T) {
+	variants := []struct {
+		name  string
+		flags []string
+	}{
+		{BackendCode, []string{"--backend", BackendCode}},
+		{BackendClosures, []string{"--backend", BackendClosures}},
+		{BackendClosures + "-O0", []string{"--backend", BackendClosures, "-O0"}},
+		{BackendVM, []string{"--backend", BackendVM}},
+		{BackendVM + "-O2", []string{"--backend", BackendVM, "-O2"}},
+	}
+
+	for _, run := range scriptRuns(t) {
+		t.Run(filepath.Base(run.dir)+"/"+run.golden, func(t *testing.T) {
+			goldenPath := filepath.Join(run.dir, run.golden+".golden")
+
+			args := append([]string{"--no-cache"}, run.flags...)
+			args = append(append(args, run.dir), run.args...)
+			expected := interpret(t, args...)
+			if *update {
+				err := os.WriteFile(goldenPath, []byte(expected.golden()), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected.golden() != string(golden) {
+				t.Fatalf("the output differs from %v:\n%v", goldenPath, expected.golden())
+			}
+
+			for _, variant := range variants {
+				args := append(append([]string{"--no-cache"}, variant.flags...), run.flags...)
+				args = append(append(args, run.dir), run.args...)
+				if output := interpret(t, args...); output != expected {
+					t.Errorf("%v: the output differs:\n%v", variant.name, output.golden())
+				}
+			}
+
+			if run.native {
+				if output := runNative(t, run); output.firstError() != expected.firstError() {
+					t.Errorf("native: the output differs:\n%v", output.golden())
+				}
+			}
+		})
+	}
+}
+
+// runNative builds the script into the native program and runs it in the directory of the script,
+// so it accesses the files the interpreted script does. The script which doesn't compile gives the errors of the build.
+func runNative(t *testing.T, run scriptRun) scriptOutput {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("the native programs aren't built in the short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the native programs need the go command")
+	}
+
+	program := filepath.Join(t.TempDir(), "program")
+	args := append(append([]string{"build"}, run.flags...), "-o", program, run.dir)
+	if output := interpret(t, args...); output.code != 0 {
+		return output
+	}
+
+	cmd := exec.Command(program, run.args...)
+	cmd.Dir = run.dir
+	return runCommand(t, cmd)
+}
+
+// TestMovedImage runs the image moved with the script, the script still accesses its own files.
+func TestMovedImage(t *testing.T) {
+	dir := t.TempDir()
+	source, err := os.ReadFile(filepath.Join("test", "test18", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Join(dir, "built", "script"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "built", "script", "main.go"), source, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imagePath := filepath.Join(dir, "built", "images", "script.gob")
+	err = os.MkdirAll(filepath.Dir(imagePath), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output := interpret(t, "compile", "-o", imagePath, filepath.Join(dir, "built", "script")); output.code != 0 {
+		t.Fatalf("compile: %v%v", output.stdout, output.stderr)
+	}
+	err = os.Rename(filepath.Join(dir, "built"), filepath.Join(dir, "moved"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := interpret(t, "run", filepath.Join(dir, "moved", "images", "script.gob"))
+	golden, err := os.ReadFile(filepath.Join("test", "test18", "expected.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.golden() != string(golden) {
+		t.Errorf("the output differs:\n%v", output.golden())
+	}
+}
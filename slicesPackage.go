@@ -0,0 +1,111 @@
+package main
+
+import "slices"
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "slices",
+		Path:    "slices",
+		Imports: []string{"cmp"},
+		Declare: declareSlices,
+	})
+}
+
+func declareSlices(prog *Program, pkg *Package) {
+	ordered := prog.lookupStdType("cmp", "Ordered")
+
+	prog.declareGeneric(pkg, "Sort", []string{"E"}, []Type{ordered}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		E := typeArgs[0]
+		return signature([]Type{&SliceType{Elem: E}}), func(args ...any) ([]any, error) {
+			slices.SortFunc(args[0].([]any), func(a, b any) int {
+				return compareKeys(E, a, b)
+			})
+			return nil, nil
+		}
+	})
+
+	sortFunc := func(sort func(x []any, cmp func(a, b any) int)) func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		return func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+			E := typeArgs[0]
+			return signature([]Type{&SliceType{Elem: E}, signature([]Type{E, E}, IntType)}), func(args ...any) (res []any, err error) {
+				defer catchScriptError(&err)
+
+				cmp, _ := args[1].(Function)
+				sort(args[0].([]any), func(a, b any) int {
+					return callScript(cmp, CloneAny(a), CloneAny(b))[0].(int)
+				})
+				return nil, nil
+			}
+		}
+	}
+	prog.declareGeneric(pkg, "SortFunc", []string{"E"}, []Type{AnyType}, sortFunc(slices.SortFunc[[]any]))
+	prog.declareGeneric(pkg, "SortStableFunc", []string{"E"}, []Type{AnyType}, sortFunc(slices.SortStableFunc[[]any]))
+
+	prog.declareGeneric(pkg, "Collect", []string{"E"}, []Type{AnyType}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		E := typeArgs[0]
+		return signature([]Type{seqType(E)}, &SliceType{Elem: E}), func(args ...any) (res []any, err error) {
+			defer catchScriptError(&err)
+
+			seq, _ := args[0].(Function)
+			return []any{collect(seq, E)}, nil
+		}
+	})
+	prog.declareGeneric(pkg, "Sorted", []string{"E"}, []Type{ordered}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		E := typeArgs[0]
+		return signature([]Type{seqType(E)}, &SliceType{Elem: E}), func(args ...any) (res []any, err error) {
+			defer catchScriptError(&err)
+
+			seq, _ := args[0].(Function)
+			values := collect(seq, E)
+			slices.SortFunc(values, func(a, b any) int {
+				return compareKeys(E, a, b)
+			})
+			return []any{values}, nil
+		}
+	})
+
+	prog.declareGeneric(pkg, "BinarySearch", []string{"E"}, []Type{ordered}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		E := typeArgs[0]
+		return signature([]Type{&SliceType{Elem: E}, E}, IntType, BoolType), func(args ...any) ([]any, error) {
+			i, found := slices.BinarySearchFunc(args[0].([]any), args[1], func(elem, target any) int {
+				return compareKeys(E, elem, target)
+			})
+			return []any{i, found}, nil
+		}
+	})
+
+	prog.declareGeneric(pkg, "Index", []string{"E"}, []Type{ComparableType}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		return signature([]Type{&SliceType{Elem: typeArgs[0]}, typeArgs[0]}, IntType), func(args ...any) ([]any, error) {
+			i, err := indexAny(args[0].([]any), args[1])
+			return []any{i}, err
+		}
+	})
+	prog.declareGeneric(pkg, "Contains", []string{"E"}, []Type{ComparableType}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		return signature([]Type{&SliceType{Elem: typeArgs[0]}, typeArgs[0]}, BoolType), func(args ...any) ([]any, error) {
+			i, err := indexAny(args[0].([]any), args[1])
+			return []any{i >= 0}, err
+		}
+	})
+
+	prog.declareGeneric(pkg, "Reverse", []string{"E"}, []Type{AnyType}, func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error)) {
+		return signature([]Type{&SliceType{Elem: typeArgs[0]}}), func(args ...any) ([]any, error) {
+			slices.Reverse(args[0].([]any))
+			return nil, nil
+		}
+	})
+}
+
+// indexAny gives the index of the first element equal to the value or -1.
+func indexAny(slice []any, value any) (int, error) {
+	for i, elem := range slice {
+		equal, err := EqualAny(elem, value)
+		if err != nil {
+			return -1, err
+		}
+		if equal.(bool) {
+			return i, nil
+		}
+	}
+
+	return -1, nil
+}
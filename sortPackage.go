@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "sort",
+		Path:    "sort",
+		Declare: declareSort,
+	})
+}
+
+func declareSort(prog *Program, pkg *Package) {
+	sorts := []struct {
+		name string
+		elem Type
+	}{
+		{"Ints", IntType},
+		{"Float64s", Float64Type},
+		{"Strings", StringType},
+	}
+	for _, s := range sorts {
+		elem := s.elem
+		prog.declareFunction(pkg, s.name, signature([]Type{&SliceType{Elem: elem}}), func(args ...any) ([]any, error) {
+			slices.SortFunc(args[0].([]any), func(a, b any) int {
+				return compareKeys(elem, a, b)
+			})
+			return nil, nil
+		})
+	}
+
+	less := signature([]Type{IntType, IntType}, BoolType)
+	sliceSorts := []struct {
+		name string
+		sort func(x any, less func(i, j int) bool)
+	}{
+		{"Slice", sort.Slice},
+		{"SliceStable", sort.SliceStable},
+	}
+	for _, s := range sliceSorts {
+		sortSlice := s.sort
+		prog.declareFunction(pkg, s.name, signature([]Type{AnyType, less}), func(args ...any) (res []any, err error) {
+			defer catchScriptError(&err)
+
+			slice, err := swapper(args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			less, _ := args[1].(Function)
+			sortSlice(slice, func(i, j int) bool {
+				return callScript(less, i, j)[0].(bool)
+			})
+			return nil, nil
+		})
+	}
+
+	prog.declareFunction(pkg, "Search", signature([]Type{IntType, signature([]Type{IntType}, BoolType)}, IntType), func(args ...any) (res []any, err error) {
+		defer catchScriptError(&err)
+
+		f, _ := args[1].(Function)
+		return []any{sort.Search(args[0].(int), func(i int) bool {
+			return callScript(f, i)[0].(bool)
+		})}, nil
+	})
+}
+
+// swapper gives the slice sort.Slice sorts in place, the other values are rejected the way reflect does.
+func swapper(value any) ([]any, error) {
+	iface, ok := value.(InterfaceValue)
+	if !ok {
+		return nil, fmt.Errorf("reflect: call of Swapper on zero Value")
+	}
+
+	kind := "ptr"
+	switch Type := iface.Type.Underlying().(type) {
+	case *SliceType:
+		return iface.Value.([]any), nil
+	case *BasicType:
+		kind = Type.String()
+	case *StructType:
+		kind = "struct"
+	case *MapType:
+		kind = "map"
+	case *FunctionType:
+		kind = "func"
+	}
+
+	return nil, fmt.Errorf("reflect: call of Swapper on %v Value", kind)
+}
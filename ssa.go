@@ -0,0 +1,794 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// the operations of the SSA form which are not the instructions of the machine
+const (
+	// ssaPhi is the argument of the predecessor the block is entered from
+	ssaPhi vmOpcode = vmDepth + 1 + iota
+	// ssaParam is the register b the function is entered with: the parameter or the zero result
+	ssaParam
+	// ssaConstant is the constant of the operand b
+	ssaConstant
+	// ssaExtract is the register b of the registers set by the instruction of the argument
+	ssaExtract
+)
+
+var ssaOpcodeNames = map[vmOpcode]string{ssaPhi: "phi", ssaParam: "param", ssaConstant: "constant", ssaExtract: "extract"}
+
+// ssaValue is the value of the SSA form, it is set once: by the instruction of the machine or by the operation above.
+type ssaValue struct {
+	id    int
+	block *ssaBlock
+	// the instruction, its operands are the arguments, its other fields are the ones it is compiled with
+	in   vmInstruction
+	args []*ssaValue
+	// the count of the registers the value sets
+	width int
+	// the value this one is replaced with, the arguments are rewritten at the end of the pass
+	forward *ssaValue
+}
+
+// ssaBlock is the instructions which run one after another, the last one is the jump, the return or the next of the range loop.
+type ssaBlock struct {
+	id int
+	// the phis have the arguments in the order of the predecessors
+	phis, values []*ssaValue
+	preds, succs []*ssaBlock
+}
+
+// ssaFunction is the SSA form of the bytecode of the function. The constants and the types the passes add
+// go to the pools of the function, the other fields of the function stay as they are till the form is compiled back.
+type ssaFunction struct {
+	*vmFunction
+	// the entry block is the first, the blocks go in the order they are compiled back in
+	blocks    []*ssaBlock
+	constants map[int32]*ssaValue
+	// the operands of the constants of the basic types
+	constantIndex map[any]int32
+	values        int
+	blockCount    int
+}
+
+func (s *ssaFunction) block() *ssaBlock {
+	s.blockCount++
+	return &ssaBlock{id: s.blockCount - 1}
+}
+
+func (s *ssaFunction) value(block *ssaBlock, in vmInstruction, args ...*ssaValue) *ssaValue {
+	s.values++
+	return &ssaValue{id: s.values - 1, block: block, in: in, args: args}
+}
+
+// constant is the value of the constant operand.
+func (s *ssaFunction) constant(operand int32) *ssaValue {
+	if v, ok := s.constants[operand]; ok {
+		return v
+	}
+
+	v := s.value(nil, vmInstruction{op: ssaConstant, b: operand})
+	s.constants[operand] = v
+	return v
+}
+
+// constantValue is the value of the constant, the constants of the basic types are in the pool once.
+func (s *ssaFunction) constantValue(value any) *ssaValue {
+	switch value.(type) {
+	case int, int32, uint8, float64, complex128, string, bool, nil:
+		if operand, ok := s.constantIndex[value]; ok {
+			return s.constant(operand)
+		}
+	}
+
+	s.vmFunction.constants = append(s.vmFunction.constants, unboxed(value))
+	operand := int32(-len(s.vmFunction.constants))
+	switch value.(type) {
+	case int, int32, uint8, float64, complex128, string, bool, nil:
+		s.constantIndex[value] = operand
+	}
+	return s.constant(operand)
+}
+
+// basic reports whether the value is the constant of the basic type, the value of the operations the passes run.
+func (s *ssaFunction) basic(v *ssaValue) (any, bool) {
+	if v.in.op != ssaConstant {
+		return nil, false
+	}
+
+	switch value := s.vmFunction.constants[-1-v.in.b].boxed(); value.(type) {
+	case int, int32, uint8, float64, complex128, string, bool:
+		return value, true
+	}
+	return nil, false
+}
+
+// resolve is the value the value is replaced with.
+func (v *ssaValue) resolve() *ssaValue {
+	for v.forward != nil {
+		v = v.forward
+	}
+	return v
+}
+
+func (v *ssaValue) String() string {
+	return fmt.Sprintf("v%v", v.id)
+}
+
+// terminator reports whether the instruction ends the block.
+func (v *ssaValue) terminator() bool {
+	switch v.in.op {
+	case vmJump, vmJumpIfFalse, vmNext, vmReturn, vmTailCall, vmMissingReturn:
+		return true
+	}
+	return false
+}
+
+func (b *ssaBlock) terminator() *ssaValue {
+	return b.values[len(b.values)-1]
+}
+
+func (b *ssaBlock) String() string {
+	return fmt.Sprintf("b%v", b.id)
+}
+
+// predIndex is the index in the predecessors of the successor j of the block,
+// the block may go to the same successor by both of its jumps.
+func (b *ssaBlock) predIndex(j int) int {
+	succ := b.succs[j]
+	occurrence := 0
+	for _, other := range b.succs[:j] {
+		if other == succ {
+			occurrence++
+		}
+	}
+
+	for i, pred := range succ.preds {
+		if pred != b {
+			continue
+		}
+		if occurrence == 0 {
+			return i
+		}
+		occurrence--
+	}
+	panic("the block is not the predecessor of its successor")
+}
+
+// removeEdge removes the successor j of the block, the phis of the successor lose the arguments of the block.
+func (b *ssaBlock) removeEdge(j int) {
+	succ, index := b.succs[j], b.predIndex(j)
+	succ.preds = slices.Delete(succ.preds, index, index+1)
+	for _, phi := range succ.phis {
+		phi.args = slices.Delete(phi.args, index, index+1)
+	}
+	b.succs = slices.Delete(b.succs, j, j+1)
+}
+
+// rewrite replaces the arguments with the values they are replaced with and removes the replaced values.
+func (s *ssaFunction) rewrite() {
+	for _, block := range s.blocks {
+		for _, v := range slices.Concat(block.phis, block.values) {
+			for i, arg := range v.args {
+				v.args[i] = arg.resolve()
+			}
+		}
+	}
+
+	s.sweep(func(v *ssaValue) bool {
+		return v.forward == nil
+	})
+}
+
+// sweep removes the values which are not kept. The statements the removed value starts are started by the next value
+// of the block: the step budget counts the same statements.
+func (s *ssaFunction) sweep(keep func(v *ssaValue) bool) {
+	for _, block := range s.blocks {
+		block.phis = slices.DeleteFunc(block.phis, func(v *ssaValue) bool {
+			return !keep(v)
+		})
+
+		values := block.values[:0]
+		steps := int32(0)
+		for _, v := range block.values {
+			if !v.terminator() && !keep(v) {
+				steps += v.in.steps
+				continue
+			}
+			v.in.steps += steps
+			steps = 0
+			values = append(values, v)
+		}
+		clear(block.values[len(values):])
+		block.values = values
+	}
+}
+
+// removeTrivialPhis replaces the phis of one value, besides the phi itself, with the value.
+func (s *ssaFunction) removeTrivialPhis() {
+	for changed := true; changed; {
+		changed = false
+		for _, block := range s.blocks {
+			for _, phi := range block.phis {
+				if phi.forward != nil {
+					continue
+				}
+
+				var same *ssaValue
+				trivial := true
+				for _, arg := range phi.args {
+					arg = arg.resolve()
+					if arg == phi || arg == same {
+						continue
+					}
+					if same != nil {
+						trivial = false
+						break
+					}
+					same = arg
+				}
+				if !trivial {
+					continue
+				}
+				if same == nil {
+					// the phi of the loop no value comes into is never read
+					same = s.constantValue(nil)
+				}
+				phi.forward, changed = same, true
+			}
+		}
+	}
+	s.rewrite()
+}
+
+// prune removes the blocks which are not reached from the entry.
+func (s *ssaFunction) prune() {
+	reached := map[*ssaBlock]bool{}
+	for _, block := range s.order() {
+		reached[block] = true
+	}
+
+	for _, block := range s.blocks {
+		if reached[block] {
+			continue
+		}
+		for j := len(block.succs) - 1; j >= 0; j-- {
+			block.removeEdge(j)
+		}
+	}
+	s.blocks = slices.DeleteFunc(s.blocks, func(block *ssaBlock) bool {
+		return !reached[block]
+	})
+}
+
+// order is the blocks reached from the entry in the reverse postorder: the block goes after its predecessors
+// but the ones of the loops.
+func (s *ssaFunction) order() []*ssaBlock {
+	var res []*ssaBlock
+	visited := map[*ssaBlock]bool{}
+	var visit func(block *ssaBlock)
+	visit = func(block *ssaBlock) {
+		visited[block] = true
+		for _, succ := range block.succs {
+			if !visited[succ] {
+				visit(succ)
+			}
+		}
+		res = append(res, block)
+	}
+	visit(s.blocks[0])
+
+	slices.Reverse(res)
+	return res
+}
+
+// dominators gives the immediate dominator of each block reached from the entry, the one of the entry is nil.
+func (s *ssaFunction) dominators(order []*ssaBlock) map[*ssaBlock]*ssaBlock {
+	index := make(map[*ssaBlock]int, len(order))
+	for i, block := range order {
+		index[block] = i
+	}
+
+	idom := map[*ssaBlock]*ssaBlock{order[0]: order[0]}
+	intersect := func(a, b *ssaBlock) *ssaBlock {
+		for a != b {
+			for index[a] > index[b] {
+				a = idom[a]
+			}
+			for index[b] > index[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, block := range order[1:] {
+			var dominator *ssaBlock
+			for _, pred := range block.preds {
+				if _, ok := idom[pred]; !ok {
+					continue
+				}
+				if dominator == nil {
+					dominator = pred
+				} else {
+					dominator = intersect(pred, dominator)
+				}
+			}
+			if idom[block] != dominator {
+				idom[block], changed = dominator, true
+			}
+		}
+	}
+
+	idom[order[0]] = nil
+	return idom
+}
+
+// vmSuccessors are the instructions the instruction goes to.
+func vmSuccessors(code []vmInstruction, pc int) []int {
+	switch in := code[pc]; in.op {
+	case vmJump:
+		return []int{int(in.a)}
+	case vmJumpIfFalse:
+		return []int{pc + 1, int(in.b)}
+	case vmNext:
+		return []int{pc + 1, int(in.c)}
+	case vmReturn, vmTailCall, vmMissingReturn:
+		return nil
+	}
+	return []int{pc + 1}
+}
+
+// vmReachable marks the instructions reached from the first one.
+func vmReachable(code []vmInstruction) []bool {
+	reached := make([]bool, len(code))
+	stack := []int{0}
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reached[pc] {
+			continue
+		}
+		reached[pc] = true
+		stack = append(stack, vmSuccessors(code, pc)...)
+	}
+
+	return reached
+}
+
+// ssaBuilder builds the SSA form of the bytecode, the registers are the variables the phis merge.
+type ssaBuilder struct {
+	*ssaFunction
+	// the bytecode being built: the one of the function or of the function being inlined
+	source *vmFunction
+	// the operands of the constants and the indexes of the types of the source are moved by these
+	constantBase, typeBase int32
+	// the values of the registers at the end of the blocks
+	defs       map[*ssaBlock]map[int32]*ssaValue
+	sealed     map[*ssaBlock]bool
+	filled     map[*ssaBlock]bool
+	incomplete map[*ssaBlock]map[int32]*ssaValue
+	// the block the bytecode is entered from, the registers it doesn't set are never read
+	start *ssaBlock
+	// the returns of the inlined function jump to exit, results are the values they return
+	exit    *ssaBlock
+	results [][]*ssaValue
+}
+
+// buildSSA builds the SSA form of the bytecode of the function.
+func buildSSA(f *vmFunction) (*ssaFunction, error) {
+	s := &ssaFunction{vmFunction: f, constants: map[int32]*ssaValue{}, constantIndex: map[any]int32{}}
+	for i, constant := range f.constants {
+		switch value := constant.boxed(); value.(type) {
+		case int, int32, uint8, float64, complex128, string, bool, nil:
+			if _, ok := s.constantIndex[value]; !ok {
+				s.constantIndex[value] = int32(-1 - i)
+			}
+		}
+	}
+
+	// the deferred calls run and the bodies of the loops over the functions are entered by the machine itself
+	switch {
+	case f.defers >= 0:
+		return nil, fmt.Errorf("the function defers the calls")
+	case slices.ContainsFunc(f.code, func(in vmInstruction) bool { return in.op == vmSelect || in.op == vmRangeFunc }):
+		return nil, fmt.Errorf("the function has the select statement or the range loop over the function")
+	}
+
+	// the entry sets the registers the machine sets when the function is called
+	entry := s.block()
+	b := s.builder(f, entry)
+	for register := range f.frameSize() {
+		v := s.value(entry, vmInstruction{op: ssaParam, b: int32(register)})
+		v.width = 1
+		entry.values = append(entry.values, v)
+		b.defs[entry][int32(register)] = v
+	}
+	entry.values = append(entry.values, s.value(entry, vmInstruction{op: vmJump}))
+	s.blocks = append(s.blocks, entry)
+	s.blocks = append(s.blocks, b.build(entry)...)
+
+	s.removeTrivialPhis()
+	for _, block := range s.blocks {
+		for _, v := range block.values {
+			if v.in.op == vmNext && v.args[0].in.op != vmRange {
+				return nil, fmt.Errorf("the state of the range loop is merged from several ones")
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *ssaFunction) builder(source *vmFunction, start *ssaBlock) *ssaBuilder {
+	return &ssaBuilder{
+		ssaFunction: s,
+		source:      source,
+		start:       start,
+		defs:        map[*ssaBlock]map[int32]*ssaValue{start: {}},
+		sealed:      map[*ssaBlock]bool{start: true},
+		filled:      map[*ssaBlock]bool{start: true},
+		incomplete:  map[*ssaBlock]map[int32]*ssaValue{},
+	}
+}
+
+// build builds the blocks of the bytecode of the source, they are entered from the start which jumps to the first one.
+// The blocks are returned in the order of the bytecode.
+func (b *ssaBuilder) build(start *ssaBlock) []*ssaBlock {
+	code := b.source.code
+	reached := vmReachable(code)
+	leaders := map[int]bool{0: true}
+	for pc, in := range code {
+		if !reached[pc] {
+			continue
+		}
+		for _, next := range vmSuccessors(code, pc) {
+			if next != pc+1 || in.op == vmJumpIfFalse || in.op == vmNext {
+				leaders[next] = true
+			}
+		}
+	}
+
+	// the blocks and the instructions they end before
+	var blocks []*ssaBlock
+	byPC := map[int]*ssaBlock{}
+	ends := map[*ssaBlock]int{}
+	starts := map[*ssaBlock]int{}
+	for pc := range code {
+		if !reached[pc] || !leaders[pc] {
+			continue
+		}
+		block := b.block()
+		blocks = append(blocks, block)
+		byPC[pc], starts[block] = block, pc
+		end := pc + 1
+		for end < len(code) && !leaders[end] && !(&ssaValue{in: code[end-1]}).terminator() {
+			end++
+		}
+		ends[block] = end
+	}
+
+	start.succs = append(start.succs, byPC[0])
+	byPC[0].preds = append(byPC[0].preds, start)
+	for _, block := range blocks {
+		for _, next := range vmSuccessors(code, ends[block]-1) {
+			block.succs = append(block.succs, byPC[next])
+			byPC[next].preds = append(byPC[next].preds, block)
+		}
+	}
+
+	b.seal(byPC[0])
+	for _, block := range b.order(byPC[0], blocks) {
+		b.defs[block] = map[int32]*ssaValue{}
+		for pc := starts[block]; pc < ends[block]; pc++ {
+			b.instruction(block, code[pc])
+		}
+		if len(block.values) == 0 || !block.terminator().terminator() {
+			// the block goes on to the next instruction
+			block.values = append(block.values, b.value(block, vmInstruction{op: vmJump}))
+		}
+		b.filled[block] = true
+
+		for _, succ := range block.succs {
+			if succ != b.exit {
+				b.seal(succ)
+			}
+		}
+	}
+
+	return blocks
+}
+
+// order is the blocks of the bytecode from the first one in the reverse postorder, the blocks are filled in it.
+func (b *ssaBuilder) order(first *ssaBlock, blocks []*ssaBlock) []*ssaBlock {
+	var res []*ssaBlock
+	visited := map[*ssaBlock]bool{}
+	for _, block := range blocks {
+		visited[block] = false
+	}
+	var visit func(block *ssaBlock)
+	visit = func(block *ssaBlock) {
+		visited[block] = true
+		for _, succ := range block.succs {
+			if done, ok := visited[succ]; ok && !done {
+				visit(succ)
+			}
+		}
+		res = append(res, block)
+	}
+	visit(first)
+
+	slices.Reverse(res)
+	return res
+}
+
+// seal adds the arguments to the phis of the block when all its predecessors are filled.
+func (b *ssaBuilder) seal(block *ssaBlock) {
+	if b.sealed[block] {
+		return
+	}
+	for _, pred := range block.preds {
+		if !b.filled[pred] {
+			return
+		}
+	}
+
+	b.sealed[block] = true
+	phis := b.incomplete[block]
+	registers := make([]int32, 0, len(phis))
+	for register := range phis {
+		registers = append(registers, register)
+	}
+	slices.Sort(registers)
+	for _, register := range registers {
+		b.addArguments(block, register, phis[register])
+	}
+}
+
+func (b *ssaBuilder) write(block *ssaBlock, register int32, v *ssaValue) {
+	b.defs[block][register] = v
+}
+
+// read is the value of the register in the block, the phis are added where the values of the predecessors merge.
+func (b *ssaBuilder) read(block *ssaBlock, register int32) *ssaValue {
+	if v, ok := b.defs[block][register]; ok {
+		return v
+	}
+
+	var v *ssaValue
+	switch {
+	case !b.sealed[block]:
+		v = b.phi(block)
+		if b.incomplete[block] == nil {
+			b.incomplete[block] = map[int32]*ssaValue{}
+		}
+		b.incomplete[block][register] = v
+	case block == b.start:
+		// the register is never set before it is read
+		v = b.constantValue(nil)
+	case len(block.preds) == 1:
+		v = b.read(block.preds[0], register)
+	default:
+		v = b.phi(block)
+		b.write(block, register, v)
+		b.addArguments(block, register, v)
+	}
+	b.write(block, register, v)
+	return v
+}
+
+func (b *ssaBuilder) phi(block *ssaBlock) *ssaValue {
+	v := b.value(block, vmInstruction{op: ssaPhi})
+	v.width = 1
+	block.phis = append(block.phis, v)
+	return v
+}
+
+func (b *ssaBuilder) addArguments(block *ssaBlock, register int32, phi *ssaValue) {
+	for _, pred := range block.preds {
+		phi.args = append(phi.args, b.read(pred, register))
+	}
+}
+
+// operand is the value of the operand of the source.
+func (b *ssaBuilder) operand(block *ssaBlock, operand int32) *ssaValue {
+	if operand >= 0 {
+		return b.read(block, operand)
+	}
+	return b.constant(operand - b.constantBase)
+}
+
+func (b *ssaBuilder) operands(block *ssaBlock, from int32, count int) []*ssaValue {
+	res := make([]*ssaValue, count)
+	for i := range res {
+		res[i] = b.read(block, from+int32(i))
+	}
+	return res
+}
+
+// define makes the instruction set the registers from the register. The values of the instruction setting several
+// registers are the extracts of it, they are in no block: they take the registers of the instruction.
+func (b *ssaBuilder) define(block *ssaBlock, v *ssaValue, register int32, width int) {
+	v.width = width
+	if width == 1 {
+		b.write(block, register, v)
+		return
+	}
+
+	for i := range width {
+		extract := b.value(block, vmInstruction{op: ssaExtract, b: int32(i)}, v)
+		extract.width = 1
+		b.write(block, register+int32(i), extract)
+	}
+}
+
+// instruction adds the value of the instruction of the source to the block.
+func (b *ssaBuilder) instruction(block *ssaBlock, in vmInstruction) {
+	v := b.value(block, in)
+	block.values = append(block.values, v)
+	params := int32(len(b.source.function.inputVariables))
+	results := len(b.source.function.outputVariables)
+
+	switch in.op {
+	case vmMove, vmCopy, vmNot, vmLen, vmField, vmFieldRef:
+		v.args = []*ssaValue{b.operand(block, in.b)}
+		b.define(block, v, in.a, 1)
+	case vmConvert, vmBox:
+		v.args = []*ssaValue{b.operand(block, in.b)}
+		v.in.c += b.typeBase
+		b.define(block, v, in.a, 1)
+	case vmZero:
+		v.in.b += b.typeBase
+		b.define(block, v, in.a, 1)
+	case vmAdd, vmSub, vmMul, vmDiv, vmOr, vmAnd, vmEq, vmNe, vmLt, vmLe, vmGt, vmGe:
+		v.args = []*ssaValue{b.operand(block, in.b), b.operand(block, in.c)}
+		b.define(block, v, in.a, 1)
+	case vmIndex, vmIndexRef:
+		v.args = []*ssaValue{b.operand(block, in.b), b.operand(block, in.c)}
+		v.in.d += b.typeBase
+		b.define(block, v, in.a, 1)
+	case vmSetIndex:
+		v.args = []*ssaValue{b.operand(block, in.a), b.operand(block, in.b), b.operand(block, in.c)}
+	case vmPackage, vmDepth:
+		v.in.b -= b.constantBase
+		if in.op == vmPackage {
+			b.define(block, v, in.a, 1)
+		}
+	case vmEval, vmExec:
+		for _, register := range b.source.escapes[in.b].registers {
+			v.args = append(v.args, b.read(block, register))
+		}
+		if in.op == vmEval {
+			b.define(block, v, in.a, int(in.c))
+		}
+	case vmCall, vmCallValue:
+		call := b.source.calls[in.b]
+		v.args = b.operands(block, in.c, call.args)
+		if in.op == vmCallValue {
+			v.args = append(v.args, b.operand(block, in.d))
+		}
+		if call.results > 0 {
+			b.define(block, v, in.a, call.results)
+		}
+	case vmJumpIfFalse:
+		v.args = []*ssaValue{b.operand(block, in.a)}
+	case vmRange:
+		// the state of the loop takes two registers, the next of the loop reads both
+		v.args = []*ssaValue{b.operand(block, in.b)}
+		v.width = 2
+		b.write(block, in.a, v)
+	case vmNext:
+		v.args = []*ssaValue{b.read(block, in.a)}
+		b.define(block, v, in.b, 2)
+	case vmReturn:
+		v.args = b.operands(block, params, results)
+		if b.exit != nil {
+			// the inlined function goes on with the caller
+			b.results = append(b.results, v.args)
+			v.in, v.args = vmInstruction{op: vmJump, steps: in.steps}, nil
+			block.succs = append(block.succs, b.exit)
+			b.exit.preds = append(b.exit.preds, block)
+		}
+	case vmTailCall:
+		v.args = b.operands(block, in.a, int(params))
+	case vmCell:
+		v.args = []*ssaValue{b.operand(block, in.b)}
+		b.define(block, v, in.a, 1)
+	case vmLoad:
+		v.args = []*ssaValue{b.read(block, in.b)}
+		b.define(block, v, in.a, 1)
+	case vmStore:
+		v.args = []*ssaValue{b.read(block, in.a), b.operand(block, in.b)}
+	}
+}
+
+// dumpSSA writes the SSA form after the pass, if the dump is requested.
+func (prog *Program) dumpSSA(name, pass string, s *ssaFunction) {
+	if prog.IRDump == nil {
+		return
+	}
+
+	fmt.Fprintf(prog.IRDump, "%v after %v:\n", name, pass)
+	s.dump(prog.IRDump, "\t")
+}
+
+func (s *ssaFunction) dump(w io.Writer, indent string) {
+	for _, block := range s.blocks {
+		preds := make([]string, len(block.preds))
+		for i, pred := range block.preds {
+			preds[i] = pred.String()
+		}
+		if len(preds) == 0 {
+			fmt.Fprintf(w, "%v%v:\n", indent, block)
+		} else {
+			fmt.Fprintf(w, "%v%v: <- %v\n", indent, block, strings.Join(preds, ", "))
+		}
+
+		for _, v := range slices.Concat(block.phis, block.values) {
+			fmt.Fprintf(w, "%v\t%v %v\n", indent, stepMarker(v.in.steps), s.describe(v))
+		}
+	}
+}
+
+func (s *ssaFunction) describe(v *ssaValue) string {
+	name, ok := ssaOpcodeNames[v.in.op]
+	if !ok {
+		name = vmOpcodeNames[v.in.op]
+	}
+	args := make([]string, len(v.args))
+	for i, arg := range v.args {
+		args[i] = s.operand(arg)
+	}
+
+	var res string
+	switch in := v.in; in.op {
+	case ssaParam:
+		res = fmt.Sprintf("%v r%v", name, in.b)
+	case vmField, vmFieldRef:
+		res = fmt.Sprintf("%v %v, %v", name, args[0], in.c)
+	case vmZero:
+		res = fmt.Sprintf("%v %v", name, s.types[in.b])
+	case vmConvert, vmBox:
+		res = fmt.Sprintf("%v %v, %v", name, args[0], s.types[in.c])
+	case vmPackage:
+		res = fmt.Sprintf("%v %v", name, s.vmFunction.constants[-1-in.b].ref.(*PackageValue).Value)
+	case vmDepth:
+		res = fmt.Sprintf("%v %v", name, s.vmFunction.operand(in.b))
+	case vmEval, vmExec:
+		res = fmt.Sprintf("%v %v(%v)", name, describeInstruction(s.escapes[in.b].instruction), strings.Join(args, ", "))
+	case vmCall:
+		res = fmt.Sprintf("%v %v(%v)", name, s.calls[in.b].function.Name(), strings.Join(args, ", "))
+	case vmCallValue:
+		res = fmt.Sprintf("%v %v(%v)", name, args[len(args)-1], strings.Join(args[:len(args)-1], ", "))
+	case vmJump:
+		res = fmt.Sprintf("%v %v", name, v.block.succs[0])
+	case vmJumpIfFalse:
+		res = fmt.Sprintf("%v %v, %v else %v", name, args[0], v.block.succs[1], v.block.succs[0])
+	case vmRange:
+		res = fmt.Sprintf("%v %v, kind %v", name, args[0], in.c)
+	case vmNext:
+		res = fmt.Sprintf("%v %v, %v else %v", name, args[0], v.block.succs[1], v.block.succs[0])
+	default:
+		res = strings.TrimSpace(name + " " + strings.Join(args, ", "))
+	}
+
+	if v.width == 0 {
+		return res
+	}
+	return fmt.Sprintf("%v = %v", v, res)
+}
+
+// operand writes the argument for the dump: the constant, the value or the register of the value setting several ones.
+func (s *ssaFunction) operand(v *ssaValue) string {
+	switch v.in.op {
+	case ssaConstant:
+		return s.vmFunction.operand(v.in.b)
+	case ssaExtract:
+		return fmt.Sprintf("%v.%v", v.args[0], v.in.b)
+	}
+	return v.String()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const inlining = `package main
+
+import "fmt"
+
+func square(x int) int {
+	return x * x;
+}
+
+func norm(a, b int) int {
+	return square(a) + square(b);
+}
+
+func twice(a, b int) int {
+	return (a + b) * (a + b);
+}
+
+func folded() int {
+	x := 2 * 3;
+	return x + 4;
+}
+
+func deep(n int) int {
+	if n == 0 {
+		return square(n);
+	}
+	return deep(n - 1);
+}
+
+func main() {
+	fmt.Println(norm(3, 4), twice(1, 2), folded(), deep(3));
+}
+`
+
+const deadCode = `package main
+
+func unused(a int, s string) int {
+	same := a == 2;
+	n := len(s);
+	f := float64(a);
+	r := string(rune(a));
+	return a;
+}
+
+func compare(a, b any) bool {
+	same := a == b;
+	return true;
+}
+
+func main() {
+	unused(1, "a");
+	compare([]int{1}, []int{1});
+}
+`
+
+// compiledFunction is the bytecode of the function of the script lowered at the optimization level.
+func compiledFunction(t *testing.T, source string, level int, name string) *vmFunction {
+	t.Helper()
+
+	prog := compileScript(t, source)
+	prog.Backend = BackendVM
+	prog.Optimization = level
+	prog.Lower()
+	return prog.functions[prog.functionID[MainPackagePath+"."+name]].(*IntrpretatedFunction).vm
+}
+
+// count is the count of the instructions of the opcode in the bytecode.
+func count(f *vmFunction, op vmOpcode) int {
+	res := 0
+	for _, in := range f.code {
+		if in.op == op {
+			res++
+		}
+	}
+	return res
+}
+
+func TestSSA(t *testing.T) {
+	for level := range 3 {
+		for _, script := range []struct{ source, output string }{{recursion, recursionOutput}, {inlining, "25 9 10 0\n"}} {
+			prog := compileScript(t, script.source)
+			prog.Backend = BackendVM
+			prog.Optimization = level
+
+			var err error
+			output := captureOutput(t, func() {
+				err = prog.Execute(context.Background())
+			})
+			if err != nil {
+				t.Fatalf("-O%v: %v", level, err)
+			}
+			if output != script.output {
+				t.Errorf("-O%v: the output is %q, %q is expected", level, output, script.output)
+			}
+		}
+	}
+
+	t.Run("inlining", func(t *testing.T) {
+		if calls := count(compiledFunction(t, inlining, 1, "norm"), vmCall); calls != 2 {
+			t.Errorf("norm makes %v calls at -O1, 2 are expected", calls)
+		}
+		if calls := count(compiledFunction(t, inlining, 2, "norm"), vmCall); calls != 0 {
+			t.Errorf("norm makes %v calls at -O2, the calls of square are expected to be inlined", calls)
+		}
+	})
+
+	t.Run("common subexpressions", func(t *testing.T) {
+		if adds := count(compiledFunction(t, inlining, 1, "twice"), vmAdd); adds != 1 {
+			t.Errorf("twice adds %v times, the sum is expected to be computed once", adds)
+		}
+	})
+
+	t.Run("constants", func(t *testing.T) {
+		f := compiledFunction(t, inlining, 1, "folded")
+		if len(f.code) != 2 || f.code[0].op != vmMove || f.code[1].op != vmReturn {
+			t.Errorf("the constant result is expected to be returned, the bytecode is %v instructions", len(f.code))
+		}
+	})
+
+	t.Run("divide by zero", func(t *testing.T) {
+		prog := compileScript(t, "package main\n\nfunc main() {\n\ta := 2 - 2;\n\ta = 1 / a;\n}\n")
+		prog.Backend = BackendVM
+		prog.Optimization = 2
+
+		err := prog.Execute(context.Background())
+		if err != errDivideByZero {
+			t.Errorf("the error is %v, %q is expected", err, errDivideByZero)
+		}
+	})
+
+	t.Run("stack overflow", func(t *testing.T) {
+		// the depth of the inlined call is checked like the one of the call
+		var errs [3]string
+		for level := range errs {
+			prog := compileScript(t, inlining)
+			prog.Backend = BackendVM
+			prog.Optimization = level
+			prog.MaxCallDepth = 5
+
+			captureOutput(t, func() {
+				if err := prog.Execute(context.Background()); err != nil {
+					errs[level] = err.Error()
+				}
+			})
+		}
+		if errs[0] == "" || errs[1] != errs[0] || errs[2] != errs[0] {
+			t.Errorf("the errors are %q, the same stack overflow is expected", errs)
+		}
+	})
+
+	t.Run("dead code", func(t *testing.T) {
+		// the operations of the basic values can't fail and are removed
+		f := compiledFunction(t, deadCode, 1, "unused")
+		for _, op := range []vmOpcode{vmEq, vmLen, vmConvert} {
+			if n := count(f, op); n != 0 {
+				t.Errorf("unused has %v %v instructions at -O1, they are expected to be removed", n, vmOpcodeNames[op])
+			}
+		}
+
+		// the comparison of the interface values fails if the dynamic type is uncomparable
+		if eqs := count(compiledFunction(t, deadCode, 1, "compare"), vmEq); eqs != 1 {
+			t.Errorf("compare has %v eq instructions at -O1, the comparison is expected to be kept", eqs)
+		}
+		for level := range 3 {
+			prog := compileScript(t, deadCode)
+			prog.Backend = BackendVM
+			prog.Optimization = level
+
+			err := prog.Execute(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "uncomparable") {
+				t.Errorf("-O%v: the error is %v, the comparison of the slices is expected to fail", level, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// ssaPass is the optimization of the SSA form of the bytecode, it runs if the optimization level is high enough.
+type ssaPass struct {
+	name  string
+	level int
+	run   func(s *ssaFunction)
+}
+
+var ssaPasses = []ssaPass{
+	{"inline", 2, (*ssaFunction).inline},
+	{"copies", 1, (*ssaFunction).propagateCopies},
+	{"fold", 1, (*ssaFunction).foldConstants},
+	{"cse", 1, (*ssaFunction).eliminateCommonSubexpressions},
+	{"dead", 1, (*ssaFunction).eliminateDeadCode},
+}
+
+// inlineLimit is the count of the instructions of the biggest function which is inlined.
+const inlineLimit = 32
+
+// optimizeVM builds the SSA form of the bytecode of the function, runs the passes of the optimization level on it
+// and compiles it back into the bytecode. The function the form can't be built of keeps its bytecode.
+func (prog *Program) optimizeVM(f *vmFunction) {
+	if prog.Optimization < 1 {
+		return
+	}
+
+	name := f.function.Name()
+	s, err := buildSSA(f)
+	if err != nil {
+		if prog.IRDump != nil {
+			fmt.Fprintf(prog.IRDump, "%v after ssa:\n\tnot built: %v\n", name, err)
+		}
+		return
+	}
+	prog.dumpSSA(name, "ssa", s)
+
+	for _, pass := range ssaPasses {
+		if pass.level > prog.Optimization {
+			continue
+		}
+
+		pass.run(s)
+		prog.dumpSSA(name, pass.name, s)
+	}
+
+	s.compile()
+	prog.dumpBytecode(name, "registers", f, "")
+}
+
+// inlinable reports whether the calls of the function are replaced with its body: the function is small
+// and calls nothing, so the functions being called are the same in it and in its caller.
+func inlinable(f *vmFunction) bool {
+	if f == nil || len(f.code) > inlineLimit {
+		return false
+	}
+
+	reached := vmReachable(f.code)
+	for pc, in := range f.code {
+		if !reached[pc] {
+			continue
+		}
+		switch in.op {
+		case vmCall, vmCallValue, vmEval, vmExec, vmTailCall, vmMissingReturn, vmDepth, vmSelect, vmRangeFunc:
+			return false
+		case vmRange:
+			// the receive may park the goroutine in the function
+			if rangeKind(in.c) == rangeChannel {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// inline replaces the calls of the small functions with their bodies. The arguments are copied like the call copies them
+// and the depth of the calls is checked where the call would check it.
+func (s *ssaFunction) inline() {
+	// the constants and the types of the inlined functions are added to the pools once
+	bases := map[*vmFunction][2]int32{}
+
+	for i := 0; i < len(s.blocks); i++ {
+		block := s.blocks[i]
+		for at, v := range block.values {
+			if v.in.op != vmCall {
+				continue
+			}
+			call := s.calls[v.in.b]
+			callee := call.compiled
+			if !inlinable(callee) || call.args != len(callee.function.inputVariables) ||
+				call.results >= 0 && call.results != len(callee.function.outputVariables) {
+				continue
+			}
+
+			base, ok := bases[callee]
+			if !ok {
+				base = [2]int32{int32(len(s.vmFunction.constants)), int32(len(s.types))}
+				s.vmFunction.constants = append(s.vmFunction.constants, callee.constants...)
+				s.types = append(s.types, callee.types...)
+				bases[callee] = base
+			}
+			s.inlineCall(block, at, callee, base[0], base[1])
+			// the rest of the block is the block after the inlined ones, it is looked at later
+			break
+		}
+	}
+
+	s.prune()
+	s.removeTrivialPhis()
+}
+
+// inlineCall replaces the call at the index of the block with the body of the callee: the block sets the parameters
+// and jumps to the body, the returns of the body jump to the block of the rest of the block.
+func (s *ssaFunction) inlineCall(block *ssaBlock, at int, callee *vmFunction, constantBase, typeBase int32) {
+	call := block.values[at]
+	rest := s.block()
+	rest.values = block.values[at+1:]
+	for _, v := range rest.values {
+		v.block = rest
+	}
+	rest.succs = block.succs
+	for _, succ := range rest.succs {
+		for i, pred := range succ.preds {
+			if pred == block {
+				succ.preds[i] = rest
+			}
+		}
+	}
+
+	b := s.builder(callee, block)
+	b.constantBase, b.typeBase, b.exit = constantBase, typeBase, rest
+	depth := s.value(block, vmInstruction{op: vmDepth, steps: call.in.steps, b: s.constantValue(callee.function).in.b})
+	block.values = append(block.values[:at:at], depth)
+	block.succs = nil
+
+	params := callee.function.inputVariables
+	for i, arg := range call.args {
+		if copied(params[i].Type) {
+			arg = s.value(block, vmInstruction{op: vmCopy}, arg)
+			arg.width = 1
+			block.values = append(block.values, arg)
+		}
+		b.write(block, int32(i), arg)
+	}
+	for i, output := range callee.function.outputVariables {
+		var zero *ssaValue
+		if value := callee.zeros[i]; value.kind != kindAny {
+			zero = s.constantValue(value.boxed())
+		} else {
+			zero = s.value(block, vmInstruction{op: vmZero, b: int32(len(s.types))})
+			zero.width = 1
+			s.types = append(s.types, output.Type)
+			block.values = append(block.values, zero)
+		}
+		b.write(block, int32(len(params)+i), zero)
+	}
+	block.values = append(block.values, s.value(block, vmInstruction{op: vmJump}))
+
+	body := b.build(block)
+	index := slices.Index(s.blocks, block)
+	s.blocks = slices.Insert(s.blocks, index+1, append(body, rest)...)
+
+	// the results are merged from the returns
+	results := make([]*ssaValue, len(callee.function.outputVariables))
+	for i := range results {
+		phi := b.phi(rest)
+		for _, values := range b.results {
+			phi.args = append(phi.args, values[i])
+		}
+		results[i] = phi
+	}
+	switch {
+	case call.width == 1:
+		call.forward = results[0]
+	case call.width > 1:
+		s.forwardExtracts(call, results)
+	}
+}
+
+// forwardExtracts replaces the registers of the instruction setting several ones with the values.
+func (s *ssaFunction) forwardExtracts(v *ssaValue, values []*ssaValue) {
+	for _, block := range s.blocks {
+		for _, user := range slices.Concat(block.phis, block.values) {
+			for _, arg := range user.args {
+				if arg.in.op == ssaExtract && arg.args[0] == v {
+					arg.forward = values[arg.in.b]
+				}
+			}
+		}
+	}
+}
+
+// propagateCopies replaces the moves with the values they move, the copies of the basic constants with the constants
+// and the phis of one value with the value.
+func (s *ssaFunction) propagateCopies() {
+	for _, block := range s.blocks {
+		for _, v := range block.values {
+			switch v.in.op {
+			case vmMove:
+				v.forward = v.args[0].resolve()
+			case vmCopy:
+				if _, ok := s.basic(v.args[0].resolve()); ok {
+					v.forward = v.args[0].resolve()
+				}
+			}
+		}
+	}
+
+	s.removeTrivialPhis()
+}
+
+// foldConstants evaluates the operations of the constants once and turns the conditional jumps on the constants
+// into the plain ones. The operations which fail at run time are left to fail there.
+func (s *ssaFunction) foldConstants() {
+	for _, block := range s.order() {
+		for _, v := range block.values {
+			for i, arg := range v.args {
+				v.args[i] = arg.resolve()
+			}
+
+			switch v.in.op {
+			case vmAdd, vmSub, vmMul, vmDiv, vmOr, vmAnd, vmEq, vmNe, vmLt, vmLe, vmGt, vmGe, vmNot:
+				if value, ok := s.evaluate(v); ok {
+					v.forward = s.constantValue(value)
+				}
+			case vmJumpIfFalse:
+				condition, ok := s.basic(v.args[0])
+				if !ok {
+					continue
+				}
+				// the jump goes on to the first successor if the condition holds
+				taken := 0
+				if condition == false {
+					taken = 1
+				}
+				block.removeEdge(1 - taken)
+				v.in.op, v.args = vmJump, nil
+			}
+		}
+	}
+
+	s.prune()
+	s.removeTrivialPhis()
+}
+
+// evaluate runs the operation of the constants like the machine does, the operation which fails is not evaluated.
+func (s *ssaFunction) evaluate(v *ssaValue) (res any, ok bool) {
+	operands := make([]any, len(v.args))
+	for i, arg := range v.args {
+		if operands[i], ok = s.basic(arg); !ok {
+			return nil, false
+		}
+	}
+
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	var err error
+	switch op := v.in.op; op {
+	case vmAdd, vmSub, vmMul, vmDiv:
+		if x, ok := operands[0].(int); ok {
+			if y, ok := operands[1].(int); ok {
+				var value vmValue
+				value, err = arithmetic(op, x, y)
+				return value.boxed(), err == nil
+			}
+		}
+		res, err = vmArithmetic[op-vmAdd](operands[0], operands[1])
+	case vmOr:
+		res, err = OrAny(operands[0], operands[1])
+	case vmAnd:
+		res, err = AndAny(operands[0], operands[1])
+	case vmNot:
+		res, err = NotAny(operands[0])
+	default:
+		res, err = CompareAny(operands[0], operands[1], vmCompareTypes[op-vmEq])
+	}
+	return res, err == nil
+}
+
+// ssaKey is the operation and the operands the value is computed of, the values of the same key are the same.
+type ssaKey struct {
+	op   vmOpcode
+	x, y *ssaValue
+	c    int32
+}
+
+// eliminateCommonSubexpressions replaces the value with the same one computed in the block dominating it.
+// Only the operations of the values no instruction changes are looked at: the conversions into the basic types
+// and not the ones making the slices, for example.
+func (s *ssaFunction) eliminateCommonSubexpressions() {
+	order := s.order()
+	idom := s.dominators(order)
+	children := map[*ssaBlock][]*ssaBlock{}
+	for _, block := range order[1:] {
+		children[idom[block]] = append(children[idom[block]], block)
+	}
+
+	values := map[ssaKey]*ssaValue{}
+	var visit func(block *ssaBlock)
+	visit = func(block *ssaBlock) {
+		var added []ssaKey
+		for _, v := range block.values {
+			for i, arg := range v.args {
+				v.args[i] = arg.resolve()
+			}
+
+			key, ok := s.key(v)
+			if !ok {
+				continue
+			}
+			if same, ok := values[key]; ok {
+				v.forward = same
+				continue
+			}
+			values[key] = v
+			added = append(added, key)
+		}
+
+		for _, child := range children[block] {
+			visit(child)
+		}
+		for _, key := range added {
+			delete(values, key)
+		}
+	}
+	visit(order[0])
+
+	s.rewrite()
+}
+
+func (s *ssaFunction) key(v *ssaValue) (ssaKey, bool) {
+	switch v.in.op {
+	case vmAdd, vmSub, vmMul, vmDiv, vmOr, vmAnd, vmEq, vmNe, vmLt, vmLe, vmGt, vmGe:
+		return ssaKey{op: v.in.op, x: v.args[0], y: v.args[1]}, true
+	case vmNot:
+		return ssaKey{op: v.in.op, x: v.args[0]}, true
+	case vmConvert:
+		if _, ok := s.types[v.in.c].Underlying().(*BasicType); ok {
+			return ssaKey{op: v.in.op, x: v.args[0], c: v.in.c}, true
+		}
+	}
+
+	return ssaKey{}, false
+}
+
+// eliminateDeadCode removes the values which are not used and change nothing, the operations which may fail are kept.
+func (s *ssaFunction) eliminateDeadCode() {
+	s.prune()
+
+	live := map[*ssaValue]bool{}
+	var mark func(v *ssaValue)
+	mark = func(v *ssaValue) {
+		if live[v] {
+			return
+		}
+		live[v] = true
+		for _, arg := range v.args {
+			mark(arg)
+		}
+	}
+	for _, block := range s.blocks {
+		for _, v := range block.values {
+			if !s.removable(v) {
+				mark(v)
+			}
+		}
+	}
+
+	s.sweep(func(v *ssaValue) bool {
+		return live[v]
+	})
+}
+
+// removable reports whether the value changes nothing and can't fail, so it is removed if it is not used.
+func (s *ssaFunction) removable(v *ssaValue) bool {
+	switch v.in.op {
+	case vmMove, vmCopy, vmZero, vmAdd, vmSub, vmMul, vmOr, vmAnd, vmNot, vmLt, vmLe, vmGt, vmGe,
+		vmBox, vmPackage, ssaParam:
+		return true
+	case vmEq, vmNe:
+		// the interface values of the uncomparable types fail
+		return s.kind(v.args[0]) != reflect.Invalid && s.kind(v.args[1]) != reflect.Invalid
+	case vmLen:
+		return s.kind(v.args[0]) == reflect.String
+	case vmConvert:
+		return convertible(s.kind(v.args[0]), s.kind(v))
+	case vmDiv:
+		// only the division by the integer zero fails
+		divisor, ok := s.basic(v.args[1])
+		switch divisor.(type) {
+		case float64, complex128:
+			return true
+		}
+		return ok && !reflect.ValueOf(divisor).IsZero()
+	}
+	return false
+}
+
+// convertible reports whether the value of the basic kind is converted to the other one without the failure.
+func convertible(from, to reflect.Kind) bool {
+	numeric := func(kind reflect.Kind) bool {
+		switch kind {
+		case reflect.Int, reflect.Int32, reflect.Uint8, reflect.Float64:
+			return true
+		}
+		return false
+	}
+
+	switch {
+	case from == reflect.Invalid || to == reflect.Invalid:
+		return false
+	case from == to:
+		return true
+	case to == reflect.Complex128:
+		return numeric(from)
+	case to == reflect.String:
+		return from == reflect.Int || from == reflect.Int32 || from == reflect.Uint8
+	}
+	return numeric(from) && numeric(to)
+}
+
+// kind is the kind of the basic values the value has, reflect.Invalid if it isn't known.
+func (s *ssaFunction) kind(v *ssaValue) reflect.Kind {
+	// the values of the cycles of the phis are expected to have the kind of the other arguments
+	const pending = reflect.UnsafePointer + 1
+	kinds := map[*ssaValue]reflect.Kind{}
+
+	basicKind := func(Type Type) reflect.Kind {
+		if basicType, ok := underlyingBasic(Type); ok && basicType.reflectType != nil {
+			return basicType.reflectType.Kind()
+		}
+		return reflect.Invalid
+	}
+	// same is the kind all the known kinds agree on
+	same := func(x, y reflect.Kind) reflect.Kind {
+		switch {
+		case x == pending:
+			return y
+		case y == pending || x == y:
+			return x
+		}
+		return reflect.Invalid
+	}
+
+	var kindOf func(v *ssaValue) reflect.Kind
+	kindOf = func(v *ssaValue) reflect.Kind {
+		if kind, ok := kinds[v]; ok {
+			return kind
+		}
+		kinds[v] = pending
+
+		kind := reflect.Invalid
+		switch v.in.op {
+		case ssaConstant:
+			if value, ok := s.basic(v); ok {
+				kind = reflect.TypeOf(value).Kind()
+			}
+		case ssaParam:
+			inputs, outputs := s.function.inputVariables, s.function.outputVariables
+			switch register := int(v.in.b); {
+			case register < len(inputs):
+				kind = basicKind(inputs[register].Type)
+			case register < len(inputs)+len(outputs):
+				kind = basicKind(outputs[register-len(inputs)].Type)
+			}
+		case vmEq, vmNe, vmLt, vmLe, vmGt, vmGe, vmNot, vmOr, vmAnd:
+			kind = reflect.Bool
+		case vmLen:
+			kind = reflect.Int
+		case vmZero:
+			kind = basicKind(s.types[v.in.b])
+		case vmConvert:
+			kind = basicKind(s.types[v.in.c])
+		case vmMove, vmCopy:
+			kind = kindOf(v.args[0])
+		case vmAdd, vmSub, vmMul, vmDiv, ssaPhi:
+			kind = pending
+			for _, arg := range v.args {
+				kind = same(kind, kindOf(arg))
+			}
+			if kind == pending {
+				kind = reflect.Invalid
+			}
+		}
+
+		kinds[v] = kind
+		return kind
+	}
+
+	return kindOf(v)
+}
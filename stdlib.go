@@ -0,0 +1,399 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// StdPackage is the package of the standard library implemented by the interpreter.
+// It has no source files, its members are declared right in the program.
+type StdPackage struct {
+	Name string
+	Path string
+	// the standard packages the declarations refer to, they are declared first
+	Imports []string
+
+	Declare func(prog *Program, pkg *Package)
+}
+
+var stdPackages = map[string]*StdPackage{}
+
+// RegisterStdPackage makes the package importable by the scripts.
+func RegisterStdPackage(std *StdPackage) {
+	if _, ok := stdPackages[std.Path]; ok {
+		panic(fmt.Sprintf("standard package %v already registered", std.Path))
+	}
+
+	stdPackages[std.Path] = std
+}
+
+// PackageValue is a variable or a constant declared by a standard package.
+type PackageValue struct {
+	Type  Type
+	Value any
+	// the constants have untyped types and take part in the constant expressions
+	Constant bool
+}
+
+func (prog *Program) RegisterValue(name string, value *PackageValue) error {
+	if _, ok := prog.values[name]; ok {
+		return fmt.Errorf("%v redeclared in this block", name)
+	}
+
+	prog.values[name] = value
+
+	return nil
+}
+
+// declareFunction registers the host function of the package.
+func (prog *Program) declareFunction(pkg *Package, name string, signature *FunctionType, handler func(args ...any) ([]any, error)) {
+	prog.RegisterFunction(GenericFunction{
+		name:      pkg.QualifiedName(name),
+		signature: signature,
+		handler:   handler,
+	})
+}
+
+// declareType registers the named type of the package, its definition is given right away.
+func (prog *Program) declareType(pkg *Package, name string, rhs Type) *NamedType {
+	res := NewNamedType(name, pkg)
+	res.rhs = rhs
+	prog.RegisterType(pkg.QualifiedName(name), res)
+
+	return res
+}
+
+// declareMethod registers the host method of the named type, the receiver is the first parameter of the signature.
+func (prog *Program) declareMethod(namedType *NamedType, name string, signature *FunctionType, handler func(args ...any) ([]any, error)) {
+	prog.RegisterFunction(GenericFunction{
+		name:      namedType.MethodName(name),
+		signature: signature,
+		handler:   handler,
+	})
+}
+
+// declareGeneric registers the generic host function of the package,
+// the instances get their signatures and handlers by the type arguments.
+func (prog *Program) declareGeneric(pkg *Package, name string, typeParams []string, constraints []Type, instantiate func(typeArgs []Type) (*FunctionType, func(args ...any) ([]any, error))) {
+	prog.RegisterFunctionTemplate(pkg.QualifiedName(name), &FunctionTemplate{
+		name:       name,
+		typeParams: typeParams,
+		host: &hostTemplate{
+			program:     prog,
+			pkg:         pkg,
+			constraints: constraints,
+			instantiate: instantiate,
+		},
+		instances: map[string]int{},
+	})
+}
+
+// lookupStdType finds the type declared by the standard package the package imports.
+func (prog *Program) lookupStdType(path, name string) Type {
+	res, ok := prog.types[path+"."+name]
+	if !ok {
+		panic(fmt.Sprintf("standard type %v.%v is not declared", path, name))
+	}
+
+	return res
+}
+
+// signature is the shorthand for the signatures of the host functions.
+func signature(params []Type, results ...Type) *FunctionType {
+	return &FunctionType{Params: params, Result: NewResult(results)}
+}
+
+// variadic is the shorthand for the signatures of the variadic host functions,
+// the last parameter is the type of the rest arguments.
+func variadic(params []Type, results ...Type) *FunctionType {
+	params[len(params)-1] = &SliceType{Elem: params[len(params)-1]}
+	return &FunctionType{Params: params, Result: NewResult(results), Variadic: true}
+}
+
+// hostStruct makes the struct of the standard package with unexported fields only,
+// the scripts can't access its representation.
+func hostStruct(pkg *Package, fields ...*Field) *StructType {
+	return &StructType{Fields: fields, pkg: pkg}
+}
+
+// NewStructValue makes the struct value with the given field values.
+func NewStructValue(fields ...any) *StructValue {
+	res := &StructValue{fields: make([]*any, len(fields))}
+	for i, field := range fields {
+		value := field
+		res.fields[i] = &value
+	}
+
+	return res
+}
+
+// NewPointer makes the pointer to the new variable with the value.
+func NewPointer(value any) *any {
+	return &value
+}
+
+// hostMethod makes the handler of the method of the struct of the standard package,
+// the receiver is the pointer to the struct.
+func hostMethod(method func(receiver *StructValue, args ...any) []any) func(args ...any) ([]any, error) {
+	return func(args ...any) ([]any, error) {
+		cell, _ := args[0].(*any)
+		if cell == nil {
+			return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+		}
+
+		return method((*cell).(*StructValue), args[1:]...), nil
+	}
+}
+
+// hostGetter makes the method which gives the field of the struct.
+func hostGetter(index int) func(args ...any) ([]any, error) {
+	return hostMethod(func(receiver *StructValue, args ...any) []any {
+		return []any{*receiver.fields[index]}
+	})
+}
+
+// declareHostFunctions declares the functions of the host Go library,
+// the types of their parameters and results are mapped to the types of the script.
+func (prog *Program) declareHostFunctions(pkg *Package, functions map[string]any) {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		function := reflect.ValueOf(functions[name])
+		prog.declareFunction(pkg, name, hostType(function.Type()).(*FunctionType), prog.hostCall(function))
+	}
+}
+
+// declareConstant declares the untyped constant of the package.
+func (prog *Program) declareConstant(pkg *Package, name string, Type Type, value any) {
+	prog.RegisterValue(pkg.QualifiedName(name), &PackageValue{Type: Type, Value: value, Constant: true})
+}
+
+var errorInterface = reflect.TypeOf((*error)(nil)).Elem()
+
+// hostType is the type of the script the values of the host type are represented with,
+// int64 is represented with int.
+func hostType(t reflect.Type) Type {
+	if t == errorInterface {
+		return ErrorType
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return BoolType
+	case reflect.Int, reflect.Int64:
+		return IntType
+	case reflect.Int32:
+		return Int32Type
+	case reflect.Uint8:
+		return Uint8Type
+	case reflect.Float64:
+		return Float64Type
+	case reflect.Complex128:
+		return Complex128Type
+	case reflect.String:
+		return StringType
+	case reflect.Slice:
+		return &SliceType{Elem: hostType(t.Elem())}
+	case reflect.Func:
+		params := make([]Type, t.NumIn())
+		for i := range params {
+			params[i] = hostType(t.In(i))
+		}
+		results := make([]Type, t.NumOut())
+		for i := range results {
+			results[i] = hostType(t.Out(i))
+		}
+		return &FunctionType{Params: params, Result: NewResult(results), Variadic: t.IsVariadic()}
+	}
+
+	panic(fmt.Sprintf("unsupported host type %v", t))
+}
+
+// scriptError is the error of the script function called by the host function.
+type scriptError struct {
+	err error
+}
+
+// callScript calls the script function back from the host code, its error unwinds the host function.
+func callScript(function Function, args ...any) []any {
+	if function == nil {
+		panic(scriptError{fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")})
+	}
+
+	res, err := function.Call(args...)
+	if err != nil {
+		panic(scriptError{err})
+	}
+
+	return res
+}
+
+// catchScriptError is deferred by the handlers which call the script back,
+// the panic of the host code is the panic of the script.
+func catchScriptError(err *error) {
+	switch r := recover().(type) {
+	case nil:
+	case scriptError:
+		*err = r.err
+	default:
+		*err = fmt.Errorf("%v", r)
+	}
+}
+
+// hostCall makes the handler which calls the host function, the panic of the host function is the panic of the script.
+func (prog *Program) hostCall(function reflect.Value) func(args ...any) ([]any, error) {
+	Type := function.Type()
+
+	return func(args ...any) (res []any, err error) {
+		defer catchScriptError(&err)
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			in[i] = prog.toHost(arg, Type.In(i))
+		}
+
+		var out []reflect.Value
+		if Type.IsVariadic() {
+			out = function.CallSlice(in)
+		} else {
+			out = function.Call(in)
+		}
+
+		res = make([]any, len(out))
+		for i, value := range out {
+			res[i] = prog.fromHost(value)
+		}
+		return res, nil
+	}
+}
+
+// toHost converts the value of the script to the value of the host type.
+func (prog *Program) toHost(value any, t reflect.Type) reflect.Value {
+	if t == errorInterface {
+		res := reflect.New(t).Elem()
+		if err := prog.toHostError(value); err != nil {
+			res.Set(reflect.ValueOf(err))
+		}
+		return res
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		slice := value.([]any)
+		if slice == nil {
+			return reflect.Zero(t)
+		}
+		res := reflect.MakeSlice(t, len(slice), len(slice))
+		for i, elem := range slice {
+			res.Index(i).Set(prog.toHost(elem, t.Elem()))
+		}
+		return res
+	case reflect.Func:
+		function, _ := value.(Function)
+		if function == nil {
+			return reflect.Zero(t)
+		}
+		return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+			args := make([]any, len(in))
+			for i, arg := range in {
+				args[i] = prog.fromHost(arg)
+			}
+
+			res := callScript(function, args...)
+			out := make([]reflect.Value, len(res))
+			for i, value := range res {
+				out[i] = prog.toHost(value, t.Out(i))
+			}
+			return out
+		})
+	}
+
+	return reflect.ValueOf(value).Convert(t)
+}
+
+// fromHost converts the host value to the value of the script.
+func (prog *Program) fromHost(value reflect.Value) any {
+	if value.Type() == errorInterface {
+		if value.IsNil() {
+			return nil
+		}
+		return prog.hostError(value.Interface().(error))
+	}
+
+	switch value.Kind() {
+	case reflect.Int64:
+		return int(value.Int())
+	case reflect.Slice:
+		if value.IsNil() {
+			return []any(nil)
+		}
+		res := make([]any, value.Len())
+		for i := range res {
+			res[i] = prog.fromHost(value.Index(i))
+		}
+		return res
+	}
+
+	return value.Interface()
+}
+
+// hostErrors convert the errors of the host library to the errors of the standard packages,
+// the sentinel errors are replaced with the values of the packages, so they can be compared.
+var (
+	hostErrors    = map[reflect.Type]func(prog *Program, err error) any{}
+	hostSentinels = map[error]string{}
+)
+
+func (prog *Program) hostError(err error) any {
+	if err, ok := err.(valueError); ok {
+		return err.value
+	}
+	if name, ok := hostSentinels[err]; ok {
+		if value, ok := prog.values[name]; ok {
+			return value.Value
+		}
+	}
+	if convert, ok := hostErrors[reflect.TypeOf(err)]; ok {
+		return convert(prog, err)
+	}
+
+	return prog.NewError(err.Error())
+}
+
+// valueError is the error value of the script passed to the host code.
+type valueError struct {
+	prog  *Program
+	value any
+}
+
+func (e valueError) Error() string {
+	msg, err := e.prog.ErrorMessage(e.value)
+	if err != nil {
+		panic(scriptError{err})
+	}
+
+	return msg
+}
+
+// toHostError converts the error value of the script to the host error,
+// the values of the sentinel errors become the host sentinels.
+func (prog *Program) toHostError(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	for sentinel, name := range hostSentinels {
+		if sentinelValue, ok := prog.values[name]; ok {
+			if equal, err := EqualAny(value, sentinelValue.Value); err == nil && equal.(bool) {
+				return sentinel
+			}
+		}
+	}
+
+	return valueError{prog: prog, value: value}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "strconv",
+		Path:    "strconv",
+		Imports: []string{"errors"},
+		Declare: declareStrconv,
+	})
+
+	hostErrors[reflect.TypeOf(&strconv.NumError{})] = func(prog *Program, err error) any {
+		numError := err.(*strconv.NumError)
+		return prog.NewNumError(numError.Func, numError.Num, prog.hostError(numError.Err))
+	}
+	hostSentinels[strconv.ErrSyntax] = "strconv.ErrSyntax"
+	hostSentinels[strconv.ErrRange] = "strconv.ErrRange"
+}
+
+func declareStrconv(prog *Program, pkg *Package) {
+	prog.declareHostFunctions(pkg, map[string]any{
+		"Atoi":             strconv.Atoi,
+		"FormatBool":       strconv.FormatBool,
+		"FormatFloat":      strconv.FormatFloat,
+		"FormatInt":        strconv.FormatInt,
+		"Itoa":             strconv.Itoa,
+		"ParseBool":        strconv.ParseBool,
+		"ParseFloat":       strconv.ParseFloat,
+		"ParseInt":         strconv.ParseInt,
+		"Quote":            strconv.Quote,
+		"QuoteRune":        strconv.QuoteRune,
+		"QuoteRuneToASCII": strconv.QuoteRuneToASCII,
+		"QuoteToASCII":     strconv.QuoteToASCII,
+		"Unquote":          strconv.Unquote,
+		"UnquoteChar":      strconv.UnquoteChar,
+	})
+
+	prog.RegisterValue(pkg.QualifiedName("ErrSyntax"), &PackageValue{Type: ErrorType, Value: prog.NewError(strconv.ErrSyntax.Error())})
+	prog.RegisterValue(pkg.QualifiedName("ErrRange"), &PackageValue{Type: ErrorType, Value: prog.NewError(strconv.ErrRange.Error())})
+	prog.declareConstant(pkg, "IntSize", UntypedIntType, strconv.IntSize)
+
+	numError := prog.declareType(pkg, "NumError", hostStruct(pkg,
+		&Field{Name: "Func", Type: StringType},
+		&Field{Name: "Num", Type: StringType},
+		&Field{Name: "Err", Type: ErrorType},
+	))
+	numErrorPointer := &PointerType{Elem: numError}
+
+	prog.declareMethod(numError, "Error", signature([]Type{numErrorPointer}, StringType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		function, num, err := (*receiver.fields[0]).(string), (*receiver.fields[1]).(string), *receiver.fields[2]
+		return []any{"strconv." + function + ": parsing " + strconv.Quote(num) + ": " + prog.Sprint([]any{err})}
+	}))
+	prog.declareMethod(numError, "Unwrap", signature([]Type{numErrorPointer}, ErrorType), hostGetter(2))
+}
+
+// NewNumError makes the error of the conversion of the string to the number.
+func (prog *Program) NewNumError(function, num string, err any) any {
+	return InterfaceValue{
+		Type:  &PointerType{Elem: prog.lookupStdType("strconv", "NumError")},
+		Value: NewPointer(NewStructValue(function, num, err)),
+	}
+}
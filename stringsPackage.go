@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "strings",
+		Path:    "strings",
+		Declare: declareStrings,
+	})
+}
+
+func declareStrings(prog *Program, pkg *Package) {
+	prog.declareHostFunctions(pkg, map[string]any{
+		"Compare":       strings.Compare,
+		"Contains":      strings.Contains,
+		"ContainsAny":   strings.ContainsAny,
+		"ContainsFunc":  strings.ContainsFunc,
+		"ContainsRune":  strings.ContainsRune,
+		"Count":         strings.Count,
+		"Cut":           strings.Cut,
+		"CutPrefix":     strings.CutPrefix,
+		"CutSuffix":     strings.CutSuffix,
+		"EqualFold":     strings.EqualFold,
+		"Fields":        strings.Fields,
+		"FieldsFunc":    strings.FieldsFunc,
+		"HasPrefix":     strings.HasPrefix,
+		"HasSuffix":     strings.HasSuffix,
+		"Index":         strings.Index,
+		"IndexAny":      strings.IndexAny,
+		"IndexByte":     strings.IndexByte,
+		"IndexFunc":     strings.IndexFunc,
+		"IndexRune":     strings.IndexRune,
+		"Join":          strings.Join,
+		"LastIndex":     strings.LastIndex,
+		"LastIndexAny":  strings.LastIndexAny,
+		"LastIndexByte": strings.LastIndexByte,
+		"LastIndexFunc": strings.LastIndexFunc,
+		"Map":           strings.Map,
+		"Repeat":        strings.Repeat,
+		"Replace":       strings.Replace,
+		"ReplaceAll":    strings.ReplaceAll,
+		"Split":         strings.Split,
+		"SplitAfter":    strings.SplitAfter,
+		"SplitAfterN":   strings.SplitAfterN,
+		"SplitN":        strings.SplitN,
+		"Title":         strings.Title,
+		"ToLower":       strings.ToLower,
+		"ToTitle":       strings.ToTitle,
+		"ToUpper":       strings.ToUpper,
+		"ToValidUTF8":   strings.ToValidUTF8,
+		"Trim":          strings.Trim,
+		"TrimFunc":      strings.TrimFunc,
+		"TrimLeft":      strings.TrimLeft,
+		"TrimLeftFunc":  strings.TrimLeftFunc,
+		"TrimPrefix":    strings.TrimPrefix,
+		"TrimRight":     strings.TrimRight,
+		"TrimRightFunc": strings.TrimRightFunc,
+		"TrimSpace":     strings.TrimSpace,
+		"TrimSuffix":    strings.TrimSuffix,
+	})
+
+	declareBuilder(prog, pkg)
+}
+
+// declareBuilder declares strings.Builder, its bytes are kept in the field of the struct,
+// so the zero value is ready to use.
+func declareBuilder(prog *Program, pkg *Package) {
+	bytes := &SliceType{Elem: Uint8Type}
+	builder := prog.declareType(pkg, "Builder", hostStruct(pkg, &Field{Name: "buf", Type: bytes}))
+	builderPointer := &PointerType{Elem: builder}
+
+	write := func(receiver *StructValue, str string) {
+		*receiver.fields[0] = append((*receiver.fields[0]).([]any), StringToSlice(str, Uint8Type)...)
+	}
+
+	prog.declareMethod(builder, "String", signature([]Type{builderPointer}, StringType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		return []any{SliceToString((*receiver.fields[0]).([]any))}
+	}))
+	prog.declareMethod(builder, "Len", signature([]Type{builderPointer}, IntType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		return []any{len((*receiver.fields[0]).([]any))}
+	}))
+	prog.declareMethod(builder, "Cap", signature([]Type{builderPointer}, IntType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		return []any{cap((*receiver.fields[0]).([]any))}
+	}))
+	prog.declareMethod(builder, "Reset", signature([]Type{builderPointer}), hostMethod(func(receiver *StructValue, args ...any) []any {
+		*receiver.fields[0] = []any(nil)
+		return nil
+	}))
+	grow := hostMethod(func(receiver *StructValue, args ...any) []any {
+		buf := (*receiver.fields[0]).([]any)
+		if n := args[0].(int); cap(buf)-len(buf) < n {
+			*receiver.fields[0] = append(make([]any, 0, 2*cap(buf)+n), buf...)
+		}
+		return nil
+	})
+	prog.declareMethod(builder, "Grow", signature([]Type{builderPointer, IntType}), func(args ...any) ([]any, error) {
+		if args[1].(int) < 0 {
+			return nil, fmt.Errorf("strings.Builder.Grow: negative count")
+		}
+		return grow(args...)
+	})
+
+	prog.declareMethod(builder, "Write", signature([]Type{builderPointer, bytes}, IntType, ErrorType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		write(receiver, SliceToString(args[0].([]any)))
+		return []any{len(args[0].([]any)), nil}
+	}))
+	prog.declareMethod(builder, "WriteString", signature([]Type{builderPointer, StringType}, IntType, ErrorType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		write(receiver, args[0].(string))
+		return []any{len(args[0].(string)), nil}
+	}))
+	prog.declareMethod(builder, "WriteByte", signature([]Type{builderPointer, Uint8Type}, ErrorType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		*receiver.fields[0] = append((*receiver.fields[0]).([]any), args[0])
+		return []any{nil}
+	}))
+	prog.declareMethod(builder, "WriteRune", signature([]Type{builderPointer, Int32Type}, IntType, ErrorType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		str := string(args[0].(int32))
+		write(receiver, str)
+		return []any{len(str), nil}
+	}))
+}
@@ -0,0 +1,190 @@
+package main
+
+import "fmt"
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "sync",
+		Path:    "sync",
+		Declare: declareSync,
+	})
+}
+
+// lockSignature is the signature of the methods of sync.Locker.
+var lockSignature = signature(nil)
+
+func declareSync(prog *Program, pkg *Package) {
+	prog.declareType(pkg, "Locker", &InterfaceType{Methods: []*Method{
+		{Name: "Lock", Signature: lockSignature},
+		{Name: "Unlock", Signature: lockSignature},
+	}})
+
+	declareMutex(prog, pkg)
+	declareRWMutex(prog, pkg)
+
+	// the counter of the work the script waits for
+	waitGroup := prog.declareType(pkg, "WaitGroup", hostStruct(pkg, &Field{Name: "count", Type: IntType}))
+	waitGroupPointer := &PointerType{Elem: waitGroup}
+	add := func(receiver *StructValue, delta int) error {
+		count := (*receiver.fields[0]).(int) + delta
+		if count < 0 {
+			return fmt.Errorf("sync: negative WaitGroup counter")
+		}
+
+		*receiver.fields[0] = count
+		if count == 0 {
+			prog.notify(receiver)
+		}
+		return nil
+	}
+	prog.declareMethod(waitGroup, "Add", signature([]Type{waitGroupPointer, IntType}), syncMethod(func(receiver *StructValue, args ...any) error {
+		return add(receiver, args[0].(int))
+	}))
+	prog.declareMethod(waitGroup, "Done", signature([]Type{waitGroupPointer}), syncMethod(func(receiver *StructValue, args ...any) error {
+		return add(receiver, -1)
+	}))
+	prog.declareMethod(waitGroup, "Wait", signature([]Type{waitGroupPointer}), syncMethod(func(receiver *StructValue, args ...any) error {
+		for (*receiver.fields[0]).(int) != 0 {
+			if err := prog.wait(receiver); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	once := prog.declareType(pkg, "Once", hostStruct(pkg, &Field{Name: "done", Type: BoolType}, &Field{Name: "running", Type: BoolType}))
+	prog.declareMethod(once, "Do", signature([]Type{&PointerType{Elem: once}, signature(nil)}), syncMethod(func(receiver *StructValue, args ...any) error {
+		// the other goroutines return when the function has returned
+		for (*receiver.fields[1]).(bool) {
+			if err := prog.wait(receiver); err != nil {
+				return err
+			}
+		}
+		if (*receiver.fields[0]).(bool) {
+			return nil
+		}
+
+		// the function is done even if it panics
+		*receiver.fields[0] = true
+		function, _ := args[0].(Function)
+		if function == nil {
+			return fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+		}
+		*receiver.fields[1] = true
+		_, err := function.Call()
+		*receiver.fields[1] = false
+		prog.notify(receiver)
+		return err
+	}))
+}
+
+func declareMutex(prog *Program, pkg *Package) {
+	mutex := prog.declareType(pkg, "Mutex", hostStruct(pkg, &Field{Name: "locked", Type: BoolType}))
+	mutexPointer := &PointerType{Elem: mutex}
+
+	prog.declareMethod(mutex, "Lock", signature([]Type{mutexPointer}), syncMethod(func(receiver *StructValue, args ...any) error {
+		for (*receiver.fields[0]).(bool) {
+			if err := prog.wait(receiver); err != nil {
+				return err
+			}
+		}
+
+		*receiver.fields[0] = true
+		return nil
+	}))
+	prog.declareMethod(mutex, "TryLock", signature([]Type{mutexPointer}, BoolType), hostMethod(func(receiver *StructValue, args ...any) []any {
+		if (*receiver.fields[0]).(bool) {
+			return []any{false}
+		}
+
+		*receiver.fields[0] = true
+		return []any{true}
+	}))
+	prog.declareMethod(mutex, "Unlock", signature([]Type{mutexPointer}), syncMethod(func(receiver *StructValue, args ...any) error {
+		if !(*receiver.fields[0]).(bool) {
+			return FatalError{"sync: unlock of unlocked mutex"}
+		}
+
+		*receiver.fields[0] = false
+		prog.notify(receiver)
+		return nil
+	}))
+}
+
+func declareRWMutex(prog *Program, pkg *Package) {
+	rwMutex := prog.declareType(pkg, "RWMutex", hostStruct(pkg,
+		&Field{Name: "locked", Type: BoolType},
+		&Field{Name: "readers", Type: IntType},
+	))
+	rwMutexPointer := &PointerType{Elem: rwMutex}
+	// the writer waits for the readers and the readers wait for the writer
+	free := func(receiver *StructValue, write bool) bool {
+		return !(*receiver.fields[0]).(bool) && (!write || (*receiver.fields[1]).(int) == 0)
+	}
+	lock := func(receiver *StructValue, write bool) {
+		if write {
+			*receiver.fields[0] = true
+		} else {
+			*receiver.fields[1] = (*receiver.fields[1]).(int) + 1
+		}
+	}
+
+	for _, method := range []struct {
+		name  string
+		write bool
+	}{{"Lock", true}, {"RLock", false}} {
+		write := method.write
+		prog.declareMethod(rwMutex, method.name, signature([]Type{rwMutexPointer}), syncMethod(func(receiver *StructValue, args ...any) error {
+			for !free(receiver, write) {
+				if err := prog.wait(receiver); err != nil {
+					return err
+				}
+			}
+
+			lock(receiver, write)
+			return nil
+		}))
+		prog.declareMethod(rwMutex, "Try"+method.name, signature([]Type{rwMutexPointer}, BoolType), hostMethod(func(receiver *StructValue, args ...any) []any {
+			if !free(receiver, write) {
+				return []any{false}
+			}
+
+			lock(receiver, write)
+			return []any{true}
+		}))
+	}
+
+	prog.declareMethod(rwMutex, "Unlock", signature([]Type{rwMutexPointer}), syncMethod(func(receiver *StructValue, args ...any) error {
+		if !(*receiver.fields[0]).(bool) {
+			return FatalError{"sync: Unlock of unlocked RWMutex"}
+		}
+
+		*receiver.fields[0] = false
+		prog.notify(receiver)
+		return nil
+	}))
+	prog.declareMethod(rwMutex, "RUnlock", signature([]Type{rwMutexPointer}), syncMethod(func(receiver *StructValue, args ...any) error {
+		readers := (*receiver.fields[1]).(int)
+		if readers == 0 {
+			return FatalError{"sync: RUnlock of unlocked RWMutex"}
+		}
+
+		*receiver.fields[1] = readers - 1
+		if readers == 1 {
+			prog.notify(receiver)
+		}
+		return nil
+	}))
+}
+
+// syncMethod makes the handler of the method of the synchronization primitive, the method has no results.
+func syncMethod(method func(receiver *StructValue, args ...any) error) func(args ...any) ([]any, error) {
+	return func(args ...any) ([]any, error) {
+		cell, _ := args[0].(*any)
+		if cell == nil {
+			return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+		}
+
+		return nil, method((*cell).(*StructValue), args[1:]...)
+	}
+}
@@ -1,4 +1,48 @@
 .\solution.exe .\test\test1\main.go
 .\solution.exe .\test\test2\main.go
 .\solution.exe .\test\test3\main.go
-.\solution.exe .\test\test4\main.go
\ No newline at end of file
+.\solution.exe .\test\test4\main.go
+.\solution.exe .\test\test5
+.\solution.exe .\test\test6\main.go
+.\solution.exe .\test\test7\main.go
+.\solution.exe .\test\test8\main.go
+.\solution.exe .\test\test9\main.go
+.\solution.exe .\test\test10\main.go
+.\solution.exe .\test\test11\main.go
+.\solution.exe .\test\test12\main.go
+.\solution.exe .\test\test13\main.go
+.\solution.exe .\test\test14\main.go
+.\solution.exe .\test\test15\main.go
+.\solution.exe .\test\test16\main.go
+.\solution.exe .\test\test17\main.go
+.\solution.exe .\test\test18\main.go
+.\solution.exe .\test\test19\main.go
+.\solution.exe --virtual-time .\test\test20\main.go
+.\solution.exe .\test\test21\main.go
+.\solution.exe .\test\test22\main.go
+.\solution.exe -O0 .\test\test22\main.go
+.\solution.exe .\test\test23\main.go
+.\solution.exe --backend closures .\test\test22\main.go
+.\solution.exe --backend closures .\test\test23\main.go
+.\solution.exe compile -o .\test\test23.gob .\test\test23\main.go
+.\solution.exe run .\test\test23.gob
+.\solution.exe run --backend closures .\test\test23.gob
+del .\test\test23.gob
+.\solution.exe .\test\test24\main.go
+.\solution.exe --backend closures .\test\test24\main.go
+.\solution.exe --tail-calls .\test\test25\main.go
+.\solution.exe --tail-calls --backend closures .\test\test25\main.go
+.\solution.exe --tail-calls --backend vm .\test\test25\main.go
+.\solution.exe .\test\test26\main.go
+.\solution.exe --max-steps 1000000 .\test\test26\main.go spin
+.\solution.exe --backend closures --max-steps 1000000 .\test\test26\main.go spin
+.\solution.exe --timeout 500ms .\test\test26\main.go spin
+.\solution.exe --timeout 500ms .\test\test26\main.go sleep
+.\solution.exe --max-heap 64MB .\test\test26\main.go grow
+.\solution.exe .\test\test27\main.go
+.\solution.exe .\test\test28\main.go
+.\solution.exe .\test\test29\main.go
+.\solution.exe --virtual-time .\test\test30\main.go
+.\solution.exe .\test\test31\main.go
+.\solution.exe --backend closures .\test\test31\main.go
+.\solution.exe --backend code .\test\test31\main.go
@@ -0,0 +1,73 @@
+package main
+
+func sum(values []int) int {
+	total := 0;
+	for _, v := range values {
+		total = total + v;
+	}
+	return total;
+}
+
+func pairs(n int) func(func(int, int) bool) {
+	return func(yield func(int, int) bool) {
+		for i := range n {
+			if !yield(i, i*i) {
+				return;
+			}
+		}
+	};
+}
+
+func firstSquare(limit int) int {
+	for _, sq := range pairs(10) {
+		if (sq > limit) {
+			return sq;
+		}
+	}
+	return -1;
+}
+
+func main() {
+	values := []int{1, 2, 3, 4};
+	println(sum(values));
+
+	for i, r := range "héllo" {
+		println(i, r);
+	}
+
+	ages := map[string]int{"bob": 42};
+	for name, age := range ages {
+		println(name, age);
+	}
+
+	var funcs []func() int;
+	for i := range 3 {
+		funcs = append(funcs, func() int {
+			return i;
+		});
+	}
+	for _, f := range funcs {
+		println(f());
+	}
+
+	for i, sq := range pairs(5) {
+		if (i == 3) {
+			break;
+		}
+		println(i, sq);
+	}
+	println(firstSquare(20));
+
+	var k int;
+	var v int;
+	for k, v = range values {
+	}
+	println(k, v);
+
+	a, b := 1, "two";
+	a, c := 3, true;
+	println(a, b, c);
+
+	for x, y := range 5 {
+	}
+}
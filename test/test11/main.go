@@ -0,0 +1,71 @@
+package main
+
+func collatz(n int) int {
+	steps := 0;
+loop:
+	if (n == 1) {
+		goto done;
+	}
+	if ((n/2*2) == n) {
+		n = n / 2;
+	} else {
+		n = 3*n + 1;
+	}
+	steps = steps + 1;
+	goto loop;
+done:
+	return steps;
+}
+
+func machine(input []int) bool {
+	i := 0;
+	if (i == len(input) || input[i] != 1) {
+		goto reject;
+	}
+as:
+	i = i + 1;
+	if (i == len(input)) {
+		goto reject;
+	}
+	if (input[i] == 1) {
+		goto as;
+	}
+	if (input[i] != 2) {
+		goto reject;
+	}
+bs:
+	i = i + 1;
+	if (i == len(input)) {
+		return true;
+	}
+	if (input[i] == 2) {
+		goto bs;
+	}
+reject:
+	return false;
+}
+
+func main() {
+	println(collatz(6));
+	println(collatz(27));
+	println(machine([]int{1, 1, 2}), machine([]int{1, 2, 1}), machine([]int{2}));
+
+	n := 0;
+outer:
+	for {
+		for {
+			n = n + 1;
+			if (n == 5) {
+				goto finished;
+			}
+			goto outer;
+		}
+	}
+finished:
+	println("finished", n);
+
+	goto skip;
+	x := 1;
+skip:
+	println(x);
+}
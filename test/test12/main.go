@@ -0,0 +1,58 @@
+package main
+
+type Point struct {
+	X int
+	Y int
+}
+
+func main() {
+	s := make([]int, 3, 10);
+	println(len(s), cap(s), s);
+
+	t := append(s, 4);
+	t[0] = 7;
+	println(s[0], len(t), cap(t));
+
+	dst := make([]int, 2);
+	n := copy(dst, []int{1, 2, 3});
+	println(n, dst);
+
+	points := make([]Point, 2);
+	points[0].X = 1;
+	moved := make([]Point, 2);
+	copy(moved, points);
+	points[0].X = 5;
+	println(moved[0].X, points[0].X);
+
+	ages := make(map[string]int);
+	ages["bob"] = 42;
+	ages["alice"] = 37;
+	delete(ages, "bob");
+	delete(ages, "nobody");
+	println(len(ages), ages["alice"]);
+	clear(ages);
+	println(len(ages));
+
+	clear(s);
+	println(s, t[0]);
+
+	p := new(int);
+	*p = 3;
+	q := new(Point);
+	q.Y = *p + 1;
+	println(*p, q.Y, *new(5));
+
+	println(min(3, 1, 2), max(1.5, 2), min(-2.5, 1));
+	var a int32;
+	a = 7;
+	println(max(a, 9));
+
+	c := complex(1, 2);
+	d := c * c;
+	println(real(d), imag(d), d == complex(-3, 4));
+
+	var e []int;
+	println(len(e), cap(e), e == nil);
+
+	make([]int, -1);
+}
@@ -0,0 +1,43 @@
+package main
+
+func reverse(s string) string {
+	runes := []rune(s);
+	for i := range len(runes) / 2 {
+		j := len(runes) - 1 - i;
+		runes[i], runes[j] = runes[j], runes[i];
+	}
+	return string(runes);
+}
+
+func upper(s string) string {
+	b := []byte(s);
+	for i, c := range b {
+		if (c >= 'a') && (c <= 'z') {
+			b[i] = c - 32;
+		}
+	}
+	return string(b);
+}
+
+func main() {
+	s := "héllo, world";
+	println(len(s), s[0], s[1], s[0] == 'h');
+	println(s[7:], s[:5], s[7:9], s[:]);
+	println(upper(s), reverse(s));
+	println(string('G') + string(111), string(rune(19990)));
+	println(len([]rune(s)), len([]byte(s)));
+
+	println("apple" < "banana", "b" <= "a", "b" > "a", "abc" >= "abd", max("x", "y", "w"));
+
+	numbers := []int{0, 1, 2, 3, 4, 5};
+	head := numbers[:2];
+	head = append(head, 10);
+	println(numbers[2], len(numbers[1:4]), cap(numbers[1:4]), cap(numbers[1:3:4]));
+
+	var b byte;
+	b = 255;
+	b = b + 1;
+	println(b, '\n', 'a' + 1);
+
+	s[0] = 'H';
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type Point struct {
+	X int
+	Y int
+}
+
+type Celsius float64
+
+func (c Celsius) String() string {
+	return fmt.Sprintf("%.1f°C", float64(c));
+}
+
+type Shape struct {
+	Name   string
+	Origin *Point
+	Tags   []string
+	size   Celsius
+}
+
+type NotFound struct {
+	Name string
+}
+
+func (e *NotFound) Error() string {
+	return "not found: " + e.Name;
+}
+
+func find(name string) error {
+	if name == "" {
+		return fmt.Errorf("find: %w", &NotFound{Name: "<empty>"});
+	}
+	return nil;
+}
+
+func main() {
+	p := Point{X: 1, Y: -2};
+	fmt.Println(p, &p, []int{1, 2, 3}, map[string]int{"b": 2, "a": 1, "c": 3});
+	fmt.Printf("%v %+v %#v %T\n", p, p, p, p);
+	fmt.Printf("%#v %#v %#v\n", []string{"x", "y"}, map[int]bool{2: true, 1: false}, []int(nil));
+
+	fmt.Printf("|%d|%5d|%-5d|%05d|%x|%X|%o|%b|%c|%q|%U|\n", 42, 42, 42, -42, 255, 255, 8, 5, 'G', 'G', 'G');
+	fmt.Printf("|%f|%.2f|%8.3f|%e|%g|%G|\n", 3.14159, 3.14159, 3.14159, 1234.5678, 0.000012, 123456789.0);
+	fmt.Printf("|%s|%10s|%-10s|%.3s|%q|%x|% x|\n", "go", "go", "go", "golang", "a\"b", "hi", "hi");
+	fmt.Printf("|%t|%v|%5t|\n", true, false, true);
+	fmt.Printf("|%*d|%-*d|%.*f|\n", 6, 7, 4, 8, 1, 2.55);
+	fmt.Printf("%[2]d %[1]d %d\n", 10, 20);
+
+	c := Celsius(36.6);
+	fmt.Println(c, []Celsius{1, 2.5});
+	fmt.Printf("%v %s %d %.2f\n", c, c, c, c);
+
+	s := Shape{Name: "box", Tags: []string{"a", "b"}, size: 20};
+	fmt.Printf("%v\n%+v\n", s, s);
+
+	var missing *Point;
+	var e error;
+	fmt.Println(missing, e, nil);
+	fmt.Printf("%d %s %!\n", "str", 5);
+	fmt.Printf("%d %d\n", 1);
+	fmt.Printf("%d\n", 1, "extra");
+
+	fmt.Print("a", "b", 1, 2, "c", 3.5, "\n");
+	line := fmt.Sprintln("x", 1, true);
+	fmt.Print(line);
+	fmt.Print(fmt.Sprint(len(line)) + "\n");
+
+	err := find("");
+	fmt.Println(err);
+	fmt.Printf("%v | %q\n", err, err);
+	fmt.Println(find("x") == nil);
+
+	n, _ := fmt.Fprintf(os.Stdout, "%s=%v\n", "pi", 3.14);
+	fmt.Fprintln(os.Stderr, "to stderr");
+	fmt.Println(n);
+
+	panic(fmt.Errorf("bad shape %q: %w", s.Name, err));
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func capitalize(word string) string {
+	runes := []rune(word);
+	if len(runes) == 0 {
+		return word;
+	}
+	runes[0] = unicode.ToUpper(runes[0]);
+	return string(runes);
+}
+
+func main() {
+	line := "  the quick  brown fox,jumps over the lazy dog  ";
+	words := strings.Fields(line);
+	fmt.Println(len(words), words);
+	fmt.Printf("%q\n", strings.Split("a,b,,c", ","));
+	fmt.Println(strings.Join(words, "-"), strings.TrimSpace(line) == line);
+	fmt.Println(strings.Contains(line, "fox"), strings.HasPrefix("golang", "go"), strings.HasSuffix("golang", "ng"));
+	fmt.Println(strings.Index("chicken", "ken"), strings.LastIndex("go gopher", "go"), strings.Count("cheese", "e"));
+	fmt.Println(strings.Replace("oink oink oink", "k", "ky", 2), strings.ReplaceAll("oink oink", "oink", "moo"));
+	fmt.Println(strings.ToUpper("Hello"), strings.ToLower("WORLD"), strings.Repeat("ab", 3));
+	fmt.Println(strings.Trim("xxhixx", "x"), strings.TrimPrefix("prefix-body", "prefix-"), strings.TrimLeft("aabc", "a"));
+	before, after, found := strings.Cut("key=value", "=");
+	fmt.Println(before, after, found);
+	fmt.Println(strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return -1;
+		}
+		return unicode.ToUpper(r);
+	}, "a1b2c3"));
+	fmt.Println(strings.FieldsFunc("a1b22c", unicode.IsDigit), strings.EqualFold("Go", "GO"));
+
+	var sb strings.Builder;
+	for i, word := range words {
+		if i > 0 {
+			sb.WriteByte(' ');
+		}
+		sb.WriteString(capitalize(word));
+	}
+	sb.WriteRune('!');
+	fmt.Fprintf(&sb, " (%d words)", len(words));
+	fmt.Println(sb.String(), sb.Len());
+
+	n, err := strconv.Atoi("123");
+	fmt.Println(n + 1, err);
+	_, err = strconv.Atoi("12a");
+	fmt.Println(err);
+	_, err = strconv.ParseInt("99999999999999999999", 10, 64);
+	fmt.Println(err);
+	f, _ := strconv.ParseFloat("2.5", 64);
+	b, _ := strconv.ParseBool("true");
+	fmt.Println(f * 2, b, strconv.Itoa(-45) + "!", strconv.FormatInt(255, 2), strconv.FormatFloat(3.14159, 'f', 2, 64));
+	fmt.Println(strconv.Quote("tab\there \"quoted\""), strconv.QuoteRune('☺'));
+	s, err := strconv.Unquote("'ab'");
+	fmt.Printf("%q %v\n", s, err);
+
+	fmt.Println(unicode.IsLetter('x'), unicode.IsLetter('1'), unicode.IsSpace('\t'), unicode.IsUpper('Q'), unicode.IsPunct('!'));
+	fmt.Println(string(unicode.ToLower('Ж')), unicode.MaxRune);
+
+	fmt.Println(math.Sqrt(2), math.Pow(2, 10), math.Floor(-2.5), math.Ceil(2.1), math.Abs(-3.2));
+	fmt.Println(math.Inf(1), math.Inf(-1), math.NaN(), math.IsNaN(math.NaN()), math.MaxInt64, math.MinInt64);
+	fmt.Printf("%.4f %.4f %v %v\n", math.Pi, math.E, math.Max(3, 7), math.Mod(7, 3));
+	fmt.Println(math.Round(2.5), math.Trunc(-2.7), math.Hypot(3, 4), math.Log2(1024), math.MaxUint8);
+
+	fmt.Println(strings.Repeat("x", -1));
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+type sentinel string
+
+func (s sentinel) Error() string {
+	return string(s);
+}
+
+func notFound() error {
+	return sentinel("not found");
+}
+
+type QueryError struct {
+	Query string
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return "query " + e.Query + ": " + e.Err.Error();
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err;
+}
+
+type Temporary struct {
+	code int
+}
+
+func (t Temporary) Error() string {
+	return fmt.Sprintf("temporary failure %d", t.code);
+}
+
+func (t Temporary) Is(target error) bool {
+	return target == notFound() && t.code == 404;
+}
+
+func find(db map[string]int, key string) (int, error) {
+	value := db[key];
+	if value == 0 {
+		return 0, &QueryError{Query: key, Err: notFound()};
+	}
+	return value, nil;
+}
+
+func lookup(db map[string]int, key string) (int, error) {
+	value, err := find(db, key);
+	if err != nil {
+		return 0, fmt.Errorf("lookup %q: %w", key, err);
+	}
+	return value, nil;
+}
+
+func parseAll(inputs []string) ([]int, error) {
+	var errs []error;
+	var res []int;
+	for _, input := range inputs {
+		n, err := strconv.Atoi(input);
+		if err != nil {
+			errs = append(errs, err);
+		} else {
+			res = append(res, n);
+		}
+	}
+	return res, errors.Join(errs...);
+}
+
+func main() {
+	db := map[string]int{"a": 1, "b": 2};
+
+	value, err := lookup(db, "a");
+	fmt.Println(value, err, err == nil);
+
+	_, err = lookup(db, "z");
+	fmt.Println(err != nil, err);
+	fmt.Println(errors.Is(err, notFound()), errors.Is(err, errors.New("not found")));
+
+	var queryErr *QueryError;
+	if errors.As(err, &queryErr) {
+		fmt.Println("query:", queryErr.Query, queryErr.Err == notFound());
+	}
+
+	inner := errors.Unwrap(err);
+	fmt.Println(inner, errors.Unwrap(inner) == notFound(), errors.Unwrap(notFound()) == nil);
+
+	var temp error;
+	temp = Temporary{404};
+	fmt.Println(errors.Is(temp, notFound()), errors.Is(Temporary{500}, notFound()), temp == Temporary{404});
+
+	var target Temporary;
+	wrapped := fmt.Errorf("retry: %w", temp);
+	fmt.Println(errors.As(wrapped, &target), target.code);
+
+	numbers, err := parseAll([]string{"1", "x", "3", "y"});
+	fmt.Println(numbers);
+	fmt.Println(err);
+	var numErr *strconv.NumError;
+	fmt.Println(errors.As(err, &numErr), numErr.Num, errors.Is(err, strconv.ErrSyntax));
+
+	_, err = parseAll([]string{"4"});
+	fmt.Println(err == nil, errors.Join(nil, nil) == nil);
+
+	both := fmt.Errorf("%w and %w", notFound(), Temporary{1});
+	fmt.Println(both, errors.Is(both, notFound()), errors.As(both, &target), target.code);
+
+	var none error;
+	fmt.Println(none == nil, errors.Is(none, nil), errors.Is(none, notFound()));
+
+	fmt.Println(errors.Is(fmt.Errorf("open: %w", errors.ErrUnsupported), errors.ErrUnsupported), errors.ErrUnsupported);
+	fmt.Println(errors.As(err, nil));
+	errors.As(errors.New("last"), nil);
+}
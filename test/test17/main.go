@@ -0,0 +1,102 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type Person struct {
+	Name string
+	Age  int
+}
+
+type Names []string
+
+func byAge(a, b Person) int {
+	return cmp.Compare(a.Age, b.Age);
+}
+
+func main() {
+	ints := []int{5, 2, 8, -1, 3};
+	sort.Ints(ints);
+	fmt.Println(ints);
+
+	words := strings.Fields("pear apple fig banana cherry");
+	sort.Strings(words);
+	fmt.Println(words, len(words));
+
+	floats := []float64{2.5, -1, 3.25, 0};
+	sort.Float64s(floats);
+	fmt.Println(floats);
+
+	people := []Person{
+		Person{"Alice", 31},
+		Person{"Bob", 25},
+		Person{"Carol", 31},
+		Person{"Dave", 19},
+		Person{"Eve", 25},
+	};
+	sort.SliceStable(people, func(i, j int) bool {
+		return people[i].Age < people[j].Age;
+	});
+	fmt.Println(people);
+
+	sort.Slice(people, func(i, j int) bool {
+		return people[i].Name > people[j].Name;
+	});
+	fmt.Println(people);
+
+	slices.SortStableFunc(people, byAge);
+	fmt.Println(people);
+
+	slices.SortFunc(people, func(a, b Person) int {
+		return strings.Compare(a.Name, b.Name);
+	});
+	fmt.Println(people);
+
+	names := Names{"zed", "amy", "kim"};
+	slices.Sort(names);
+	fmt.Println(names, slices.Index(names, "kim"), slices.Contains(names, "bob"));
+
+	runes := []rune("generics");
+	slices.Sort(runes);
+	fmt.Println(string(runes));
+
+	pos, found := slices.BinarySearch(ints, 5);
+	fmt.Println(pos, found);
+	pos, found = slices.BinarySearch(ints, 4);
+	fmt.Println(pos, found);
+
+	slices.Reverse(ints);
+	fmt.Println(ints, slices.Index(ints, 42));
+
+	idx := sort.Search(len(words), func(i int) bool {
+		return words[i] >= "cherry";
+	});
+	fmt.Println(idx, words[idx]);
+
+	ages := map[string]int{"x": 3, "a": 1, "m": 2};
+	keys := slices.Sorted(maps.Keys(ages));
+	fmt.Println(keys, slices.Sorted(maps.Values(ages)));
+	seen := false;
+	for age := range maps.Values(ages) {
+		if age == 2 {
+			seen = true;
+			break;
+		}
+	}
+	fmt.Println(seen);
+	fmt.Println(len(slices.Collect(maps.Keys(ages))));
+	fmt.Println(cmp.Compare("a", "b"), cmp.Less(2.5, 1.5), cmp.Compare[int](3, 3));
+
+	compare := cmp.Compare[string];
+	fmt.Println(compare("b", "a"));
+
+	sort.Slice(ints, func(i, j int) bool {
+		return ints[i] < ints[len(ints)];
+	});
+}
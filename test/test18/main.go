@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func check(err error) {
+	if err != nil {
+		fmt.Println("error:", err);
+	}
+}
+
+func countWords(name string) (int, error) {
+	file, err := os.Open(name);
+	if err != nil {
+		return 0, err;
+	}
+	defer file.Close();
+
+	scanner := bufio.NewScanner(file);
+	scanner.Split(bufio.ScanWords);
+	count := 0;
+	for scanner.Scan() {
+		count = count + 1;
+	}
+	return count, scanner.Err();
+}
+
+func main() {
+	fmt.Println(len(os.Args) >= 1, os.Getenv("GOINTERPRETER_UNSET_VARIABLE") == "");
+
+	err := os.WriteFile("notes.txt", []byte("first line\nsecond line here\n\nlast"), 420);
+	check(err);
+
+	data, err := os.ReadFile("notes.txt");
+	check(err);
+	fmt.Printf("%q\n", string(data));
+
+	file, err := os.Open("notes.txt");
+	check(err);
+	scanner := bufio.NewScanner(file);
+	lines := 0;
+	for scanner.Scan() {
+		lines = lines + 1;
+		fmt.Println(lines, strings.ToUpper(scanner.Text()));
+	}
+	check(scanner.Err());
+	check(file.Close());
+	fmt.Println(file.Close());
+
+	words, err := countWords("notes.txt");
+	fmt.Println(words, err);
+
+	_, err = os.ReadFile("missing.txt");
+	fmt.Println(err, errors.Is(err, os.ErrNotExist));
+	var pathErr *os.PathError;
+	if errors.As(err, &pathErr) {
+		fmt.Println(pathErr.Op, pathErr.Path);
+	}
+
+	_, err = countWords("missing.txt");
+	fmt.Println(err);
+
+	_, err = os.ReadFile("../test1/main.go");
+	fmt.Println(err);
+	_, err = os.Open("/etc/passwd");
+	fmt.Println(err);
+
+	check(os.Remove("notes.txt"));
+	fmt.Println(errors.Is(os.Remove("notes.txt"), os.ErrNotExist));
+
+	input := bufio.NewScanner(os.Stdin);
+	for input.Scan() {
+		fmt.Println("stdin:", input.Text());
+	}
+
+	fmt.Println("exiting");
+	os.Exit(3);
+	fmt.Println("unreachable");
+}
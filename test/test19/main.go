@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type Address struct {
+	City string `json:"city"`
+	Zip  int    `json:"zip,omitempty"`
+}
+
+type Person struct {
+	Name    string            `json:"name"`
+	Age     int               `json:"age,omitempty"`
+	Email   string            `json:"-"`
+	Tags    []string          `json:"tags"`
+	Address *Address          `json:"address,omitempty"`
+	Scores  map[string]int    `json:"scores,omitempty"`
+	Extra   map[string]any    `json:"extra"`
+	Small   uint8
+	secret  string
+}
+
+func show(data []byte, err error) {
+	if err != nil {
+		fmt.Println("error:", err);
+		return;
+	}
+	fmt.Println(string(data));
+}
+
+func main() {
+	p := Person{Name: "Ann", Email: "ann@example.com", Tags: []string{"a", "<b>"}, secret: "x"};
+	show(json.Marshal(p));
+
+	p.Age = 42;
+	p.Address = &Address{City: "Paris"};
+	p.Scores = map[string]int{"math": 5, "art": 3};
+	show(json.Marshal(&p));
+	show(json.MarshalIndent(p, "", "  "));
+
+	show(json.Marshal([]any{1, 2.5, 100000000000000000000.0, "s", true, nil, []byte("hi"), map[int]string{10: "a", -1: "b", 3: "c"}}));
+	show(json.Marshal(map[string]func(){"f": nil}));
+	show(json.Marshal(complex(1, 2)));
+
+	var q Person;
+	err := json.Unmarshal([]byte(`{"name":"Bob","AGE":7,"tags":["x","y"],"address":{"city":"Rome","zip":123},"extra":{"n":1.5,"list":[1,"two",null,false],"obj":{}},"unknown":1}`), &q);
+	fmt.Println(err, q.Name, q.Age, q.Tags, q.Address.City, q.Address.Zip, q.Extra["n"], q.Extra["list"], q.Extra["obj"]);
+
+	fmt.Println(json.Unmarshal([]byte(`{"age":"x"}`), &q));
+	fmt.Println(json.Unmarshal([]byte(`{"address":{"city":5}}`), &q));
+	fmt.Println(json.Unmarshal([]byte(`{"scores":{"a":"b"}}`), &q));
+	fmt.Println(json.Unmarshal([]byte(`{"age":1.5}`), &q));
+	fmt.Println(json.Unmarshal([]byte(`{"Small":300}`), &q));
+	fmt.Println(json.Unmarshal([]byte(`[1]`), &q));
+	fmt.Println(json.Unmarshal([]byte(`{"age":1, "age":"x", "name": 5}`), &q), q.Age, q.Name);
+
+	var n int;
+	fmt.Println(json.Unmarshal([]byte(`"s"`), &n));
+	fmt.Println(json.Unmarshal([]byte(`12`), &n), n);
+	fmt.Println(json.Unmarshal([]byte(`{"a":1,}`), &n));
+	fmt.Println(json.Unmarshal([]byte(``), &n));
+	fmt.Println(json.Unmarshal([]byte(`1`), n));
+	fmt.Println(json.Unmarshal([]byte(`1`), nil));
+	var np *int;
+	fmt.Println(json.Unmarshal([]byte(`1`), np));
+
+	var syntaxErr *json.SyntaxError;
+	err = json.Unmarshal([]byte(`[1, 2 3]`), &n);
+	fmt.Println(errors.As(err, &syntaxErr), syntaxErr.Offset, err);
+
+	var anything any;
+	fmt.Println(json.Unmarshal([]byte(`{"k":[1,{"z":null}]}`), &anything), anything);
+
+	var m map[string][]int;
+	fmt.Println(json.Unmarshal([]byte(`{"b":[1,2],"a":null}`), &m), m, len(m));
+	var s fmt.Stringer;
+	fmt.Println(json.Unmarshal([]byte(`{}`), &s));
+	fmt.Println(json.Valid([]byte(`{"a":[]}`)), json.Valid([]byte(`{a}`)));
+	show(json.Marshal(q));
+}
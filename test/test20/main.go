@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type Task struct {
+	name string
+	cost time.Duration
+}
+
+func run(tasks []Task) time.Duration {
+	start := time.Now();
+	for _, task := range tasks {
+		time.Sleep(task.cost);
+		fmt.Println(task.name, "took", task.cost);
+	}
+	return time.Since(start);
+}
+
+func main() {
+	d := 90 * time.Minute;
+	fmt.Println(d, d.Hours(), d.Minutes());
+	fmt.Println(time.Duration(1500) * time.Millisecond);
+	fmt.Println(d.Round(time.Hour), d.Truncate(time.Hour), (time.Duration(0) - d).Abs());
+
+	p, err := time.ParseDuration("1h15m30.5s");
+	fmt.Println(p, p.Seconds(), err);
+	_, err = time.ParseDuration("soon");
+	fmt.Println(err);
+
+	start := time.Now();
+	fmt.Println(start);
+	fmt.Println(start.Format(time.RFC3339), start.Weekday(), start.Month());
+
+	total := run([]Task{Task{"parse", 250 * time.Millisecond}, Task{"check", 2 * time.Second}, Task{"link", time.Minute}});
+	fmt.Println("total", total);
+	fmt.Println(time.Now().Format(time.Kitchen), time.Now().Sub(start) == total);
+
+	deadline := start.Add(24 * time.Hour);
+	fmt.Println(deadline.Format(time.DateOnly), time.Until(deadline), deadline.After(time.Now()));
+	fmt.Println(deadline.AddDate(0, 1, 25).Format(time.DateTime), deadline.YearDay());
+
+	t, err := time.Parse(time.DateTime, "2024-02-29 12:30:45");
+	fmt.Println(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), err);
+	fmt.Println(t.Unix(), time.Unix(t.Unix(), 0).Equal(t), t.Compare(start));
+
+	var zero time.Time;
+	fmt.Println(zero.IsZero(), zero);
+	fmt.Println(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Weekday());
+
+	zone := time.FixedZone("MSK", 3 * 60 * 60);
+	moscow := t.In(zone);
+	fmt.Println(moscow, moscow.Location(), moscow.Equal(t), moscow == t);
+	fmt.Println(moscow.UTC() == t, t.Location() == time.UTC);
+
+	var nowhere *time.Location;
+	time.Date(2000, time.January, 1, 0, 0, 0, 0, nowhere);
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+type Counter struct {
+	mu    sync.Mutex
+	hits  map[string]int
+	total atomic.Int64
+}
+
+func (c *Counter) Hit(page string) {
+	c.mu.Lock();
+	defer c.mu.Unlock();
+	c.hits[page] = c.hits[page] + 1;
+	c.total.Add(1);
+}
+
+func withLock(l sync.Locker, f func()) {
+	l.Lock();
+	f();
+	l.Unlock();
+}
+
+func main() {
+	c := &Counter{hits: map[string]int{}};
+	var wg sync.WaitGroup;
+	for _, page := range []string{"home", "about", "home"} {
+		wg.Add(1);
+		c.Hit(page);
+		wg.Done();
+	}
+	wg.Wait();
+	fmt.Println(c.hits, c.total.Load());
+
+	fmt.Println(c.mu.TryLock(), c.mu.TryLock());
+	c.mu.Unlock();
+	withLock(&c.mu, func() {
+		fmt.Println("locked via Locker");
+	});
+
+	var rw sync.RWMutex;
+	rw.RLock();
+	rw.RLock();
+	fmt.Println(rw.TryLock(), rw.TryRLock());
+	rw.RUnlock();
+	rw.RUnlock();
+	rw.RUnlock();
+	fmt.Println(rw.TryLock(), rw.TryRLock());
+	rw.Unlock();
+
+	var once sync.Once;
+	for i := range 3 {
+		once.Do(func() {
+			fmt.Println("init", i);
+		});
+	}
+
+	var small atomic.Int32;
+	small.Store(40);
+	fmt.Println(small.Add(2), small.Swap(7), small.CompareAndSwap(1, 2), small.CompareAndSwap(7, 9), small.Load());
+	var flag atomic.Bool;
+	fmt.Println(flag.Load(), flag.CompareAndSwap(false, true), flag.Load());
+
+	defer fmt.Println("deferred calls don't run");
+	var mu sync.Mutex;
+	mu.Unlock();
+}
@@ -0,0 +1,101 @@
+package main
+
+import "fmt"
+
+func fib(n int) int {
+	if n < 2 {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+
+func find(grid [][]int, target int) (int, int) {
+	i := 0;
+	for i < len(grid) {
+		for j, v := range grid[i] {
+			if v == target {
+				return i, j;
+			}
+		}
+		i = i + 1;
+	}
+	return -1, -1;
+}
+
+func collatz(n int) int {
+	steps := 0;
+loop:
+	if n == 1 {
+		goto done;
+	}
+	if (n / 2 * 2) == n {
+		n = n / 2;
+	} else {
+		n = 3 * n + 1;
+	}
+	steps = steps + 1;
+	goto loop;
+done:
+	return steps;
+}
+
+func firstNegative(values []int) int {
+	for _, v := range values {
+		if v < 0 {
+			goto found;
+		}
+	}
+	return 0;
+found:
+	return -1;
+}
+
+func main() {
+	fmt.Println(fib(20));
+	fmt.Println(find([][]int{[]int{1, 2}, []int{3, 4, 5}}, 5));
+	fmt.Println(find([][]int{[]int{1}}, 7));
+	fmt.Println(collatz(27), firstNegative([]int{1, 2}), firstNegative([]int{1, -2}));
+
+	var funcs []func() int;
+	n := 0;
+	for n < 3 {
+		k := n * 10;
+		funcs = append(funcs, func() int {
+			return k;
+		});
+		n = n + 1;
+	}
+	for _, f := range funcs {
+		fmt.Print(f(), " ");
+	}
+	fmt.Println();
+
+	count := 0;
+	for {
+		count = count + 1;
+		if count > 2 {
+			for _, c := range "abc" {
+				if c == 'b' {
+					break;
+				}
+				fmt.Println("rune", string(c));
+			}
+			break;
+		}
+		x := count;
+		fmt.Println("count", x);
+	}
+
+	total := 0;
+	for i := range 4 {
+		j := 0;
+		for {
+			if j == i {
+				break;
+			}
+			total = total + j;
+			j = j + 1;
+		}
+	}
+	fmt.Println("total", total);
+}
@@ -0,0 +1,121 @@
+package main
+
+import "fmt"
+
+func shadow() {
+	x := 1;
+	if x > 0 {
+		x := 2;
+		if x > 1 {
+			x := "three";
+			fmt.Println(x);
+		}
+		fmt.Println(x);
+		x = 4;
+	}
+	fmt.Println(x);
+}
+
+func pair(s string) (int, string) {
+	return len(s), s + "!";
+}
+
+func shadowPair() {
+	n, s := pair("outer");
+	if n > 0 {
+		n, s := pair("in");
+		fmt.Println(n, s);
+	}
+	fmt.Println(n, s);
+}
+
+func capture() []func() int {
+	res := []func() int{};
+	for i := range 3 {
+		j := i * 10;
+		res = append(res, func() int {
+			return i + j;
+		});
+	}
+	return res;
+}
+
+func search(words []string, target string) int {
+	for i, word := range words {
+		if word == target {
+			found := i;
+			return found;
+		}
+	}
+	return -1;
+}
+
+func nested(n int) int {
+	total := 0;
+	for i := range n {
+		for j := range n {
+			if j > i {
+				break;
+			}
+			total = total + j;
+		}
+	}
+	return total;
+}
+
+func escape(grid [][]int) string {
+	for _, row := range grid {
+		for _, v := range row {
+			if v < 0 {
+				goto negative;
+			}
+		}
+	}
+	return "all positive";
+negative:
+	return "found negative";
+}
+
+func counter() (func() int, func() int) {
+	count := 0;
+	inc := func() int {
+		count = count + 1;
+		return count;
+	};
+	get := func() int {
+		return count;
+	};
+	return inc, get;
+}
+
+func sum(values ...int) (total int) {
+	for _, v := range values {
+		total = total + v;
+	}
+	return;
+}
+
+func main() {
+	shadow();
+	shadowPair();
+
+	for _, f := range capture() {
+		fmt.Print(f(), " ");
+	}
+	fmt.Println();
+
+	fmt.Println(search([]string{"a", "b", "c"}, "b"), search([]string{"a"}, "z"));
+	fmt.Println(nested(5));
+	fmt.Println(escape([][]int{[]int{1, 2}, []int{3}}), escape([][]int{[]int{1}, []int{2, -3}}));
+
+	inc, get := counter();
+	inc();
+	inc();
+	fmt.Println(inc(), get());
+
+	fmt.Println(sum(), sum(1, 2, 3));
+
+	x := 1.5;
+	y := 2;
+	fmt.Println(x * 2 - 1, (y * y + 1) < 6, "ab" < "b", x / 4);
+}
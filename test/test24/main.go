@@ -0,0 +1,96 @@
+package main
+
+import "fmt"
+
+func safeDiv(a, b int) (res int, err error) {
+	defer func() {
+		r := recover();
+		if r != nil {
+			err = fmt.Errorf("recovered: %v", r);
+		}
+	}();
+
+	if b == 0 {
+		panic("division by zero");
+	}
+	return a / b, nil;
+}
+
+func index(s []int, i int) (res int) {
+	defer func() {
+		r := recover();
+		if r != nil {
+			fmt.Println("recovered:", r);
+			res = -1;
+		}
+	}();
+
+	return s[i];
+}
+
+func helper() any {
+	return recover();
+}
+
+func indirect() (res string) {
+	defer func() {
+		res = fmt.Sprint("indirect: ", helper(), " ", recover());
+	}();
+
+	panic("lost");
+}
+
+func replaced() (res string) {
+	defer func() {
+		res = fmt.Sprint("last panic: ", recover());
+	}();
+	defer func() {
+		panic("second");
+	}();
+
+	panic("first");
+}
+
+type depthError struct {
+	depth int
+}
+
+func (e depthError) Error() string {
+	return fmt.Sprint("too deep at ", e.depth);
+}
+
+func custom() (res string) {
+	defer func() {
+		res = fmt.Sprintf("%T %v", recover(), recover());
+	}();
+
+	panic(depthError{depth: 3});
+}
+
+func deep(n int) int {
+	return deep(n + 1) + 1;
+}
+
+func overflow() (res string) {
+	defer func() {
+		res = fmt.Sprint("caught: ", recover());
+	}();
+
+	deep(0);
+	return "unreachable";
+}
+
+func main() {
+	fmt.Println(safeDiv(7, 2));
+	fmt.Println(safeDiv(1, 0));
+	fmt.Println(index([]int{1, 2, 3}, 1));
+	fmt.Println(index([]int{1, 2, 3}, 5));
+	fmt.Println(recover());
+	fmt.Println(replaced());
+
+	fmt.Println(custom());
+
+	fmt.Println(overflow());
+	fmt.Println(overflow());
+	fmt.Println(indirect());
+}
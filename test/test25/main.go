@@ -0,0 +1,75 @@
+package main
+
+import "fmt"
+
+func sum(n, acc int) int {
+	if n == 0 {
+		return acc;
+	}
+	return sum(n - 1, acc + n);
+}
+
+func gcd(a, b int) int {
+	if b == 0 {
+		return a;
+	}
+	return gcd(b, a - (a / b * b));
+}
+
+func collatz(n, steps int) (int, int) {
+	if n == 1 {
+		return n, steps;
+	}
+	if (n / 2 * 2) == n {
+		return collatz(n / 2, steps + 1);
+	}
+	return collatz(3 * n + 1, steps + 1);
+}
+
+func countdown(n int) (res []func() int) {
+	if n == 0 {
+		return res;
+	}
+	res = countdown(n - 1);
+	return append(res, func() int {
+		return n;
+	});
+}
+
+func deferred(n int) int {
+	if n == 0 {
+		return 0;
+	}
+	defer func() {}();
+	return deferred(n - 1);
+}
+
+func even(n int) bool {
+	if n == 0 {
+		return true;
+	}
+	return odd(n - 1);
+}
+
+func odd(n int) bool {
+	if n == 0 {
+		return false;
+	}
+	return even(n - 1);
+}
+
+func main() {
+	fmt.Println(sum(1000000, 0));
+	fmt.Println(gcd(1071, 462));
+	fmt.Println(collatz(27, 0));
+
+	total := 0;
+	for _, f := range countdown(5) {
+		total = total + f();
+	}
+	fmt.Println(total);
+
+	fmt.Println(deferred(100));
+	fmt.Println(even(10));
+	fmt.Println(even(1000000));
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+func fib(n int) int {
+	if n < 2 {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+
+func spin() {
+	defer func() {
+		fmt.Println("the deferred call doesn't run:", recover());
+	}();
+
+	i := 0;
+	for {
+		i = i + 1;
+	}
+}
+
+func grow() {
+	chunks := [][]int{};
+	for {
+		chunks = append(chunks, make([]int, 1024));
+	}
+}
+
+func main() {
+	fmt.Println(fib(20));
+
+	mode := "";
+	if len(os.Args) > 1 {
+		mode = os.Args[1];
+	}
+	if mode == "spin" {
+		spin();
+	}
+	if mode == "sleep" {
+		time.Sleep(time.Hour);
+	}
+	if mode == "grow" {
+		grow();
+	}
+	fmt.Println("done");
+}
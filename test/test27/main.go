@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type Grid [3][3]int
+
+type Point struct {
+	coords [2]int
+	name   string
+}
+
+func sum(values [4]int) int {
+	total := 0;
+	for _, v := range values {
+		total = total + v;
+	}
+	return total;
+}
+
+func fill(p *[4]int, value int) {
+	for i := range p {
+		p[i] = value;
+	}
+}
+
+func main() {
+	var a [4]int;
+	fmt.Println(a, len(a), cap(a));
+
+	a[1] = 5;
+	b := a;
+	b[2] = 7;
+	fmt.Println(a, b, a == b, sum(b));
+
+	primes := [...]int{2, 3, 5, 7, 11};
+	fmt.Println(len(primes), primes);
+	for i, p := range primes {
+		primes[4] = 0;
+		fmt.Print(i, ":", p, " ");
+	}
+	fmt.Println();
+
+	s := primes[1:3];
+	s[0] = 30;
+	fmt.Println(s, len(s), cap(s), primes);
+
+	fill(&a, 9);
+	fmt.Println(a);
+	p := &b;
+	p[0] = 1;
+	fmt.Println(len(p), b, *p);
+
+	var g Grid;
+	g[1][1] = 5;
+	row := g[1];
+	row[0] = 4;
+	fmt.Println(g, row);
+
+	pt := Point{coords: [2]int{1, 2}, name: "a"};
+	other := pt;
+	other.coords[0] = 10;
+	fmt.Println(pt, other, pt == other);
+	fmt.Printf("%v %+v %#v\n", pt.coords, pt, [2]string{"x", "y"});
+
+	names := [3]string{"x", "y"};
+	for i := range names {
+		names[i] = names[i] + "!";
+	}
+	fmt.Println(names, names == [3]string{"x!", "y!", "!"});
+
+	var boxed any;
+	boxed = [2]int{1, 2};
+	fmt.Println(boxed == [2]int{1, 2}, boxed);
+
+	data, _ := json.Marshal([3]uint8{1, 2, 3});
+	fmt.Println(string(data));
+	var decoded [2]int;
+	json.Unmarshal([]byte("[4, 5, 6]"), &decoded);
+	fmt.Println(decoded);
+
+	idx := 4;
+	defer func() {
+		fmt.Println("recovered:", recover());
+	}();
+	fmt.Println(a[idx]);
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type Counter struct {
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func (c *Counter) Inc(key string) {
+	c.mu.Lock();
+	defer c.mu.Unlock();
+	c.count[key] = c.count[key] + 1;
+}
+
+func square(wg *sync.WaitGroup, results []int, i int) {
+	defer wg.Done();
+	results[i] = i * i;
+}
+
+func fib(n int) int {
+	if n < 2 {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+
+func main() {
+	var wg sync.WaitGroup;
+
+	results := make([]int, 8);
+	for i := range 8 {
+		wg.Add(1);
+		go square(&wg, results, i);
+	}
+	wg.Wait();
+	fmt.Println(results);
+
+	c := &Counter{count: map[string]int{}};
+	for i := range 50 {
+		wg.Add(1);
+		go func() {
+			defer wg.Done();
+			if i < 20 {
+				c.Inc("low");
+			} else {
+				c.Inc("high");
+			}
+		}();
+	}
+	wg.Wait();
+	fmt.Println(c.count["low"], c.count["high"]);
+
+	var total atomic.Int32;
+	fibs := make([]int, 4);
+	for i := range 4 {
+		wg.Add(1);
+		go func(n int) {
+			defer wg.Done();
+			fibs[i] = fib(n);
+			total.Add(int32(fibs[i]));
+		}(15 + i);
+	}
+	wg.Wait();
+	fmt.Println(fibs, total.Load());
+
+	var once sync.Once;
+	var calls atomic.Int32;
+	for range 5 {
+		wg.Add(1);
+		go func() {
+			defer wg.Done();
+			once.Do(func() {
+				calls.Add(1);
+			});
+		}();
+	}
+	wg.Wait();
+	fmt.Println("once:", calls.Load());
+
+	var rw sync.RWMutex;
+	shared := map[int]int{};
+	for i := range 10 {
+		wg.Add(2);
+		go func() {
+			defer wg.Done();
+			rw.Lock();
+			shared[i] = i * 10;
+			rw.Unlock();
+		}();
+		go func() {
+			defer wg.Done();
+			rw.RLock();
+			_ = len(shared);
+			rw.RUnlock();
+		}();
+	}
+	wg.Wait();
+	keys := []int{};
+	for k := range shared {
+		keys = append(keys, k);
+	}
+	sort.Ints(keys);
+	fmt.Println(keys, shared[9]);
+
+	done := false;
+	var mu sync.Mutex;
+	wg.Add(1);
+	go func() {
+		defer wg.Done();
+		defer func() {
+			r := recover();
+			mu.Lock();
+			done = r != nil;
+			mu.Unlock();
+			fmt.Println("recovered:", r);
+		}();
+		var m map[string]int;
+		m["x"] = 1;
+	}();
+	wg.Wait();
+	fmt.Println("done:", done);
+
+	var nested sync.WaitGroup;
+	sum := 0;
+	var sumMu sync.Mutex;
+	var spawn func(int);
+	spawn = func(depth int) {
+		defer nested.Done();
+		sumMu.Lock();
+		sum = sum + depth;
+		sumMu.Unlock();
+		if depth < 4 {
+			nested.Add(2);
+			go spawn(depth + 1);
+			go spawn(depth + 1);
+		}
+	};
+	nested.Add(1);
+	go spawn(0);
+	nested.Wait();
+	fmt.Println("sum:", sum);
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Job struct {
+	ID   int
+	Name string
+}
+
+func produce(out chan<- int, n int) {
+	for i := range n {
+		out <- i * i;
+	}
+	close(out);
+}
+
+func consume(in <-chan int) int {
+	sum := 0;
+	for v := range in {
+		sum = sum + v;
+	}
+	return sum;
+}
+
+func worker(id int, jobs <-chan Job, results chan<- string, wg *sync.WaitGroup) {
+	defer wg.Done();
+	for job := range jobs {
+		results <- fmt.Sprintf("job %v %v", job.ID, job.Name);
+	}
+}
+
+func Merge[T any](a, b <-chan T) []T {
+	var res []T;
+	for a != nil || b != nil {
+		select {
+		case v, ok := <-a:
+			if !ok {
+				a = nil;
+			} else {
+				res = append(res, v);
+			}
+		case v, ok := <-b:
+			if !ok {
+				b = nil;
+			} else {
+				res = append(res, v);
+			}
+		}
+	}
+	return res;
+}
+
+func sendClosed() (err any) {
+	defer func() {
+		err = recover();
+	}();
+	ch := make(chan int, 1);
+	close(ch);
+	ch <- 1;
+	return nil;
+}
+
+func closeNil() (err any) {
+	defer func() {
+		err = recover();
+	}();
+	var ch chan int;
+	close(ch);
+	return nil;
+}
+
+func closeTwice() (err any) {
+	defer func() {
+		err = recover();
+	}();
+	ch := make(chan int);
+	close(ch);
+	close(ch);
+	return nil;
+}
+
+func firstReady(chans []chan string) string {
+	for i := range 10 {
+		select {
+		case v := <-chans[0]:
+			return v;
+		case v := <-chans[1]:
+			return v;
+		default:
+			if i == 2 {
+				chans[1] <- "second";
+			}
+		}
+	}
+	return "none";
+}
+
+func main() {
+	ch := make(chan int);
+	go produce(ch, 5);
+	fmt.Println("sum", consume(ch));
+
+	buffered := make(chan string, 3);
+	buffered <- "a";
+	buffered <- "b";
+	fmt.Println(len(buffered), cap(buffered));
+	fmt.Println(<-buffered, <-buffered, len(buffered));
+
+	var unbuffered chan int;
+	fmt.Println(unbuffered == nil, len(unbuffered), cap(unbuffered));
+	fmt.Printf("%T %T %T\n", ch, buffered, (<-chan int)(ch));
+
+	ping := make(chan string);
+	pong := make(chan string);
+	go func() {
+		for msg := range ping {
+			pong <- msg + "!";
+		}
+		close(pong);
+	}();
+	for _, word := range []string{"one", "two", "three"} {
+		ping <- word;
+		fmt.Println(<-pong);
+	}
+	close(ping);
+	v, ok := <-pong;
+	fmt.Printf("%q %v\n", v, ok);
+
+	jobs := make(chan Job, 10);
+	results := make(chan string, 10);
+	var wg sync.WaitGroup;
+	for id := range 3 {
+		wg.Add(1);
+		go worker(id, jobs, results, &wg);
+	}
+	for i := range 6 {
+		jobs <- Job{ID: i, Name: fmt.Sprint("task", i)};
+	}
+	close(jobs);
+	wg.Wait();
+	close(results);
+	count := 0;
+	for range results {
+		count = count + 1;
+	}
+	fmt.Println("results", count);
+
+	a := make(chan int);
+	b := make(chan int);
+	go func() {
+		for i := range 3 {
+			a <- i;
+		}
+		close(a);
+	}();
+	go func() {
+		for i := range 3 {
+			b <- 10 + i;
+		}
+		close(b);
+	}();
+	merged := Merge(a, b);
+	total := 0;
+	for _, m := range merged {
+		total = total + m;
+	}
+	fmt.Println("merged", len(merged), total);
+
+	fmt.Println(sendClosed());
+	fmt.Println(closeNil());
+	fmt.Println(closeTwice());
+
+	chans := []chan string{make(chan string), make(chan string, 1)};
+	fmt.Println(firstReady(chans));
+
+	select {
+	case x := <-ch:
+		fmt.Println("closed", x);
+	default:
+		fmt.Println("default");
+	}
+
+	done := make(chan struct{});
+	quit := make(chan bool);
+	go func() {
+		defer close(done);
+		n := 0;
+		for {
+			select {
+			case <-quit:
+				fmt.Println("quit after", n >= 5);
+				return;
+			case unbuffered <- 1:
+				fmt.Println("never");
+			default:
+				n = n + 1;
+				if n == 5 {
+					quit <- true;
+				}
+			}
+		}
+	}();
+	<-quit;
+	quit <- true;
+	<-done;
+
+	var x int;
+	var y int;
+	var okx bool;
+	c2 := make(chan int, 2);
+	c2 <- 7;
+	c2 <- 8;
+	x = <-c2;
+	y, okx = <-c2;
+	fmt.Println(x, y, okx);
+
+	chOfCh := make(chan chan int, 1);
+	inner := make(chan int, 1);
+	chOfCh <- inner;
+	(<-chOfCh) <- 42;
+	fmt.Println(<-inner);
+
+	for {
+		select {
+		case c2 <- 1:
+			fmt.Println("sent", len(c2));
+			break;
+		}
+		if len(c2) == 2 {
+			break;
+		}
+	}
+	fmt.Println(<-c2 + <-c2);
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+func worker(d time.Duration, out chan<- string) {
+	time.Sleep(d);
+	out <- "done";
+}
+
+func timeout(d time.Duration) string {
+	out := make(chan string, 1);
+	go worker(d, out);
+	select {
+	case v := <-out:
+		return v;
+	case <-time.After(50 * time.Millisecond):
+		return "timeout";
+	}
+}
+
+func main() {
+	fmt.Println(timeout(time.Millisecond));
+	fmt.Println(timeout(time.Second));
+
+	start := time.Now();
+	ticker := time.NewTicker(20 * time.Millisecond);
+	ticks := 0;
+	for range ticker.C {
+		ticks = ticks + 1;
+		if ticks == 3 {
+			ticker.Stop();
+			break;
+		}
+	}
+	fmt.Println("ticks", ticks, time.Since(start) >= (60 * time.Millisecond));
+
+	timer := time.NewTimer(time.Hour);
+	fmt.Println("stop", timer.Stop());
+	fmt.Println("stop again", timer.Stop());
+	fmt.Println("reset", timer.Reset(10*time.Millisecond));
+	t := <-timer.C;
+	fmt.Println("fired", !t.Before(start));
+	fmt.Println("stop fired", timer.Stop());
+
+	var wg sync.WaitGroup;
+	wg.Add(1);
+	fired := false;
+	time.AfterFunc(10*time.Millisecond, func() {
+		fired = true;
+		wg.Done();
+	});
+	wg.Wait();
+	fmt.Println("after func", fired);
+
+	tick := time.Tick(5 * time.Millisecond);
+	for i := range 3 {
+		<-tick;
+		fmt.Println("tick", i);
+	}
+	fmt.Println("nil tick", time.Tick(0) == nil);
+
+	results := make(chan int);
+	for i := range 3 {
+		go func() {
+			<-time.After(time.Duration(3-i) * 30 * time.Millisecond);
+			results <- i;
+		}();
+	}
+	for range 3 {
+		fmt.Println("result", <-results);
+	}
+
+	defer func() {
+		fmt.Println("recovered", recover());
+	}();
+	time.NewTicker(0);
+}
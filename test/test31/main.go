@@ -0,0 +1,30 @@
+package main
+
+import "fmt"
+
+type node struct {
+	x int
+}
+
+func trace(name string, v int) int {
+	fmt.Println(name);
+	return v;
+}
+
+func check(name string, v bool) bool {
+	fmt.Println(name);
+	return v;
+}
+
+func positive(p *node) bool {
+	return p != nil && p.x > 0;
+}
+
+func main() {
+	fmt.Println(trace("a", 1) + trace("b", 2) - trace("c", 3));
+	fmt.Println(check("d", false) && check("e", true));
+	fmt.Println(check("f", true) || check("g", true));
+	fmt.Println(check("h", true) && check("i", false) || check("j", true));
+	var p *node;
+	fmt.Println(positive(p), positive(&node{x: 1}));
+}
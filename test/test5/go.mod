@@ -0,0 +1,3 @@
+module example.com/test5
+
+go 1.22
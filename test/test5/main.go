@@ -0,0 +1,12 @@
+package main
+
+import (
+	"example.com/test5/util"
+	m "example.com/test5/util/mathutil"
+)
+
+func main() {
+	println(greeting("world"));
+	println(util.Twice(21), m.Square(7));
+	util.Report("done");
+}
@@ -0,0 +1,5 @@
+package mathutil
+
+func Square(n int) int {
+	return n * n;
+}
@@ -0,0 +1,5 @@
+package util
+
+func prefix() string {
+	return "util: ";
+}
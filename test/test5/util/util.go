@@ -0,0 +1,11 @@
+package util
+
+import "example.com/test5/util/mathutil"
+
+func Twice(n int) int {
+	return mathutil.Square(1) * n + n;
+}
+
+func Report(msg string) {
+	println(prefix() + msg);
+}
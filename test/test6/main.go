@@ -0,0 +1,59 @@
+package main
+
+type Celsius float64
+type Fahrenheit float64
+
+type ID = int
+
+type (
+	Names []string
+	Ages  map[string]int
+	Op    func(int, int) int
+)
+
+func (c Celsius) Fahrenheit() Fahrenheit {
+	return Fahrenheit(c*1.8 + 32);
+}
+
+func (n Names) First() string {
+	return n[0];
+}
+
+func add(a int, b int) int {
+	return a + b;
+}
+
+func apply(op Op, a int, b int) int {
+	return op(a, b);
+}
+
+func main() {
+	var c Celsius;
+	c = 100;
+	println(c.Fahrenheit());
+
+	var id ID;
+	var i int;
+	i = 7;
+	id = i;
+	println(id);
+
+	var names Names;
+	names = Names{"alice", "bob"};
+	names[1] = "carol";
+	println(names.First(), names[1]);
+
+	var ages Ages;
+	ages = Ages{"alice": 30};
+	ages["bob"] = 25;
+	println(ages["alice"], ages["bob"], ages["nobody"]);
+
+	println(apply(add, 2, 3));
+
+	var f float64;
+	f = float64(c);
+	println(f / 4);
+
+	var temp Fahrenheit;
+	temp = c;
+}
@@ -0,0 +1,107 @@
+package main
+
+type Number interface {
+	~int | ~float64
+}
+
+type Meters int
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(item T) {
+	s.items = append(s.items, item);
+}
+
+func (s *Stack[T]) Pop() T {
+	var item T;
+	item = s.items[len(s.items) - 1];
+
+	var rest []T;
+	var i int;
+	for i < (len(s.items) - 1) {
+		rest = append(rest, s.items[i]);
+		i = i + 1;
+	}
+	s.items = rest;
+	return item;
+}
+
+func (s Stack[T]) Len() int {
+	return len(s.items);
+}
+
+type Point struct {
+	X, Y int
+}
+
+func (p *Point) Move(dx int, dy int) {
+	p.X = p.X + dx;
+	p.Y = p.Y + dy;
+}
+
+func Map[T, U any](s []T, f func(T) U) []U {
+	var res []U;
+	var i int;
+	for i < len(s) {
+		res = append(res, f(s[i]));
+		i = i + 1;
+	}
+	return res;
+}
+
+func Sum[T Number](s []T) T {
+	var res T;
+	var i int;
+	for i < len(s) {
+		res = res + s[i];
+		i = i + 1;
+	}
+	return res;
+}
+
+func Index[T comparable](s []T, x T) int {
+	var i int;
+	for i < len(s) {
+		if (s[i] == x) {
+			return i;
+		}
+		i = i + 1;
+	}
+	return -1;
+}
+
+func double(x int) float64 {
+	return float64(x) * 1.5;
+}
+
+func main() {
+	println(Map([]int{1, 2, 3}, double));
+	println(Sum([]int{1, 2, 3}), Sum([]float64{0.5, 0.25}), Sum([]Meters{10, 20}));
+	println(Index([]string{"a", "b", "c"}, "c"), Index[int]([]int{1, 2}, 5));
+
+	var s Stack[string];
+	s.Push("first");
+	s.Push("second");
+	println(s.Len(), s.Pop(), s.Len());
+
+	var p Pair[string, int];
+	p = Pair[string, int]{Key: "answer", Value: 42};
+	println(p.Key, p.Value, p);
+
+	var pt *Point;
+	pt = &Point{1, 2};
+	pt.Move(2, 3);
+	var q Point;
+	q = *pt;
+	q.Move(10, 10);
+	println(*pt, q);
+
+	println(Sum([]string{"a"}));
+}
@@ -0,0 +1,71 @@
+package main
+
+type Logger func(string, ...any)
+
+func sum(xs ...int) int {
+	var res int;
+	var i int;
+	for i < len(xs) {
+		res = res + xs[i];
+		i = i + 1;
+	}
+	return res;
+}
+
+func join(sep string, parts ...string) string {
+	if (len(parts) == 0) {
+		return "";
+	}
+
+	var res string;
+	res = parts[0];
+	var i int;
+	i = 1;
+	for i < len(parts) {
+		res = res + sep + parts[i];
+		i = i + 1;
+	}
+	return res;
+}
+
+func Max[T ~int | ~float64](first T, rest ...T) T {
+	var res T;
+	res = first;
+	var i int;
+	for i < len(rest) {
+		if (rest[i] > res) {
+			res = rest[i];
+		}
+		i = i + 1;
+	}
+	return res;
+}
+
+func logf(prefix string, args ...any) {
+	print(prefix, ": ");
+	println(args...);
+}
+
+func main() {
+	println(sum(), sum(1), sum(1, 2, 3));
+
+	var nums []int;
+	nums = []int{4, 5, 6};
+	println(sum(nums...));
+
+	nums = append(nums, []int{7, 8}...);
+	println(len(nums), nums);
+
+	println(join(", ", "a", "b", "c"), join("-"));
+	println(Max(3, 9, 4), Max(2.5), Max(1.5, 2, 0.5));
+
+	var log Logger;
+	log = logf;
+	log("info", "answer", 42, true);
+
+	var x any;
+	x = 42;
+	println(x == 42, x != "42", x == nil);
+
+	println(sum(1, nums...));
+}
@@ -0,0 +1,81 @@
+package main
+
+func div(a, b int) (q, r int) {
+	q = a / b;
+	r = a - q*b;
+	return;
+}
+
+func swap(a, b string) (string, string) {
+	return b, a;
+}
+
+func safeDiv(a, b int) (q int, ok bool) {
+	if (b == 0) {
+		return 0, false;
+	}
+	q, _ = div(a, b);
+	return q, true;
+}
+
+func double(x int) (res int) {
+	defer func() {
+		res = res * 2;
+	}();
+	return x + 1;
+}
+
+func trace(name string) (func(), string) {
+	println("enter", name);
+	return func() {
+		println("leave", name);
+	}, name;
+}
+
+func counter() func() int {
+	var n int;
+	return func() int {
+		n = n + 1;
+		return n;
+	};
+}
+
+func work() {
+	var leave func();
+	leave, _ = trace("work");
+	defer leave();
+
+	var i int;
+	for i < 3 {
+		defer println("deferred", i);
+		i = i + 1;
+	}
+	println("working");
+}
+
+func main() {
+	var q int;
+	var r int;
+	q, r = div(17, 5);
+	println(q, r);
+
+	var a string;
+	var b string;
+	a, b = swap("x", "y");
+	a, b = b, a;
+	println(a, b);
+
+	println(safeDiv(7, 2));
+	println(safeDiv(7, 0));
+	println(double(20));
+
+	var next func() int;
+	next = counter();
+	next();
+	next();
+	println(next());
+
+	work();
+
+	q = div(1, 1);
+}
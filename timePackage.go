@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "time",
+		Path:    "time",
+		Imports: []string{"errors"},
+		Declare: declareTime,
+	})
+}
+
+// Clock is the time the scripts see.
+type Clock interface {
+	Now() time.Time
+	// Sleep sleeps for the duration, the error is the one of the context done before the sleep is over
+	Sleep(ctx context.Context, d time.Duration) error
+	// the location the times of the scripts are shown in
+	Location() *time.Location
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+func (wallClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (wallClock) Location() *time.Location {
+	return time.Local
+}
+
+// VirtualClock makes the timings of the scripts reproducible: it starts at the fixed moment
+// and jumps forward at once when no goroutine of the script can run, to the time the first sleeping one
+// wakes up at or the first timer fires at.
+type VirtualClock struct {
+	now time.Time
+}
+
+func NewVirtualClock() *VirtualClock {
+	return &VirtualClock{now: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	return c.now
+}
+
+// Sleep returns the error of the context done, the ticker may move the clock for good.
+func (c *VirtualClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+	return ctx.Err()
+}
+
+func (c *VirtualClock) Location() *time.Location {
+	return time.UTC
+}
+
+// unixToInternal is the number of the seconds from the zero time, January 1 of the year 1, to the Unix epoch.
+// The times of the scripts count the seconds from the zero time, so the zero value is the zero time.
+const unixToInternal = (1969*365 + 1969/4 - 1969/100 + 1969/400) * 24 * 60 * 60
+
+func declareTime(prog *Program, pkg *Package) {
+	duration := prog.declareType(pkg, "Duration", IntType)
+	month := prog.declareType(pkg, "Month", IntType)
+	weekday := prog.declareType(pkg, "Weekday", IntType)
+	location := prog.declareType(pkg, "Location", hostStruct(pkg, &Field{Name: "name", Type: StringType}))
+	locationPointer := &PointerType{Elem: location}
+	// the time keeps the location it is shown in, the nil location is UTC
+	timeType := prog.declareType(pkg, "Time", hostStruct(pkg,
+		&Field{Name: "sec", Type: IntType},
+		&Field{Name: "nsec", Type: IntType},
+		&Field{Name: "loc", Type: locationPointer},
+	))
+
+	// the locations of the script are shared like the host ones, so the times in the same location are equal
+	locations := map[*time.Location]any{}
+	hostLocations := map[any]*time.Location{}
+	newLocation := func(loc *time.Location, name string) any {
+		if res, ok := locations[loc]; ok {
+			return res
+		}
+		res := NewPointer(NewStructValue(name))
+		locations[loc] = res
+		hostLocations[res] = loc
+		return res
+	}
+	utc := newLocation(time.UTC, "UTC")
+	prog.RegisterValue(pkg.QualifiedName("UTC"), &PackageValue{Type: locationPointer, Value: utc})
+	prog.RegisterValue(pkg.QualifiedName("Local"), &PackageValue{Type: locationPointer, Value: newLocation(prog.Clock.Location(), "Local")})
+
+	hostLocation := func(loc any) *time.Location {
+		if isNilPointer(loc) {
+			return time.UTC
+		}
+		return hostLocations[loc]
+	}
+	toTime := func(value any) time.Time {
+		fields := value.(*StructValue).fields
+		return time.Unix(int64((*fields[0]).(int)-unixToInternal), int64((*fields[1]).(int))).In(hostLocation(*fields[2]))
+	}
+	fromTime := func(t time.Time) any {
+		var loc any
+		if t.Location() != time.UTC {
+			loc = newLocation(t.Location(), t.Location().String())
+		}
+		return NewStructValue(int(t.Unix())+unixToInternal, t.Nanosecond(), loc)
+	}
+
+	prog.declareMethod(location, "String", signature([]Type{locationPointer}, StringType), func(args ...any) ([]any, error) {
+		return []any{hostLocation(args[0]).String()}, nil
+	})
+	prog.declareFunction(pkg, "LoadLocation", signature([]Type{StringType}, locationPointer, ErrorType), func(args ...any) ([]any, error) {
+		loc, err := time.LoadLocation(args[0].(string))
+		if err != nil {
+			return []any{nil, prog.hostError(err)}, nil
+		}
+		if loc == time.Local {
+			loc = prog.Clock.Location()
+		}
+		return []any{newLocation(loc, loc.String()), nil}, nil
+	})
+	prog.declareFunction(pkg, "FixedZone", signature([]Type{StringType, IntType}, locationPointer), func(args ...any) ([]any, error) {
+		loc := time.FixedZone(args[0].(string), args[1].(int))
+		return []any{newLocation(loc, loc.String())}, nil
+	})
+
+	units := []time.Duration{time.Nanosecond, time.Microsecond, time.Millisecond, time.Second, time.Minute, time.Hour}
+	for i, name := range []string{"Nanosecond", "Microsecond", "Millisecond", "Second", "Minute", "Hour"} {
+		prog.declareConstant(pkg, name, duration, int(units[i]))
+	}
+	for m := time.January; m <= time.December; m++ {
+		prog.declareConstant(pkg, m.String(), month, int(m))
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		prog.declareConstant(pkg, d.String(), weekday, int(d))
+	}
+
+	layouts := map[string]string{
+		"Layout":      time.Layout,
+		"ANSIC":       time.ANSIC,
+		"UnixDate":    time.UnixDate,
+		"RubyDate":    time.RubyDate,
+		"RFC822":      time.RFC822,
+		"RFC822Z":     time.RFC822Z,
+		"RFC850":      time.RFC850,
+		"RFC1123":     time.RFC1123,
+		"RFC1123Z":    time.RFC1123Z,
+		"RFC3339":     time.RFC3339,
+		"RFC3339Nano": time.RFC3339Nano,
+		"Kitchen":     time.Kitchen,
+		"Stamp":       time.Stamp,
+		"StampMilli":  time.StampMilli,
+		"StampMicro":  time.StampMicro,
+		"StampNano":   time.StampNano,
+		"DateTime":    time.DateTime,
+		"DateOnly":    time.DateOnly,
+		"TimeOnly":    time.TimeOnly,
+	}
+	for name, layout := range layouts {
+		prog.declareConstant(pkg, name, UntypedStringType, layout)
+	}
+
+	prog.declareMethod(month, "String", signature([]Type{month}, StringType), func(args ...any) ([]any, error) {
+		return []any{time.Month(args[0].(int)).String()}, nil
+	})
+	prog.declareMethod(weekday, "String", signature([]Type{weekday}, StringType), func(args ...any) ([]any, error) {
+		return []any{time.Weekday(args[0].(int)).String()}, nil
+	})
+
+	durationMethod := func(name string, results []Type, call func(d time.Duration, args ...any) any, params ...Type) {
+		prog.declareMethod(duration, name, signature(append([]Type{duration}, params...), results...), func(args ...any) ([]any, error) {
+			return []any{call(time.Duration(args[0].(int)), args[1:]...)}, nil
+		})
+	}
+	durationMethod("String", []Type{StringType}, func(d time.Duration, args ...any) any {
+		return d.String()
+	})
+	for _, method := range []struct {
+		name string
+		unit func(d time.Duration) float64
+	}{
+		{"Hours", time.Duration.Hours},
+		{"Minutes", time.Duration.Minutes},
+		{"Seconds", time.Duration.Seconds},
+	} {
+		unit := method.unit
+		durationMethod(method.name, []Type{Float64Type}, func(d time.Duration, args ...any) any {
+			return unit(d)
+		})
+	}
+	for _, method := range []struct {
+		name string
+		unit func(d time.Duration) int64
+	}{
+		{"Milliseconds", time.Duration.Milliseconds},
+		{"Microseconds", time.Duration.Microseconds},
+		{"Nanoseconds", time.Duration.Nanoseconds},
+	} {
+		unit := method.unit
+		durationMethod(method.name, []Type{IntType}, func(d time.Duration, args ...any) any {
+			return int(unit(d))
+		})
+	}
+	durationMethod("Round", []Type{duration}, func(d time.Duration, args ...any) any {
+		return int(d.Round(time.Duration(args[0].(int))))
+	}, duration)
+	durationMethod("Truncate", []Type{duration}, func(d time.Duration, args ...any) any {
+		return int(d.Truncate(time.Duration(args[0].(int))))
+	}, duration)
+	durationMethod("Abs", []Type{duration}, func(d time.Duration, args ...any) any {
+		return int(d.Abs())
+	})
+
+	// the methods of Time convert the receiver and the arguments to the host time
+	timeMethod := func(name string, params []Type, results []Type, call func(t time.Time, args ...any) any) {
+		prog.declareMethod(timeType, name, signature(append([]Type{timeType}, params...), results...), func(args ...any) ([]any, error) {
+			return []any{call(toTime(args[0]), args[1:]...)}, nil
+		})
+	}
+	timeMethod("Add", []Type{duration}, []Type{timeType}, func(t time.Time, args ...any) any {
+		return fromTime(t.Add(time.Duration(args[0].(int))))
+	})
+	timeMethod("AddDate", []Type{IntType, IntType, IntType}, []Type{timeType}, func(t time.Time, args ...any) any {
+		return fromTime(t.AddDate(args[0].(int), args[1].(int), args[2].(int)))
+	})
+	timeMethod("Sub", []Type{timeType}, []Type{duration}, func(t time.Time, args ...any) any {
+		return int(t.Sub(toTime(args[0])))
+	})
+	timeMethod("Truncate", []Type{duration}, []Type{timeType}, func(t time.Time, args ...any) any {
+		return fromTime(t.Truncate(time.Duration(args[0].(int))))
+	})
+	timeMethod("Round", []Type{duration}, []Type{timeType}, func(t time.Time, args ...any) any {
+		return fromTime(t.Round(time.Duration(args[0].(int))))
+	})
+	timeMethod("Before", []Type{timeType}, []Type{BoolType}, func(t time.Time, args ...any) any {
+		return t.Before(toTime(args[0]))
+	})
+	timeMethod("After", []Type{timeType}, []Type{BoolType}, func(t time.Time, args ...any) any {
+		return t.After(toTime(args[0]))
+	})
+	timeMethod("Equal", []Type{timeType}, []Type{BoolType}, func(t time.Time, args ...any) any {
+		return t.Equal(toTime(args[0]))
+	})
+	timeMethod("Compare", []Type{timeType}, []Type{IntType}, func(t time.Time, args ...any) any {
+		return t.Compare(toTime(args[0]))
+	})
+	timeMethod("IsZero", nil, []Type{BoolType}, func(t time.Time, args ...any) any {
+		return t.IsZero()
+	})
+	timeMethod("Format", []Type{StringType}, []Type{StringType}, func(t time.Time, args ...any) any {
+		return t.Format(args[0].(string))
+	})
+	timeMethod("String", nil, []Type{StringType}, func(t time.Time, args ...any) any {
+		return t.String()
+	})
+	timeMethod("Location", nil, []Type{locationPointer}, func(t time.Time, args ...any) any {
+		if t.Location() == time.UTC {
+			return utc
+		}
+		return newLocation(t.Location(), t.Location().String())
+	})
+	timeMethod("In", []Type{locationPointer}, []Type{timeType}, func(t time.Time, args ...any) any {
+		return fromTime(t.In(hostLocation(args[0])))
+	})
+	timeMethod("UTC", nil, []Type{timeType}, func(t time.Time, args ...any) any {
+		return fromTime(t.UTC())
+	})
+	timeMethod("Local", nil, []Type{timeType}, func(t time.Time, args ...any) any {
+		return fromTime(t.In(prog.Clock.Location()))
+	})
+	timeMethod("Month", nil, []Type{month}, func(t time.Time, args ...any) any {
+		return int(t.Month())
+	})
+	timeMethod("Weekday", nil, []Type{weekday}, func(t time.Time, args ...any) any {
+		return int(t.Weekday())
+	})
+	// the methods are declared in the same order each time, the compiled programs call them by their indices
+	fields := []struct {
+		name  string
+		field func(t time.Time) int
+	}{
+		{"Year", time.Time.Year},
+		{"Day", time.Time.Day},
+		{"Hour", time.Time.Hour},
+		{"Minute", time.Time.Minute},
+		{"Second", time.Time.Second},
+		{"Nanosecond", time.Time.Nanosecond},
+		{"YearDay", time.Time.YearDay},
+	}
+	for _, f := range fields {
+		field := f.field
+		timeMethod(f.name, nil, []Type{IntType}, func(t time.Time, args ...any) any {
+			return field(t)
+		})
+	}
+	stamps := []struct {
+		name  string
+		stamp func(t time.Time) int64
+	}{
+		{"Unix", time.Time.Unix},
+		{"UnixMilli", time.Time.UnixMilli},
+		{"UnixMicro", time.Time.UnixMicro},
+		{"UnixNano", time.Time.UnixNano},
+	}
+	for _, s := range stamps {
+		stamp := s.stamp
+		timeMethod(s.name, nil, []Type{IntType}, func(t time.Time, args ...any) any {
+			return int(stamp(t))
+		})
+	}
+
+	prog.declareFunction(pkg, "Now", signature(nil, timeType), func(args ...any) ([]any, error) {
+		return []any{fromTime(prog.Clock.Now())}, nil
+	})
+	prog.declareFunction(pkg, "Since", signature([]Type{timeType}, duration), func(args ...any) ([]any, error) {
+		return []any{int(prog.Clock.Now().Sub(toTime(args[0])))}, nil
+	})
+	prog.declareFunction(pkg, "Until", signature([]Type{timeType}, duration), func(args ...any) ([]any, error) {
+		return []any{int(toTime(args[0]).Sub(prog.Clock.Now()))}, nil
+	})
+	prog.declareFunction(pkg, "Sleep", signature([]Type{duration}), func(args ...any) ([]any, error) {
+		return nil, prog.sleep(time.Duration(args[0].(int)))
+	})
+	declareTimers(prog, pkg, duration, timeType, fromTime)
+	prog.declareFunction(pkg, "Unix", signature([]Type{IntType, IntType}, timeType), func(args ...any) ([]any, error) {
+		return []any{fromTime(time.Unix(int64(args[0].(int)), int64(args[1].(int))).In(prog.Clock.Location()))}, nil
+	})
+	prog.declareFunction(pkg, "UnixMilli", signature([]Type{IntType}, timeType), func(args ...any) ([]any, error) {
+		return []any{fromTime(time.UnixMilli(int64(args[0].(int))).In(prog.Clock.Location()))}, nil
+	})
+	prog.declareFunction(pkg, "Date", signature([]Type{IntType, month, IntType, IntType, IntType, IntType, IntType, locationPointer}, timeType), func(args ...any) ([]any, error) {
+		if isNilPointer(args[7]) {
+			return nil, fmt.Errorf("time: missing Location in call to Date")
+		}
+		date := make([]int, 7)
+		for i := range date {
+			date[i] = args[i].(int)
+		}
+		return []any{fromTime(time.Date(date[0], time.Month(date[1]), date[2], date[3], date[4], date[5], date[6], hostLocation(args[7])))}, nil
+	})
+	prog.declareFunction(pkg, "Parse", signature([]Type{StringType, StringType}, timeType, ErrorType), func(args ...any) ([]any, error) {
+		t, err := time.Parse(args[0].(string), args[1].(string))
+		if err != nil {
+			return []any{fromTime(time.Time{}), prog.hostError(err)}, nil
+		}
+		return []any{fromTime(t), nil}, nil
+	})
+	prog.declareFunction(pkg, "ParseDuration", signature([]Type{StringType}, duration, ErrorType), func(args ...any) ([]any, error) {
+		d, err := time.ParseDuration(args[0].(string))
+		if err != nil {
+			return []any{0, prog.hostError(err)}, nil
+		}
+		return []any{int(d), nil}, nil
+	})
+}
+
+// declareTimers declares the timers and the tickers, they run on the scheduler, so they fire on the virtual clock too.
+// The channel of the timer has room for one value, the value the receiver has not taken is dropped like in Go.
+func declareTimers(prog *Program, pkg *Package, duration, timeType Type, fromTime func(t time.Time) any) {
+	timeChannel := &ChannelType{Dir: RecvOnly, Elem: timeType}
+	timerType := prog.declareType(pkg, "Timer", hostStruct(pkg, &Field{Name: "C", Type: timeChannel}))
+	timerPointer := &PointerType{Elem: timerType}
+	tickerType := prog.declareType(pkg, "Ticker", hostStruct(pkg, &Field{Name: "C", Type: timeChannel}))
+	tickerPointer := &PointerType{Elem: tickerType}
+
+	// the timers by the values of Timer and Ticker which have started them
+	timers := map[*StructValue]*timer{}
+	start := func(d, period time.Duration, fire func(now time.Time)) *timer {
+		t := &timer{when: prog.Clock.Now().Add(d), period: period, fire: fire}
+		prog.startTimer(t)
+		return t
+	}
+	send := func(ch *ChannelValue) func(now time.Time) {
+		return func(now time.Time) {
+			prog.trySend(ch, fromTime(now))
+		}
+	}
+	// the methods work on the timer the value has started, the value copied has none
+	timerMethod := func(kind, name string, method func(t *timer, args ...any) ([]any, error)) func(args ...any) ([]any, error) {
+		return func(args ...any) ([]any, error) {
+			cell, _ := args[0].(*any)
+			if cell == nil {
+				return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+			}
+
+			t, ok := timers[(*cell).(*StructValue)]
+			if !ok {
+				return nil, fmt.Errorf("time: %v called on uninitialized %v", name, kind)
+			}
+			return method(t, args[1:]...)
+		}
+	}
+
+	prog.declareFunction(pkg, "After", signature([]Type{duration}, timeChannel), func(args ...any) ([]any, error) {
+		ch := NewChannel(timeType, 1)
+		start(time.Duration(args[0].(int)), 0, send(ch))
+		return []any{ch}, nil
+	})
+	prog.declareFunction(pkg, "Tick", signature([]Type{duration}, timeChannel), func(args ...any) ([]any, error) {
+		d := time.Duration(args[0].(int))
+		if d <= 0 {
+			return []any{nil}, nil
+		}
+
+		ch := NewChannel(timeType, 1)
+		start(d, d, send(ch))
+		return []any{ch}, nil
+	})
+
+	prog.declareFunction(pkg, "NewTimer", signature([]Type{duration}, timerPointer), func(args ...any) ([]any, error) {
+		ch := NewChannel(timeType, 1)
+		value := NewStructValue(ch)
+		timers[value] = start(time.Duration(args[0].(int)), 0, send(ch))
+		return []any{NewPointer(value)}, nil
+	})
+	prog.declareFunction(pkg, "AfterFunc", signature([]Type{duration, signature(nil)}, timerPointer), func(args ...any) ([]any, error) {
+		function, _ := args[1].(Function)
+		if function == nil {
+			return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+		}
+
+		// the function runs on its own goroutine, the timer has no channel
+		value := NewStructValue(nil)
+		timers[value] = start(time.Duration(args[0].(int)), 0, func(now time.Time) {
+			prog.spawn(function, nil)
+		})
+		return []any{NewPointer(value)}, nil
+	})
+	prog.declareMethod(timerType, "Stop", signature([]Type{timerPointer}, BoolType), timerMethod("Timer", "Stop", func(t *timer, args ...any) ([]any, error) {
+		return []any{prog.stopTimer(t)}, nil
+	}))
+	prog.declareMethod(timerType, "Reset", signature([]Type{timerPointer, duration}, BoolType), timerMethod("Timer", "Reset", func(t *timer, args ...any) ([]any, error) {
+		active := prog.stopTimer(t)
+		t.when = prog.Clock.Now().Add(time.Duration(args[0].(int)))
+		prog.startTimer(t)
+		return []any{active}, nil
+	}))
+
+	prog.declareFunction(pkg, "NewTicker", signature([]Type{duration}, tickerPointer), func(args ...any) ([]any, error) {
+		d := time.Duration(args[0].(int))
+		if d <= 0 {
+			return nil, fmt.Errorf("non-positive interval for NewTicker")
+		}
+
+		ch := NewChannel(timeType, 1)
+		value := NewStructValue(ch)
+		timers[value] = start(d, d, send(ch))
+		return []any{NewPointer(value)}, nil
+	})
+	prog.declareMethod(tickerType, "Stop", signature([]Type{tickerPointer}), func(args ...any) ([]any, error) {
+		cell, _ := args[0].(*any)
+		if cell == nil {
+			return nil, fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+		}
+
+		// the ticker which has not been started is stopped quietly like in Go
+		if t, ok := timers[(*cell).(*StructValue)]; ok {
+			prog.stopTimer(t)
+		}
+		return nil, nil
+	})
+	prog.declareMethod(tickerType, "Reset", signature([]Type{tickerPointer, duration}), timerMethod("Ticker", "Reset", func(t *timer, args ...any) ([]any, error) {
+		d := time.Duration(args[0].(int))
+		if d <= 0 {
+			return nil, fmt.Errorf("non-positive interval for Ticker.Reset")
+		}
+
+		prog.stopTimer(t)
+		t.when, t.period = prog.Clock.Now().Add(d), d
+		prog.startTimer(t)
+		return nil, nil
+	}))
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/antlr4-go/antlr/v4"
+	"github.com/jessevdk/go-flags"
+	"github.com/karetskiiVO/GOInterpreter/parser"
+)
+
+// goshimSource is the runtime shim package of the built scripts.
+//
+//go:embed goshim/goshim.go
+var goshimSource []byte
+
+// build is the build command: the script is checked the way it is before it runs,
+// then it is emitted as the Go module, which the local go build can build into the native program.
+func build(args []string) int {
+	var options struct {
+		Output       string `short:"o" long:"output" value-name:"FILE" description:"build the native program with the local go build"`
+		Emit         string `long:"emit" value-name:"DIR" description:"write the Go module of the script into the directory"`
+		Root         string `long:"root" value-name:"DIR" description:"the directory the program may access the files in, the relative one is found from the directory of the program when it runs, the working directory by default"`
+		VirtualTime  bool   `long:"virtual-time" description:"run the program on the virtual clock which starts at 2009-11-10 23:00:00 UTC and moves only by sleeping"`
+		MaxCallDepth int    `long:"max-call-depth" value-name:"DEPTH" default:"100000" description:"the depth of the calls beyond which the stack overflows with the runtime panic, 0 is no limit"`
+		TailCalls    bool   `long:"tail-calls" description:"run the function which returns the call of itself in the same call, so the recursive loops don't grow the stack"`
+
+		Args struct {
+			SourcePath string `positional-arg-name:"script" required:"yes"`
+		} `positional-args:"yes"`
+	}
+
+	flagsParser := flags.NewNamedParser("build", flags.Default&(^flags.PrintErrors))
+	flagsParser.AddGroup("Build Options", "", &options)
+	_, err := flagsParser.ParseArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if options.Output == "" && options.Emit == "" {
+		fmt.Println("nothing to build: give the program with -o or the directory of the Go module with --emit")
+		return 1
+	}
+
+	loader := NewPackageLoader()
+	_, err = loader.LoadMain(options.Args.SourcePath)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	program := NewProgram()
+	declare(program, loader)
+	errs := compile(program, loader)
+	if len(errs) != 0 {
+		for _, err := range errs {
+			fmt.Println(err)
+		}
+		return 1
+	}
+
+	dir := options.Emit
+	if dir == "" {
+		dir, err = os.MkdirTemp("", "gointerpreter-build")
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	// the root isn't the path of the building machine, the program finds it when it runs:
+	// the program run in the directory of the script accesses the files the interpreted script does
+	transpiler := &transpiler{program: program, loader: loader, tailCalls: options.TailCalls}
+	mainDir, err := transpiler.emit(dir, fmt.Sprintf("goshim.Config{Root: %q, VirtualTime: %v, MaxCallDepth: %v}", options.Root, options.VirtualTime, options.MaxCallDepth))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if options.Output == "" {
+		return 0
+	}
+
+	output, err := filepath.Abs(options.Output)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	goBuild := exec.Command("go", "build", "-o", output, "./"+filepath.ToSlash(mainDir))
+	goBuild.Dir = dir
+	goBuild.Stdout, goBuild.Stderr = os.Stdout, os.Stderr
+	if err := goBuild.Run(); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	return 0
+}
+
+// transpiler emits the Go source of the checked program. The scripts are Go already,
+// so the source is kept as it is but for the calls the interpreter makes in its own way:
+// they go to the shim package, and the main function of the script is run by the shim.
+// The shim counts the calls of the functions too, so the stack overflows at the same depth.
+type transpiler struct {
+	program *Program
+	loader  *PackageLoader
+	// the returns of the calls of the functions themselves reuse their frames
+	tailCalls bool
+}
+
+// shimmedCalls are the functions of the standard packages the shim has its own versions of,
+// the member keeps the import used when all the calls go to the shim.
+var shimmedCalls = map[string]struct {
+	member    string
+	functions []string
+}{
+	"os":   {"Getenv", []string{"ReadFile", "WriteFile", "Remove", "Open"}},
+	"time": {"Now", []string{"Now", "Since", "Until", "Sleep"}},
+}
+
+// emit writes the module into the directory, the config is the literal of goshim.Config the program runs with.
+// mainDir is the directory of the main package in the module.
+func (t *transpiler) emit(dir, config string) (mainDir string, err error) {
+	modulePath := t.loader.modulePath
+	if modulePath == "" {
+		modulePath = "script"
+	}
+	shimPath := modulePath + "/goshim"
+
+	err = writeSource(filepath.Join(dir, "go.mod"), []byte(fmt.Sprintf("module %v\n\ngo 1.23\n", modulePath)))
+	if err != nil {
+		return "", err
+	}
+	err = writeSource(filepath.Join(dir, "goshim", "goshim.go"), goshimSource)
+	if err != nil {
+		return "", err
+	}
+
+	for _, pkg := range t.loader.Packages {
+		if pkg.Std != nil {
+			continue
+		}
+
+		pkgDir := "."
+		if t.loader.moduleDir != "" {
+			pkgDir, err = filepath.Abs(pkg.Dir)
+			if err != nil {
+				return "", err
+			}
+			pkgDir, err = filepath.Rel(t.loader.moduleDir, pkgDir)
+			if err != nil {
+				return "", err
+			}
+		}
+		if pkg.Path == MainPackagePath {
+			mainDir = pkgDir
+		}
+
+		for _, file := range pkg.Files {
+			source, err := t.emitFile(pkg, file, shimPath)
+			if err != nil {
+				return "", err
+			}
+
+			err = writeSource(filepath.Join(dir, pkgDir, filepath.Base(file.Name)), source)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	entry := fmt.Sprintf(`package main
+
+import "%v"
+
+func main() {
+	goshim.Run(%v, scriptMain)
+}
+`, shimPath, config)
+	return mainDir, writeSource(filepath.Join(dir, mainDir, "goshim_main.go"), []byte(entry))
+}
+
+// edit replaces the text from the start up to the end, the end is not included.
+type edit struct {
+	start, end int
+	text       string
+}
+
+func (t *transpiler) emitFile(pkg *Package, file *SourceFile, shimPath string) ([]byte, error) {
+	source, err := os.ReadFile(file.Name)
+	if err != nil {
+		return nil, err
+	}
+	// the positions of the tokens are the indices of the runes
+	text := []rune(string(source))
+
+	tokens := terminals(file.Tree, nil)
+	packageName := file.Tree.Package().NAME().GetSymbol()
+	edits := make([]edit, 0)
+	replace := func(token antlr.Token, text string) {
+		edits = append(edits, edit{start: token.GetStart(), end: token.GetStop() + 1, text: text})
+	}
+	shimmed := false
+	// the imports which are used only by the shimmed calls are kept used by their members
+	kept := map[string]string{}
+
+	calls := t.traceCalls(file.Tree, file, nil)
+	edits = append(edits, calls...)
+	shimmed = len(calls) != 0
+
+	for i, token := range tokens {
+		if token.GetTokenType() != parser.GoParserNAME || token == packageName || i > 0 && tokens[i-1].GetText() == "." {
+			continue
+		}
+		next := func(n int) string {
+			if i+n < len(tokens) {
+				return tokens[i+n].GetText()
+			}
+			return ""
+		}
+
+		name := token.GetText()
+		switch {
+		case name == "main" && pkg.Path == MainPackagePath:
+			replace(token, "scriptMain")
+		case (name == "print" || name == "println") && next(1) == "(" && !t.declared(pkg, name):
+			replace(token, "goshim.P"+name[1:])
+			shimmed = true
+		case next(1) == ".":
+			imported, ok := file.Imports[name]
+			if !ok || imported.Std == nil {
+				continue
+			}
+			calls, ok := shimmedCalls[imported.Path]
+			if !ok || !slices.Contains(calls.functions, next(2)) {
+				continue
+			}
+
+			replace(token, "goshim")
+			shimmed = true
+			kept[name] = calls.member
+		}
+	}
+
+	if shimmed {
+		// the import of the shim goes right after the package clause
+		end := packageName.GetStop() + 1
+		edits = append(edits, edit{start: end, end: end, text: fmt.Sprintf("\n\nimport goshim %q\n", shimPath)})
+	}
+	// the insertions go before the replacements which start at the same place
+	slices.SortStableFunc(edits, func(a, b edit) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return (a.end - a.start) - (b.end - b.start)
+	})
+
+	var res strings.Builder
+	last := 0
+	for _, edit := range edits {
+		res.WriteString(string(text[last:edit.start]))
+		res.WriteString(edit.text)
+		last = edit.end
+	}
+	res.WriteString(string(text[last:]))
+	// the names are sorted, so the same script is emitted the same way each time
+	names := make([]string, 0, len(kept))
+	for name := range kept {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(&res, "\nvar _ = %v.%v\n", name, kept[name])
+	}
+
+	formatted, err := format.Source([]byte(res.String()))
+	if err != nil {
+		return nil, fmt.Errorf("%v: the emitted source is broken: %v", file.Name, err)
+	}
+
+	return formatted, nil
+}
+
+// traceCalls appends the edits which enter the functions in the shim when they are called,
+// and mark the tail calls, which replace the frame of the calling function.
+// The go statements tell the shim to count the calls by the goroutines.
+func (t *transpiler) traceCalls(tree antlr.Tree, file *SourceFile, res []edit) []edit {
+	switch ctx := tree.(type) {
+	case *parser.BlockContext:
+		if name, ok := file.Functions[ctx]; ok {
+			start := ctx.GetStart().GetStop() + 1
+			res = append(res, edit{start: start, end: start, text: fmt.Sprintf("goshimFrame := goshim.Enter(%q); defer goshim.Leave(goshimFrame);", name)})
+		}
+	case *parser.FunctionReturnContext:
+		if t.tailCalls && file.TailCalls[ctx] {
+			res = append(res, markTailCall(ctx)...)
+		}
+	case *parser.GoStatementContext:
+		start := ctx.GetStart().GetStart()
+		res = append(res, edit{start: start, end: start, text: "goshim.Go(); "})
+	}
+
+	for _, child := range tree.GetChildren() {
+		res = t.traceCalls(child, file, res)
+	}
+	return res
+}
+
+// markTailCall wraps the last argument of the returned call into goshim.Tail, it is evaluated right before the call.
+// The call without the arguments wraps the function, and the call with the results of the other call isn't marked.
+func markTailCall(ctx *parser.FunctionReturnContext) []edit {
+	var call *parser.SimpleExpresionContext
+	for tree := antlr.Tree(ctx.Expression(0)); call == nil; tree = tree.GetChild(0) {
+		call, _ = tree.(*parser.SimpleExpresionContext)
+		if call == nil && tree.GetChildCount() != 1 {
+			return nil
+		}
+	}
+	args, ok := call.GetChild(call.GetChildCount() - 1).(*parser.CallExpressionContext)
+	if !ok {
+		return nil
+	}
+
+	wrap := func(start, end int) []edit {
+		return []edit{
+			{start: start, end: start, text: "goshim.Tail(goshimFrame, "},
+			{start: end, end: end, text: ")"},
+		}
+	}
+	expressions := args.AllExpression()
+	if len(expressions) == 0 {
+		return wrap(call.GetStart().GetStart(), args.GetStart().GetStart())
+	}
+
+	var function *parser.FunctionDefinitionContext
+	for tree := antlr.Tree(ctx); function == nil && tree != nil; tree = tree.GetParent() {
+		function, _ = tree.(*parser.FunctionDefinitionContext)
+	}
+	params := 0
+	if function != nil && function.Arguments() != nil {
+		for _, declaration := range function.Arguments().AllParameterDeclaration() {
+			params += len(declaration.AllNAME())
+		}
+	}
+	if len(expressions) == 1 && params > 1 {
+		return nil
+	}
+
+	last := expressions[len(expressions)-1]
+	return wrap(last.GetStart().GetStart(), last.GetStop().GetStop()+1)
+}
+
+// declared reports that the package declares the function, which hides the builtin one.
+func (t *transpiler) declared(pkg *Package, name string) bool {
+	_, ok := t.program.functionID[pkg.QualifiedName(name)]
+	return ok
+}
+
+// terminals appends the tokens of the tree in the order of the source.
+func terminals(tree antlr.Tree, res []antlr.Token) []antlr.Token {
+	if terminal, ok := tree.(antlr.TerminalNode); ok {
+		if terminal.GetSymbol().GetTokenType() == antlr.TokenEOF {
+			return res
+		}
+		return append(res, terminal.GetSymbol())
+	}
+
+	for _, child := range tree.GetChildren() {
+		res = terminals(child, res)
+	}
+	return res
+}
+
+func writeSource(path string, source []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, source, 0644)
+}
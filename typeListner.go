@@ -0,0 +1,574 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/karetskiiVO/GOInterpreter/parser"
+)
+
+// GoTypeListener registers the names of the declared types,
+// so the declarations may use the types before they are defined.
+type GoTypeListener struct {
+	*parser.BaseGoListener
+
+	program  *Program
+	resolver *TypeResolver
+	Errors   []error
+}
+
+func NewGoTypeListener(program *Program, pkg *Package, file *SourceFile) *GoTypeListener {
+	return &GoTypeListener{
+		program:  program,
+		resolver: NewTypeResolver(program, pkg, file),
+	}
+}
+
+func (l *GoTypeListener) EnterTypeDefinition(ctx *parser.TypeDefinitionContext) {
+	name := ctx.NAME().GetText()
+
+	var err error
+	if ctx.TypeParameters() != nil {
+		err = l.program.RegisterTypeTemplate(l.resolver.pkg.QualifiedName(name), NewTypeTemplate(l.resolver, ctx))
+	} else {
+		err = l.program.RegisterType(l.resolver.pkg.QualifiedName(name), NewNamedType(name, l.resolver.pkg))
+	}
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+	}
+}
+
+func (l *GoTypeListener) EnterTypeAlias(ctx *parser.TypeAliasContext) {
+	name := ctx.NAME().GetText()
+
+	err := l.program.RegisterTypeAlias(l.resolver.pkg.QualifiedName(name), &TypeAlias{
+		resolver: l.resolver,
+		typename: ctx.Typename(),
+	})
+	if err != nil {
+		l.Errors = append(l.Errors, err)
+	}
+}
+
+// TypeAlias is resolved when it is used for the first time,
+// aliases may refer to each other in any order.
+type TypeAlias struct {
+	resolver  *TypeResolver
+	typename  parser.ITypenameContext
+	resolving bool
+}
+
+type TypeResolver struct {
+	program *Program
+	pkg     *Package
+	file    *SourceFile
+
+	// the type arguments of the generic declaration instance
+	typeArgs map[string]Type
+}
+
+func NewTypeResolver(program *Program, pkg *Package, file *SourceFile) *TypeResolver {
+	return &TypeResolver{
+		program: program,
+		pkg:     pkg,
+		file:    file,
+	}
+}
+
+// Bind makes the resolver for the instance of the generic declaration.
+func (r *TypeResolver) Bind(typeParams []string, typeArgs []Type) *TypeResolver {
+	res := NewTypeResolver(r.program, r.pkg, r.file)
+	res.typeArgs = map[string]Type{}
+
+	for i := range typeParams {
+		res.typeArgs[typeParams[i]] = typeArgs[i]
+	}
+
+	return res
+}
+
+// Resolve resolves the type of values, the constraint interfaces can't be used there.
+func (r *TypeResolver) Resolve(ctx parser.ITypenameContext) (Type, error) {
+	res, err := r.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if iface, ok := res.Underlying().(*InterfaceType); ok && iface.IsConstraint() {
+		return nil, fmt.Errorf("cannot use type %v outside a type constraint: interface contains type constraints", res)
+	}
+
+	return res, nil
+}
+
+func (r *TypeResolver) resolve(ctx parser.ITypenameContext) (Type, error) {
+	switch {
+	case ctx.QualifiedName() != nil:
+		return r.ResolveQualifiedName(ctx.QualifiedName(), ctx.TypeArguments())
+	case ctx.SliceType() != nil:
+		return r.ResolveSliceType(ctx.SliceType())
+	case ctx.ArrayType() != nil:
+		return r.ResolveArrayType(ctx.ArrayType())
+	case ctx.MapType() != nil:
+		return r.ResolveMapType(ctx.MapType())
+	case ctx.ChannelType() != nil:
+		return r.ResolveChannelType(ctx.ChannelType())
+	case ctx.FunctionType() != nil:
+		return r.ResolveFunctionType(ctx.FunctionType())
+	case ctx.PointerType() != nil:
+		return r.ResolvePointerType(ctx.PointerType())
+	case ctx.StructType() != nil:
+		return r.ResolveStructType(ctx.StructType())
+	case ctx.InterfaceType() != nil:
+		return r.ResolveInterfaceType(ctx.InterfaceType())
+	default:
+		return r.resolve(ctx.Typename())
+	}
+}
+
+func (r *TypeResolver) ResolveSliceType(ctx parser.ISliceTypeContext) (Type, error) {
+	elem, err := r.Resolve(ctx.Typename())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SliceType{Elem: elem}, nil
+}
+
+// ResolveArrayType resolves the array type with the length, [...]T is resolved by the composite literal itself.
+func (r *TypeResolver) ResolveArrayType(ctx parser.IArrayTypeContext) (Type, error) {
+	if ctx.ELLIPSIS() != nil {
+		return nil, fmt.Errorf("invalid use of [...] array (outside a composite literal)")
+	}
+
+	elem, err := r.Resolve(ctx.Typename())
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(ctx.NUMBER().GetText())
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid array length %v (untyped int constant)", ctx.NUMBER().GetText())
+	}
+
+	return &ArrayType{Len: length, Elem: elem}, nil
+}
+
+func (r *TypeResolver) ResolveMapType(ctx parser.IMapTypeContext) (Type, error) {
+	key, err := r.Resolve(ctx.Typename(0))
+	if err != nil {
+		return nil, err
+	}
+	elem, err := r.Resolve(ctx.Typename(1))
+	if err != nil {
+		return nil, err
+	}
+
+	// the underlying type of a named type may be unknown during the declaration
+	if key.Underlying() != nil && !IsComparable(key) {
+		return nil, fmt.Errorf("invalid map key type %v", key)
+	}
+
+	return &MapType{Key: key, Elem: elem}, nil
+}
+
+func (r *TypeResolver) ResolveChannelType(ctx parser.IChannelTypeContext) (Type, error) {
+	elem, err := r.Resolve(ctx.Typename())
+	if err != nil {
+		return nil, err
+	}
+
+	dir := SendRecv
+	switch {
+	case ctx.GetStart().GetText() == "<-":
+		dir = RecvOnly
+	case ctx.GetChildCount() == 3:
+		dir = SendOnly
+	}
+
+	return &ChannelType{Dir: dir, Elem: elem}, nil
+}
+
+func (r *TypeResolver) ResolveFunctionType(ctx parser.IFunctionTypeContext) (Type, error) {
+	res := &FunctionType{
+		Params: make([]Type, 0),
+	}
+
+	if ctx.ParameterTypes() != nil {
+		err := r.resolveParameterTypes(ctx.ParameterTypes(), res)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ctx.ResultTypes() != nil {
+		var err error
+		res.Result, err = r.resolveResultTypes(ctx.ResultTypes())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// resolveParameterTypes appends the parameters to the signature, ...T is the slice of the rest arguments.
+func (r *TypeResolver) resolveParameterTypes(ctx parser.IParameterTypesContext, signature *FunctionType) error {
+	if ctx.TypeList() != nil {
+		for _, typename := range ctx.TypeList().AllTypename() {
+			param, err := r.Resolve(typename)
+			if err != nil {
+				return err
+			}
+
+			signature.Params = append(signature.Params, param)
+		}
+	}
+
+	if ctx.VariadicType() != nil {
+		elem, err := r.Resolve(ctx.VariadicType().Typename())
+		if err != nil {
+			return err
+		}
+
+		signature.Params = append(signature.Params, &SliceType{Elem: elem})
+		signature.Variadic = true
+	}
+
+	return nil
+}
+
+func (r *TypeResolver) resolveResultTypes(ctx parser.IResultTypesContext) (Type, error) {
+	if ctx.Typename() != nil {
+		return r.Resolve(ctx.Typename())
+	}
+
+	results := make([]Type, 0)
+	for _, typename := range ctx.TypeList().AllTypename() {
+		result, err := r.Resolve(typename)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	return NewResult(results), nil
+}
+
+func (r *TypeResolver) ResolvePointerType(ctx parser.IPointerTypeContext) (Type, error) {
+	elem, err := r.Resolve(ctx.Typename())
+	if err != nil {
+		return nil, err
+	}
+
+	return &PointerType{Elem: elem}, nil
+}
+
+func (r *TypeResolver) ResolveStructType(ctx parser.IStructTypeContext) (Type, error) {
+	res := &StructType{
+		Fields: make([]*Field, 0),
+		pkg:    r.pkg,
+	}
+
+	for _, fieldDeclaration := range ctx.AllFieldDeclaration() {
+		Type, err := r.Resolve(fieldDeclaration.Typename())
+		if err != nil {
+			return nil, err
+		}
+
+		tag := ""
+		if fieldDeclaration.STRING() != nil {
+			tag, err = strconv.Unquote(fieldDeclaration.STRING().GetText())
+			if err != nil {
+				return nil, fmt.Errorf("invalid string literal %v", fieldDeclaration.STRING().GetText())
+			}
+		}
+
+		for _, name := range fieldDeclaration.AllNAME() {
+			if _, ok := res.FieldIndex(name.GetText(), r.pkg); ok {
+				return nil, fmt.Errorf("%v redeclared", name.GetText())
+			}
+
+			res.Fields = append(res.Fields, &Field{Name: name.GetText(), Type: Type, Tag: tag})
+		}
+	}
+
+	return res, nil
+}
+
+func (r *TypeResolver) ResolveInterfaceType(ctx parser.IInterfaceTypeContext) (Type, error) {
+	res := &InterfaceType{
+		Methods: make([]*Method, 0),
+		Terms:   make([]*Term, 0),
+	}
+
+	for _, element := range ctx.AllInterfaceElement() {
+		if element.MethodSpecification() != nil {
+			method, err := r.ResolveMethodSpecification(element.MethodSpecification())
+			if err != nil {
+				return nil, err
+			}
+
+			for _, other := range res.Methods {
+				if other.Name == method.Name {
+					return nil, fmt.Errorf("duplicate method %v", method.Name)
+				}
+			}
+			res.Methods = append(res.Methods, method)
+			continue
+		}
+
+		constraint, err := r.ResolveConstraint(element.TypeConstraint())
+		if err != nil {
+			return nil, err
+		}
+
+		// the embedded interface brings its methods and its type set
+		embedded := constraint.Underlying().(*InterfaceType)
+		res.Methods = append(res.Methods, embedded.Methods...)
+		res.comparable = res.comparable || embedded.comparable
+		if len(embedded.Terms) != 0 {
+			if len(res.Terms) != 0 {
+				return nil, fmt.Errorf("intersection of the unions is not supported in %v", ctx.GetText())
+			}
+			res.Terms = embedded.Terms
+		}
+	}
+
+	return res, nil
+}
+
+func (r *TypeResolver) ResolveMethodSpecification(ctx parser.IMethodSpecificationContext) (*Method, error) {
+	signature := &FunctionType{
+		Params: make([]Type, 0),
+	}
+
+	if ctx.ParameterTypes() != nil {
+		err := r.resolveParameterTypes(ctx.ParameterTypes(), signature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ctx.ResultTypes() != nil {
+		var err error
+		signature.Result, err = r.resolveResultTypes(ctx.ResultTypes())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Method{Name: ctx.NAME().GetText(), Signature: signature}, nil
+}
+
+// ResolveConstraint resolves the constraint of a type parameter, a union or a single type
+// is a shorthand for the interface with the type set.
+func (r *TypeResolver) ResolveConstraint(ctx parser.ITypeConstraintContext) (Type, error) {
+	terms := ctx.AllTypeTerm()
+
+	if len(terms) == 1 && terms[0].GetStart().GetText() != "~" {
+		res, err := r.resolve(terms[0].Typename())
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := res.Underlying().(*InterfaceType); ok {
+			return res, nil
+		}
+	}
+
+	res := &InterfaceType{
+		Methods: make([]*Method, 0),
+		Terms:   make([]*Term, 0, len(terms)),
+	}
+	for _, term := range terms {
+		Type, err := r.resolve(term.Typename())
+		if err != nil {
+			return nil, err
+		}
+
+		tilde := term.GetStart().GetText() == "~"
+		if iface, ok := Type.Underlying().(*InterfaceType); ok {
+			if tilde || len(iface.Methods) != 0 || iface.comparable {
+				return nil, fmt.Errorf("cannot use %v in union", Type)
+			}
+			res.Terms = append(res.Terms, iface.Terms...)
+			continue
+		}
+		if tilde && !Identical(Type, Type.Underlying()) {
+			return nil, fmt.Errorf("invalid use of ~ (underlying type of %v is %v)", Type, Type.Underlying())
+		}
+
+		res.Terms = append(res.Terms, &Term{Tilde: tilde, Type: Type})
+	}
+
+	return res, nil
+}
+
+func (r *TypeResolver) ResolveTypeArguments(ctx parser.ITypeArgumentsContext) ([]Type, error) {
+	res := make([]Type, 0)
+
+	for _, typename := range ctx.TypeList().AllTypename() {
+		Type, err := r.Resolve(typename)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, Type)
+	}
+
+	return res, nil
+}
+
+func (r *TypeResolver) ResolveQualifiedName(ctx parser.IQualifiedNameContext, typeArguments parser.ITypeArgumentsContext) (Type, error) {
+	names := ctx.AllNAME()
+	text := names[0].GetText()
+	name := text
+	pkg := r.pkg
+
+	if len(names) == 2 {
+		name = names[1].GetText()
+		text += "." + name
+
+		var ok bool
+		pkg, ok = r.file.Imports[names[0].GetText()]
+		if !ok {
+			return nil, fmt.Errorf("undefined: %v", names[0].GetText())
+		}
+		if !IsExported(name) {
+			return nil, fmt.Errorf("name %v not exported by package %v", name, names[0].GetText())
+		}
+	}
+
+	if template, ok := r.program.typeTemplates[pkg.QualifiedName(name)]; ok {
+		if typeArguments == nil {
+			return nil, fmt.Errorf("cannot use generic type %v without instantiation", template)
+		}
+
+		typeArgs, err := r.ResolveTypeArguments(typeArguments)
+		if err != nil {
+			return nil, err
+		}
+
+		return template.Instantiate(typeArgs)
+	}
+
+	var res Type
+	var ok bool
+	var err error
+	if len(names) == 2 {
+		res, ok, err = r.lookup(pkg.QualifiedName(name))
+	} else {
+		res, ok, err = r.LookupName(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if len(names) == 2 {
+			return nil, fmt.Errorf("undefined: %v", text)
+		}
+		return nil, fmt.Errorf("unknown type %v", text)
+	}
+
+	if typeArguments != nil {
+		return nil, fmt.Errorf("%v is not a generic type", text)
+	}
+
+	return res, nil
+}
+
+// LookupName looks for a type among the type parameters, in the current package, then among the predeclared types.
+func (r *TypeResolver) LookupName(name string) (Type, bool, error) {
+	if res, ok := r.typeArgs[name]; ok {
+		return res, true, nil
+	}
+
+	res, ok, err := r.lookup(r.pkg.QualifiedName(name))
+	if ok || err != nil {
+		return res, ok, err
+	}
+
+	return r.lookup(name)
+}
+
+func (r *TypeResolver) lookup(name string) (Type, bool, error) {
+	if alias, ok := r.program.typeAliases[name]; ok {
+		if alias.resolving {
+			return nil, false, fmt.Errorf("invalid recursive type alias %v", name)
+		}
+
+		alias.resolving = true
+		res, err := alias.resolver.Resolve(alias.typename)
+		alias.resolving = false
+		if err != nil {
+			return nil, false, err
+		}
+
+		delete(r.program.typeAliases, name)
+		r.program.types[name] = res
+		return res, true, nil
+	}
+
+	res, ok := r.program.types[name]
+	return res, ok, nil
+}
+
+// FunctionName is the name the function is registered with in the program.
+// Methods are registered with the name of the receiver type.
+func (r *TypeResolver) FunctionName(ctx parser.IFunctionDefinitionContext) (string, error) {
+	name := ctx.NAME().GetText()
+
+	if ctx.Receiver() == nil {
+		return r.pkg.QualifiedName(name), nil
+	}
+
+	receiverType, err := r.Resolve(ctx.Receiver().Typename())
+	if err != nil {
+		return r.pkg.QualifiedName(name), err
+	}
+
+	if pointerType, ok := receiverType.(*PointerType); ok {
+		receiverType = pointerType.Elem
+	}
+
+	namedType, ok := receiverType.(*NamedType)
+	if !ok || namedType.pkg != r.pkg {
+		return r.pkg.QualifiedName(name), fmt.Errorf("cannot define new methods on non-local type %v", receiverType)
+	}
+	switch namedType.Underlying().(type) {
+	case *PointerType, *InterfaceType:
+		return r.pkg.QualifiedName(name), fmt.Errorf("invalid receiver type %v (pointer or interface type)", namedType)
+	}
+
+	return namedType.MethodName(name), nil
+}
+
+// GenericReceiver finds the generic type of the method and the names of its type parameters.
+func (r *TypeResolver) GenericReceiver(ctx parser.IReceiverContext) (*TypeTemplate, []string, error) {
+	base, _ := receiverTypename(ctx.Typename())
+	qualifiedName := base.QualifiedName()
+
+	if qualifiedName == nil || len(qualifiedName.AllNAME()) != 1 {
+		return nil, nil, fmt.Errorf("cannot define new methods on non-local type %v", base.GetText())
+	}
+
+	template, ok := r.program.typeTemplates[r.pkg.QualifiedName(qualifiedName.GetText())]
+	if !ok {
+		return nil, nil, fmt.Errorf("%v is not a generic type", qualifiedName.GetText())
+	}
+
+	typeParams := make([]string, 0)
+	for _, typename := range base.TypeArguments().TypeList().AllTypename() {
+		if typename.QualifiedName() == nil || len(typename.QualifiedName().AllNAME()) != 1 || typename.TypeArguments() != nil {
+			return nil, nil, fmt.Errorf("receiver type parameter %v must be an identifier", typename.GetText())
+		}
+
+		typeParams = append(typeParams, typename.GetText())
+	}
+	if len(typeParams) != len(template.typeParams) {
+		return nil, nil, fmt.Errorf("receiver declares %v type parameters, but receiver base type declares %v", len(typeParams), len(template.typeParams))
+	}
+
+	return template, typeParams, nil
+}
@@ -0,0 +1,795 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Type is a compile time type of an expression.
+// Values of all types are stored with the representation of their underlying type,
+// so the types only live in the compiler.
+type Type interface {
+	String() string
+	Underlying() Type
+}
+
+type BasicKind int
+
+const (
+	Invalid BasicKind = iota
+
+	Bool
+	Int
+	Int32
+	Uint8
+	Float64
+	Complex128
+	String
+
+	UntypedBool
+	UntypedInt
+	UntypedRune
+	UntypedFloat
+	UntypedString
+	UntypedNil
+)
+
+type BasicType struct {
+	kind        BasicKind
+	name        string
+	reflectType reflect.Type
+}
+
+func (t *BasicType) String() string {
+	return t.name
+}
+
+func (t *BasicType) Underlying() Type {
+	return t
+}
+
+func (t *BasicType) Untyped() bool {
+	return t.kind >= UntypedBool && t.kind <= UntypedNil
+}
+
+var (
+	// InvalidType is the type of erroneous expressions, it is compatible with everything
+	// to report only the first error.
+	InvalidType = &BasicType{Invalid, "invalid type", nil}
+
+	BoolType       = &BasicType{Bool, "bool", reflect.TypeOf(false)}
+	IntType        = &BasicType{Int, "int", reflect.TypeOf(0)}
+	Int32Type      = &BasicType{Int32, "int32", reflect.TypeOf(int32(0))}
+	Uint8Type      = &BasicType{Uint8, "uint8", reflect.TypeOf(uint8(0))}
+	Float64Type    = &BasicType{Float64, "float64", reflect.TypeOf(0.0)}
+	Complex128Type = &BasicType{Complex128, "complex128", reflect.TypeOf(0i)}
+	StringType     = &BasicType{String, "string", reflect.TypeOf("")}
+
+	UntypedBoolType   = &BasicType{UntypedBool, "untyped bool", reflect.TypeOf(false)}
+	UntypedIntType    = &BasicType{UntypedInt, "untyped int", reflect.TypeOf(0)}
+	UntypedRuneType   = &BasicType{UntypedRune, "untyped rune", reflect.TypeOf(int32(0))}
+	UntypedFloatType  = &BasicType{UntypedFloat, "untyped float", reflect.TypeOf(0.0)}
+	UntypedStringType = &BasicType{UntypedString, "untyped string", reflect.TypeOf("")}
+	UntypedNilType    = &BasicType{UntypedNil, "untyped nil", nil}
+)
+
+// NamedType is a type introduced by a type definition.
+// The right hand side is kept as is, the underlying type is found on demand,
+// so named types may refer to types declared later.
+type NamedType struct {
+	name string
+	pkg  *Package
+	rhs  Type
+
+	// the generic type and the type arguments of an instantiated type
+	template *TypeTemplate
+	typeArgs []Type
+}
+
+func NewNamedType(name string, pkg *Package) *NamedType {
+	return &NamedType{
+		name: name,
+		pkg:  pkg,
+	}
+}
+
+func (t *NamedType) String() string {
+	if t.pkg == nil || t.pkg.Path == MainPackagePath {
+		return t.name
+	}
+
+	return t.pkg.Name + "." + t.name
+}
+
+func (t *NamedType) Underlying() Type {
+	if t.rhs == nil {
+		return nil
+	}
+
+	return t.rhs.Underlying()
+}
+
+// MethodName is the name the method of the type is registered with in the program.
+func (t *NamedType) MethodName(method string) string {
+	return t.pkg.QualifiedName(t.name + "." + method)
+}
+
+type SliceType struct {
+	Elem Type
+}
+
+func (t *SliceType) String() string {
+	return "[]" + t.Elem.String()
+}
+
+func (t *SliceType) Underlying() Type {
+	return t
+}
+
+// ArrayType is the array of the fixed length, its values are copied as a whole.
+type ArrayType struct {
+	Len  int
+	Elem Type
+}
+
+func (t *ArrayType) String() string {
+	return "[" + strconv.Itoa(t.Len) + "]" + t.Elem.String()
+}
+
+func (t *ArrayType) Underlying() Type {
+	return t
+}
+
+type MapType struct {
+	Key  Type
+	Elem Type
+}
+
+func (t *MapType) String() string {
+	return "map[" + t.Key.String() + "]" + t.Elem.String()
+}
+
+func (t *MapType) Underlying() Type {
+	return t
+}
+
+// ChannelDir is the direction the values go through the channel in.
+type ChannelDir int
+
+const (
+	SendRecv ChannelDir = iota
+	SendOnly
+	RecvOnly
+)
+
+type ChannelType struct {
+	Dir  ChannelDir
+	Elem Type
+}
+
+func (t *ChannelType) String() string {
+	switch t.Dir {
+	case SendOnly:
+		return "chan<- " + t.Elem.String()
+	case RecvOnly:
+		return "<-chan " + t.Elem.String()
+	}
+
+	return "chan " + t.Elem.String()
+}
+
+func (t *ChannelType) Underlying() Type {
+	return t
+}
+
+type PointerType struct {
+	Elem Type
+}
+
+func (t *PointerType) String() string {
+	return "*" + t.Elem.String()
+}
+
+func (t *PointerType) Underlying() Type {
+	return t
+}
+
+type Field struct {
+	Name string
+	Type Type
+	// the tag is a part of the type: the fields with different tags are different
+	Tag string
+}
+
+type StructType struct {
+	Fields []*Field
+
+	// the package the unexported fields belong to
+	pkg *Package
+}
+
+func (t *StructType) String() string {
+	fields := make([]string, len(t.Fields))
+	for i, field := range t.Fields {
+		fields[i] = field.Name + " " + field.Type.String()
+		if field.Tag != "" {
+			fields[i] += " " + strconv.Quote(field.Tag)
+		}
+	}
+
+	return "struct{" + strings.Join(fields, "; ") + "}"
+}
+
+func (t *StructType) Underlying() Type {
+	return t
+}
+
+// FieldIndex looks for the field accessible from the package.
+func (t *StructType) FieldIndex(name string, pkg *Package) (int, bool) {
+	for i, field := range t.Fields {
+		if field.Name == name && (IsExported(name) || t.pkg == pkg) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+type Method struct {
+	Name      string
+	Signature *FunctionType
+}
+
+// Term is an element of a union: ~int matches all the types with the underlying type int.
+type Term struct {
+	Tilde bool
+	Type  Type
+}
+
+func (t *Term) String() string {
+	if t.Tilde {
+		return "~" + t.Type.String()
+	}
+
+	return t.Type.String()
+}
+
+// InterfaceType is a method set, the interfaces with a type set are used only as constraints.
+type InterfaceType struct {
+	Methods []*Method
+	// the union of the terms, empty union means all the types
+	Terms []*Term
+
+	comparable bool
+}
+
+func (t *InterfaceType) String() string {
+	if t == ComparableType {
+		return "comparable"
+	}
+	if t == AnyType {
+		return "any"
+	}
+
+	elements := make([]string, 0)
+	for _, method := range t.Methods {
+		elements = append(elements, method.Name+strings.TrimPrefix(method.Signature.String(), "func"))
+	}
+	if len(t.Terms) != 0 {
+		terms := make([]string, len(t.Terms))
+		for i, term := range t.Terms {
+			terms[i] = term.String()
+		}
+		elements = append(elements, strings.Join(terms, " | "))
+	}
+	if t.comparable {
+		elements = append(elements, "comparable")
+	}
+
+	return "interface{" + strings.Join(elements, "; ") + "}"
+}
+
+func (t *InterfaceType) Underlying() Type {
+	return t
+}
+
+// IsConstraint reports whether the interface may be used only as a type constraint.
+func (t *InterfaceType) IsConstraint() bool {
+	return len(t.Terms) != 0 || t.comparable
+}
+
+var (
+	AnyType        = &InterfaceType{}
+	ComparableType = &InterfaceType{comparable: true}
+
+	// ErrorType is the predeclared interface of the errors, it belongs to no package.
+	ErrorType = &NamedType{
+		name: "error",
+		rhs: &InterfaceType{Methods: []*Method{
+			{Name: "Error", Signature: &FunctionType{Params: []Type{}, Result: StringType}},
+		}},
+	}
+)
+
+// TypeParam is a type parameter of a generic declaration, it appears in the types
+// only while the type arguments are inferred: the generic code is compiled per instance.
+type TypeParam struct {
+	name       string
+	constraint Type
+}
+
+func (t *TypeParam) String() string {
+	return t.name
+}
+
+func (t *TypeParam) Underlying() Type {
+	return t
+}
+
+type FunctionType struct {
+	Params []Type
+	// the result is nil for no results and the tuple for several results
+	Result Type
+	// the last parameter of the variadic function is the slice of the rest arguments
+	Variadic bool
+}
+
+func (t *FunctionType) String() string {
+	params := make([]string, len(t.Params))
+	for i, param := range t.Params {
+		params[i] = param.String()
+	}
+	if t.Variadic {
+		params[len(params)-1] = "..." + t.Params[len(t.Params)-1].(*SliceType).Elem.String()
+	}
+
+	res := "func(" + strings.Join(params, ", ") + ")"
+	if t.Result != nil {
+		res += " " + t.Result.String()
+	}
+
+	return res
+}
+
+func (t *FunctionType) Underlying() Type {
+	return t
+}
+
+// TupleType is the type of the several results of the function call.
+type TupleType struct {
+	Types []Type
+}
+
+func (t *TupleType) String() string {
+	types := make([]string, len(t.Types))
+	for i, Type := range t.Types {
+		types[i] = Type.String()
+	}
+
+	return "(" + strings.Join(types, ", ") + ")"
+}
+
+func (t *TupleType) Underlying() Type {
+	return t
+}
+
+// Results is the list of the types of the values the call pushes.
+func Results(result Type) []Type {
+	switch result := result.(type) {
+	case nil:
+		return nil
+	case *TupleType:
+		return result.Types
+	}
+
+	return []Type{result}
+}
+
+// NewResult makes the result of the function type from the list of the types.
+func NewResult(types []Type) Type {
+	switch len(types) {
+	case 0:
+		return nil
+	case 1:
+		return types[0]
+	}
+
+	return &TupleType{Types: types}
+}
+
+func Identical(t1, t2 Type) bool {
+	if t1 == t2 {
+		return true
+	}
+
+	switch t1 := t1.(type) {
+	case *SliceType:
+		t2, ok := t2.(*SliceType)
+		return ok && Identical(t1.Elem, t2.Elem)
+	case *ArrayType:
+		t2, ok := t2.(*ArrayType)
+		return ok && t1.Len == t2.Len && Identical(t1.Elem, t2.Elem)
+	case *MapType:
+		t2, ok := t2.(*MapType)
+		return ok && Identical(t1.Key, t2.Key) && Identical(t1.Elem, t2.Elem)
+	case *PointerType:
+		t2, ok := t2.(*PointerType)
+		return ok && Identical(t1.Elem, t2.Elem)
+	case *ChannelType:
+		t2, ok := t2.(*ChannelType)
+		return ok && t1.Dir == t2.Dir && Identical(t1.Elem, t2.Elem)
+	case *StructType:
+		t2, ok := t2.(*StructType)
+		if !ok || len(t1.Fields) != len(t2.Fields) {
+			return false
+		}
+		for i := range t1.Fields {
+			if t1.Fields[i].Name != t2.Fields[i].Name || t1.Fields[i].Tag != t2.Fields[i].Tag || !Identical(t1.Fields[i].Type, t2.Fields[i].Type) {
+				return false
+			}
+		}
+		return true
+	case *InterfaceType:
+		t2, ok := t2.(*InterfaceType)
+		if !ok || len(t1.Methods) != len(t2.Methods) || len(t1.Terms) != len(t2.Terms) || t1.comparable != t2.comparable {
+			return false
+		}
+		for i := range t1.Methods {
+			if t1.Methods[i].Name != t2.Methods[i].Name || !Identical(t1.Methods[i].Signature, t2.Methods[i].Signature) {
+				return false
+			}
+		}
+		for i := range t1.Terms {
+			if t1.Terms[i].Tilde != t2.Terms[i].Tilde || !Identical(t1.Terms[i].Type, t2.Terms[i].Type) {
+				return false
+			}
+		}
+		return true
+	case *TupleType:
+		t2, ok := t2.(*TupleType)
+		if !ok || len(t1.Types) != len(t2.Types) {
+			return false
+		}
+		for i := range t1.Types {
+			if !Identical(t1.Types[i], t2.Types[i]) {
+				return false
+			}
+		}
+		return true
+	case *FunctionType:
+		t2, ok := t2.(*FunctionType)
+		if !ok || len(t1.Params) != len(t2.Params) || t1.Variadic != t2.Variadic {
+			return false
+		}
+		for i := range t1.Params {
+			if !Identical(t1.Params[i], t2.Params[i]) {
+				return false
+			}
+		}
+		if t1.Result == nil || t2.Result == nil {
+			return t1.Result == t2.Result
+		}
+		return Identical(t1.Result, t2.Result)
+	}
+
+	return false
+}
+
+func IsUntyped(t Type) bool {
+	basic, ok := t.(*BasicType)
+	return ok && basic.Untyped()
+}
+
+// IsNamed reports whether the type has a name: predeclared types are named too.
+func IsNamed(t Type) bool {
+	switch t := t.(type) {
+	case *NamedType:
+		return true
+	case *BasicType:
+		return !t.Untyped()
+	}
+
+	return false
+}
+
+func basicKind(t Type) (BasicKind, bool) {
+	if t == nil {
+		return 0, false
+	}
+	basic, ok := t.Underlying().(*BasicType)
+	if !ok {
+		return 0, false
+	}
+
+	return basic.kind, true
+}
+
+func IsBoolean(t Type) bool {
+	kind, ok := basicKind(t)
+	return ok && (kind == Bool || kind == UntypedBool)
+}
+
+func IsNumeric(t Type) bool {
+	kind, ok := basicKind(t)
+	return ok && (kind >= Int && kind <= Complex128 || kind >= UntypedInt && kind <= UntypedFloat)
+}
+
+func IsFloat(t Type) bool {
+	kind, ok := basicKind(t)
+	return ok && (kind == Float64 || kind == UntypedFloat)
+}
+
+func IsComplex(t Type) bool {
+	kind, ok := basicKind(t)
+	return ok && kind == Complex128
+}
+
+func IsInteger(t Type) bool {
+	kind, ok := basicKind(t)
+	return ok && (kind >= Int && kind <= Uint8 || kind == UntypedInt || kind == UntypedRune)
+}
+
+func IsString(t Type) bool {
+	kind, ok := basicKind(t)
+	return ok && (kind == String || kind == UntypedString)
+}
+
+func IsOrdered(t Type) bool {
+	return IsNumeric(t) && !IsComplex(t) || IsString(t)
+}
+
+// Wider reports whether the untyped numeric constant kind of t2 wins over the kind of t1
+// in the operation on both constants: int, rune, then float.
+func Wider(t1, t2 Type) bool {
+	kind1, ok1 := basicKind(t1)
+	kind2, ok2 := basicKind(t2)
+	return ok1 && ok2 && IsNumeric(t1) && IsNumeric(t2) && kind2 > kind1
+}
+
+// IsBytesOrRunes reports whether the type is the slice of bytes or runes, which converts to string.
+func IsBytesOrRunes(t Type) bool {
+	slice, ok := t.Underlying().(*SliceType)
+	if !ok {
+		return false
+	}
+
+	kind, ok := basicKind(slice.Elem)
+	return ok && (kind == Uint8 || kind == Int32)
+}
+
+func IsComparable(t Type) bool {
+	switch t := t.Underlying().(type) {
+	case *BasicType:
+		return t.kind != UntypedNil
+	case *PointerType, *ChannelType, *InterfaceType:
+		return true
+	case *TypeParam:
+		iface, ok := t.constraint.Underlying().(*InterfaceType)
+		if !ok || iface.comparable {
+			return ok
+		}
+		for _, term := range iface.Terms {
+			if !IsComparable(term.Type) {
+				return false
+			}
+		}
+		return len(iface.Terms) != 0
+	case *StructType:
+		for _, field := range t.Fields {
+			if !IsComparable(field.Type) {
+				return false
+			}
+		}
+		return true
+	case *ArrayType:
+		return IsComparable(t.Elem)
+	}
+
+	return false
+}
+
+// IsNillable reports whether nil is a valid value of the type.
+func IsNillable(t Type) bool {
+	switch t.Underlying().(type) {
+	case *SliceType, *MapType, *FunctionType, *PointerType, *ChannelType, *InterfaceType:
+		return true
+	}
+
+	return false
+}
+
+func IsInterface(t Type) bool {
+	_, ok := t.Underlying().(*InterfaceType)
+	return ok
+}
+
+// DefaultType is the type an untyped constant gets when there is no other type for it.
+func DefaultType(t Type) Type {
+	basic, ok := t.(*BasicType)
+	if !ok {
+		return t
+	}
+
+	switch basic.kind {
+	case UntypedBool:
+		return BoolType
+	case UntypedInt:
+		return IntType
+	case UntypedRune:
+		return Int32Type
+	case UntypedFloat:
+		return Float64Type
+	case UntypedString:
+		return StringType
+	}
+
+	return t
+}
+
+func AssignableTo(v, t Type, program *Program) bool {
+	if Identical(v, t) || v == InvalidType || t == InvalidType {
+		return true
+	}
+
+	vu, tu := v.Underlying(), t.Underlying()
+
+	if IsUntyped(v) {
+		switch v.(*BasicType).kind {
+		case UntypedBool:
+			return IsBoolean(tu) || IsInterface(tu) && Implements(BoolType, t, program) == nil
+		case UntypedInt:
+			return IsNumeric(tu) || IsInterface(tu) && Implements(IntType, t, program) == nil
+		case UntypedRune:
+			return IsNumeric(tu) || IsInterface(tu) && Implements(Int32Type, t, program) == nil
+		case UntypedFloat:
+			return IsNumeric(tu) || IsInterface(tu) && Implements(Float64Type, t, program) == nil
+		case UntypedString:
+			return IsString(tu) || IsInterface(tu) && Implements(StringType, t, program) == nil
+		case UntypedNil:
+			return IsNillable(tu)
+		}
+	}
+
+	if IsInterface(tu) {
+		return Implements(v, t, program) == nil
+	}
+
+	// the bidirectional channel is assignable to the channel of the same values going one way
+	if vc, ok := vu.(*ChannelType); ok && vc.Dir == SendRecv {
+		if tc, ok := tu.(*ChannelType); ok && Identical(vc.Elem, tc.Elem) {
+			return !IsNamed(v) || !IsNamed(t)
+		}
+	}
+
+	return Identical(vu, tu) && (!IsNamed(v) || !IsNamed(t))
+}
+
+// Implements checks that the type has all the methods of the interface.
+func Implements(t Type, iface Type, program *Program) error {
+	for _, method := range iface.Underlying().(*InterfaceType).Methods {
+		signature, ok := program.MethodSignature(t, method.Name)
+		if !ok {
+			return fmt.Errorf("%v does not implement %v (missing method %v)", t, iface, method.Name)
+		}
+		if !Identical(signature, method.Signature) {
+			return fmt.Errorf("%v does not implement %v (wrong type for method %v)", t, iface, method.Name)
+		}
+	}
+
+	return nil
+}
+
+func ConvertibleTo(v, t Type, program *Program) bool {
+	if AssignableTo(v, t, program) {
+		return true
+	}
+
+	vu, tu := v.Underlying(), t.Underlying()
+	if identicalIgnoreTags(vu, tu) {
+		return true
+	}
+
+	// string(r) is the UTF-8 encoding of the rune, the strings convert to the bytes and the runes
+	if IsString(tu) && (IsInteger(vu) || IsBytesOrRunes(vu)) || IsString(vu) && IsBytesOrRunes(tu) {
+		return true
+	}
+
+	// the complex numbers are converted only to the complex numbers
+	return IsNumeric(vu) && IsNumeric(tu) && (IsComplex(vu) == IsComplex(tu) || IsUntyped(vu))
+}
+
+// identicalIgnoreTags compares the types the way the conversions do, the struct tags are ignored.
+func identicalIgnoreTags(t1, t2 Type) bool {
+	s1, ok1 := t1.(*StructType)
+	s2, ok2 := t2.(*StructType)
+	if !ok1 || !ok2 {
+		return Identical(t1, t2)
+	}
+
+	if len(s1.Fields) != len(s2.Fields) {
+		return false
+	}
+	for i := range s1.Fields {
+		if s1.Fields[i].Name != s2.Fields[i].Name || !identicalIgnoreTags(s1.Fields[i].Type, s2.Fields[i].Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// RuntimeType is the type of the go values used to store values of the type.
+func RuntimeType(t Type) reflect.Type {
+	switch t := t.Underlying().(type) {
+	case *BasicType:
+		return t.reflectType
+	case *SliceType:
+		return reflect.TypeOf([]any(nil))
+	case *ArrayType:
+		return reflect.TypeOf((*ArrayValue)(nil))
+	case *MapType:
+		return reflect.TypeOf(map[any]any(nil))
+	case *FunctionType:
+		return reflect.TypeOf((*Function)(nil)).Elem()
+	case *PointerType:
+		return reflect.TypeOf((*any)(nil))
+	case *ChannelType:
+		return reflect.TypeOf((*ChannelValue)(nil))
+	case *StructType:
+		return reflect.TypeOf((*StructValue)(nil))
+	case *InterfaceType:
+		return reflect.TypeOf((*any)(nil)).Elem()
+	}
+
+	panic(fmt.Sprintf("unknown type: %v", t))
+}
+
+// Satisfies checks that the type argument is in the type set of the constraint.
+func Satisfies(t Type, constraint Type, program *Program) error {
+	iface, ok := constraint.Underlying().(*InterfaceType)
+	if !ok {
+		return fmt.Errorf("cannot use %v as constraint", constraint)
+	}
+
+	// the type parameters appear only while the type arguments are inferred
+	if _, ok := t.(*TypeParam); ok {
+		return nil
+	}
+
+	if iface.comparable && !IsComparable(t) {
+		return fmt.Errorf("%v does not satisfy comparable", t)
+	}
+
+	if len(iface.Terms) != 0 {
+		found := false
+		for _, term := range iface.Terms {
+			if Identical(t, term.Type) || (term.Tilde && Identical(t.Underlying(), term.Type.Underlying())) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%v does not satisfy %v (%v missing in %v)", t, constraint, t, termsString(iface.Terms))
+		}
+	}
+
+	for _, method := range iface.Methods {
+		signature, ok := program.MethodSignature(t, method.Name)
+		if !ok || !Identical(signature, method.Signature) {
+			return fmt.Errorf("%v does not satisfy %v (missing method %v)", t, constraint, method.Name)
+		}
+	}
+
+	return nil
+}
+
+func termsString(terms []*Term) string {
+	res := make([]string, len(terms))
+	for i, term := range terms {
+		res[i] = term.String()
+	}
+
+	return strings.Join(res, " | ")
+}
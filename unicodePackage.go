@@ -0,0 +1,38 @@
+package main
+
+import "unicode"
+
+func init() {
+	RegisterStdPackage(&StdPackage{
+		Name:    "unicode",
+		Path:    "unicode",
+		Declare: declareUnicode,
+	})
+}
+
+func declareUnicode(prog *Program, pkg *Package) {
+	prog.declareHostFunctions(pkg, map[string]any{
+		"IsControl":  unicode.IsControl,
+		"IsDigit":    unicode.IsDigit,
+		"IsGraphic":  unicode.IsGraphic,
+		"IsLetter":   unicode.IsLetter,
+		"IsLower":    unicode.IsLower,
+		"IsMark":     unicode.IsMark,
+		"IsNumber":   unicode.IsNumber,
+		"IsPrint":    unicode.IsPrint,
+		"IsPunct":    unicode.IsPunct,
+		"IsSpace":    unicode.IsSpace,
+		"IsSymbol":   unicode.IsSymbol,
+		"IsTitle":    unicode.IsTitle,
+		"IsUpper":    unicode.IsUpper,
+		"SimpleFold": unicode.SimpleFold,
+		"ToLower":    unicode.ToLower,
+		"ToTitle":    unicode.ToTitle,
+		"ToUpper":    unicode.ToUpper,
+	})
+
+	prog.declareConstant(pkg, "MaxRune", UntypedRuneType, unicode.MaxRune)
+	prog.declareConstant(pkg, "ReplacementChar", UntypedRuneType, unicode.ReplacementChar)
+	prog.declareConstant(pkg, "MaxASCII", UntypedRuneType, unicode.MaxASCII)
+	prog.declareConstant(pkg, "MaxLatin1", UntypedRuneType, unicode.MaxLatin1)
+}
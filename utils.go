@@ -8,127 +8,299 @@ import (
 
 func CloneAny(val any) any {
 	switch val.(type) {
-	case int:
-		return val.(int)
-	case string:
-		return strings.Clone(val.(string))
-	case bool:
-		return val.(bool)
+	case int, int32, uint8, float64, complex128, string, bool:
+		// the basic values are immutable, boxing them again would allocate
+		return val
+	case *StructValue:
+		return val.(*StructValue).Clone()
+	case *ArrayValue:
+		return val.(*ArrayValue).Clone()
+	case InterfaceValue:
+		return InterfaceValue{Type: val.(InterfaceValue).Type, Value: CloneAny(val.(InterfaceValue).Value)}
+	case []any, map[any]any, Function, *any, *ChannelValue, nil:
+		// slices, maps, functions, pointers and channels are references
+		return val
 	default:
 		panic(fmt.Sprintf("unknown type: %v", reflect.TypeOf(val).String()))
 	}
 }
 
-func ReflectType(typename string) (reflect.Type, error) {
-	switch typename {
-	case "int":
-		return reflect.TypeOf(1), nil
-	case "bool":
-		return reflect.TypeOf(true), nil
-	case "string":
-		return reflect.TypeOf("string"), nil
-	default:
-		return nil, fmt.Errorf("unknown type %v", typename)
+func NewVariable(Type Type) any {
+	switch Type := Type.Underlying().(type) {
+	case *BasicType:
+		return reflect.Zero(Type.reflectType).Interface()
+	case *SliceType:
+		return []any(nil)
+	case *MapType:
+		return map[any]any(nil)
+	case *StructType:
+		res := &StructValue{
+			fields: make([]*any, len(Type.Fields)),
+		}
+		for i, field := range Type.Fields {
+			value := NewVariable(field.Type)
+			res.fields[i] = &value
+		}
+		return res
+	case *ArrayType:
+		res := &ArrayValue{
+			elems: make([]any, Type.Len),
+		}
+		for i := range res.elems {
+			res.elems[i] = NewVariable(Type.Elem)
+		}
+		return res
 	}
+
+	return nil
 }
 
-func NewVariable(Type reflect.Type) any {
-	switch Type {
-	case reflect.TypeOf(0):
-		return 0
-	case reflect.TypeOf(false):
-		return false
-	case reflect.TypeOf(""):
-		return ""
+// StructValue is the value of a struct, the fields are cells to take their addresses.
+type StructValue struct {
+	fields []*any
+}
+
+func (s *StructValue) Clone() *StructValue {
+	res := &StructValue{
+		fields: make([]*any, len(s.fields)),
+	}
+	for i, field := range s.fields {
+		value := CloneAny(*field)
+		res.fields[i] = &value
 	}
 
-	return nil
+	return res
 }
 
-func AddAny(val1, val2 any) (any, error) {
-	if reflect.TypeOf(val1) != reflect.TypeOf(val2) {
-		return nil, fmt.Errorf(
-			"invalid operation %v(type:%v) + %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+func (s *StructValue) String() string {
+	fields := make([]string, len(s.fields))
+	for i, field := range s.fields {
+		fields[i] = fmt.Sprint(*field)
 	}
 
-	switch val1.(type) {
-	case int:
-		return val1.(int) + val2.(int), nil
-	case string:
-		return val1.(string) + val2.(string), nil
-	default:
-		return nil, fmt.Errorf(
-			"invalid operation %v(type:%v) + %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+// ArrayValue is the value of an array, it is copied as a whole like the struct.
+// The slices of the array share its elements.
+type ArrayValue struct {
+	elems []any
+}
+
+func (a *ArrayValue) Clone() *ArrayValue {
+	res := &ArrayValue{
+		elems: make([]any, len(a.elems)),
 	}
+	for i, elem := range a.elems {
+		res.elems[i] = CloneAny(elem)
+	}
+
+	return res
 }
 
-func MulAny(val1, val2 any) (any, error) {
-	if reflect.TypeOf(val1) != reflect.TypeOf(val2) {
-		return nil, fmt.Errorf(
-			"invalid operation %v(type:%v) * %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+func (a *ArrayValue) String() string {
+	return fmt.Sprint(a.elems)
+}
+
+// InterfaceValue is the non-nil value of an interface, it keeps the dynamic type of the value.
+type InterfaceValue struct {
+	Type  Type
+	Value any
+}
+
+func (v InterfaceValue) String() string {
+	return fmt.Sprint(v.Value)
+}
+
+// Unbox gives the dynamic value of the interface value.
+func Unbox(val any) any {
+	if val, ok := val.(InterfaceValue); ok {
+		return val.Value
 	}
 
-	switch val1.(type) {
-	case int:
-		return val1.(int) * val2.(int), nil
-	default:
-		return nil, fmt.Errorf(
-			"invalid operation %v(type:%v) * %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+	return val
+}
+
+func UnboxAll(vals []any) []any {
+	res := make([]any, len(vals))
+	for i, val := range vals {
+		res[i] = Unbox(val)
 	}
+
+	return res
 }
 
-func DivAny(val1, val2 any) (any, error) {
-	if reflect.TypeOf(val1) != reflect.TypeOf(val2) {
-		return nil, fmt.Errorf(
-			"invalid operation %v(type:%v) / %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+// ConvertAny converts the value to the representation of the type.
+func ConvertAny(val any, Type Type) any {
+	if val == nil {
+		return NewVariable(Type)
 	}
 
-	switch val1.(type) {
+	if str, ok := val.(string); ok && IsBytesOrRunes(Type) {
+		return StringToSlice(str, Type.Underlying().(*SliceType).Elem)
+	}
+
+	basicType, ok := Type.Underlying().(*BasicType)
+	if !ok || reflect.TypeOf(val) == basicType.reflectType {
+		return val
+	}
+
+	if basicType.kind == String {
+		switch val := val.(type) {
+		case []any:
+			return SliceToString(val)
+		case int:
+			return string(rune(val))
+		case int32:
+			return string(val)
+		case uint8:
+			return string(rune(val))
+		}
+	}
+
+	// reflect doesn't convert the real numbers to the complex ones
+	if basicType.kind == Complex128 {
+		return complex(reflect.ValueOf(val).Convert(Float64Type.reflectType).Float(), 0)
+	}
+
+	return reflect.ValueOf(val).Convert(basicType.reflectType).Interface()
+}
+
+func AddAny(val1, val2 any) (any, error) {
+	switch val1 := val1.(type) {
 	case int:
-		return val1.(int) / val2.(int), nil
-	default:
-		return nil, fmt.Errorf(
-			"invalid operation %v(type:%v) / %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+		if val2, ok := val2.(int); ok {
+			return val1 + val2, nil
+		}
+	case int32:
+		if val2, ok := val2.(int32); ok {
+			return val1 + val2, nil
+		}
+	case uint8:
+		if val2, ok := val2.(uint8); ok {
+			return val1 + val2, nil
+		}
+	case string:
+		if val2, ok := val2.(string); ok {
+			return val1 + val2, nil
+		}
+	case float64:
+		if val2, ok := val2.(float64); ok {
+			return val1 + val2, nil
+		}
+	case complex128:
+		if val2, ok := val2.(complex128); ok {
+			return val1 + val2, nil
+		}
 	}
+
+	return nil, fmt.Errorf(
+		"invalid operation %v(type:%v) + %v(type:%v)",
+		val1, reflect.TypeOf(val1),
+		val2, reflect.TypeOf(val2),
+	)
 }
 
-func SubAny(val1, val2 any) (any, error) {
-	if reflect.TypeOf(val1) != reflect.TypeOf(val2) {
-		return nil, fmt.Errorf(
-			"invalid operation %v(type:%v) - %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+func MulAny(val1, val2 any) (any, error) {
+	switch val1 := val1.(type) {
+	case int:
+		if val2, ok := val2.(int); ok {
+			return val1 * val2, nil
+		}
+	case int32:
+		if val2, ok := val2.(int32); ok {
+			return val1 * val2, nil
+		}
+	case uint8:
+		if val2, ok := val2.(uint8); ok {
+			return val1 * val2, nil
+		}
+	case float64:
+		if val2, ok := val2.(float64); ok {
+			return val1 * val2, nil
+		}
+	case complex128:
+		if val2, ok := val2.(complex128); ok {
+			return val1 * val2, nil
+		}
 	}
 
-	switch val1.(type) {
+	return nil, fmt.Errorf(
+		"invalid operation %v(type:%v) * %v(type:%v)",
+		val1, reflect.TypeOf(val1),
+		val2, reflect.TypeOf(val2),
+	)
+}
+
+func DivAny(val1, val2 any) (any, error) {
+	switch val1 := val1.(type) {
 	case int:
-		return val1.(int) - val2.(int), nil
-	default:
-		return nil, fmt.Errorf(
-			"invalid operation !%v(type:%v) - %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+		if val2, ok := val2.(int); ok {
+			if val2 == 0 {
+				return nil, errDivideByZero
+			}
+			return val1 / val2, nil
+		}
+	case int32:
+		if val2, ok := val2.(int32); ok {
+			if val2 == 0 {
+				return nil, errDivideByZero
+			}
+			return val1 / val2, nil
+		}
+	case uint8:
+		if val2, ok := val2.(uint8); ok {
+			if val2 == 0 {
+				return nil, errDivideByZero
+			}
+			return val1 / val2, nil
+		}
+	case float64:
+		if val2, ok := val2.(float64); ok {
+			return val1 / val2, nil
+		}
+	case complex128:
+		if val2, ok := val2.(complex128); ok {
+			return val1 / val2, nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"invalid operation %v(type:%v) / %v(type:%v)",
+		val1, reflect.TypeOf(val1),
+		val2, reflect.TypeOf(val2),
+	)
+}
+
+func SubAny(val1, val2 any) (any, error) {
+	switch val1 := val1.(type) {
+	case int:
+		if val2, ok := val2.(int); ok {
+			return val1 - val2, nil
+		}
+	case int32:
+		if val2, ok := val2.(int32); ok {
+			return val1 - val2, nil
+		}
+	case uint8:
+		if val2, ok := val2.(uint8); ok {
+			return val1 - val2, nil
+		}
+	case float64:
+		if val2, ok := val2.(float64); ok {
+			return val1 - val2, nil
+		}
+	case complex128:
+		if val2, ok := val2.(complex128); ok {
+			return val1 - val2, nil
+		}
 	}
+
+	return nil, fmt.Errorf(
+		"invalid operation %v(type:%v) - %v(type:%v)",
+		val1, reflect.TypeOf(val1),
+		val2, reflect.TypeOf(val2),
+	)
 }
 
 func NotAny(val1 any) (any, error) {
@@ -233,6 +405,9 @@ func CompareAny(val1, val2 any, compareType string) (any, error) {
 }
 
 func EqualAny(val1, val2 any) (any, error) {
+	if val1 == nil || val2 == nil {
+		return val1 == nil && val2 == nil, nil
+	}
 	if reflect.TypeOf(val1) != reflect.TypeOf(val2) {
 		return nil, fmt.Errorf(
 			"invalid operation %v(type:%v) compare %v(type:%v)",
@@ -246,8 +421,49 @@ func EqualAny(val1, val2 any) (any, error) {
 		return val1.(bool) == val2.(bool), nil
 	case int:
 		return val1.(int) == val2.(int), nil
+	case int32:
+		return val1.(int32) == val2.(int32), nil
+	case uint8:
+		return val1.(uint8) == val2.(uint8), nil
 	case string:
 		return val1.(string) == val2.(string), nil
+	case float64:
+		return val1.(float64) == val2.(float64), nil
+	case complex128:
+		return val1.(complex128) == val2.(complex128), nil
+	case []any:
+		// slices, maps and functions can be compared only with nil
+		return (val1.([]any) == nil) == (val2.([]any) == nil), nil
+	case map[any]any:
+		return (val1.(map[any]any) == nil) == (val2.(map[any]any) == nil), nil
+	case *any:
+		return val1.(*any) == val2.(*any), nil
+	case *ChannelValue:
+		return val1.(*ChannelValue) == val2.(*ChannelValue), nil
+	case InterfaceValue:
+		if !Identical(val1.(InterfaceValue).Type, val2.(InterfaceValue).Type) {
+			return false, nil
+		}
+		if !IsComparable(val1.(InterfaceValue).Type) {
+			return nil, fmt.Errorf("runtime error: comparing uncomparable type %v", val1.(InterfaceValue).Type)
+		}
+		return EqualAny(val1.(InterfaceValue).Value, val2.(InterfaceValue).Value)
+	case *StructValue:
+		for i, field := range val1.(*StructValue).fields {
+			eq, err := EqualAny(*field, *val2.(*StructValue).fields[i])
+			if err != nil || !eq.(bool) {
+				return eq, err
+			}
+		}
+		return true, nil
+	case *ArrayValue:
+		for i, elem := range val1.(*ArrayValue).elems {
+			eq, err := EqualAny(elem, val2.(*ArrayValue).elems[i])
+			if err != nil || !eq.(bool) {
+				return eq, err
+			}
+		}
+		return true, nil
 	default:
 		return nil, fmt.Errorf(
 			"invalid operation !%v(type:%v) compare %v(type:%v)",
@@ -258,22 +474,98 @@ func EqualAny(val1, val2 any) (any, error) {
 }
 
 func LessAny(val1, val2 any) (any, error) {
-	if reflect.TypeOf(val1) != reflect.TypeOf(val2) {
-		return nil, fmt.Errorf(
-			"invalid operation %v(type:%v) compare %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
-		)
+	switch val1 := val1.(type) {
+	case int:
+		if val2, ok := val2.(int); ok {
+			return val1 < val2, nil
+		}
+	case int32:
+		if val2, ok := val2.(int32); ok {
+			return val1 < val2, nil
+		}
+	case uint8:
+		if val2, ok := val2.(uint8); ok {
+			return val1 < val2, nil
+		}
+	case float64:
+		if val2, ok := val2.(float64); ok {
+			return val1 < val2, nil
+		}
+	case string:
+		if val2, ok := val2.(string); ok {
+			return val1 < val2, nil
+		}
 	}
 
-	switch val1.(type) {
+	return nil, fmt.Errorf(
+		"invalid operation %v(type:%v) compare %v(type:%v)",
+		val1, reflect.TypeOf(val1),
+		val2, reflect.TypeOf(val2),
+	)
+}
+
+// ExtremumAny gives the least or the greatest of the values of the same type.
+func ExtremumAny(vals []any, greatest bool) (any, error) {
+	switch vals[0].(type) {
 	case int:
-		return val1.(int) < val2.(int), nil
+		return extremum[int](vals, greatest), nil
+	case int32:
+		return extremum[int32](vals, greatest), nil
+	case uint8:
+		return extremum[uint8](vals, greatest), nil
+	case float64:
+		return extremum[float64](vals, greatest), nil
+	case string:
+		return extremum[string](vals, greatest), nil
 	default:
 		return nil, fmt.Errorf(
-			"invalid operation !%v(type:%v) compare %v(type:%v)",
-			val1, reflect.TypeOf(val1),
-			val2, reflect.TypeOf(val2),
+			"invalid operation min/max %v(type:%v)",
+			vals[0], reflect.TypeOf(vals[0]),
 		)
 	}
 }
+
+func extremum[T int | int32 | uint8 | float64 | string](vals []any, greatest bool) T {
+	res := vals[0].(T)
+	for _, val := range vals[1:] {
+		if greatest {
+			res = max(res, val.(T))
+		} else {
+			res = min(res, val.(T))
+		}
+	}
+
+	return res
+}
+
+// StringToSlice gives the bytes or the runes of the string.
+func StringToSlice(str string, elem Type) []any {
+	if kind, _ := basicKind(elem); kind == Uint8 {
+		res := make([]any, len(str))
+		for i := range len(str) {
+			res[i] = str[i]
+		}
+		return res
+	}
+
+	res := make([]any, 0, len(str))
+	for _, r := range str {
+		res = append(res, r)
+	}
+	return res
+}
+
+// SliceToString joins the bytes or the runes into the string.
+func SliceToString(slice []any) string {
+	var builder strings.Builder
+	for _, element := range slice {
+		switch element := element.(type) {
+		case uint8:
+			builder.WriteByte(element)
+		case int32:
+			builder.WriteRune(element)
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,794 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"unicode/utf8"
+)
+
+// vmKind tells where the register keeps its value.
+type vmKind uint8
+
+const (
+	// kindAny keeps the value in ref, the zero register is nil
+	kindAny vmKind = iota
+	kindInt
+	kindInt32
+	kindUint8
+	kindFloat
+	kindBool
+)
+
+// vmValue is the value in the register of the machine. The numbers and the booleans are kept in the scalar,
+// so the arithmetic on them boxes nothing, the other values are in ref. The values are boxed only when they leave
+// the machine: in the arguments of the host functions, in the cells of the shared variables and in the instruction trees.
+type vmValue struct {
+	kind   vmKind
+	scalar uint64
+	ref    any
+}
+
+func intValue(i int) vmValue {
+	return vmValue{kind: kindInt, scalar: uint64(i)}
+}
+
+func floatValue(f float64) vmValue {
+	return vmValue{kind: kindFloat, scalar: math.Float64bits(f)}
+}
+
+func boolValue(b bool) vmValue {
+	if b {
+		return vmValue{kind: kindBool, scalar: 1}
+	}
+	return vmValue{kind: kindBool}
+}
+
+// unboxed is the register value of the value of the instruction trees and of the host functions.
+func unboxed(value any) vmValue {
+	switch value := value.(type) {
+	case int:
+		return intValue(value)
+	case int32:
+		return vmValue{kind: kindInt32, scalar: uint64(value)}
+	case uint8:
+		return vmValue{kind: kindUint8, scalar: uint64(value)}
+	case float64:
+		return floatValue(value)
+	case bool:
+		return boolValue(value)
+	}
+
+	return vmValue{ref: value}
+}
+
+// boxed is the value the instruction trees and the host functions use.
+func (v vmValue) boxed() any {
+	switch v.kind {
+	case kindInt:
+		return int(v.scalar)
+	case kindInt32:
+		return int32(v.scalar)
+	case kindUint8:
+		return uint8(v.scalar)
+	case kindFloat:
+		return math.Float64frombits(v.scalar)
+	case kindBool:
+		return v.scalar != 0
+	}
+
+	return v.ref
+}
+
+// clone copies the value like CloneAny, the scalars are copied as they are.
+func (v vmValue) clone() vmValue {
+	if v.kind == kindAny {
+		v.ref = CloneAny(v.ref)
+	}
+	return v
+}
+
+// machine runs the compiled functions, the calls between them stay in its loop.
+// The registers of the calls follow each other, the machines are reused by the calls from the host.
+type machine struct {
+	registers []vmValue
+	frames    []vmFrame
+	// the registers used since the machine was taken, they are cleared when it is released
+	used int
+}
+
+// vmFrame is the caller waiting for the result of the call.
+type vmFrame struct {
+	function *vmFunction
+	pc, base int
+	// the register of the first result, -1 if the results are dropped
+	results int
+}
+
+var errDivideByZero = errors.New("runtime error: integer divide by zero")
+
+func (prog *Program) machine() *machine {
+	if n := len(prog.machines); n != 0 {
+		m := prog.machines[n-1]
+		prog.machines = prog.machines[:n-1]
+		return m
+	}
+
+	return &machine{}
+}
+
+func (prog *Program) release(m *machine) {
+	clear(m.registers[:m.used])
+	m.frames, m.used = m.frames[:0], 0
+	prog.machines = append(prog.machines, m)
+}
+
+// grow makes the registers up to n.
+func (m *machine) grow(n int) {
+	if n > len(m.registers) {
+		m.registers = append(m.registers, make([]vmValue, n-len(m.registers))...)
+	}
+	m.used = max(m.used, n)
+}
+
+// run calls the compiled function from the host, the function literal sees the variables it has captured.
+func (f *vmFunction) run(captured map[string]*any, args []any) ([]any, error) {
+	prog := f.program
+	if err := f.function.checkArguments(len(args)); err != nil {
+		return nil, err
+	}
+	depth := len(prog.calls)
+	if prog.MaxCallDepth > 0 && depth >= prog.MaxCallDepth {
+		return nil, StackOverflowError{Chain: append(slices.Clone(prog.calls), f.function.name)}
+	}
+	prog.calls = append(prog.calls, f.function.name)
+
+	m := prog.machine()
+	m.grow(f.registers)
+	for i, arg := range args {
+		m.registers[i] = unboxed(CloneAny(arg))
+	}
+	var res []any
+	err := f.frame(m.registers, captured)
+	if err == nil {
+		res, _, err = m.exec(prog, f, 0, 0, nil)
+	}
+	prog.release(m)
+	prog.calls = prog.calls[:depth]
+	return res, err
+}
+
+// enter makes the registers of the call and copies the arguments into them.
+func (m *machine) enter(f *vmFunction, base int, args []vmValue, captured map[string]*any) ([]vmValue, error) {
+	m.grow(base + f.registers)
+	registers := m.registers[base : base+f.registers]
+	for i, arg := range args {
+		registers[i] = arg.clone()
+	}
+	return registers, f.frame(registers, captured)
+}
+
+// frame sets the registers the call starts with besides the arguments: the results, the cells of the captured
+// variables and the deferred calls.
+func (f *vmFunction) frame(registers []vmValue, captured map[string]*any) error {
+	f.zeroResults(registers)
+	if f.defers >= 0 {
+		var defers any = []DeferredCall(nil)
+		registers[f.defers] = vmValue{ref: &defers}
+	}
+	free := len(f.function.inputVariables) + len(f.function.outputVariables)
+	for i, name := range f.free {
+		cell, ok := captured[name]
+		if !ok {
+			return fmt.Errorf("variable %v not declarated", name)
+		}
+		registers[free+i] = vmValue{ref: cell}
+	}
+	return nil
+}
+
+// frameSize is the count of the registers the machine sets when the function is called.
+func (f *vmFunction) frameSize() int {
+	res := len(f.function.inputVariables) + len(f.function.outputVariables) + len(f.free)
+	if f.defers >= 0 {
+		res++
+	}
+	return res
+}
+
+// deferredCalls takes the calls the function has deferred, they run once.
+func (f *vmFunction) deferredCalls(registers []vmValue) []DeferredCall {
+	defers := registers[f.defers].ref.(*any)
+	calls := (*defers).([]DeferredCall)
+	*defers = []DeferredCall(nil)
+	return calls
+}
+
+// result is the value of the result i in its register, the shared result is in the cell.
+func (f *vmFunction) result(value vmValue, i int) vmValue {
+	if f.resultCells != nil && f.resultCells[i] {
+		return unboxed(*value.ref.(*any))
+	}
+	return value
+}
+
+// zeroResults sets the results to the zero values, the function may return without setting them.
+func (f *vmFunction) zeroResults(registers []vmValue) {
+	params := len(f.function.inputVariables)
+	for i, zero := range f.zeros {
+		if zero.kind == kindAny {
+			// the structs and the arrays are made for each call
+			zero = unboxed(NewVariable(f.function.outputVariables[i].Type))
+		}
+		registers[params+i] = zero
+	}
+}
+
+// vmBody is the body of the range loop over the function, it runs in the call of the yield function.
+type vmBody struct {
+	start, end int
+}
+
+// exec runs the function whose registers are from the base from the instruction pc. The body of the range loop
+// runs till it leaves the body, the instruction it has left to is returned: the end of the body goes on with the loop.
+func (m *machine) exec(prog *Program, f *vmFunction, base, pc int, body *vmBody) ([]any, int, error) {
+	entry := len(m.frames)
+	registers := m.registers[base : base+f.registers]
+	code, constants := f.code, f.constants
+	operand := func(operand int32) vmValue {
+		if operand >= 0 {
+			return registers[operand]
+		}
+		return constants[-1-operand]
+	}
+
+	var err error
+	for {
+		if body != nil && len(m.frames) == entry && (pc < body.start || pc >= body.end) {
+			return nil, pc, nil
+		}
+		in := &code[pc]
+		pc++
+		for range in.steps {
+			if err = prog.step(); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		switch in.op {
+		case vmMove:
+			registers[in.a] = operand(in.b)
+		case vmCopy:
+			registers[in.a] = operand(in.b).clone()
+		case vmZero:
+			registers[in.a] = unboxed(NewVariable(f.types[in.b]))
+		case vmAdd, vmSub, vmMul, vmDiv:
+			x, y := operand(in.b), operand(in.c)
+			switch {
+			case x.kind == kindInt && y.kind == kindInt:
+				registers[in.a], err = arithmetic(in.op, int(x.scalar), int(y.scalar))
+			case x.kind == kindFloat && y.kind == kindFloat:
+				registers[in.a] = floatArithmetic(in.op, math.Float64frombits(x.scalar), math.Float64frombits(y.scalar))
+			default:
+				var res any
+				if in.op == vmAdd {
+					res, err = prog.add(x.boxed(), y.boxed())
+				} else {
+					res, err = vmArithmetic[in.op-vmAdd](x.boxed(), y.boxed())
+				}
+				registers[in.a] = unboxed(res)
+			}
+		case vmOr, vmAnd:
+			x, y := operand(in.b), operand(in.c)
+			if x.kind == kindBool && y.kind == kindBool {
+				if in.op == vmOr {
+					registers[in.a] = vmValue{kind: kindBool, scalar: x.scalar | y.scalar}
+				} else {
+					registers[in.a] = vmValue{kind: kindBool, scalar: x.scalar & y.scalar}
+				}
+				continue
+			}
+			var res any
+			if in.op == vmOr {
+				res, err = OrAny(x.boxed(), y.boxed())
+			} else {
+				res, err = AndAny(x.boxed(), y.boxed())
+			}
+			registers[in.a] = unboxed(res)
+		case vmNot:
+			x := operand(in.b)
+			if x.kind == kindBool {
+				registers[in.a] = vmValue{kind: kindBool, scalar: 1 - x.scalar}
+				continue
+			}
+			var res any
+			res, err = NotAny(x.boxed())
+			registers[in.a] = unboxed(res)
+		case vmEq, vmNe, vmLt, vmLe, vmGt, vmGe:
+			x, y := operand(in.b), operand(in.c)
+			switch {
+			case x.kind == kindInt && y.kind == kindInt:
+				registers[in.a] = boolValue(compare(in.op, int(x.scalar), int(y.scalar)))
+			case x.kind == kindFloat && y.kind == kindFloat:
+				registers[in.a] = boolValue(compare(in.op, math.Float64frombits(x.scalar), math.Float64frombits(y.scalar)))
+			default:
+				var res any
+				res, err = CompareAny(x.boxed(), y.boxed(), vmCompareTypes[in.op-vmEq])
+				registers[in.a] = unboxed(res)
+			}
+		case vmConvert:
+			registers[in.a] = unboxed(ConvertAny(operand(in.b).boxed(), f.types[in.c]))
+		case vmBox:
+			registers[in.a] = vmValue{ref: InterfaceValue{Type: f.types[in.c], Value: operand(in.b).boxed()}}
+		case vmLen:
+			var n int
+			n, err = length(operand(in.b).ref)
+			registers[in.a] = intValue(n)
+		case vmIndex, vmIndexRef:
+			container, index := operand(in.b), operand(in.c)
+			var res any
+			if slice, ok := elementsOf(container.ref); ok && index.kind == kindInt {
+				// the index of the slice isn't boxed
+				i := int(index.scalar)
+				if i < 0 || i >= len(slice) {
+					err = fmt.Errorf("runtime error: index out of range [%v] with length %v", i, len(slice))
+					break
+				}
+				res = slice[i]
+			} else {
+				res, err = indexValue(container.ref, index.boxed(), f.types[in.d])
+			}
+			if in.op == vmIndex {
+				res = CloneAny(res)
+			}
+			registers[in.a] = unboxed(res)
+		case vmSetIndex:
+			container, index := operand(in.a), operand(in.b)
+			if slice, ok := elementsOf(container.ref); ok && index.kind == kindInt {
+				i := int(index.scalar)
+				if i < 0 || i >= len(slice) {
+					err = fmt.Errorf("runtime error: index out of range [%v] with length %v", i, len(slice))
+					break
+				}
+				slice[i] = CloneAny(operand(in.c).boxed())
+				continue
+			}
+			err = prog.setIndex(container.ref, index.boxed(), operand(in.c).boxed())
+		case vmField, vmFieldRef:
+			var cell *any
+			cell, err = structField(operand(in.b).ref, int(in.c))
+			if err != nil {
+				break
+			}
+			if in.op == vmField {
+				registers[in.a] = unboxed(CloneAny(*cell))
+			} else {
+				registers[in.a] = unboxed(*cell)
+			}
+		case vmPackage:
+			registers[in.a] = unboxed(CloneAny(constants[-1-in.b].ref.(*PackageValue).Value))
+		case vmEval, vmExec:
+			err = f.eval(registers, in)
+		case vmCall, vmCallValue:
+			call := &f.calls[in.b]
+			callee, function := call.compiled, call.function
+			var captured map[string]*any
+			if in.op == vmCallValue {
+				function, _ = operand(in.d).ref.(Function)
+				if function == nil {
+					err = fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+					break
+				}
+				callee = nil
+				switch function := function.(type) {
+				case *IntrpretatedFunction:
+					callee = function.vm
+				case Closure:
+					callee, captured = function.function.vm, function.variables
+				}
+			}
+			args := registers[in.c : int(in.c)+call.args]
+
+			if callee == nil {
+				boxed := make([]any, len(args))
+				for i, arg := range args {
+					boxed[i] = arg.boxed()
+				}
+				var res []any
+				res, err = function.Call(boxed...)
+				if err != nil {
+					break
+				}
+				if call.results >= 0 {
+					if len(res) != call.results {
+						err = fmt.Errorf("wrong count of return values of statement")
+						break
+					}
+					for i, value := range res {
+						registers[int(in.a)+i] = unboxed(value)
+					}
+				}
+				continue
+			}
+
+			// the call of the compiled function goes on in the loop
+			if err = callee.function.checkArguments(len(args)); err != nil {
+				break
+			}
+			if prog.MaxCallDepth > 0 && len(prog.calls) >= prog.MaxCallDepth {
+				err = StackOverflowError{Chain: append(slices.Clone(prog.calls), callee.function.name)}
+				break
+			}
+			prog.calls = append(prog.calls, callee.function.name)
+			results := int(in.a)
+			if call.results < 0 {
+				results = -1
+			}
+			m.frames = append(m.frames, vmFrame{function: f, pc: pc, base: base, results: results})
+			base += f.registers
+			f, pc = callee, 0
+			registers, err = m.enter(f, base, args, captured)
+			code, constants = f.code, f.constants
+		case vmJump:
+			pc = int(in.a)
+		case vmJumpIfFalse:
+			condition := operand(in.a)
+			if condition.kind != kindBool {
+				value := condition.boxed()
+				err = fmt.Errorf("statement: %v(type: %v) is not bool", value, reflect.TypeOf(value))
+				break
+			}
+			if condition.scalar == 0 {
+				pc = int(in.b)
+			}
+		case vmRange:
+			container := operand(in.b)
+			if container.kind == kindInt && rangeKind(in.c) == rangeInt {
+				registers[in.a], registers[in.a+1] = intValue(0), container
+				continue
+			}
+			registers[in.a] = vmValue{ref: newIterator(rangeKind(in.c), container.boxed())}
+		case vmNext:
+			if state := &registers[in.a]; state.kind == kindInt {
+				if int(state.scalar) >= int(registers[in.a+1].scalar) {
+					pc = int(in.c)
+					continue
+				}
+				registers[in.b] = *state
+				state.scalar++
+				continue
+			}
+
+			var ok bool
+			ok, err = registers[in.a].ref.(*vmIterator).next(prog, registers[in.b:in.b+2])
+			if !ok && err == nil {
+				pc = int(in.c)
+			}
+		case vmReturn:
+			if body != nil && len(m.frames) == entry {
+				// the return from the body of the loop is the return of the function the loop is in
+				return nil, pc - 1, nil
+			}
+			if f.defers >= 0 {
+				if _, err = prog.deferred(f.deferredCalls(registers), len(prog.calls)-1, nil); err != nil {
+					break
+				}
+			}
+			params, results := len(f.function.inputVariables), len(f.function.outputVariables)
+			if len(m.frames) == entry {
+				res := make([]any, results)
+				for i, value := range registers[params : params+results] {
+					res[i] = f.result(value, i).boxed()
+				}
+				return res, 0, nil
+			}
+
+			caller := m.frames[len(m.frames)-1]
+			m.frames = m.frames[:len(m.frames)-1]
+			prog.calls = prog.calls[:len(prog.calls)-1]
+			callee, res := f, registers[params:params+results]
+			f, pc, base = caller.function, caller.pc, caller.base
+			registers = m.registers[base : base+f.registers]
+			code, constants = f.code, f.constants
+			if caller.results >= 0 {
+				for i, value := range res {
+					registers[caller.results+i] = callee.result(value, i)
+				}
+			}
+		case vmTailCall:
+			params := len(f.function.inputVariables)
+			copy(registers, registers[in.a:int(in.a)+params])
+			f.zeroResults(registers)
+			pc = 0
+		case vmMissingReturn:
+			err = fmt.Errorf("missing return in function %v", f.function.name)
+		case vmCell:
+			value := operand(in.b).boxed()
+			registers[in.a] = vmValue{ref: &value}
+		case vmLoad:
+			value := *registers[in.b].ref.(*any)
+			if in.c != 0 {
+				value = CloneAny(value)
+			}
+			registers[in.a] = unboxed(value)
+		case vmStore:
+			value := operand(in.b).boxed()
+			if in.c != 0 {
+				value = CloneAny(value)
+			}
+			*registers[in.a].ref.(*any) = value
+		case vmSelect:
+			sel := &f.selects[in.b]
+			channels := make([]*ChannelValue, len(sel.sends))
+			values := make([]any, len(sel.sends))
+			for i, send := range sel.sends {
+				if i == sel.fallback {
+					continue
+				}
+				channels[i], _ = registers[int(in.c)+2*i].ref.(*ChannelValue)
+				if send {
+					values[i] = registers[int(in.c)+2*i+1].boxed()
+				}
+			}
+			i, received, chooseErr := prog.choose(channels, sel.sends, values, sel.fallback)
+			if err = chooseErr; err != nil {
+				break
+			}
+			registers[in.a], registers[in.a+1] = unboxed(received[0]), unboxed(received[1])
+			pc = sel.targets[i]
+		case vmRangeFunc:
+			function, _ := operand(in.b).ref.(Function)
+			if function == nil {
+				err = fmt.Errorf("runtime error: invalid memory address or nil pointer dereference")
+				break
+			}
+			pc, err = m.rangeFunction(prog, f, base, in, function)
+			registers = m.registers[base : base+f.registers]
+		case vmDepth:
+			// the inlined call overflows the stack where the call would
+			if prog.MaxCallDepth > 0 && len(prog.calls) >= prog.MaxCallDepth {
+				callee := constants[-1-in.b].ref.(*IntrpretatedFunction)
+				err = StackOverflowError{Chain: append(slices.Clone(prog.calls), callee.name)}
+			}
+		}
+
+		// the panic unwinds the calls, the deferred calls of each one may recover it
+		for err != nil {
+			if body != nil && len(m.frames) == entry {
+				return nil, 0, err
+			}
+			if f.defers >= 0 {
+				var recovered bool
+				recovered, err = prog.deferred(f.deferredCalls(registers), len(prog.calls)-1, err)
+				if recovered {
+					pc = f.exit
+					break
+				}
+			}
+			if len(m.frames) == entry {
+				return nil, 0, err
+			}
+
+			caller := m.frames[len(m.frames)-1]
+			m.frames = m.frames[:len(m.frames)-1]
+			prog.calls = prog.calls[:len(prog.calls)-1]
+			f, base = caller.function, caller.base
+			registers = m.registers[base : base+f.registers]
+			code, constants = f.code, f.constants
+		}
+	}
+}
+
+// rangeFunction calls the iterator function of the loop, the yield function runs the body in the registers of the loop.
+// The loop goes on at the instruction returned: after the loop or where the body has left to.
+func (m *machine) rangeFunction(prog *Program, f *vmFunction, base int, in *vmInstruction, function Function) (int, error) {
+	after := int(in.c)
+	body := &vmBody{start: int(in.d), end: after - 1}
+	exit := after
+	finished := false
+	var bodyErr error
+	_, err := function.Call(HostFunction{
+		name: "yield",
+		call: func(args ...any) ([]any, error) {
+			if finished {
+				return nil, fmt.Errorf("range function continued iteration after function for loop body returned false")
+			}
+
+			values := m.registers[base+int(in.a) : base+int(in.a)+2]
+			for i, arg := range args[:min(len(args), 2)] {
+				values[i] = unboxed(arg)
+			}
+			_, next, err := m.exec(prog, f, base, body.start, body)
+			if err != nil || next != body.end {
+				finished, exit, bodyErr = true, next, err
+			}
+			return []any{!finished}, nil
+		},
+	})
+	finished = true
+
+	// the return from the body of the loop is the return from the function
+	if bodyErr != nil {
+		return 0, bodyErr
+	}
+	return exit, err
+}
+
+// elementsOf are the elements of the slice or of the array.
+func elementsOf(container any) ([]any, bool) {
+	switch container := container.(type) {
+	case []any:
+		return container, true
+	case *ArrayValue:
+		return container.elems, true
+	}
+
+	return nil, false
+}
+
+var vmArithmetic = [...]func(val1, val2 any) (any, error){AddAny, SubAny, MulAny, DivAny}
+
+func arithmetic(op vmOpcode, x, y int) (vmValue, error) {
+	switch op {
+	case vmAdd:
+		return intValue(x + y), nil
+	case vmSub:
+		return intValue(x - y), nil
+	case vmMul:
+		return intValue(x * y), nil
+	}
+	if y == 0 {
+		return vmValue{}, errDivideByZero
+	}
+	return intValue(x / y), nil
+}
+
+func floatArithmetic(op vmOpcode, x, y float64) vmValue {
+	switch op {
+	case vmAdd:
+		return floatValue(x + y)
+	case vmSub:
+		return floatValue(x - y)
+	case vmMul:
+		return floatValue(x * y)
+	}
+	return floatValue(x / y)
+}
+
+var vmCompareTypes = [...]string{"==", "!=", "<", "<=", ">", ">="}
+
+func compare[T int | float64](op vmOpcode, x, y T) bool {
+	switch op {
+	case vmEq:
+		return x == y
+	case vmNe:
+		return x != y
+	case vmLt:
+		return x < y
+	case vmLe:
+		return x <= y
+	case vmGt:
+		return x > y
+	}
+	return x >= y
+}
+
+// eval runs the escape with the copies of the variables it uses and with the cells of the shared ones.
+func (f *vmFunction) eval(registers []vmValue, in *vmInstruction) error {
+	prog := f.program
+	escape := &f.escapes[in.b]
+	variables := make(map[string]*any, len(escape.names))
+	for i, name := range escape.names {
+		if escape.cells[i] {
+			variables[name] = registers[escape.registers[i]].ref.(*any)
+			continue
+		}
+		// the optimized function may pass the constant for the variable
+		var value any
+		if register := escape.registers[i]; register >= 0 {
+			value = registers[register].boxed()
+		} else {
+			value = f.constants[-1-register].boxed()
+		}
+		variables[name] = &value
+	}
+
+	stacklen := len(prog.stack)
+	defer func() {
+		prog.stack = prog.stack[:stacklen]
+	}()
+	err := escape.instruction.Execute(variables)
+	if err != nil || in.op == vmExec {
+		return err
+	}
+
+	if len(prog.stack) != stacklen+int(in.c) {
+		return fmt.Errorf("wrong count of return values of statement")
+	}
+	for i, value := range prog.stack[stacklen:] {
+		registers[int(in.a)+i] = unboxed(value)
+	}
+	return nil
+}
+
+// vmIterator is the state of the range loop over the container which is not the int.
+type vmIterator struct {
+	kind      rangeKind
+	container any
+	// the keys of the map when the loop has started, the ones deleted since then are skipped
+	keys  []any
+	index int
+}
+
+func newIterator(kind rangeKind, container any) *vmIterator {
+	res := &vmIterator{kind: kind, container: container}
+	if m, ok := container.(map[any]any); ok {
+		res.keys = make([]any, 0, len(m))
+		for key := range m {
+			res.keys = append(res.keys, key)
+		}
+	}
+
+	return res
+}
+
+// next sets the iteration values, ok is false when the loop is over.
+func (it *vmIterator) next(prog *Program, values []vmValue) (ok bool, err error) {
+	switch it.kind {
+	case rangeSlice:
+		slice := elements(it.container)
+		if it.index >= len(slice) {
+			return false, nil
+		}
+		values[0], values[1] = intValue(it.index), unboxed(CloneAny(slice[it.index]))
+		it.index++
+	case rangeString:
+		str := it.container.(string)
+		if it.index >= len(str) {
+			return false, nil
+		}
+		r, size := utf8.DecodeRuneInString(str[it.index:])
+		values[0], values[1] = intValue(it.index), unboxed(r)
+		it.index += size
+	case rangeMap:
+		m := it.container.(map[any]any)
+		for it.index < len(it.keys) {
+			key := it.keys[it.index]
+			it.index++
+			if value, ok := m[key]; ok {
+				values[0], values[1] = unboxed(key), unboxed(CloneAny(value))
+				return true, nil
+			}
+		}
+		return false, nil
+	case rangeInt:
+		switch n := it.container.(type) {
+		case int32:
+			if int32(it.index) >= n {
+				return false, nil
+			}
+			values[0] = unboxed(int32(it.index))
+		case uint8:
+			if it.index >= int(n) {
+				return false, nil
+			}
+			values[0] = unboxed(uint8(it.index))
+		default:
+			return false, nil
+		}
+		it.index++
+	case rangeChannel:
+		ch, _ := it.container.(*ChannelValue)
+		value, ok, err := prog.receive(ch)
+		if err != nil || !ok {
+			return false, err
+		}
+		values[0] = unboxed(value)
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
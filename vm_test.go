@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+const recursion = `package main
+
+import "fmt"
+
+type point struct {
+	x int
+	y int
+}
+
+func fib(n int) int {
+	if n < 2 {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+
+func factorial(n int) int {
+	if n == 0 {
+		return 1;
+	}
+	return n * factorial(n - 1);
+}
+
+func divmod(a, b int) (int, int) {
+	return a / b, a - ((a / b) * b);
+}
+
+func sum(values []int) (total int) {
+	for _, v := range values {
+		total = total + v;
+	}
+	return;
+}
+
+func words(s string) map[string]int {
+	res := map[string]int{};
+	start := 0;
+	for i, r := range s {
+		if r == ' ' {
+			res[s[start:i]] = res[s[start:i]] + 1;
+			start = i + 1;
+		}
+	}
+	res[s[start:]] = res[s[start:]] + 1;
+	return res;
+}
+
+func find(grid [][]int, target int) (int, int) {
+	for i, row := range grid {
+		for j, v := range row {
+			if v == target {
+				return i, j;
+			}
+		}
+	}
+	return len(grid), 0;
+}
+
+func move(p point, dx int) point {
+	p.x = p.x + dx;
+	return p;
+}
+
+func countdown(n int) int {
+	steps := 0;
+loop:
+	if n > 0 {
+		n = n - 1;
+		steps = steps + 1;
+		goto loop;
+	}
+	return steps;
+}
+
+func loop(n int) int {
+	total := 0;
+	for i := range n {
+		total = total + (i * i);
+	}
+	return total;
+}
+
+func main() {
+	fmt.Println(fib(20), factorial(10));
+	q, r := divmod(17, 5);
+	fmt.Println(q, r);
+	fmt.Println(sum([]int{1, 2, 3, 4}));
+	counts := words("a b a c b a");
+	fmt.Println(counts["a"], counts["b"], counts["c"], len(counts));
+	fmt.Println(find([][]int{[]int{1, 2}, []int{3, 4}}, 4));
+	p := point{1, 2};
+	moved := move(p, 10);
+	fmt.Println(p.x, moved.x);
+	fmt.Println(countdown(5));
+	n := 0;
+	for n < 100 {
+		n = n + 7;
+		if n > 50 {
+			break;
+		}
+	}
+	fmt.Println(n);
+}
+`
+
+const recursionOutput = "6765 3628800\n3 2\n10\n3 2 1 3\n1 1\n1 11\n5\n56\n"
+
+const closures = `package main
+
+import "fmt"
+
+func counter() (func() int, func()) {
+	n := 0;
+	return func() int {
+		n = n + 1;
+		return n;
+	}, func() {
+		n = 0;
+	};
+}
+
+func accumulate(n int) (total int) {
+	defer func() {
+		total = total * 2;
+	}();
+	add := func(v int) {
+		total = total + v;
+	};
+	for i := range n {
+		add(i);
+	}
+	return;
+}
+
+func divide(a, b int) (res int, err error) {
+	defer func() {
+		r := recover();
+		if r != nil {
+			err = fmt.Errorf("recovered: %v", r);
+		}
+	}();
+	return a / b, nil;
+}
+
+func fail(n int) int {
+	if n == 0 {
+		panic("fail");
+	}
+	return fail(n - 1) + 1;
+}
+
+func safe(n int) (res string) {
+	defer func() {
+		res = fmt.Sprint("recovered ", recover());
+	}();
+	fail(n);
+	return "ok";
+}
+
+func inc(p *int) {
+	*p = *p + 1;
+}
+
+func squares(n int) func(func(int, int) bool) {
+	return func(yield func(int, int) bool) {
+		for i := range n {
+			if !yield(i, i * i) {
+				return;
+			}
+		}
+	};
+}
+
+func firstOver(limit int) int {
+	for i, sq := range squares(10) {
+		if i != 1 && sq > limit {
+			return sq;
+		}
+	}
+	return -1;
+}
+
+func collect(n int) []int {
+	var res []int;
+	for _, sq := range squares(n) {
+		if sq > 10 {
+			break;
+		}
+		res = append(res, sq);
+	}
+	return res;
+}
+
+func poll(ch chan int) string {
+	select {
+	case v := <-ch:
+		return fmt.Sprint("received ", v);
+	default:
+		return "empty";
+	}
+}
+
+func main() {
+	next, reset := counter();
+	next();
+	next();
+	fmt.Println(next());
+	reset();
+	fmt.Println(next());
+	fmt.Println(accumulate(10));
+	fmt.Println(divide(7, 2));
+	fmt.Println(divide(7, 0));
+	fmt.Println(safe(3));
+	x := 1;
+	inc(&x);
+	fmt.Println(x);
+	fmt.Println(firstOver(20), collect(10));
+	ch := make(chan int, 1);
+	fmt.Println(poll(ch));
+	ch <- 5;
+	fmt.Println(poll(ch));
+	var funcs []func() int;
+	for i := range 3 {
+		funcs = append(funcs, func() int {
+			return i * 10;
+		});
+	}
+	fmt.Println(funcs[0](), funcs[1](), funcs[2]());
+}
+`
+
+const closuresOutput = "3\n1\n90\n3 <nil>\n0 recovered: runtime error: integer divide by zero\nrecovered fail\n2\n25 [0 1 4 9]\nempty\nreceived 5\n0 10 20\n"
+
+func TestVM(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			prog := compileScript(t, recursion)
+			prog.Backend = backend
+
+			var err error
+			output := captureOutput(t, func() {
+				err = prog.Execute(context.Background())
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output != recursionOutput {
+				t.Errorf("the output is %q, %q is expected", output, recursionOutput)
+			}
+		})
+	}
+
+	t.Run("compiled", func(t *testing.T) {
+		prog := compileScript(t, recursion)
+		prog.Backend = BackendVM
+		prog.Lower()
+
+		for _, name := range []string{"fib", "factorial", "divmod", "sum", "words", "find", "move", "countdown", "loop", "main"} {
+			function := prog.functions[prog.functionID[MainPackagePath+"."+name]].(*IntrpretatedFunction)
+			if function.vm == nil {
+				t.Errorf("%v is not compiled into the bytecode", name)
+			}
+		}
+	})
+
+	for _, backend := range backends {
+		t.Run("closures/"+backend, func(t *testing.T) {
+			prog := compileScript(t, closures)
+			prog.Backend = backend
+
+			var err error
+			output := captureOutput(t, func() {
+				err = prog.Execute(context.Background())
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output != closuresOutput {
+				t.Errorf("the output is %q, %q is expected", output, closuresOutput)
+			}
+		})
+	}
+
+	t.Run("closures compiled", func(t *testing.T) {
+		prog := compileScript(t, closures)
+		prog.Backend = BackendVM
+		prog.Lower()
+
+		// the function literals, the deferred calls, the select statements and the loops over the functions
+		// are run by the machine
+		for _, function := range prog.functions {
+			if function, ok := function.(*IntrpretatedFunction); ok && function.vm == nil {
+				t.Errorf("%v is not compiled into the bytecode", function.Name())
+			}
+		}
+	})
+
+	t.Run("no allocations", func(t *testing.T) {
+		// the default backend keeps the numbers in the registers and passes them to the calls unboxed,
+		// so the iterations of the loop and the recursive calls allocate nothing
+		prog := compileScript(t, recursion)
+		prog.Lower()
+		prog.start(context.Background())
+
+		for _, call := range []struct {
+			name        string
+			short, long int
+		}{{"loop", 1000, 100000}, {"fib", 15, 25}} {
+			function := prog.functions[prog.functionID[MainPackagePath+"."+call.name]]
+			allocs := func(n int) float64 {
+				return testing.AllocsPerRun(10, func() {
+					if _, err := function.Call(n); err != nil {
+						t.Fatal(err)
+					}
+				})
+			}
+			if short, long := allocs(call.short), allocs(call.long); long != short {
+				t.Errorf("%v(%v) allocates %v times, %v(%v) %v times", call.name, call.long, long, call.name, call.short, short)
+			}
+		}
+	})
+
+	t.Run("divide by zero", func(t *testing.T) {
+		prog := compileScript(t, "package main\n\nfunc main() {\n\ta := 0;\n\ta = 1 / a;\n}\n")
+		prog.Backend = BackendVM
+
+		err := prog.Execute(context.Background())
+		if err != errDivideByZero {
+			t.Errorf("the error is %v, %q is expected", err, errDivideByZero)
+		}
+	})
+}
+
+// benchmarkCall measures the calls of the function of the script on each backend
+// and on the machine running the optimized bytecode.
+func benchmarkCall(b *testing.B, source string, name string, arg int, expected int) {
+	for _, backend := range backends {
+		b.Run(backend, func(b *testing.B) {
+			benchmarkBackend(b, source, backend, 0, name, arg, expected)
+		})
+	}
+	b.Run(BackendVM+"-O2", func(b *testing.B) {
+		benchmarkBackend(b, source, BackendVM, 2, name, arg, expected)
+	})
+}
+
+func benchmarkBackend(b *testing.B, source string, backend string, level int, name string, arg int, expected int) {
+	prog := compileScript(b, source)
+	prog.Backend = backend
+	prog.Optimization = level
+	prog.Lower()
+	prog.start(context.Background())
+	function := prog.functions[prog.functionID[MainPackagePath+"."+name]]
+
+	b.ResetTimer()
+	for range b.N {
+		res, err := function.Call(arg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if res[0] != expected {
+			b.Fatalf("%v(%v) is %v, %v is expected", name, arg, res[0], expected)
+		}
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkCall(b, recursion, "fib", 20, 6765)
+}
+
+func BenchmarkFactorial(b *testing.B) {
+	benchmarkCall(b, recursion, "factorial", 10, 3628800)
+}
+
+func BenchmarkLoop(b *testing.B) {
+	b.ReportAllocs()
+	benchmarkCall(b, recursion, "loop", 1000, 332833500)
+}
+
+// BenchmarkClosure measures the function of the deferred call and of the closure called in the loop.
+func BenchmarkClosure(b *testing.B) {
+	benchmarkCall(b, closures, "accumulate", 1000, 999000)
+}